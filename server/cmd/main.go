@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,8 +15,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
+	"github.com/visual-api-testing-platform/server/internal/auth"
 	"github.com/visual-api-testing-platform/server/internal/engine"
 	"github.com/visual-api-testing-platform/server/internal/handlers"
+	"github.com/visual-api-testing-platform/server/internal/jobs"
+	"github.com/visual-api-testing-platform/server/internal/mail"
+	"github.com/visual-api-testing-platform/server/internal/ratelimit"
 	"github.com/visual-api-testing-platform/server/internal/repository"
 )
 
@@ -44,15 +50,43 @@ func main() {
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(pool)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(pool)
+	userTokenRepo := repository.NewUserTokenRepository(pool)
 	flowRepo := repository.NewFlowRepository(pool)
+	flowVersionRepo := repository.NewFlowVersionRepository(pool)
 	testRunRepo := repository.NewTestRunRepository(pool)
+	loadRunRepo := repository.NewLoadRunRepository(pool)
+	auditRepo := repository.NewAuditRepository(pool)
+	analyticsRepo := repository.NewAnalyticsRepository(pool)
+	quarantineRepo := repository.NewQuarantineRepository(pool)
+	snapshotRepo := repository.NewSnapshotRepository(pool)
+	schemaRepo := repository.NewSchemaRepository(pool)
+	specRepo := repository.NewAPISpecRepository(pool)
+
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	auditRetentionDays := envInt("AUDIT_RETENTION_DAYS", 365)
+	if auditRetentionDays > 0 {
+		retention := time.Duration(auditRetentionDays) * 24 * time.Hour
+		go jobs.Every(jobsCtx, "audit retention", 24*time.Hour, jobs.AuditRetention(auditRepo, retention))
+	}
+
+	// Test runs outside the retention policy keep their status and durations but lose node outputs
+	testRunKeepRuns := envInt("TEST_RUN_RETENTION_COUNT", 100)
+	testRunKeepDays := envInt("TEST_RUN_RETENTION_DAYS", 30)
+	if testRunKeepRuns > 0 || testRunKeepDays > 0 {
+		keepFor := time.Duration(testRunKeepDays) * 24 * time.Hour
+		go jobs.Every(jobsCtx, "test run retention", time.Hour, jobs.TestRunRetention(testRunRepo, testRunKeepRuns, keepFor))
+	}
 
 	// Initialize execution hub
 	hub := engine.NewExecutionHub()
 	go hub.Run()
 
 	// Initialize flow runner
-	flowRunner := engine.NewFlowRunner(hub)
+	flowRunner := engine.NewFlowRunner(hub, snapshotRepo, schemaRepo, specRepo)
 
 	// Initialize handlers
 	jwtSecret := os.Getenv("JWT_SECRET")
@@ -60,12 +94,46 @@ func main() {
 		jwtSecret = "your-secret-key-change-in-production"
 	}
 
-	authHandler := handlers.NewAuthHandler(userRepo, jwtSecret)
-	flowHandler := handlers.NewFlowHandler(flowRepo)
-	nodeHandler := handlers.NewNodeHandler(flowRepo)
-	testRunHandler := handlers.NewTestRunHandler(testRunRepo, flowRepo, flowRunner)
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:5173"
+	}
+
+	accountHandler := handlers.NewAccountHandler(userRepo, userTokenRepo, newMailSender(), appURL)
+	authHandler := handlers.NewAuthHandler(userRepo, loginAttemptRepo, auditRepo, accountHandler, jwtSecret)
+	flowHandler := handlers.NewFlowHandler(flowRepo, auditRepo)
+	flowVersionHandler := handlers.NewFlowVersionHandler(flowRepo, flowVersionRepo, auditRepo)
+	nodeHandler := handlers.NewNodeHandler(flowRepo, specRepo)
+	testRunHandler := handlers.NewTestRunHandler(testRunRepo, flowRepo, quarantineRepo, auditRepo, flowRunner)
+	loadTestHandler := handlers.NewLoadTestHandler(loadRunRepo, flowRepo, quarantineRepo, auditRepo, flowRunner)
+	auditHandler := handlers.NewAuditHandler(auditRepo, userRepo)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsRepo, flowRepo, quarantineRepo)
+	quarantineHandler := handlers.NewQuarantineHandler(quarantineRepo, flowRepo, auditRepo)
+	snapshotHandler := handlers.NewSnapshotHandler(snapshotRepo, flowRepo, testRunRepo, auditRepo)
+	schemaHandler := handlers.NewSchemaHandler(schemaRepo, userRepo, auditRepo)
+	specHandler := handlers.NewAPISpecHandler(specRepo, userRepo, auditRepo)
+	importHandler := handlers.NewImportHandler(flowRepo, specRepo, userRepo, auditRepo)
+	exportHandler := handlers.NewExportHandler(flowRepo, schemaRepo, specRepo)
+	coverageHandler := handlers.NewCoverageHandler(specRepo, flowRepo, testRunRepo)
 	wsHandler := handlers.NewWebSocketHandler(hub)
 
+	// OIDC single sign-on is enabled when an issuer is configured
+	var oidcHandler *handlers.OIDCHandler
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		provider := auth.NewOIDCProvider(auth.OIDCConfig{
+			Issuer:               issuer,
+			ClientID:             os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret:         os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:          os.Getenv("OIDC_REDIRECT_URL"),
+			GroupsClaim:          os.Getenv("OIDC_GROUPS_CLAIM"),
+			GroupRoles:           parseGroupRoles(os.Getenv("OIDC_GROUP_ROLES")),
+			DefaultRole:          os.Getenv("OIDC_DEFAULT_ROLE"),
+			TrustUnverifiedEmail: os.Getenv("OIDC_TRUST_UNVERIFIED_EMAIL") == "true",
+		})
+		oidcHandler = handlers.NewOIDCHandler(provider, userRepo, authHandler, os.Getenv("OIDC_POST_LOGIN_REDIRECT"))
+		log.Printf("OIDC single sign-on enabled for issuer %s", issuer)
+	}
+
 	// Setup Gin router
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
@@ -73,11 +141,26 @@ func main() {
 
 	router := gin.Default()
 
+	// Only honour X-Forwarded-For from known proxies, otherwise clients could
+	// spoof their IP and sidestep per-IP rate limits
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	// Rate limiters
+	authLimiter := ratelimit.New(10, time.Minute)      // per IP on login/register
+	executionLimiter := ratelimit.New(30, time.Minute) // per user on flow and node execution
+
 	// CORS configuration
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"http://localhost:5173", "http://localhost:3000"}
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "If-Match"}
+	config.ExposeHeaders = []string{"Retry-After", "ETag"}
 	config.AllowCredentials = true
 	router.Use(cors.New(config))
 
@@ -89,16 +172,37 @@ func main() {
 	// Auth routes (public)
 	api := router.Group("/api")
 	{
-		auth := api.Group("/auth")
+		authRoutes := api.Group("/auth")
+		authRoutes.Use(handlers.RateLimitMiddleware(authLimiter))
 		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
+			authRoutes.POST("/register", authHandler.Register)
+			authRoutes.POST("/login", authHandler.Login)
+
+			if oidcHandler != nil {
+				authRoutes.GET("/oidc/login", oidcHandler.Login)
+				authRoutes.GET("/oidc/callback", oidcHandler.Callback)
+			}
+
+			authRoutes.POST("/password/forgot", accountHandler.ForgotPassword)
+			authRoutes.POST("/password/reset", accountHandler.ResetPassword)
+			authRoutes.POST("/email/verify", accountHandler.VerifyEmail)
 		}
 
 		// Protected routes
 		protected := api.Group("/")
 		protected.Use(authHandler.AuthMiddleware())
 		{
+			// Current user
+			me := protected.Group("/me")
+			{
+				me.GET("", accountHandler.GetMe)
+				me.PUT("", accountHandler.UpdateMe)
+				me.DELETE("", accountHandler.DeleteMe)
+				me.PUT("/password", accountHandler.ChangePassword)
+				me.POST("/email/verification", accountHandler.ResendVerification)
+				me.POST("/confirmation", accountHandler.RequestConfirmation)
+			}
+
 			// Flows
 			flows := protected.Group("/flows")
 			{
@@ -107,24 +211,94 @@ func main() {
 				flows.GET("/:id", flowHandler.GetFlow)
 				flows.PUT("/:id", flowHandler.UpdateFlow)
 				flows.DELETE("/:id", flowHandler.DeleteFlow)
+				flows.GET("/:id/export", exportHandler.ExportFlow)
+				flows.POST("/import", importHandler.ImportFlow)
+
+				// Single-node edits
+				flows.POST("/:id/nodes", flowHandler.CreateNode)
+				flows.PATCH("/:id/nodes/:nodeId", flowHandler.UpdateNode)
+				flows.DELETE("/:id/nodes/:nodeId", flowHandler.DeleteNode)
+
+				// Version history
+				flows.GET("/:id/versions", flowVersionHandler.ListVersions)
+				flows.GET("/:id/versions/:version", flowVersionHandler.GetVersion)
+				flows.POST("/:id/versions/:version/restore", flowVersionHandler.RestoreVersion)
+				flows.GET("/:id/diff", flowVersionHandler.DiffVersions)
 
 				// Test runs
-				flows.POST("/:id/run", testRunHandler.RunFlow)
+				flows.POST("/:id/run", handlers.RateLimitMiddleware(executionLimiter), testRunHandler.RunFlow)
 				flows.GET("/:id/test-runs", testRunHandler.GetTestRunsByFlow)
+				flows.GET("/:id/stats", analyticsHandler.GetFlowStats)
+				flows.GET("/:id/flaky", analyticsHandler.GetFlakyNodes)
+				flows.PUT("/:id/nodes/:nodeId/quarantine", quarantineHandler.QuarantineNode)
+				flows.DELETE("/:id/nodes/:nodeId/quarantine", quarantineHandler.ReleaseNode)
+				flows.GET("/:id/nodes/:nodeId/snapshot", snapshotHandler.GetSnapshot)
+				flows.POST("/:id/nodes/:nodeId/snapshot/approve", snapshotHandler.ApproveSnapshot)
+				flows.DELETE("/:id/nodes/:nodeId/snapshot", snapshotHandler.DeleteSnapshot)
+
+				// Load tests
+				flows.POST("/:id/load", handlers.RateLimitMiddleware(executionLimiter), loadTestHandler.StartLoadTest)
+				flows.GET("/:id/load-runs", loadTestHandler.ListLoadRuns)
 			}
 
 			// Nodes
 			nodes := protected.Group("/nodes")
 			{
-				nodes.POST("/:flowId/:nodeId/execute", nodeHandler.ExecuteNode)
+				nodes.POST("/:flowId/:nodeId/execute", handlers.RateLimitMiddleware(executionLimiter), nodeHandler.ExecuteNode)
 			}
 
 			// Test runs
 			testRuns := protected.Group("/test-runs")
 			{
+				testRuns.GET("", testRunHandler.ListTestRuns)
 				testRuns.GET("/:id", testRunHandler.GetTestRun)
+				testRuns.POST("/:id/cancel", testRunHandler.CancelTestRun)
+				testRuns.GET("/:id/compare/:otherId", testRunHandler.CompareTestRuns)
+			}
+
+			// Load runs
+			loadRuns := protected.Group("/load-runs")
+			{
+				loadRuns.GET("/:id", loadTestHandler.GetLoadRun)
+				loadRuns.POST("/:id/cancel", loadTestHandler.CancelLoadRun)
 			}
 
+			// Shared JSON Schema library
+			schemas := protected.Group("/schemas")
+			{
+				schemas.GET("", schemaHandler.ListSchemas)
+				schemas.POST("", schemaHandler.CreateSchema)
+				schemas.GET("/:id", schemaHandler.GetSchema)
+				schemas.PUT("/:id", schemaHandler.UpdateSchema)
+				schemas.DELETE("/:id", schemaHandler.DeleteSchema)
+			}
+
+			// OpenAPI spec library
+			specs := protected.Group("/specs")
+			{
+				specs.GET("", specHandler.ListSpecs)
+				specs.POST("", specHandler.CreateSpec)
+				specs.GET("/:id", specHandler.GetSpec)
+				specs.GET("/:id/operations", specHandler.ListOperations)
+				specs.PUT("/:id", specHandler.UpdateSpec)
+				specs.DELETE("/:id", specHandler.DeleteSpec)
+			}
+
+			// Flow generation from API descriptions and captured requests
+			imports := protected.Group("/import")
+			{
+				imports.POST("/openapi", importHandler.ImportOpenAPI)
+				imports.POST("/postman", importHandler.ImportPostman)
+				imports.POST("/har", importHandler.ImportHAR)
+				imports.POST("/curl", importHandler.ImportCurl)
+			}
+
+			// Spec coverage of the user's flows
+			protected.GET("/coverage", coverageHandler.GetCoverage)
+
+			// Audit log
+			protected.GET("/audit", auditHandler.ListEvents)
+
 			// WebSocket
 			protected.GET("/ws", wsHandler.HandleWebSocket)
 		}
@@ -155,6 +329,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -166,3 +341,41 @@ func main() {
 	log.Println("Server exited")
 }
 
+// newMailSender picks the mail transport: SMTP when MAIL_SMTP_ADDR is set,
+// .eml files in MAIL_DIR as a local stand-in, or the server log otherwise
+func newMailSender() mail.Sender {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	if addr := os.Getenv("MAIL_SMTP_ADDR"); addr != "" {
+		return mail.NewSMTPSender(addr, os.Getenv("MAIL_SMTP_USERNAME"), os.Getenv("MAIL_SMTP_PASSWORD"), from)
+	}
+	if dir := os.Getenv("MAIL_DIR"); dir != "" {
+		return mail.NewFileSender(dir, from)
+	}
+	return mail.LogSender{}
+}
+
+// parseGroupRoles parses a mapping like "qa-admins=admin,qa=editor"
+func parseGroupRoles(value string) map[string]string {
+	roles := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		group, role, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && group != "" && role != "" {
+			roles[strings.TrimSpace(group)] = strings.TrimSpace(role)
+		}
+	}
+	return roles
+}
+
+// envInt reads an integer environment variable, returning def when it is unset or invalid
+func envInt(name string, def int) int {
+	if value := os.Getenv(name); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return def
+}
@@ -1,5 +1,6 @@
 -- Enable UUID extension
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
 
 -- Users table
 CREATE TABLE IF NOT EXISTS users (
@@ -7,6 +8,10 @@ CREATE TABLE IF NOT EXISTS users (
     email VARCHAR(255) UNIQUE NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
     name VARCHAR(255),
+    role VARCHAR(50) NOT NULL DEFAULT 'editor', -- Workspace role: admin, editor, viewer
+    oidc_issuer VARCHAR(255), -- Identity provider the account is linked to
+    oidc_subject VARCHAR(255), -- Subject claim at that identity provider
+    email_verified_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -19,6 +24,12 @@ CREATE TABLE IF NOT EXISTS flows (
     description TEXT,
     tags TEXT[], -- Array of tags
     edges JSONB NOT NULL DEFAULT '[]'::jsonb, -- Array of edges
+    variables JSONB NOT NULL DEFAULT '{}'::jsonb, -- Values available to nodes during runs
+    version INTEGER NOT NULL DEFAULT 1, -- Incremented on every save
+    search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+    ) STORED,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -38,32 +49,194 @@ CREATE TABLE IF NOT EXISTS flow_nodes (
     data_config JSONB DEFAULT '{}'::jsonb,
     data_output JSONB,
     data_error TEXT,
+    sort_order INTEGER NOT NULL DEFAULT 0, -- Position of the node within the flow
+    search_vector tsvector GENERATED ALWAYS AS (
+        to_tsvector('simple', coalesce(data_label, '') || ' ' || coalesce(data_config->>'url', ''))
+    ) STORED,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     UNIQUE(flow_id, node_id) -- Ensure node_id is unique per flow
 );
 
+-- Flow versions table: immutable snapshots of flow definitions, one per save
+CREATE TABLE IF NOT EXISTS flow_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    tags TEXT[],
+    nodes JSONB NOT NULL DEFAULT '[]'::jsonb, -- Array of nodes without runtime status/output
+    edges JSONB NOT NULL DEFAULT '[]'::jsonb,
+    variables JSONB NOT NULL DEFAULT '{}'::jsonb,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(flow_id, version)
+);
+
 -- Test runs table
 CREATE TABLE IF NOT EXISTS test_runs (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     flow_id UUID REFERENCES flows(id) ON DELETE CASCADE,
+    flow_version INTEGER, -- Flow version that was executed
     status VARCHAR(50) NOT NULL, -- pending, running, success, failed
-    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    completed_at TIMESTAMP,
+    started_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ,
     duration_ms INTEGER,
     node_results JSONB DEFAULT '{}'::jsonb, -- Map of node_id -> {status, output, error, duration}
     error TEXT,
+    trigger_source VARCHAR(50) NOT NULL DEFAULT 'manual', -- manual, api, schedule, ci
+    environment VARCHAR(100) NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    compacted_at TIMESTAMPTZ, -- Set when retention removed node outputs
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Test run nodes table (per-node status and duration of each run, for analytics)
+CREATE TABLE IF NOT EXISTS test_run_nodes (
+    test_run_id UUID NOT NULL REFERENCES test_runs(id) ON DELETE CASCADE,
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    node_id VARCHAR(255) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    quarantined BOOLEAN NOT NULL DEFAULT false, -- Node was quarantined when the run started
+    created_at TIMESTAMPTZ NOT NULL, -- Start time of the run
+    PRIMARY KEY (test_run_id, node_id)
+);
+
+-- Load test runs (aggregated metrics of virtual users executing a flow repeatedly)
+CREATE TABLE IF NOT EXISTS load_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    flow_version INTEGER, -- Flow version that was executed
+    status VARCHAR(50) NOT NULL, -- running, success, failed, cancelled
+    config JSONB NOT NULL, -- vus, ramp-up, duration or iterations, think time, thresholds
+    metrics JSONB, -- Request rates, error rates and latency percentiles per node
+    thresholds JSONB NOT NULL DEFAULT '[]'::jsonb, -- Threshold results
+    error TEXT,
+    environment VARCHAR(100) NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    started_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    duration_ms INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Node quarantines (failures of these nodes do not fail the run)
+CREATE TABLE IF NOT EXISTS node_quarantines (
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    node_id VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    quarantined_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (flow_id, node_id)
+);
+
+-- Shared JSON Schema library for schema assertions
+CREATE TABLE IF NOT EXISTS schemas (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    schema JSONB NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Shared OpenAPI spec library for API node contract validation
+CREATE TABLE IF NOT EXISTS api_specs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    api_version VARCHAR(255) NOT NULL DEFAULT '',
+    document JSONB NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Golden snapshots for snapshot assertions (approved_by is NULL when recorded by the first run)
+CREATE TABLE IF NOT EXISTS node_snapshots (
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    node_id VARCHAR(255) NOT NULL,
+    value JSONB NOT NULL,
+    source_test_run_id UUID REFERENCES test_runs(id) ON DELETE SET NULL,
+    approved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (flow_id, node_id)
+);
+
+-- Login attempts table (audit trail for password logins and account lockout)
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email VARCHAR(255) NOT NULL, -- Lower-cased email the attempt was made for
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL for unknown emails
+    ip_address VARCHAR(64),
+    user_agent TEXT,
+    success BOOLEAN NOT NULL,
+    reason VARCHAR(50) NOT NULL DEFAULT '', -- invalid_credentials, unknown_user, locked
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- User tokens table (single-use password reset / email verification tokens)
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(50) NOT NULL, -- password_reset, email_verification, account_confirmation
+    token_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 of the emailed token
+    email VARCHAR(255) NOT NULL, -- Address the token was sent to
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Audit events table (append-only record of flow edits, test runs and auth events)
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    actor_email VARCHAR(255) NOT NULL DEFAULT '', -- Kept after the actor is deleted
+    action VARCHAR(100) NOT NULL, -- e.g. flow.update, test_run.start, auth.login_failed
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL DEFAULT '',
+    ip_address VARCHAR(64),
+    user_agent TEXT,
+    metadata JSONB DEFAULT '{}'::jsonb,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
 -- Indexes for better performance
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oidc_identity ON users(oidc_issuer, oidc_subject) WHERE oidc_subject IS NOT NULL;
 CREATE INDEX IF NOT EXISTS idx_flows_user_id ON flows(user_id);
 CREATE INDEX IF NOT EXISTS idx_flows_created_at ON flows(created_at);
+CREATE INDEX IF NOT EXISTS idx_flows_user_id_updated_at ON flows(user_id, updated_at DESC, id);
+CREATE INDEX IF NOT EXISTS idx_flows_search_vector ON flows USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_flows_tags ON flows USING GIN (tags);
 CREATE INDEX IF NOT EXISTS idx_flow_nodes_flow_id ON flow_nodes(flow_id);
 CREATE INDEX IF NOT EXISTS idx_flow_nodes_node_id ON flow_nodes(flow_id, node_id);
+CREATE INDEX IF NOT EXISTS idx_flow_nodes_sort_order ON flow_nodes(flow_id, sort_order);
+CREATE INDEX IF NOT EXISTS idx_flow_nodes_search_vector ON flow_nodes USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_flow_nodes_type ON flow_nodes(type, flow_id);
+CREATE INDEX IF NOT EXISTS idx_flow_nodes_url_trgm ON flow_nodes USING GIN ((data_config->>'url') gin_trgm_ops);
 CREATE INDEX IF NOT EXISTS idx_test_runs_flow_id ON test_runs(flow_id);
+CREATE INDEX IF NOT EXISTS idx_test_runs_flow_id_created_at ON test_runs(flow_id, created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_test_runs_status ON test_runs(status);
 CREATE INDEX IF NOT EXISTS idx_test_runs_created_at ON test_runs(created_at);
+CREATE INDEX IF NOT EXISTS idx_test_runs_created_at_id ON test_runs(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_test_runs_tags ON test_runs USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_test_run_nodes_flow_created_at ON test_run_nodes(flow_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_test_runs_flow_version ON test_runs(flow_id, flow_version);
+CREATE INDEX IF NOT EXISTS idx_load_runs_flow_id_created_at ON load_runs(flow_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email_created_at ON login_attempts(email, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created_at ON login_attempts(ip_address, created_at);
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens(user_id, purpose);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_resource ON audit_events(resource_type, resource_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events(action, created_at);
 
 -- Function to update updated_at timestamp
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -84,3 +257,46 @@ CREATE TRIGGER update_flows_updated_at BEFORE UPDATE ON flows
 CREATE TRIGGER update_flow_nodes_updated_at BEFORE UPDATE ON flow_nodes
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+-- Audit events are append-only. The only permitted update is clearing actor_id
+-- when the actor's account is deleted (ON DELETE SET NULL); retention uses DELETE.
+CREATE OR REPLACE FUNCTION prevent_audit_event_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.actor_id IS NOT NULL AND NEW.actor_id IS NULL
+       AND (to_jsonb(NEW) - 'actor_id') = (to_jsonb(OLD) - 'actor_id') THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION prevent_audit_event_update();
+
+-- Migrations applied by migrate.sh. This schema already includes every
+-- migration below, so they are recorded as applied.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    filename VARCHAR(255) PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO schema_migrations (filename) VALUES
+    ('migration_001_separate_nodes.sql'),
+    ('migration_002_oidc_users.sql'),
+    ('migration_003_login_attempts.sql'),
+    ('migration_004_account_management.sql'),
+    ('migration_005_audit_events.sql'),
+    ('migration_006_flow_versions.sql'),
+    ('migration_007_node_sort_order.sql'),
+    ('migration_008_flow_summaries.sql'),
+    ('migration_009_flow_search.sql'),
+    ('migration_010_test_run_history.sql'),
+    ('migration_011_test_run_nodes.sql'),
+    ('migration_012_node_quarantine.sql'),
+    ('migration_013_node_snapshots.sql'),
+    ('migration_014_schemas.sql'),
+    ('migration_015_flow_variables.sql'),
+    ('migration_016_api_specs.sql'),
+    ('migration_017_load_runs.sql'),
+    ('migration_018_run_timestamptz.sql')
+ON CONFLICT (filename) DO NOTHING;
@@ -1,7 +1,8 @@
 #!/bin/bash
 
 # Database migration script
-# This script runs the migration to separate nodes into their own table
+# This script runs every migration_*.sql file that has not been applied yet, in order.
+# Applied files are recorded in schema_migrations in the same transaction that applies them.
 
 set -e
 
@@ -23,24 +24,46 @@ echo "Database: $DB_NAME"
 echo "Host: $DB_HOST:$DB_PORT"
 echo ""
 
-# Check if migration file exists
-if [ ! -f "migration_001_separate_nodes.sql" ]; then
-    echo -e "${RED}Error: migration_001_separate_nodes.sql not found${NC}"
+# Check if migration files exist
+shopt -s nullglob
+migrations=(migration_*.sql)
+if [ ${#migrations[@]} -eq 0 ]; then
+    echo -e "${RED}Error: no migration_*.sql files found${NC}"
     exit 1
 fi
 
-# Run migration
-echo -e "${YELLOW}Running migration_001_separate_nodes.sql...${NC}"
-PGPASSWORD=$DB_PASSWORD psql -h $DB_HOST -p $DB_PORT -U $DB_USER -d $DB_NAME -f migration_001_separate_nodes.sql
-
-if [ $? -eq 0 ]; then
-    echo -e "${GREEN}Migration completed successfully!${NC}"
-    echo ""
-    echo -e "${YELLOW}Note: The 'nodes' column is still in the flows table for safety.${NC}"
-    echo -e "${YELLOW}After verifying the migration worked, you can remove it manually:${NC}"
-    echo -e "${YELLOW}ALTER TABLE flows DROP COLUMN IF EXISTS nodes;${NC}"
-else
-    echo -e "${RED}Migration failed!${NC}"
+psql_db() {
+    PGPASSWORD=$DB_PASSWORD psql -v ON_ERROR_STOP=1 -h "$DB_HOST" -p "$DB_PORT" -U "$DB_USER" -d "$DB_NAME" "$@"
+}
+
+if ! psql_db -q -c "CREATE TABLE IF NOT EXISTS schema_migrations (
+        filename VARCHAR(255) PRIMARY KEY,
+        applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+    )"; then
+    echo -e "${RED}Could not create the schema_migrations table${NC}"
     exit 1
 fi
+applied=$(psql_db -tA -c "SELECT filename FROM schema_migrations")
+
+# Run migrations (file names are zero-padded, so glob order is apply order)
+for migration in "${migrations[@]}"; do
+    if [[ ! "$migration" =~ ^migration_[A-Za-z0-9_]+\.sql$ ]]; then
+        echo -e "${RED}Unexpected migration file name: $migration${NC}"
+        exit 1
+    fi
+    if grep -qxF "$migration" <<< "$applied"; then
+        echo "Skipping $migration (already applied)"
+        continue
+    fi
+    echo -e "${YELLOW}Running $migration...${NC}"
+    if ! psql_db -1 -f "$migration" -c "INSERT INTO schema_migrations (filename) VALUES ('$migration')"; then
+        echo -e "${RED}Migration $migration failed!${NC}"
+        exit 1
+    fi
+done
 
+echo -e "${GREEN}Migrations completed successfully!${NC}"
+echo ""
+echo -e "${YELLOW}Note: The 'nodes' column is still in the flows table for safety.${NC}"
+echo -e "${YELLOW}After verifying the migration worked, you can remove it manually:${NC}"
+echo -e "${YELLOW}ALTER TABLE flows DROP COLUMN IF EXISTS nodes;${NC}"
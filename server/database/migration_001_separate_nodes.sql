@@ -22,7 +22,9 @@ CREATE TABLE IF NOT EXISTS flow_nodes (
 );
 
 -- Step 2: Migrate existing nodes from flows.nodes JSONB to flow_nodes table
--- This handles existing data by extracting nodes from the JSONB column
+-- This handles existing data by extracting nodes from the JSONB column. It is
+-- skipped once flow_nodes has rows, because flows.nodes is no longer kept up
+-- to date and copying it again would bring back deleted nodes.
 DO $$
 DECLARE
     flow_record RECORD;
@@ -39,6 +41,13 @@ DECLARE
     data_output_val JSONB;
     data_error_val TEXT;
 BEGIN
+    IF EXISTS (SELECT 1 FROM flow_nodes) OR NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'flows' AND column_name = 'nodes'
+    ) THEN
+        RETURN;
+    END IF;
+
     -- Loop through all flows
     FOR flow_record IN SELECT id, nodes FROM flows WHERE nodes IS NOT NULL AND nodes != '[]'::jsonb
     LOOP
@@ -134,6 +143,7 @@ CREATE INDEX IF NOT EXISTS idx_flow_nodes_flow_id ON flow_nodes(flow_id);
 CREATE INDEX IF NOT EXISTS idx_flow_nodes_node_id ON flow_nodes(flow_id, node_id);
 
 -- Step 4: Create trigger for updated_at timestamp
+DROP TRIGGER IF EXISTS update_flow_nodes_updated_at ON flow_nodes;
 CREATE TRIGGER update_flow_nodes_updated_at BEFORE UPDATE ON flow_nodes
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
@@ -0,0 +1,16 @@
+-- Migration: Login attempt audit table
+-- Backs per-account lockout after repeated failed logins
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email VARCHAR(255) NOT NULL, -- Lower-cased email the attempt was made for
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL for unknown emails
+    ip_address VARCHAR(64),
+    user_agent TEXT,
+    success BOOLEAN NOT NULL,
+    reason VARCHAR(50) NOT NULL DEFAULT '', -- invalid_credentials, unknown_user, locked
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email_created_at ON login_attempts(email, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created_at ON login_attempts(ip_address, created_at);
@@ -0,0 +1,17 @@
+-- Migration: Account management
+-- Adds email verification and single-use tokens for password reset / email verification
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(50) NOT NULL, -- password_reset, email_verification
+    token_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 of the emailed token
+    email VARCHAR(255) NOT NULL, -- Address the token was sent to
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens(user_id, purpose);
@@ -0,0 +1,37 @@
+-- Migration: Audit log
+-- Append-only record of flow edits, test runs and auth events
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    actor_email VARCHAR(255) NOT NULL DEFAULT '', -- Kept after the actor is deleted
+    action VARCHAR(100) NOT NULL, -- e.g. flow.update, test_run.start, auth.login_failed
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL DEFAULT '',
+    ip_address VARCHAR(64),
+    user_agent TEXT,
+    metadata JSONB DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_resource ON audit_events(resource_type, resource_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events(action, created_at);
+
+-- Audit events are append-only. The only permitted update is clearing actor_id
+-- when the actor's account is deleted (ON DELETE SET NULL); retention uses DELETE.
+CREATE OR REPLACE FUNCTION prevent_audit_event_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.actor_id IS NOT NULL AND NEW.actor_id IS NULL
+       AND (to_jsonb(NEW) - 'actor_id') = (to_jsonb(OLD) - 'actor_id') THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION prevent_audit_event_update();
@@ -0,0 +1,56 @@
+-- Migration: Flow version history
+-- Every save creates an immutable snapshot of the flow definition in flow_versions,
+-- and each test run records the version it executed
+
+ALTER TABLE flows ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE test_runs ADD COLUMN IF NOT EXISTS flow_version INTEGER;
+
+CREATE TABLE IF NOT EXISTS flow_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    tags TEXT[],
+    nodes JSONB NOT NULL DEFAULT '[]'::jsonb, -- Array of nodes without runtime status/output
+    edges JSONB NOT NULL DEFAULT '[]'::jsonb,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(flow_id, version)
+);
+
+-- Existing flows start their history at version 1
+INSERT INTO flow_versions (flow_id, version, name, description, tags, nodes, edges, author_id, message, created_at)
+SELECT
+    f.id,
+    f.version,
+    f.name,
+    f.description,
+    f.tags,
+    COALESCE((
+        SELECT jsonb_agg(
+            jsonb_build_object(
+                'id', n.node_id,
+                'type', n.type,
+                'position', jsonb_build_object('x', n.position_x, 'y', n.position_y),
+                'data', jsonb_build_object(
+                    'id', n.data_id,
+                    'type', n.data_type,
+                    'label', COALESCE(n.data_label, ''),
+                    'config', COALESCE(n.data_config, '{}'::jsonb)
+                )
+            )
+            ORDER BY n.created_at
+        )
+        FROM flow_nodes n
+        WHERE n.flow_id = f.id
+    ), '[]'::jsonb),
+    f.edges,
+    f.user_id,
+    'Initial version',
+    f.updated_at
+FROM flows f
+ON CONFLICT (flow_id, version) DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_test_runs_flow_version ON test_runs(flow_id, flow_version);
@@ -0,0 +1,18 @@
+-- Migration: Stable node ordering
+-- Nodes are now upserted in place instead of being deleted and reinserted on every save,
+-- so their position in the flow is stored explicitly rather than inferred from created_at
+
+ALTER TABLE flow_nodes ADD COLUMN IF NOT EXISTS sort_order INTEGER NOT NULL DEFAULT 0;
+
+-- Only flows that have never been ordered are backfilled, so running this again
+-- keeps the order users have saved since
+UPDATE flow_nodes n
+SET sort_order = ordered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY flow_id ORDER BY created_at, id) - 1 AS position
+    FROM flow_nodes
+    WHERE flow_id IN (SELECT flow_id FROM flow_nodes GROUP BY flow_id HAVING MAX(sort_order) = 0)
+) ordered
+WHERE n.id = ordered.id;
+
+CREATE INDEX IF NOT EXISTS idx_flow_nodes_sort_order ON flow_nodes(flow_id, sort_order);
@@ -0,0 +1,5 @@
+-- Migration: Flow summaries
+-- Supports the paginated flow list, which reads each flow's latest test run
+
+CREATE INDEX IF NOT EXISTS idx_test_runs_flow_id_created_at ON test_runs(flow_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_flows_user_id_updated_at ON flows(user_id, updated_at DESC, id);
@@ -0,0 +1,22 @@
+-- Migration: Flow search
+-- Full-text search over flow names, descriptions, node labels and API node URLs,
+-- plus indexes for filtering by tag, node type and URL substring
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE flows ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+    ) STORED;
+
+ALTER TABLE flow_nodes ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        to_tsvector('simple', coalesce(data_label, '') || ' ' || coalesce(data_config->>'url', ''))
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_flows_search_vector ON flows USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_flows_tags ON flows USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_flow_nodes_search_vector ON flow_nodes USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_flow_nodes_type ON flow_nodes(type, flow_id);
+CREATE INDEX IF NOT EXISTS idx_flow_nodes_url_trgm ON flow_nodes USING GIN ((data_config->>'url') gin_trgm_ops);
@@ -0,0 +1,11 @@
+-- Migration: Test run history
+-- Records what triggered each run, where it ran and its tags, and tracks runs
+-- whose node outputs were compacted by the retention job
+
+ALTER TABLE test_runs ADD COLUMN IF NOT EXISTS trigger_source VARCHAR(50) NOT NULL DEFAULT 'manual';
+ALTER TABLE test_runs ADD COLUMN IF NOT EXISTS environment VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE test_runs ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE test_runs ADD COLUMN IF NOT EXISTS compacted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_test_runs_created_at_id ON test_runs(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_test_runs_tags ON test_runs USING GIN (tags);
@@ -0,0 +1,30 @@
+-- Migration: Per-node test run results
+-- Node statuses and durations are stored in their own table so run analytics can
+-- aggregate them without unpacking test_runs.node_results
+
+CREATE TABLE IF NOT EXISTS test_run_nodes (
+    test_run_id UUID NOT NULL REFERENCES test_runs(id) ON DELETE CASCADE,
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    node_id VARCHAR(255) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL, -- Start time of the run
+    PRIMARY KEY (test_run_id, node_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_test_run_nodes_flow_created_at ON test_run_nodes(flow_id, created_at);
+
+-- Backfill from existing runs
+INSERT INTO test_run_nodes (test_run_id, flow_id, node_id, status, duration_ms, error, created_at)
+SELECT t.id,
+       t.flow_id,
+       result.key,
+       COALESCE(result.value->>'status', 'pending'),
+       COALESCE((result.value->>'duration')::integer, 0),
+       NULLIF(result.value->>'error', ''),
+       COALESCE(t.started_at, t.created_at)
+FROM test_runs t
+CROSS JOIN LATERAL jsonb_each(COALESCE(t.node_results, '{}'::jsonb)) AS result
+WHERE t.flow_id IS NOT NULL
+ON CONFLICT (test_run_id, node_id) DO NOTHING;
@@ -0,0 +1,14 @@
+-- Migration: Node quarantine
+-- Quarantined nodes still execute and report results, but their failures do not
+-- fail the run. test_run_nodes records whether a node was quarantined at the time.
+
+CREATE TABLE IF NOT EXISTS node_quarantines (
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    node_id VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    quarantined_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (flow_id, node_id)
+);
+
+ALTER TABLE test_run_nodes ADD COLUMN IF NOT EXISTS quarantined BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,14 @@
+-- Migration: Golden snapshots for snapshot assertions
+-- A verification node with assertionType "snapshot" records the first value it
+-- sees here and compares later runs against it until a new value is approved.
+
+CREATE TABLE IF NOT EXISTS node_snapshots (
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    node_id VARCHAR(255) NOT NULL,
+    value JSONB NOT NULL,
+    source_test_run_id UUID REFERENCES test_runs(id) ON DELETE SET NULL,
+    approved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (flow_id, node_id)
+);
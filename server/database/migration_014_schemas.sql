@@ -0,0 +1,13 @@
+-- Migration: Shared JSON Schema library
+-- Schemas are shared by the whole workspace and referenced by verification
+-- nodes with assertionType "schema" through schemaId.
+
+CREATE TABLE IF NOT EXISTS schemas (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    schema JSONB NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
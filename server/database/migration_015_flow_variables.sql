@@ -0,0 +1,6 @@
+-- Migration: Flow variables
+-- Variables are defined on the flow, versioned with it and available to every
+-- node during a run. Values passed when starting a run override them.
+
+ALTER TABLE flows ADD COLUMN IF NOT EXISTS variables JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE flow_versions ADD COLUMN IF NOT EXISTS variables JSONB NOT NULL DEFAULT '{}'::jsonb;
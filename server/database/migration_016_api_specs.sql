@@ -0,0 +1,16 @@
+-- Migration: OpenAPI spec library
+-- Specs are shared by the whole workspace. API nodes bind to one of a spec's
+-- operations through their contract config to have requests and responses
+-- validated against it.
+
+CREATE TABLE IF NOT EXISTS api_specs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    api_version VARCHAR(255) NOT NULL DEFAULT '',
+    document JSONB NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,23 @@
+-- Migration: Load test runs
+-- A load run executes a flow with several virtual users for a duration or a
+-- number of iterations. Iterations aren't stored as test runs; the run keeps
+-- the aggregated request rates, error rates and latency percentiles instead.
+
+CREATE TABLE IF NOT EXISTS load_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    flow_version INTEGER,
+    status VARCHAR(50) NOT NULL,
+    config JSONB NOT NULL,
+    metrics JSONB,
+    thresholds JSONB NOT NULL DEFAULT '[]'::jsonb,
+    error TEXT,
+    environment VARCHAR(100) NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    started_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    duration_ms INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_load_runs_flow_id_created_at ON load_runs(flow_id, created_at DESC);
@@ -0,0 +1,15 @@
+-- Migration: Store test run times with their time zone
+-- created_at defaulted to the database clock while started_at and the node
+-- rows were written from the server's clock, and TIMESTAMP dropped the zone of
+-- both. Analytics and coverage windows computed on the server were shifted by
+-- the zone offset whenever either clock wasn't in UTC. Existing values are
+-- read in the session's time zone.
+
+ALTER TABLE test_runs
+    ALTER COLUMN started_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN completed_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN compacted_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE test_run_nodes
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ;
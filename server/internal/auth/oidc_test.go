@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/visual-api-testing-platform/server/internal/auth/oidctest"
+)
+
+const testRedirectURL = "http://localhost:8080/api/auth/oidc/callback"
+
+func newTestProvider(t *testing.T, trustUnverifiedEmail bool) (*OIDCProvider, *oidctest.Server) {
+	t.Helper()
+	idp := oidctest.NewServer("visual-api-testing")
+	t.Cleanup(idp.Close)
+
+	provider := NewOIDCProvider(OIDCConfig{
+		Issuer:               idp.Issuer(),
+		ClientID:             idp.ClientID,
+		RedirectURL:          testRedirectURL,
+		GroupRoles:           map[string]string{"qa-admins": "admin", "qa": "editor"},
+		DefaultRole:          "viewer",
+		TrustUnverifiedEmail: trustUnverifiedEmail,
+	})
+	return provider, idp
+}
+
+// login runs the authorization code flow up to the redirect back to the client
+func login(t *testing.T, provider *OIDCProvider, idp *oidctest.Server, claims jwt.MapClaims) (code, verifier, nonce string) {
+	t.Helper()
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce = "nonce-123"
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-123", nonce, challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, err = idp.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, verifier, nonce
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	provider, idp := newTestProvider(t, false)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-123", "nonce-123", "challenge")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(authURL, idp.URL+"/authorize?") {
+		t.Errorf("auth URL %s doesn't use the discovered authorization endpoint", authURL)
+	}
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             idp.ClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "state-123",
+		"nonce":                 "nonce-123",
+		"code_challenge":        "challenge",
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if got := parsed.Query().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	provider, idp := newTestProvider(t, false)
+	code, verifier, nonce := login(t, provider, idp, jwt.MapClaims{
+		"sub":            "alice",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice",
+		"groups":         []string{"staff", "qa"},
+	})
+
+	identity, err := provider.Exchange(context.Background(), code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	if identity.Issuer != idp.Issuer() || identity.Subject != "alice" {
+		t.Errorf("identity = %s %s, want %s alice", identity.Issuer, identity.Subject, idp.Issuer())
+	}
+	if identity.Email != "alice@example.com" || !identity.EmailVerified || identity.Name != "Alice" {
+		t.Errorf("identity = %+v", identity)
+	}
+	if role := provider.RoleForGroups(identity.Groups); role != "editor" {
+		t.Errorf("role = %s, want editor", role)
+	}
+
+	// Codes can't be redeemed twice
+	if _, err := provider.Exchange(context.Background(), code, verifier, nonce); err == nil {
+		t.Error("second Exchange of the same code succeeded")
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		claims    jwt.MapClaims
+		verifier  string // replaces the PKCE verifier when set
+		nonce     string // replaces the nonce when set
+		signWith  *rsa.PrivateKey
+		wantError string
+	}{
+		{name: "wrong PKCE verifier", verifier: "not-the-verifier", wantError: "invalid_grant"},
+		{name: "bad signature", signWith: otherKey, wantError: "signature is invalid"},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "another-client"}, wantError: "audience"},
+		{name: "wrong issuer", claims: jwt.MapClaims{"iss": "https://evil.example.com"}, wantError: "issuer"},
+		{name: "wrong nonce", nonce: "replayed-nonce", wantError: "nonce mismatch"},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}, wantError: "expired"},
+		{name: "no expiry", claims: jwt.MapClaims{"exp": nil}, wantError: "exp"},
+		{name: "no subject", claims: jwt.MapClaims{"sub": nil}, wantError: "missing sub"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, idp := newTestProvider(t, false)
+			if tt.signWith != nil {
+				idp.SigningKey = tt.signWith
+			}
+
+			code, verifier, nonce := login(t, provider, idp, tt.claims)
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+
+			identity, err := provider.Exchange(context.Background(), code, verifier, nonce)
+			if err == nil {
+				t.Fatalf("Exchange succeeded with %+v", identity)
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("error %q doesn't mention %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestExchangeEmailVerified(t *testing.T) {
+	tests := []struct {
+		name     string
+		claim    interface{} // nil omits email_verified
+		trust    bool
+		verified bool
+	}{
+		{name: "omitted", claim: nil, verified: false},
+		{name: "omitted from a trusted provider", claim: nil, trust: true, verified: true},
+		{name: "true", claim: true, verified: true},
+		{name: "false", claim: false, verified: false},
+		{name: "false from a trusted provider", claim: false, trust: true, verified: false},
+		{name: "string true", claim: "true", verified: true},
+		{name: "string false", claim: "false", verified: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, idp := newTestProvider(t, tt.trust)
+			code, verifier, nonce := login(t, provider, idp, jwt.MapClaims{
+				"email":          "alice@example.com",
+				"email_verified": tt.claim,
+			})
+
+			identity, err := provider.Exchange(context.Background(), code, verifier, nonce)
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if identity.EmailVerified != tt.verified {
+				t.Errorf("EmailVerified = %v, want %v", identity.EmailVerified, tt.verified)
+			}
+		})
+	}
+}
@@ -0,0 +1,521 @@
+// Package coverage measures how much of an OpenAPI spec flows exercise: which
+// operations their API nodes call, which documented response codes are
+// asserted on or seen in runs, and which parameters are never sent.
+//
+// API nodes bound to the spec with a contract count for the bound operation.
+// Other API nodes are matched by method and path: flow variables are
+// expanded, an unresolved placeholder at the start of the URL is taken to be
+// the base URL, and other unresolved placeholders match any path parameter.
+package coverage
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/node"
+	"github.com/visual-api-testing-platform/server/internal/openapi"
+)
+
+// Report describes how much of a spec the flows exercise
+type Report struct {
+	SpecID     uuid.UUID   `json:"spec_id"`
+	Title      string      `json:"title"`
+	APIVersion string      `json:"api_version"`
+	Summary    Summary     `json:"summary"`
+	Operations []Operation `json:"operations"`
+
+	// Unmatched lists the API nodes whose requests no operation of the spec matches
+	Unmatched []Request `json:"unmatched"`
+}
+
+// Summary totals a report
+type Summary struct {
+	Flows               int     `json:"flows"`
+	Runs                int     `json:"runs"` // runs whose requests were inspected
+	Operations          int     `json:"operations"`
+	CoveredOperations   int     `json:"covered_operations"`
+	Percent             float64 `json:"percent"` // of operations covered
+	StatusCodes         int     `json:"status_codes"`
+	CoveredStatusCodes  int     `json:"covered_status_codes"`
+	Parameters          int     `json:"parameters"`
+	ExercisedParameters int     `json:"exercised_parameters"`
+}
+
+// Operation is the coverage of one operation of the spec
+type Operation struct {
+	OperationID string `json:"operation_id,omitempty"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Summary     string `json:"summary,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty"`
+	Covered     bool   `json:"covered"`
+
+	// Sources are the API nodes that call the operation
+	Sources     []Source     `json:"sources"`
+	StatusCodes []StatusCode `json:"status_codes"`
+
+	// UndocumentedStatusCodes were seen in runs but match no documented response
+	UndocumentedStatusCodes []int       `json:"undocumented_status_codes,omitempty"`
+	UnexercisedParameters   []Parameter `json:"unexercised_parameters"`
+}
+
+// Source is an API node that calls an operation
+type Source struct {
+	FlowID    uuid.UUID `json:"flow_id"`
+	FlowName  string    `json:"flow_name"`
+	NodeID    string    `json:"node_id"`
+	NodeLabel string    `json:"node_label,omitempty"`
+	Runs      int       `json:"runs"` // inspected runs in which the node sent a request to the operation
+}
+
+// StatusCode is a documented response code: a code such as 200, a class such
+// as 4XX or DEFAULT. It is covered when a flow asserts on it or a run saw it.
+type StatusCode struct {
+	Code     string `json:"code"`
+	Asserted bool   `json:"asserted"`
+	Observed bool   `json:"observed"`
+}
+
+// Parameter is a documented parameter
+type Parameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+}
+
+// Request is an API node request that matches no operation
+type Request struct {
+	FlowID    uuid.UUID `json:"flow_id"`
+	FlowName  string    `json:"flow_name"`
+	NodeID    string    `json:"node_id"`
+	NodeLabel string    `json:"node_label,omitempty"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+}
+
+// Analyzer collects the coverage of one spec from flows and their runs
+type Analyzer struct {
+	specID uuid.UUID
+	doc    *openapi.Document
+
+	operations map[*openapi.Operation]*operationState
+	unmatched  []Request
+	flows      int
+	runs       int
+}
+
+type operationState struct {
+	sources      []*Source
+	asserted     map[string]bool // documented response codes
+	observed     map[string]bool
+	undocumented map[int]bool
+	exercised    map[string]bool // parameter keys
+}
+
+// exchange is what a request sent, as far as coverage is concerned
+type exchange struct {
+	method  string
+	path    string // escaped
+	query   []string
+	headers []string
+	cookies []string
+}
+
+// New creates an analyzer for the spec with the given library ID
+func New(specID uuid.UUID, doc *openapi.Document) *Analyzer {
+	a := &Analyzer{
+		specID:     specID,
+		doc:        doc,
+		operations: make(map[*openapi.Operation]*operationState),
+	}
+	for _, op := range doc.Operations() {
+		a.operations[op] = &operationState{
+			asserted:     make(map[string]bool),
+			observed:     make(map[string]bool),
+			undocumented: make(map[int]bool),
+			exercised:    make(map[string]bool),
+		}
+	}
+	return a
+}
+
+// AddFlow records the operations the flow's API nodes call and the response
+// codes its verification nodes assert on
+func (a *Analyzer) AddFlow(flow *models.Flow) {
+	a.flows++
+	asserted := assertedStatuses(flow)
+
+	for _, n := range flow.Nodes {
+		if n.Data.Type != string(node.NodeTypeAPI) {
+			continue
+		}
+		ex, parsed := configExchange(n.Data.Config, flow.Variables)
+		op := a.operationFor(n.Data.Config, ex, parsed)
+		if op == nil {
+			if ex.method != "" {
+				rawURL, _ := n.Data.Config["url"].(string)
+				a.unmatched = append(a.unmatched, Request{
+					FlowID:    flow.ID,
+					FlowName:  flow.Name,
+					NodeID:    n.ID,
+					NodeLabel: n.Data.Label,
+					Method:    ex.method,
+					URL:       rawURL,
+				})
+			}
+			continue
+		}
+
+		state := a.operations[op]
+		a.source(state, flow, n)
+		state.exercise(op, ex)
+		codes := op.StatusCodes()
+		for _, status := range asserted[n.ID] {
+			if code, ok := documentedCode(op, codes, status); ok {
+				state.asserted[code] = true
+			}
+		}
+	}
+}
+
+// AddRun records the requests a run of the flow sent and the response codes
+// it received. Runs from before API nodes recorded their requests fall back
+// to the flow's node config.
+func (a *Analyzer) AddRun(flow *models.Flow, run *models.TestRun) {
+	a.runs++
+
+	nodes := make(map[string]models.FlowNode, len(flow.Nodes))
+	for _, n := range flow.Nodes {
+		nodes[n.ID] = n
+	}
+
+	for nodeID, result := range run.NodeResults {
+		output, _ := result.Output.(map[string]interface{})
+		status, ok := output["status"].(float64)
+		if !ok {
+			continue
+		}
+		n, known := nodes[nodeID]
+		if known && n.Data.Type != string(node.NodeTypeAPI) {
+			continue
+		}
+
+		var config map[string]interface{}
+		if known {
+			config = n.Data.Config
+		}
+		ex, parsed := outputExchange(output)
+		if !parsed {
+			if !known {
+				continue
+			}
+			ex, parsed = configExchange(config, flow.Variables)
+		}
+		op := a.operationFor(config, ex, parsed)
+		if op == nil {
+			continue
+		}
+
+		state := a.operations[op]
+		if !known {
+			n = models.FlowNode{ID: nodeID}
+		}
+		a.source(state, flow, n).Runs++
+		state.exercise(op, ex)
+		if code, ok := op.ResponseCode(int(status)); ok {
+			state.observed[code] = true
+		} else {
+			state.undocumented[int(status)] = true
+		}
+	}
+}
+
+// Report summarizes what has been collected, listing operations in the
+// spec's order
+func (a *Analyzer) Report() *Report {
+	report := &Report{
+		SpecID:     a.specID,
+		Title:      a.doc.Title,
+		APIVersion: a.doc.Version,
+		Operations: make([]Operation, 0, len(a.doc.Operations())),
+		Unmatched:  a.unmatched,
+	}
+	if report.Unmatched == nil {
+		report.Unmatched = []Request{}
+	}
+	summary := &report.Summary
+	summary.Flows = a.flows
+	summary.Runs = a.runs
+
+	for _, op := range a.doc.Operations() {
+		state := a.operations[op]
+		coverage := Operation{
+			OperationID:           op.ID,
+			Method:                op.Method,
+			Path:                  op.Path,
+			Summary:               op.Summary,
+			Deprecated:            op.Deprecated,
+			Covered:               len(state.sources) > 0,
+			Sources:               make([]Source, 0, len(state.sources)),
+			StatusCodes:           make([]StatusCode, 0),
+			UnexercisedParameters: make([]Parameter, 0),
+		}
+		for _, source := range state.sources {
+			coverage.Sources = append(coverage.Sources, *source)
+		}
+		for _, code := range op.StatusCodes() {
+			status := StatusCode{Code: code, Asserted: state.asserted[code], Observed: state.observed[code]}
+			coverage.StatusCodes = append(coverage.StatusCodes, status)
+			summary.StatusCodes++
+			if status.Asserted || status.Observed {
+				summary.CoveredStatusCodes++
+			}
+		}
+		for status := range state.undocumented {
+			coverage.UndocumentedStatusCodes = append(coverage.UndocumentedStatusCodes, status)
+		}
+		sort.Ints(coverage.UndocumentedStatusCodes)
+		for _, p := range op.Parameters() {
+			summary.Parameters++
+			if state.exercised[parameterKey(p.In, p.Name)] {
+				summary.ExercisedParameters++
+				continue
+			}
+			coverage.UnexercisedParameters = append(coverage.UnexercisedParameters, Parameter{Name: p.Name, In: p.In, Required: p.Required})
+		}
+
+		summary.Operations++
+		if coverage.Covered {
+			summary.CoveredOperations++
+		}
+		report.Operations = append(report.Operations, coverage)
+	}
+
+	if summary.Operations > 0 {
+		summary.Percent = math.Round(float64(summary.CoveredOperations)*1000/float64(summary.Operations)) / 10
+	}
+	return report
+}
+
+// operationFor finds the operation an API node calls: the one its contract
+// binds it to when the contract names this spec, otherwise the one matching
+// the request's method and path
+func (a *Analyzer) operationFor(config map[string]interface{}, ex exchange, parsed bool) *openapi.Operation {
+	if contract, ok := config["contract"].(map[string]interface{}); ok {
+		if id, _ := contract["specId"].(string); id == a.specID.String() {
+			if operationID, _ := contract["operationId"].(string); operationID != "" {
+				op, _ := a.doc.Operation(operationID)
+				return op
+			}
+			method, _ := contract["method"].(string)
+			if method == "" {
+				method = ex.method
+			}
+			path, _ := contract["path"].(string)
+			op, _ := a.doc.OperationFor(method, path)
+			return op
+		}
+	}
+	if !parsed {
+		return nil
+	}
+	op, _ := a.doc.Match(ex.method, ex.path)
+	return op
+}
+
+// source finds or adds the node among the operation's sources
+func (a *Analyzer) source(state *operationState, flow *models.Flow, n models.FlowNode) *Source {
+	for _, s := range state.sources {
+		if s.FlowID == flow.ID && s.NodeID == n.ID {
+			return s
+		}
+	}
+	s := &Source{FlowID: flow.ID, FlowName: flow.Name, NodeID: n.ID, NodeLabel: n.Data.Label}
+	state.sources = append(state.sources, s)
+	return s
+}
+
+// exercise marks the parameters a request sent. Path parameters are always
+// sent once the path matches.
+func (s *operationState) exercise(op *openapi.Operation, ex exchange) {
+	for _, p := range op.Parameters() {
+		if p.In == "path" {
+			s.exercised[parameterKey(p.In, p.Name)] = true
+		}
+	}
+	for _, name := range ex.query {
+		s.exercised[parameterKey("query", name)] = true
+	}
+	for _, name := range ex.headers {
+		s.exercised[parameterKey("header", name)] = true
+	}
+	for _, name := range ex.cookies {
+		s.exercised[parameterKey("cookie", name)] = true
+	}
+}
+
+// parameterKey identifies a parameter; header names are case-insensitive
+func parameterKey(in, name string) string {
+	if in == "header" {
+		name = http.CanonicalHeaderKey(name)
+	}
+	return in + ":" + name
+}
+
+var (
+	leadingPlaceholder = regexp.MustCompile(`^\s*\{\{[^}]*\}\}`)
+	placeholder        = regexp.MustCompile(`\{\{[^}]*\}\}`)
+)
+
+// configExchange reads the request an API node's config describes. It
+// reports false when the URL can't be parsed; the method is set regardless.
+func configExchange(config map[string]interface{}, variables map[string]interface{}) (exchange, bool) {
+	var ex exchange
+	method, _ := config["method"].(string)
+	ex.method = strings.ToUpper(method)
+	rawURL, _ := config["url"].(string)
+	if rawURL == "" {
+		return ex, false
+	}
+
+	expanded := node.ExpandVariables(rawURL, variables)
+	// An unresolved placeholder at the start is the base URL, set by the run
+	expanded = leadingPlaceholder.ReplaceAllString(expanded, "")
+	// Others stand for a value, which matches any path parameter
+	expanded = placeholder.ReplaceAllString(expanded, "_")
+	u, err := url.Parse(strings.TrimSpace(expanded))
+	if err != nil {
+		return ex, false
+	}
+	ex.path = u.EscapedPath()
+	if ex.path == "" {
+		ex.path = "/"
+	}
+	for name := range u.Query() {
+		ex.query = append(ex.query, name)
+	}
+
+	header := make(http.Header)
+	if headers, ok := config["headers"].(map[string]interface{}); ok {
+		for name, value := range headers {
+			if str, ok := value.(string); ok {
+				header.Set(name, str)
+				ex.headers = append(ex.headers, name)
+			}
+		}
+	}
+	if body, _ := config["body"].(string); body != "" && header.Get("Content-Type") == "" {
+		ex.headers = append(ex.headers, "Content-Type")
+	}
+	for _, cookie := range (&http.Request{Header: header}).Cookies() {
+		ex.cookies = append(ex.cookies, cookie.Name)
+	}
+	return ex, true
+}
+
+// outputExchange reads the request an API node recorded in its output
+func outputExchange(output map[string]interface{}) (exchange, bool) {
+	request, ok := output["request"].(map[string]interface{})
+	if !ok {
+		return exchange{}, false
+	}
+	var ex exchange
+	ex.method, _ = request["method"].(string)
+	ex.path, _ = request["path"].(string)
+	if ex.method == "" || ex.path == "" {
+		return exchange{}, false
+	}
+	ex.query = stringList(request["query"])
+	ex.headers = stringList(request["headers"])
+	ex.cookies = stringList(request["cookies"])
+	return ex, true
+}
+
+func stringList(value interface{}) []string {
+	list, _ := value.([]interface{})
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// assertedStatuses maps API node IDs to the statuses verification nodes
+// assert their responses have: codes such as "200" and classes such as "4xx"
+func assertedStatuses(flow *models.Flow) map[string][]string {
+	upstream := make(map[string]string)
+	for _, e := range flow.Edges {
+		if _, ok := upstream[e.Target]; !ok {
+			upstream[e.Target] = e.Source
+		}
+	}
+
+	asserted := make(map[string][]string)
+	for _, n := range flow.Nodes {
+		if n.Data.Type != string(node.NodeTypeVerification) {
+			continue
+		}
+		config := n.Data.Config
+		source, _ := config["source"].(string)
+		if source == "" {
+			source = upstream[n.ID]
+		}
+
+		switch config["assertionType"] {
+		case "response":
+			asserted[source] = append(asserted[source], statusList(config["status"])...)
+		case "assertions":
+			list, _ := config["assertions"].([]interface{})
+			for _, item := range list {
+				a, _ := item.(map[string]interface{})
+				path, _ := a["path"].(string)
+				path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+				if path == "status" && a["operator"] == "eq" {
+					asserted[source] = append(asserted[source], statusList(a["value"])...)
+				}
+			}
+		}
+	}
+	return asserted
+}
+
+// statusList reads a status spec: a code, a class such as "2xx" or a list of either
+func statusList(spec interface{}) []string {
+	switch v := spec.(type) {
+	case float64:
+		return []string{strconv.Itoa(int(v))}
+	case string:
+		return []string{v}
+	case []interface{}:
+		var statuses []string
+		for _, item := range v {
+			statuses = append(statuses, statusList(item)...)
+		}
+		return statuses
+	}
+	return nil
+}
+
+// documentedCode finds the documented response code an asserted status is
+// about. A class such as 4xx only covers a documented 4XX response.
+func documentedCode(op *openapi.Operation, codes []string, status string) (string, bool) {
+	if code, err := strconv.Atoi(status); err == nil {
+		return op.ResponseCode(code)
+	}
+	class := strings.ToUpper(status)
+	for _, code := range codes {
+		if code == class {
+			return code, true
+		}
+	}
+	return "", false
+}
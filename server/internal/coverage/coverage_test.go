@@ -0,0 +1,399 @@
+package coverage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/openapi"
+)
+
+const petsSpec = `
+openapi: 3.0.3
+info: {title: Pets, version: "2"}
+servers:
+  - url: https://api.test/v1
+paths:
+  /orders:
+    get:
+      operationId: listOrders
+      responses:
+        "200": {description: ok}
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - {name: limit, in: query, required: true, schema: {type: integer}}
+        - {name: offset, in: query, schema: {type: integer}}
+        - {name: x-trace, in: header, schema: {type: string}}
+        - {name: session, in: cookie, schema: {type: string}}
+      responses:
+        "200": {description: ok}
+        4XX: {description: client error}
+        default: {description: error}
+    post:
+      operationId: createPet
+      responses:
+        "201": {description: created}
+        "400": {description: invalid pet}
+  /pets/mine:
+    get:
+      operationId: listMyPets
+      responses:
+        "200": {description: ok}
+  /pets/{petId}:
+    get:
+      operationId: getPet
+      parameters:
+        - {name: petId, in: path, required: true, schema: {type: integer}}
+      responses:
+        "200": {description: ok}
+        "404": {description: not found}
+`
+
+func parseSpec(t *testing.T) *openapi.Document {
+	t.Helper()
+	decoded, err := openapi.Decode([]byte(petsSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi.Parse(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func apiNode(id string, config map[string]interface{}) models.FlowNode {
+	return models.FlowNode{ID: id, Data: models.NodeData{ID: id, Type: "api", Label: id, Config: config}}
+}
+
+func checkNode(id string, config map[string]interface{}) models.FlowNode {
+	return models.FlowNode{ID: id, Data: models.NodeData{ID: id, Type: "verification", Label: id, Config: config}}
+}
+
+// operation finds an operation's coverage in a report
+func operation(t *testing.T, report *Report, id string) Operation {
+	t.Helper()
+	for _, op := range report.Operations {
+		if op.OperationID == id {
+			return op
+		}
+	}
+	t.Fatalf("report has no operation %s", id)
+	return Operation{}
+}
+
+func TestOperationFor(t *testing.T) {
+	specID := uuid.New()
+	a := New(specID, parseSpec(t))
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		variables map[string]interface{}
+		want      string // operation ID, empty for no match
+	}{
+		{
+			name:   "leading placeholder is the base URL",
+			config: map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets"},
+			want:   "listPets",
+		},
+		{
+			name:   "leading placeholder with the server's base path",
+			config: map[string]interface{}{"method": "GET", "url": "{{host}}/v1/pets?limit=1"},
+			want:   "listPets",
+		},
+		{
+			name:   "absolute URL below the server's base path",
+			config: map[string]interface{}{"method": "get", "url": "https://api.test/v1/pets"},
+			want:   "listPets",
+		},
+		{
+			name:      "expanded variables",
+			config:    map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets/{{id}}"},
+			variables: map[string]interface{}{"baseUrl": "https://staging.api.test/v1", "id": 7.0},
+			want:      "getPet",
+		},
+		{
+			name:   "unresolved placeholder matches a path parameter",
+			config: map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets/{{create.body.id}}"},
+			want:   "getPet",
+		},
+		{
+			name:   "concrete path wins over a template",
+			config: map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets/mine"},
+			want:   "listMyPets",
+		},
+		{
+			name:   "method picks the operation",
+			config: map[string]interface{}{"method": "POST", "url": "{{baseUrl}}/pets"},
+			want:   "createPet",
+		},
+		{
+			name:   "undocumented method",
+			config: map[string]interface{}{"method": "DELETE", "url": "{{baseUrl}}/pets"},
+		},
+		{
+			name:   "undocumented path",
+			config: map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/users"},
+		},
+		{
+			name:   "placeholder doesn't span segments",
+			config: map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets/{{id}}/toys"},
+		},
+		{
+			name:   "no URL",
+			config: map[string]interface{}{"method": "GET"},
+		},
+		{
+			name: "contract bound by operation ID",
+			config: map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets",
+				"contract": map[string]interface{}{"specId": specID.String(), "operationId": "listOrders"}},
+			want: "listOrders",
+		},
+		{
+			name: "contract bound by path, with the node's method",
+			config: map[string]interface{}{"method": "GET", "url": "https://elsewhere.test/o",
+				"contract": map[string]interface{}{"specId": specID.String(), "path": "/orders"}},
+			want: "listOrders",
+		},
+		{
+			name: "contract bound by method and path",
+			config: map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets",
+				"contract": map[string]interface{}{"specId": specID.String(), "method": "post", "path": "/pets"}},
+			want: "createPet",
+		},
+		{
+			name: "contract bound to an unknown operation",
+			config: map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets",
+				"contract": map[string]interface{}{"specId": specID.String(), "operationId": "missing"}},
+		},
+		{
+			name: "contract bound to another spec",
+			config: map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets",
+				"contract": map[string]interface{}{"specId": uuid.New().String(), "operationId": "listOrders"}},
+			want: "listPets",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ex, parsed := configExchange(tt.config, tt.variables)
+			op := a.operationFor(tt.config, ex, parsed)
+			got := ""
+			if op != nil {
+				got = op.Name()
+			}
+			if got != tt.want {
+				t.Errorf("operation = %q, want %q (path %q)", got, tt.want, ex.path)
+			}
+		})
+	}
+}
+
+func TestAddFlow(t *testing.T) {
+	specID := uuid.New()
+	a := New(specID, parseSpec(t))
+
+	flow := &models.Flow{
+		ID:        uuid.New(),
+		Name:      "Pets",
+		Variables: map[string]interface{}{"limit": 5.0},
+		Nodes: []models.FlowNode{
+			apiNode("list", map[string]interface{}{
+				"method":  "GET",
+				"url":     "{{baseUrl}}/pets?limit={{limit}}",
+				"headers": map[string]interface{}{"X-Trace": "1"},
+			}),
+			checkNode("list-check", map[string]interface{}{"assertionType": "response", "status": []interface{}{200.0, "4xx", "5xx"}}),
+			apiNode("get", map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets/{{petId}}"}),
+			checkNode("get-check", map[string]interface{}{
+				"assertionType": "assertions",
+				"source":        "get",
+				"assertions": []interface{}{
+					map[string]interface{}{"path": "$.status", "operator": "eq", "value": 404.0},
+					map[string]interface{}{"path": "status", "operator": "neq", "value": 200.0},
+				},
+			}),
+			apiNode("order", map[string]interface{}{
+				"method":   "GET",
+				"url":      "https://orders.test/all",
+				"contract": map[string]interface{}{"specId": specID.String(), "operationId": "listOrders"},
+			}),
+			checkNode("order-check", map[string]interface{}{"assertionType": "response", "status": 503.0}),
+			apiNode("users", map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/users"}),
+			apiNode("blank", map[string]interface{}{}),
+		},
+		Edges: []models.FlowEdge{
+			{Source: "list", Target: "list-check"},
+			{Source: "get", Target: "get-check"},
+			{Source: "order", Target: "order-check"},
+		},
+	}
+	a.AddFlow(flow)
+	report := a.Report()
+
+	// A 4xx assertion covers the documented 4XX class. A 5xx assertion covers
+	// nothing, as only codes fall back to default.
+	list := operation(t, report, "listPets")
+	wantCodes := []StatusCode{{Code: "200", Asserted: true}, {Code: "4XX", Asserted: true}, {Code: "DEFAULT"}}
+	if !reflect.DeepEqual(list.StatusCodes, wantCodes) {
+		t.Errorf("listPets status codes = %+v, want %+v", list.StatusCodes, wantCodes)
+	}
+	wantParams := []Parameter{{Name: "offset", In: "query"}, {Name: "session", In: "cookie"}}
+	if !reflect.DeepEqual(list.UnexercisedParameters, wantParams) {
+		t.Errorf("listPets unexercised parameters = %+v, want %+v", list.UnexercisedParameters, wantParams)
+	}
+	wantSources := []Source{{FlowID: flow.ID, FlowName: "Pets", NodeID: "list", NodeLabel: "list"}}
+	if !reflect.DeepEqual(list.Sources, wantSources) {
+		t.Errorf("listPets sources = %+v, want %+v", list.Sources, wantSources)
+	}
+
+	// Only eq assertions on the status count
+	get := operation(t, report, "getPet")
+	wantCodes = []StatusCode{{Code: "200"}, {Code: "404", Asserted: true}}
+	if !reflect.DeepEqual(get.StatusCodes, wantCodes) {
+		t.Errorf("getPet status codes = %+v, want %+v", get.StatusCodes, wantCodes)
+	}
+	if len(get.UnexercisedParameters) != 0 {
+		t.Errorf("getPet unexercised parameters = %+v, want path parameters exercised", get.UnexercisedParameters)
+	}
+
+	// The contract binds the node whatever its URL; 503 isn't documented
+	orders := operation(t, report, "listOrders")
+	if !orders.Covered || orders.StatusCodes[0].Asserted {
+		t.Errorf("listOrders = %+v, want covered with nothing asserted", orders)
+	}
+
+	if operation(t, report, "createPet").Covered || operation(t, report, "listMyPets").Covered {
+		t.Error("operations no node calls are covered")
+	}
+
+	wantUnmatched := []Request{{FlowID: flow.ID, FlowName: "Pets", NodeID: "users", NodeLabel: "users", Method: "GET", URL: "{{baseUrl}}/users"}}
+	if !reflect.DeepEqual(report.Unmatched, wantUnmatched) {
+		t.Errorf("unmatched = %+v, want %+v", report.Unmatched, wantUnmatched)
+	}
+
+	wantSummary := Summary{
+		Flows:               1,
+		Operations:          5,
+		CoveredOperations:   3,
+		Percent:             60,
+		StatusCodes:         9,
+		CoveredStatusCodes:  3,
+		Parameters:          5,
+		ExercisedParameters: 3,
+	}
+	if report.Summary != wantSummary {
+		t.Errorf("summary = %+v, want %+v", report.Summary, wantSummary)
+	}
+}
+
+func TestAddRun(t *testing.T) {
+	a := New(uuid.New(), parseSpec(t))
+
+	flow := &models.Flow{
+		ID:   uuid.New(),
+		Name: "Pets",
+		Nodes: []models.FlowNode{
+			apiNode("list", map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets"}),
+			apiNode("create", map[string]interface{}{"method": "POST", "url": "{{baseUrl}}/pets"}),
+			apiNode("old", map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/pets/{{id}}"}),
+			checkNode("check", map[string]interface{}{"assertionType": "response", "status": 200.0}),
+		},
+	}
+	recorded := func(status float64, method, path string, query, headers, cookies []interface{}) models.NodeResult {
+		return models.NodeResult{Output: map[string]interface{}{
+			"status": status,
+			"request": map[string]interface{}{
+				"method":  method,
+				"path":    path,
+				"query":   query,
+				"headers": headers,
+				"cookies": cookies,
+			},
+		}}
+	}
+	runs := []*models.TestRun{
+		{NodeResults: map[string]models.NodeResult{
+			"list":   recorded(429, "GET", "/v1/pets", []interface{}{"limit"}, []interface{}{"X-Trace"}, []interface{}{}),
+			"create": recorded(302, "POST", "/v1/pets", nil, nil, nil),
+			// Runs from before requests were recorded fall back to the config
+			"old": {Output: map[string]interface{}{"status": 404.0}},
+			// The output of other nodes isn't a response
+			"check": {Output: map[string]interface{}{"status": 200.0}},
+		}},
+		{NodeResults: map[string]models.NodeResult{
+			"list": recorded(500, "GET", "/v1/pets", nil, nil, []interface{}{"session"}),
+			// A node since removed from the flow still counts by its request
+			"removed": recorded(200, "GET", "/v1/pets/mine", nil, nil, nil),
+			"failed":  {Error: "connection refused"},
+		}},
+	}
+	for _, run := range runs {
+		a.AddRun(flow, run)
+	}
+	report := a.Report()
+
+	// 429 is observed as the 4XX class and 500 falls back to default
+	list := operation(t, report, "listPets")
+	wantCodes := []StatusCode{{Code: "200"}, {Code: "4XX", Observed: true}, {Code: "DEFAULT", Observed: true}}
+	if !reflect.DeepEqual(list.StatusCodes, wantCodes) {
+		t.Errorf("listPets status codes = %+v, want %+v", list.StatusCodes, wantCodes)
+	}
+	wantParams := []Parameter{{Name: "offset", In: "query"}}
+	if !reflect.DeepEqual(list.UnexercisedParameters, wantParams) {
+		t.Errorf("listPets unexercised parameters = %+v, want %+v", list.UnexercisedParameters, wantParams)
+	}
+	if len(list.Sources) != 1 || list.Sources[0].Runs != 2 {
+		t.Errorf("listPets sources = %+v, want one node in 2 runs", list.Sources)
+	}
+
+	create := operation(t, report, "createPet")
+	if !reflect.DeepEqual(create.UndocumentedStatusCodes, []int{302}) {
+		t.Errorf("createPet undocumented status codes = %v, want [302]", create.UndocumentedStatusCodes)
+	}
+
+	get := operation(t, report, "getPet")
+	wantCodes = []StatusCode{{Code: "200"}, {Code: "404", Observed: true}}
+	if !reflect.DeepEqual(get.StatusCodes, wantCodes) {
+		t.Errorf("getPet status codes = %+v, want %+v", get.StatusCodes, wantCodes)
+	}
+
+	mine := operation(t, report, "listMyPets")
+	wantSources := []Source{{FlowID: flow.ID, FlowName: "Pets", NodeID: "removed", Runs: 1}}
+	if !reflect.DeepEqual(mine.Sources, wantSources) {
+		t.Errorf("listMyPets sources = %+v, want %+v", mine.Sources, wantSources)
+	}
+
+	if report.Summary.Runs != 2 || report.Summary.Flows != 0 {
+		t.Errorf("summary = %+v, want 2 runs of no added flows", report.Summary)
+	}
+}
+
+func TestDocumentedCode(t *testing.T) {
+	op, _ := parseSpec(t).Operation("listPets")
+	codes := op.StatusCodes()
+
+	tests := []struct {
+		status string
+		want   string // empty for none
+	}{
+		{status: "200", want: "200"},
+		{status: "404", want: "4XX"},
+		{status: "503", want: "DEFAULT"},
+		{status: "4xx", want: "4XX"},
+		{status: "4XX", want: "4XX"},
+		{status: "2xx"},
+		{status: "5xx"},
+		{status: "default", want: "DEFAULT"},
+	}
+	for _, tt := range tests {
+		got, ok := documentedCode(op, codes, tt.status)
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("documentedCode(%q) = %q, %v, want %q", tt.status, got, ok, tt.want)
+		}
+	}
+}
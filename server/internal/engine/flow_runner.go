@@ -2,11 +2,13 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/jsondiff"
 	"github.com/visual-api-testing-platform/server/internal/models"
 	"github.com/visual-api-testing-platform/server/internal/node"
 )
@@ -15,93 +17,175 @@ import (
 type FlowRunner struct {
 	nodeFactory *node.NodeFactory
 	hub         *ExecutionHub
+	snapshots   node.SnapshotStore
+	schemas     node.SchemaStore
+	specs       node.SpecStore
 }
 
-// NewFlowRunner creates a new flow runner
-func NewFlowRunner(hub *ExecutionHub) *FlowRunner {
+// NewFlowRunner creates a new flow runner. snapshots backs snapshot assertions,
+// schemas is the library schema assertions load from and specs the library API
+// node contracts load from.
+func NewFlowRunner(hub *ExecutionHub, snapshots node.SnapshotStore, schemas node.SchemaStore, specs node.SpecStore) *FlowRunner {
 	return &FlowRunner{
 		nodeFactory: node.NewNodeFactory(),
 		hub:         hub,
+		snapshots:   snapshots,
+		schemas:     schemas,
+		specs:       specs,
 	}
 }
 
-// ExecuteFlow executes a flow and returns the test run result
-func (r *FlowRunner) ExecuteFlow(ctx context.Context, flow *models.Flow) (*models.TestRun, error) {
+// RunOptions configures a single flow execution
+type RunOptions struct {
+	// TestRunID lets the caller know the run ID before execution starts
+	// (e.g. to subscribe to updates or cancel it). A new ID is generated when zero.
+	TestRunID uuid.UUID
+
+	// TriggerSource, Environment and Tags are recorded on the test run for filtering
+	TriggerSource string
+	Environment   string
+	Tags          []string
+
+	// QuarantinedNodes are executed and reported as usual, but their failures
+	// don't fail the run
+	QuarantinedNodes map[string]bool
+
+	// Variables override the flow's variables for this run
+	Variables map[string]interface{}
+
+	// Quiet runs don't broadcast updates through the hub, e.g. load test iterations
+	Quiet bool
+
+	// NodeDone, when set, is called as each node finishes with its status and
+	// how long it took. It may be called from several goroutines at once.
+	NodeDone func(nodeID string, status models.ExecutionStatus, elapsed time.Duration)
+}
+
+// ExecuteFlow executes a flow and returns the test run result.
+// When ctx is cancelled the partial run is returned with status cancelled.
+func (r *FlowRunner) ExecuteFlow(ctx context.Context, flow *models.Flow, opts RunOptions) (*models.TestRun, error) {
+	if opts.TestRunID == uuid.Nil {
+		opts.TestRunID = uuid.New()
+	}
+
 	testRun := &models.TestRun{
-		ID:          uuid.New(),
+		ID:          opts.TestRunID,
 		FlowID:      flow.ID,
 		FlowName:    flow.Name,
+		FlowVersion: flow.Version,
 		Status:      models.ExecutionStatusRunning,
 		StartedAt:   time.Now(),
 		NodeResults: make(map[string]models.NodeResult),
+
+		TriggerSource: opts.TriggerSource,
+		Environment:   opts.Environment,
+		Tags:          opts.Tags,
+	}
+	if testRun.TriggerSource == "" {
+		testRun.TriggerSource = models.TriggerSourceManual
+	}
+	if testRun.Tags == nil {
+		testRun.Tags = []string{}
 	}
 
-	// Build dependency graph
-	nodeMap := make(map[string]*models.FlowNode)
-	incomingEdges := make(map[string][]models.FlowEdge)
+	var mu sync.Mutex
+	// nodeOutputs holds successful outputs; they are never modified once stored
+	nodeOutputs := make(map[string]map[string]interface{})
+	nodeResults := make(map[string]models.NodeResult)
 
-	for i := range flow.Nodes {
-		nodeMap[flow.Nodes[i].ID] = &flow.Nodes[i]
+	// Build dependency graph. Edges from or to missing nodes are ignored.
+	nodeIDs := make(map[string]bool, len(flow.Nodes))
+	for _, n := range flow.Nodes {
+		if nodeIDs[n.ID] {
+			message := fmt.Sprintf("Duplicate node ID %s", n.ID)
+			r.finish(testRun, &mu, nodeResults, opts.Quiet, models.ExecutionStatusFailed, message)
+			return testRun, errors.New(message)
+		}
+		nodeIDs[n.ID] = true
 	}
-
+	incomingEdges := make(map[string][]models.FlowEdge)
 	for _, edge := range flow.Edges {
-		if incomingEdges[edge.Target] == nil {
-			incomingEdges[edge.Target] = make([]models.FlowEdge, 0)
+		if nodeIDs[edge.Source] && nodeIDs[edge.Target] {
+			incomingEdges[edge.Target] = append(incomingEdges[edge.Target], edge)
 		}
-		incomingEdges[edge.Target] = append(incomingEdges[edge.Target], edge)
 	}
-
-	// Find root nodes (nodes with no incoming edges)
-	rootNodes := make([]*models.FlowNode, 0)
-	for _, n := range flow.Nodes {
-		if len(incomingEdges[n.ID]) == 0 {
-			rootNodes = append(rootNodes, &n)
-		}
+	if nodeID, ok := findCycle(flow.Nodes, incomingEdges); ok {
+		message := fmt.Sprintf("Flow contains a cycle through node %s", nodeID)
+		r.finish(testRun, &mu, nodeResults, opts.Quiet, models.ExecutionStatusFailed, message)
+		return testRun, errors.New(message)
 	}
 
-	// Execute nodes concurrently
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	executedNodes := make(map[string]bool)
-	nodeOutputs := make(map[string]map[string]interface{})
+	run := node.RunInfo{
+		TestRunID:     testRun.ID,
+		FlowID:        flow.ID,
+		FlowName:      flow.Name,
+		FlowVersion:   flow.Version,
+		TriggerSource: testRun.TriggerSource,
+		Environment:   testRun.Environment,
+		Tags:          testRun.Tags,
+		StartedAt:     testRun.StartedAt,
+	}
+	variables := mergeVariables(flow.Variables, opts.Variables)
 
-	// Function to execute a node (declared first for recursive calls)
-	var executeNode func(*models.FlowNode)
-	executeNode = func(n *models.FlowNode) {
-		defer wg.Done()
+	// Function to execute a node once all its dependencies have finished
+	executeNode := func(n *models.FlowNode) {
+		startTime := time.Now()
+		assertions := &node.Assertions{}
+		if !opts.Quiet {
+			r.hub.BroadcastNodeUpdate(testRun.ID, n.ID, "running", nil, "")
+		}
 
-		// Check if dependencies are ready
-		deps := incomingEdges[n.ID]
-		for _, dep := range deps {
+		fail := func(err error, output map[string]interface{}) {
+			// A failing node may still return output describing the failure,
+			// e.g. per-assertion results; it is reported but not passed on
+			var failureOutput interface{}
+			if output != nil {
+				failureOutput = output
+			}
 			mu.Lock()
-			if !executedNodes[dep.Source] {
-				mu.Unlock()
-				// Dependency not ready, retry later
-				time.Sleep(100 * time.Millisecond)
-				wg.Add(1)
-				go executeNode(n)
-				return
+			nodeResults[n.ID] = models.NodeResult{
+				Status:      models.ExecutionStatusFailed,
+				Output:      failureOutput,
+				Error:       err.Error(),
+				Duration:    int(time.Since(startTime).Milliseconds()),
+				Quarantined: opts.QuarantinedNodes[n.ID],
+				Assertions:  assertions.Results(),
 			}
 			mu.Unlock()
+			if opts.NodeDone != nil {
+				opts.NodeDone(n.ID, models.ExecutionStatusFailed, time.Since(startTime))
+			}
+			if !opts.Quiet {
+				r.hub.BroadcastNodeUpdate(testRun.ID, n.ID, "failed", failureOutput, err.Error())
+			}
 		}
 
-		// Build input from dependencies
-		input := make(map[string]interface{})
+		// Each node gets private copies of the upstream outputs
+		deps := incomingEdges[n.ID]
+		upstream := make([]node.Upstream, 0, len(deps))
+		mu.Lock()
 		for _, dep := range deps {
+			result := nodeResults[dep.Source]
+			u := node.Upstream{
+				NodeID:       dep.Source,
+				SourceHandle: stringValue(dep.SourceHandle),
+				TargetHandle: stringValue(dep.TargetHandle),
+				Status:       result.Status,
+				Error:        result.Error,
+			}
 			if output, ok := nodeOutputs[dep.Source]; ok {
-				input[dep.Source] = output
-				// Also merge into top-level data
-				if data, ok := output["data"].(map[string]interface{}); ok {
-					for k, v := range data {
-						input[k] = v
-					}
-				}
+				u.Output = copyMap(output)
 			}
+			upstream = append(upstream, u)
 		}
+		mu.Unlock()
 
-		// Create and execute node
-		startTime := time.Now()
-		r.hub.BroadcastNodeUpdate(testRun.ID, n.ID, "running", nil, "")
+		strategy, _ := n.Data.Config["inputMerge"].(string)
+		input, err := node.BuildInput(upstream, strategy)
+		if err != nil {
+			fail(err, nil)
+			return
+		}
 
 		nodeInstance, err := r.nodeFactory.CreateNode(
 			n.Data.Type,
@@ -110,78 +194,99 @@ func (r *FlowRunner) ExecuteFlow(ctx context.Context, flow *models.Flow) (*model
 			n.Data.Config,
 		)
 		if err != nil {
-			mu.Lock()
-			testRun.NodeResults[n.ID] = models.NodeResult{
-				Status:   models.ExecutionStatusFailed,
-				Error:    err.Error(),
-				Duration: int(time.Since(startTime).Milliseconds()),
-			}
-			mu.Unlock()
-			r.hub.BroadcastNodeUpdate(testRun.ID, n.ID, "failed", nil, err.Error())
+			fail(err, nil)
 			return
 		}
 
-		output, err := nodeInstance.Execute(ctx, input)
-		duration := int(time.Since(startTime).Milliseconds())
+		nodeCtx := node.WithExecution(ctx, node.Execution{
+			Run:        run,
+			NodeID:     n.ID,
+			Snapshots:  r.snapshots,
+			Schemas:    r.schemas,
+			Specs:      r.specs,
+			Assertions: assertions,
+			Upstream:   upstream,
+			Variables:  copyMap(variables),
+		})
+		output, err := nodeInstance.Execute(nodeCtx, input)
+		if err != nil {
+			fail(err, output)
+			return
+		}
+		elapsed := time.Since(startTime)
 
 		mu.Lock()
-		executedNodes[n.ID] = true
-		if err != nil {
-			testRun.NodeResults[n.ID] = models.NodeResult{
-				Status:   models.ExecutionStatusFailed,
-				Error:    err.Error(),
-				Duration: duration,
-			}
-			r.hub.BroadcastNodeUpdate(testRun.ID, n.ID, "failed", nil, err.Error())
-		} else {
-			nodeOutputs[n.ID] = output
-			testRun.NodeResults[n.ID] = models.NodeResult{
-				Status:   models.ExecutionStatusSuccess,
-				Output:   output,
-				Duration: duration,
-			}
-			r.hub.BroadcastNodeUpdate(testRun.ID, n.ID, "success", output, "")
+		nodeOutputs[n.ID] = output
+		nodeResults[n.ID] = models.NodeResult{
+			Status:      models.ExecutionStatusSuccess,
+			Output:      output,
+			Duration:    int(elapsed.Milliseconds()),
+			Quarantined: opts.QuarantinedNodes[n.ID],
+			Assertions:  assertions.Results(),
 		}
 		mu.Unlock()
+		if opts.NodeDone != nil {
+			opts.NodeDone(n.ID, models.ExecutionStatusSuccess, elapsed)
+		}
+		if !opts.Quiet {
+			r.hub.BroadcastNodeUpdate(testRun.ID, n.ID, "success", output, "")
+		}
 	}
 
-	// Execute root nodes first
-	for _, rootNode := range rootNodes {
-		wg.Add(1)
-		go executeNode(rootNode)
+	// Start every node at once; each waits for its dependencies to finish.
+	// finished[id] is closed when node id has completed, successfully or not.
+	finished := make(map[string]chan struct{}, len(flow.Nodes))
+	for _, n := range flow.Nodes {
+		finished[n.ID] = make(chan struct{})
 	}
 
-	// Execute remaining nodes
-	for _, n := range flow.Nodes {
-		if len(incomingEdges[n.ID]) > 0 {
-			wg.Add(1)
-			go executeNode(&n)
-		}
+	var wg sync.WaitGroup
+	for i := range flow.Nodes {
+		n := &flow.Nodes[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(finished[n.ID])
+
+			for _, dep := range incomingEdges[n.ID] {
+				select {
+				case <-finished[dep.Source]:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			executeNode(n)
+		}()
 	}
 
 	// Wait for all nodes to complete (with timeout)
-	done := make(chan bool)
+	done := make(chan struct{})
 	go func() {
 		wg.Wait()
-		done <- true
+		close(done)
 	}()
 
 	select {
 	case <-done:
 		// All nodes completed
 	case <-ctx.Done():
-		testRun.Status = models.ExecutionStatusFailed
-		testRun.Error = "Execution cancelled"
+		r.finish(testRun, &mu, nodeResults, opts.Quiet, models.ExecutionStatusCancelled, "Execution cancelled")
 		return testRun, ctx.Err()
 	case <-time.After(5 * time.Minute):
-		testRun.Status = models.ExecutionStatusFailed
-		testRun.Error = "Execution timeout"
+		r.finish(testRun, &mu, nodeResults, opts.Quiet, models.ExecutionStatusFailed, "Execution timeout")
 		return testRun, fmt.Errorf("execution timeout")
 	}
 
-	// Check if any node failed
+	mu.Lock()
+	testRun.NodeResults = nodeResults
+	mu.Unlock()
+
+	// Check if any node failed; quarantined failures are reported but tolerated
 	for _, result := range testRun.NodeResults {
-		if result.Status == models.ExecutionStatusFailed {
+		if result.Status == models.ExecutionStatusFailed && !result.Quarantined {
 			testRun.Status = models.ExecutionStatusFailed
 			break
 		}
@@ -196,8 +301,123 @@ func (r *FlowRunner) ExecuteFlow(ctx context.Context, flow *models.Flow) (*model
 	duration := int(time.Since(testRun.StartedAt).Milliseconds())
 	testRun.DurationMs = &duration
 
-	r.hub.BroadcastTestRunComplete(testRun)
+	if !opts.Quiet {
+		r.hub.BroadcastTestRunComplete(testRun)
+	}
 
 	return testRun, nil
 }
 
+// NodeInput rebuilds the input a node received from the outputs of the nodes
+// it depends on, e.g. from a stored test run
+func NodeInput(flow *models.Flow, nodeID string, outputs map[string]map[string]interface{}) (map[string]interface{}, error) {
+	var upstream []node.Upstream
+	for _, edge := range flow.Edges {
+		if edge.Target != nodeID {
+			continue
+		}
+		u := node.Upstream{
+			NodeID:       edge.Source,
+			SourceHandle: stringValue(edge.SourceHandle),
+			TargetHandle: stringValue(edge.TargetHandle),
+		}
+		if output, ok := outputs[edge.Source]; ok {
+			u.Status = models.ExecutionStatusSuccess
+			u.Output = copyMap(output)
+		}
+		upstream = append(upstream, u)
+	}
+
+	var strategy string
+	for _, n := range flow.Nodes {
+		if n.ID == nodeID {
+			strategy, _ = n.Data.Config["inputMerge"].(string)
+		}
+	}
+	return node.BuildInput(upstream, strategy)
+}
+
+// findCycle reports a node on a dependency cycle, if there is one
+func findCycle(nodes []models.FlowNode, incomingEdges map[string][]models.FlowEdge) (string, bool) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(nodes))
+
+	var visit func(id string) (string, bool)
+	visit = func(id string) (string, bool) {
+		switch state[id] {
+		case visiting:
+			return id, true
+		case visited:
+			return "", false
+		}
+		state[id] = visiting
+		for _, edge := range incomingEdges[id] {
+			if cycleNode, ok := visit(edge.Source); ok {
+				return cycleNode, true
+			}
+		}
+		state[id] = visited
+		return "", false
+	}
+
+	for _, n := range nodes {
+		if cycleNode, ok := visit(n.ID); ok {
+			return cycleNode, true
+		}
+	}
+	return "", false
+}
+
+// mergeVariables overlays the run's variables on the flow's
+func mergeVariables(flowVariables, runVariables map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(flowVariables)+len(runVariables))
+	for k, v := range flowVariables {
+		merged[k] = v
+	}
+	for k, v := range runVariables {
+		merged[k] = v
+	}
+	return merged
+}
+
+// copyMap deep-copies a JSON-like map so that concurrently running nodes
+// never share mutable values
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	copied, _ := jsondiff.Normalize(m).(map[string]interface{})
+	if copied == nil {
+		copied = make(map[string]interface{})
+	}
+	return copied
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// finish completes a run that was interrupted before all nodes finished.
+// Node goroutines may still be writing results, so a snapshot is taken under the lock.
+func (r *FlowRunner) finish(testRun *models.TestRun, mu *sync.Mutex, nodeResults map[string]models.NodeResult, quiet bool, status models.ExecutionStatus, message string) {
+	mu.Lock()
+	for id, result := range nodeResults {
+		testRun.NodeResults[id] = result
+	}
+	mu.Unlock()
+
+	testRun.Status = status
+	testRun.Error = message
+	completedAt := time.Now()
+	testRun.CompletedAt = &completedAt
+	duration := int(time.Since(testRun.StartedAt).Milliseconds())
+	testRun.DurationMs = &duration
+
+	if !quiet {
+		r.hub.BroadcastTestRunComplete(testRun)
+	}
+}
@@ -62,10 +62,14 @@ func (h *ExecutionHub) Run() {
 			}
 
 		case message := <-h.broadcast:
-			// Parse message to get testRunID
+			// Parse message to get the test run or load run it is about
 			var msg map[string]interface{}
 			if err := json.Unmarshal(message, &msg); err == nil {
-				if testRunIDStr, ok := msg["testRunId"].(string); ok {
+				testRunIDStr, ok := msg["testRunId"].(string)
+				if !ok {
+					testRunIDStr, ok = msg["loadRunId"].(string)
+				}
+				if ok {
 					if testRunID, err := uuid.Parse(testRunIDStr); err == nil {
 						if clients, ok := h.clients[testRunID]; ok {
 							for client := range clients {
@@ -112,6 +116,35 @@ func (h *ExecutionHub) BroadcastTestRunComplete(testRun *models.TestRun) {
 	h.broadcast <- data
 }
 
+// BroadcastLoadProgress broadcasts the live metrics of a load run
+func (h *ExecutionHub) BroadcastLoadProgress(loadRunID uuid.UUID, metrics *models.LoadMetrics) {
+	message := map[string]interface{}{
+		"type":      "load_progress",
+		"loadRunId": loadRunID.String(),
+		"metrics":   metrics,
+	}
+
+	data, _ := json.Marshal(message)
+	h.broadcast <- data
+}
+
+// BroadcastLoadComplete broadcasts load run completion with its final
+// metrics and threshold results
+func (h *ExecutionHub) BroadcastLoadComplete(loadRun *models.LoadRun) {
+	message := map[string]interface{}{
+		"type":       "load_complete",
+		"loadRunId":  loadRun.ID.String(),
+		"status":     string(loadRun.Status),
+		"duration":   loadRun.DurationMs,
+		"metrics":    loadRun.Metrics,
+		"thresholds": loadRun.Thresholds,
+		"error":      loadRun.Error,
+	}
+
+	data, _ := json.Marshal(message)
+	h.broadcast <- data
+}
+
 // Register registers a new client
 func (h *ExecutionHub) Register(client *Client) {
 	h.register <- client
@@ -0,0 +1,486 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/histogram"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/node"
+)
+
+// Load test limits
+const (
+	MaxLoadVUs        = 200
+	MaxLoadIterations = 100000 // per virtual user
+	MaxLoadTestTime   = time.Hour
+)
+
+const (
+	// loadProgressInterval is how often live metrics are broadcast
+	loadProgressInterval = time.Second
+
+	// loadGracePeriod is how long iterations still running when a load test's
+	// time is up may take before they are abandoned
+	loadGracePeriod = 30 * time.Second
+)
+
+// LoadOptions configures a load test
+type LoadOptions struct {
+	// LoadRunID lets the caller know the run ID before it starts. A new ID is
+	// generated when zero.
+	LoadRunID uuid.UUID
+
+	Config models.LoadConfig
+
+	// Environment, Tags, QuarantinedNodes and Variables apply to every
+	// iteration as they do to a single run
+	Environment      string
+	Tags             []string
+	QuarantinedNodes map[string]bool
+	Variables        map[string]interface{}
+
+	// Progress, when set, receives the live metrics each time they are broadcast
+	Progress func(metrics *models.LoadMetrics)
+}
+
+// loadPlan is a validated load test configuration
+type loadPlan struct {
+	vus          int
+	rampUp       time.Duration
+	rampUpSteps  int
+	duration     time.Duration // zero when iterations is set
+	iterations   int
+	thinkTime    time.Duration
+	thinkTimeMax time.Duration
+	thresholds   []threshold
+}
+
+// ValidateLoadConfig checks a load test configuration, and that its
+// thresholds refer to nodes of the flow
+func ValidateLoadConfig(flow *models.Flow, config models.LoadConfig) error {
+	_, err := newLoadPlan(flow, config)
+	return err
+}
+
+func newLoadPlan(flow *models.Flow, config models.LoadConfig) (*loadPlan, error) {
+	plan := &loadPlan{vus: config.VUs, rampUpSteps: config.RampUpSteps, iterations: config.Iterations}
+	if plan.vus < 1 || plan.vus > MaxLoadVUs {
+		return nil, fmt.Errorf("vus must be between 1 and %d", MaxLoadVUs)
+	}
+	if plan.rampUpSteps < 0 || plan.rampUpSteps > plan.vus {
+		return nil, fmt.Errorf("ramp_up_steps must be between 0 and vus")
+	}
+
+	durations := []struct {
+		name   string
+		value  string
+		target *time.Duration
+	}{
+		{"ramp_up", config.RampUp, &plan.rampUp},
+		{"duration", config.Duration, &plan.duration},
+		{"think_time", config.ThinkTime, &plan.thinkTime},
+		{"think_time_max", config.ThinkTimeMax, &plan.thinkTimeMax},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("%s must be a duration such as 30s or 5m", d.name)
+		}
+		*d.target = parsed
+	}
+
+	switch {
+	case config.Duration != "" && config.Iterations != 0:
+		return nil, fmt.Errorf("set either duration or iterations, not both")
+	case config.Duration != "":
+		if plan.duration <= 0 {
+			return nil, fmt.Errorf("duration must be positive")
+		}
+	case config.Iterations < 1 || config.Iterations > MaxLoadIterations:
+		return nil, fmt.Errorf("set a duration, or iterations between 1 and %d", MaxLoadIterations)
+	}
+	if plan.rampUp+plan.duration > MaxLoadTestTime {
+		return nil, fmt.Errorf("ramp_up and duration together cannot be longer than %s", MaxLoadTestTime)
+	}
+	if plan.thinkTimeMax != 0 && plan.thinkTimeMax < plan.thinkTime {
+		return nil, fmt.Errorf("think_time_max cannot be shorter than think_time")
+	}
+
+	if len(flow.Nodes) == 0 {
+		return nil, fmt.Errorf("flow has no nodes")
+	}
+	if err := checkGraph(flow); err != nil {
+		return nil, err
+	}
+
+	nodeIDs := make(map[string]bool, len(flow.Nodes))
+	for _, n := range flow.Nodes {
+		nodeIDs[n.ID] = true
+	}
+	for _, text := range config.Thresholds {
+		t, err := parseThreshold(text)
+		if err != nil {
+			return nil, err
+		}
+		if t.scope != scopeRequests && t.scope != scopeIterations && !nodeIDs[t.scope] {
+			return nil, fmt.Errorf("threshold %q: flow has no node %s", text, t.scope)
+		}
+		plan.thresholds = append(plan.thresholds, t)
+	}
+	return plan, nil
+}
+
+// checkGraph finds the problems that keep ExecuteFlow from running a flow at all
+func checkGraph(flow *models.Flow) error {
+	nodeIDs := make(map[string]bool, len(flow.Nodes))
+	for _, n := range flow.Nodes {
+		if nodeIDs[n.ID] {
+			return fmt.Errorf("duplicate node ID %s", n.ID)
+		}
+		nodeIDs[n.ID] = true
+	}
+	incomingEdges := make(map[string][]models.FlowEdge)
+	for _, edge := range flow.Edges {
+		if nodeIDs[edge.Source] && nodeIDs[edge.Target] {
+			incomingEdges[edge.Target] = append(incomingEdges[edge.Target], edge)
+		}
+	}
+	if nodeID, ok := findCycle(flow.Nodes, incomingEdges); ok {
+		return fmt.Errorf("flow contains a cycle through node %s", nodeID)
+	}
+	return nil
+}
+
+// startDelay is when a virtual user, numbered from 1, starts during ramp-up
+func (p *loadPlan) startDelay(vu int) time.Duration {
+	if p.rampUp == 0 {
+		return 0
+	}
+	if p.rampUpSteps > 0 {
+		step := (vu - 1) * p.rampUpSteps / p.vus
+		return p.rampUp * time.Duration(step) / time.Duration(p.rampUpSteps)
+	}
+	return p.rampUp * time.Duration(vu-1) / time.Duration(p.vus)
+}
+
+// pause is the think time before a virtual user's next iteration
+func (p *loadPlan) pause(random *rand.Rand) time.Duration {
+	if p.thinkTimeMax > p.thinkTime {
+		return p.thinkTime + time.Duration(random.Int63n(int64(p.thinkTimeMax-p.thinkTime)+1))
+	}
+	return p.thinkTime
+}
+
+// ExecuteLoad runs a load test of a flow: virtual users each execute it
+// repeatedly, with their own copy of the variables plus __vu (their number)
+// and __iteration, until they have run their iterations or the duration is
+// over. Iterations don't broadcast node updates; instead the aggregated
+// metrics are broadcast every second. The run fails when a threshold is
+// breached. When ctx is cancelled, running iterations are abandoned and the
+// load run is returned with status cancelled.
+func (r *FlowRunner) ExecuteLoad(ctx context.Context, flow *models.Flow, opts LoadOptions) (*models.LoadRun, error) {
+	if opts.LoadRunID == uuid.Nil {
+		opts.LoadRunID = uuid.New()
+	}
+
+	loadRun := &models.LoadRun{
+		ID:          opts.LoadRunID,
+		FlowID:      flow.ID,
+		FlowName:    flow.Name,
+		FlowVersion: flow.Version,
+		Status:      models.ExecutionStatusRunning,
+		Config:      opts.Config,
+		Thresholds:  []models.ThresholdResult{},
+		Environment: opts.Environment,
+		Tags:        opts.Tags,
+		StartedAt:   time.Now(),
+	}
+	if loadRun.Tags == nil {
+		loadRun.Tags = []string{}
+	}
+
+	plan, err := newLoadPlan(flow, opts.Config)
+	if err != nil {
+		r.finishLoad(loadRun, models.ExecutionStatusFailed, err.Error())
+		return loadRun, err
+	}
+
+	collector := newLoadCollector(flow, loadRun.StartedAt)
+
+	// Virtual users start no iterations after the deadline, and those still
+	// running are abandoned once the grace period is over too
+	deadline := loadRun.StartedAt.Add(MaxLoadTestTime)
+	if plan.duration > 0 {
+		deadline = loadRun.StartedAt.Add(plan.rampUp + plan.duration)
+	}
+	runCtx, stop := context.WithDeadline(ctx, deadline.Add(loadGracePeriod))
+	defer stop()
+
+	var wg sync.WaitGroup
+	for vu := 1; vu <= plan.vus; vu++ {
+		wg.Add(1)
+		go func(vu int) {
+			defer wg.Done()
+			r.runVirtualUser(runCtx, flow, opts, plan, collector, vu, deadline)
+		}(vu)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(loadProgressInterval)
+	defer ticker.Stop()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		case <-ticker.C:
+			metrics := collector.snapshot()
+			r.hub.BroadcastLoadProgress(loadRun.ID, metrics)
+			if opts.Progress != nil {
+				opts.Progress(metrics)
+			}
+		}
+	}
+
+	loadRun.Metrics = collector.snapshot()
+	if ctx.Err() != nil {
+		r.finishLoad(loadRun, models.ExecutionStatusCancelled, "Load test cancelled")
+		return loadRun, ctx.Err()
+	}
+
+	var breached []string
+	for _, t := range plan.thresholds {
+		result := collector.evaluate(t)
+		loadRun.Thresholds = append(loadRun.Thresholds, result)
+		if !result.Passed {
+			breached = append(breached, t.text)
+		}
+	}
+	if len(breached) > 0 {
+		r.finishLoad(loadRun, models.ExecutionStatusFailed, fmt.Sprintf("%d threshold(s) breached: %s", len(breached), strings.Join(breached, "; ")))
+		return loadRun, nil
+	}
+
+	r.finishLoad(loadRun, models.ExecutionStatusSuccess, "")
+	return loadRun, nil
+}
+
+// runVirtualUser executes the flow over and over for one virtual user
+func (r *FlowRunner) runVirtualUser(ctx context.Context, flow *models.Flow, opts LoadOptions, plan *loadPlan, collector *loadCollector, vu int, deadline time.Time) {
+	if !sleepUntil(ctx, plan.startDelay(vu), deadline) {
+		return
+	}
+	collector.addActive(1)
+	defer collector.addActive(-1)
+
+	variables := mergeVariables(opts.Variables, map[string]interface{}{"__vu": vu})
+	random := rand.New(rand.NewSource(time.Now().UnixNano() + int64(vu)))
+
+	for iteration := 1; plan.iterations == 0 || iteration <= plan.iterations; iteration++ {
+		if !time.Now().Before(deadline) || ctx.Err() != nil {
+			return
+		}
+		variables["__iteration"] = iteration
+
+		start := time.Now()
+		testRun, err := r.ExecuteFlow(ctx, flow, RunOptions{
+			TriggerSource:    models.TriggerSourceLoad,
+			Environment:      opts.Environment,
+			Tags:             opts.Tags,
+			QuarantinedNodes: opts.QuarantinedNodes,
+			Variables:        variables,
+			Quiet:            true,
+			NodeDone:         collector.nodeDone,
+		})
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			// Abandoned iterations aren't counted
+			return
+		}
+		collector.iterationDone(testRun.Status == models.ExecutionStatusFailed, time.Since(start))
+
+		if !sleepUntil(ctx, plan.pause(random), deadline) {
+			return
+		}
+	}
+}
+
+// sleepUntil waits for d, but no later than the deadline. It reports false
+// when ctx is cancelled or the deadline passes first.
+func sleepUntil(ctx context.Context, d time.Duration, deadline time.Time) bool {
+	if remaining := time.Until(deadline); d >= remaining {
+		d = remaining
+		if d <= 0 {
+			return false
+		}
+	}
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return time.Now().Before(deadline)
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// finishLoad completes a load run and broadcasts the result
+func (r *FlowRunner) finishLoad(loadRun *models.LoadRun, status models.ExecutionStatus, message string) {
+	loadRun.Status = status
+	loadRun.Error = message
+	completedAt := time.Now()
+	loadRun.CompletedAt = &completedAt
+	duration := int(time.Since(loadRun.StartedAt).Milliseconds())
+	loadRun.DurationMs = &duration
+
+	r.hub.BroadcastLoadComplete(loadRun)
+}
+
+// loadStats counts executions of one kind and their latencies
+type loadStats struct {
+	count   int64
+	errors  int64
+	latency *histogram.Histogram
+}
+
+func newLoadStats() *loadStats {
+	return &loadStats{latency: histogram.NewLatency()}
+}
+
+func (s *loadStats) record(failed bool, elapsed time.Duration) {
+	s.count++
+	if failed {
+		s.errors++
+	}
+	s.latency.RecordDuration(elapsed)
+}
+
+// summary reports the stats over the elapsed time of the test
+func (s *loadStats) summary(elapsed time.Duration) models.LoadStats {
+	stats := models.LoadStats{Count: s.count, Errors: s.errors}
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		stats.Rate = float64(s.count) / seconds
+	}
+	if s.count > 0 {
+		stats.ErrorRate = float64(s.errors) / float64(s.count)
+	}
+	stats.Latency = models.LatencySummary{
+		Min:  microsToMs(float64(s.latency.Min())),
+		Mean: microsToMs(s.latency.Mean()),
+		P50:  microsToMs(float64(s.latency.Percentile(50))),
+		P90:  microsToMs(float64(s.latency.Percentile(90))),
+		P95:  microsToMs(float64(s.latency.Percentile(95))),
+		P99:  microsToMs(float64(s.latency.Percentile(99))),
+		Max:  microsToMs(float64(s.latency.Max())),
+	}
+	return stats
+}
+
+func microsToMs(micros float64) float64 {
+	return float64(int64(micros+0.5)) / 1000
+}
+
+// loadCollector aggregates the executions of every virtual user
+type loadCollector struct {
+	mu         sync.Mutex
+	startedAt  time.Time
+	activeVUs  int
+	apiNodes   map[string]bool
+	iterations *loadStats
+	requests   *loadStats
+	nodes      map[string]*loadStats
+}
+
+func newLoadCollector(flow *models.Flow, startedAt time.Time) *loadCollector {
+	c := &loadCollector{
+		startedAt:  startedAt,
+		apiNodes:   make(map[string]bool),
+		iterations: newLoadStats(),
+		requests:   newLoadStats(),
+		nodes:      make(map[string]*loadStats, len(flow.Nodes)),
+	}
+	for _, n := range flow.Nodes {
+		if n.Data.Type == string(node.NodeTypeAPI) {
+			c.apiNodes[n.ID] = true
+		}
+	}
+	return c
+}
+
+func (c *loadCollector) addActive(delta int) {
+	c.mu.Lock()
+	c.activeVUs += delta
+	c.mu.Unlock()
+}
+
+// nodeDone is the RunOptions.NodeDone of every iteration
+func (c *loadCollector) nodeDone(nodeID string, status models.ExecutionStatus, elapsed time.Duration) {
+	failed := status == models.ExecutionStatusFailed
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats, ok := c.nodes[nodeID]
+	if !ok {
+		stats = newLoadStats()
+		c.nodes[nodeID] = stats
+	}
+	stats.record(failed, elapsed)
+	if c.apiNodes[nodeID] {
+		c.requests.record(failed, elapsed)
+	}
+}
+
+func (c *loadCollector) iterationDone(failed bool, elapsed time.Duration) {
+	c.mu.Lock()
+	c.iterations.record(failed, elapsed)
+	c.mu.Unlock()
+}
+
+func (c *loadCollector) snapshot() *models.LoadMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elapsed := time.Since(c.startedAt)
+	metrics := &models.LoadMetrics{
+		ElapsedMs:  elapsed.Milliseconds(),
+		ActiveVUs:  c.activeVUs,
+		Iterations: c.iterations.summary(elapsed),
+		Requests:   c.requests.summary(elapsed),
+		Nodes:      make(map[string]models.LoadStats, len(c.nodes)),
+	}
+	for id, stats := range c.nodes {
+		metrics.Nodes[id] = stats.summary(elapsed)
+	}
+	return metrics
+}
+
+// evaluate checks a threshold against what has been collected
+func (c *loadCollector) evaluate(t threshold) models.ThresholdResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var stats *loadStats
+	switch t.scope {
+	case scopeRequests:
+		stats = c.requests
+	case scopeIterations:
+		stats = c.iterations
+	default:
+		stats = c.nodes[t.scope]
+	}
+	return t.evaluate(stats, time.Since(c.startedAt))
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/node"
+)
+
+func TestStartDelay(t *testing.T) {
+	tests := []struct {
+		name string
+		plan loadPlan
+		want []time.Duration // for virtual users 1, 2, ...
+	}{
+		{
+			name: "no ramp-up",
+			plan: loadPlan{vus: 3},
+			want: []time.Duration{0, 0, 0},
+		},
+		{
+			name: "linear",
+			plan: loadPlan{vus: 4, rampUp: 8 * time.Second},
+			want: []time.Duration{0, 2 * time.Second, 4 * time.Second, 6 * time.Second},
+		},
+		{
+			name: "linear with an uneven split",
+			plan: loadPlan{vus: 3, rampUp: time.Second},
+			want: []time.Duration{0, 333333333, 666666666},
+		},
+		{
+			name: "steps",
+			plan: loadPlan{vus: 6, rampUp: 10 * time.Second, rampUpSteps: 2},
+			want: []time.Duration{0, 0, 0, 5 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name: "uneven steps",
+			plan: loadPlan{vus: 5, rampUp: 9 * time.Second, rampUpSteps: 3},
+			want: []time.Duration{0, 0, 3 * time.Second, 3 * time.Second, 6 * time.Second},
+		},
+		{
+			name: "one step per user",
+			plan: loadPlan{vus: 2, rampUp: 4 * time.Second, rampUpSteps: 2},
+			want: []time.Duration{0, 2 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.plan.startDelay(i + 1); got != want {
+					t.Errorf("VU %d starts after %v, want %v", i+1, got, want)
+				}
+			}
+			// Every user starts before the ramp-up is over
+			if last := tt.plan.startDelay(tt.plan.vus); tt.plan.rampUp > 0 && last >= tt.plan.rampUp {
+				t.Errorf("last VU starts after %v, at or after the ramp-up", last)
+			}
+		})
+	}
+}
+
+func TestPause(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    loadPlan
+		min     time.Duration
+		max     time.Duration
+		varying bool
+	}{
+		{name: "no think time", plan: loadPlan{}, min: 0, max: 0},
+		{name: "fixed", plan: loadPlan{thinkTime: time.Second}, min: time.Second, max: time.Second},
+		{name: "max equal to think time", plan: loadPlan{thinkTime: time.Second, thinkTimeMax: time.Second}, min: time.Second, max: time.Second},
+		{name: "random", plan: loadPlan{thinkTime: time.Second, thinkTimeMax: 3 * time.Second}, min: time.Second, max: 3 * time.Second, varying: true},
+		{name: "random from zero", plan: loadPlan{thinkTimeMax: 10 * time.Millisecond}, min: 0, max: 10 * time.Millisecond, varying: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			random := rand.New(rand.NewSource(1))
+			seen := make(map[time.Duration]bool)
+			for i := 0; i < 200; i++ {
+				got := tt.plan.pause(random)
+				if got < tt.min || got > tt.max {
+					t.Fatalf("pause %v outside [%v, %v]", got, tt.min, tt.max)
+				}
+				seen[got] = true
+			}
+			if tt.varying && len(seen) < 2 {
+				t.Errorf("pause is always %v", tt.min)
+			}
+		})
+	}
+}
+
+// rejectingSourceSnapshots stores snapshots in memory and, like the foreign
+// key on node_snapshots, rejects a source run that was never saved. Load test
+// iterations never are.
+type rejectingSourceSnapshots struct {
+	mu        sync.Mutex
+	snapshots map[string]*models.NodeSnapshot
+}
+
+func (s *rejectingSourceSnapshots) FindSnapshot(ctx context.Context, flowID uuid.UUID, nodeID string) (*models.NodeSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshots[nodeID], nil
+}
+
+func (s *rejectingSourceSnapshots) RecordSnapshot(ctx context.Context, snapshot *models.NodeSnapshot) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snapshot.SourceTestRunID != nil {
+		return false, errors.New("violates foreign key constraint on source_test_run_id")
+	}
+	if s.snapshots[snapshot.NodeID] != nil {
+		return false, nil
+	}
+	s.snapshots[snapshot.NodeID] = snapshot
+	return true, nil
+}
+
+func TestExecuteLoadRecordsSnapshots(t *testing.T) {
+	hub := NewExecutionHub()
+	go hub.Run()
+	snapshots := &rejectingSourceSnapshots{snapshots: make(map[string]*models.NodeSnapshot)}
+	runner := NewFlowRunner(hub, snapshots, nil, nil)
+
+	flow := &models.Flow{
+		ID:   uuid.New(),
+		Name: "snapshot under load",
+		Nodes: []models.FlowNode{{
+			ID:   "verify",
+			Type: string(node.NodeTypeVerification),
+			Data: models.NodeData{
+				ID:     "verify",
+				Type:   string(node.NodeTypeVerification),
+				Label:  "Verify",
+				Config: map[string]interface{}{"assertionType": "snapshot"},
+			},
+		}},
+	}
+
+	loadRun, err := runner.ExecuteLoad(context.Background(), flow, LoadOptions{Config: models.LoadConfig{VUs: 2, Iterations: 3}})
+	if err != nil {
+		t.Fatalf("ExecuteLoad: %v", err)
+	}
+	if loadRun.Status != models.ExecutionStatusSuccess {
+		t.Fatalf("load run %s: %s", loadRun.Status, loadRun.Error)
+	}
+	if loadRun.Metrics.Iterations.Count != 6 || loadRun.Metrics.Iterations.Errors != 0 {
+		t.Errorf("%d iterations with %d errors, want 6 without errors", loadRun.Metrics.Iterations.Count, loadRun.Metrics.Iterations.Errors)
+	}
+	if snapshots.snapshots["verify"] == nil {
+		t.Error("no snapshot was recorded")
+	}
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// Threshold scopes besides node IDs
+const (
+	scopeRequests   = "requests"   // every API node execution; the default
+	scopeIterations = "iterations" // executions of the whole flow
+)
+
+// threshold is a parsed load test threshold such as "get-user: p95 < 300ms"
+type threshold struct {
+	text       string
+	scope      string
+	metric     string  // min, avg, max, pNN, rate or error_rate
+	percentile float64 // for pNN
+	operator   string
+	limit      float64 // milliseconds for latencies, a fraction for error_rate
+}
+
+var (
+	thresholdPattern = regexp.MustCompile(`^\s*(?:([^:<>=]+?)\s*:\s*)?([a-z_]+|p\d+(?:\.\d+)?)\s*(<=|>=|<|>)\s*(\d+(?:\.\d+)?)\s*(ms|s|%)?\s*$`)
+	percentileMetric = regexp.MustCompile(`^p\d+(?:\.\d+)?$`)
+)
+
+// parseThreshold reads "[scope:] metric operator value[unit]". Latencies are
+// in milliseconds unless given in seconds with s; error rates are fractions
+// unless given as a percentage.
+func parseThreshold(text string) (threshold, error) {
+	match := thresholdPattern.FindStringSubmatch(text)
+	if match == nil {
+		return threshold{}, fmt.Errorf("threshold %q must look like \"p95 < 300ms\" or \"node-id: error_rate < 1%%\"", text)
+	}
+
+	t := threshold{text: text, scope: match[1], metric: match[2], operator: match[3]}
+	if t.scope == "" {
+		t.scope = scopeRequests
+	}
+	t.limit, _ = strconv.ParseFloat(match[4], 64)
+	unit := match[5]
+
+	switch {
+	case t.metric == "min" || t.metric == "max" || t.metric == "avg":
+	case percentileMetric.MatchString(t.metric):
+		t.percentile, _ = strconv.ParseFloat(t.metric[1:], 64)
+		if t.percentile <= 0 || t.percentile > 100 {
+			return threshold{}, fmt.Errorf("threshold %q: percentiles must be above p0 and at most p100", text)
+		}
+	case t.metric == "rate":
+		if unit != "" {
+			return threshold{}, fmt.Errorf("threshold %q: rate is per second and takes no unit", text)
+		}
+		return t, nil
+	case t.metric == "error_rate":
+		switch unit {
+		case "%":
+			t.limit /= 100
+		case "":
+		default:
+			return threshold{}, fmt.Errorf("threshold %q: error_rate is a fraction or a percentage", text)
+		}
+		return t, nil
+	default:
+		return threshold{}, fmt.Errorf("threshold %q: unknown metric %s; use min, avg, max, a percentile such as p95, rate or error_rate", text, t.metric)
+	}
+
+	// The remaining metrics are latencies
+	switch unit {
+	case "s":
+		t.limit *= 1000
+	case "ms", "":
+	default:
+		return threshold{}, fmt.Errorf("threshold %q: latencies are in ms or s", text)
+	}
+	return t, nil
+}
+
+// evaluate checks the threshold against the stats of its scope, collected
+// over elapsed. Thresholds on a scope that recorded nothing fail.
+func (t threshold) evaluate(stats *loadStats, elapsed time.Duration) models.ThresholdResult {
+	result := models.ThresholdResult{Threshold: t.text, Scope: t.scope, Metric: t.metric}
+	if stats == nil || stats.count == 0 {
+		return result
+	}
+
+	var actual float64
+	switch t.metric {
+	case "min":
+		actual = microsToMs(float64(stats.latency.Min()))
+	case "avg":
+		actual = microsToMs(stats.latency.Mean())
+	case "max":
+		actual = microsToMs(float64(stats.latency.Max()))
+	case "rate":
+		if seconds := elapsed.Seconds(); seconds > 0 {
+			actual = float64(stats.count) / seconds
+		}
+	case "error_rate":
+		actual = float64(stats.errors) / float64(stats.count)
+	default:
+		actual = microsToMs(float64(stats.latency.Percentile(t.percentile)))
+	}
+	result.Actual = &actual
+
+	switch t.operator {
+	case "<":
+		result.Passed = actual < t.limit
+	case "<=":
+		result.Passed = actual <= t.limit
+	case ">":
+		result.Passed = actual > t.limit
+	case ">=":
+		result.Passed = actual >= t.limit
+	}
+	return result
+}
@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		text       string
+		scope      string
+		metric     string
+		percentile float64
+		operator   string
+		limit      float64
+	}{
+		{text: "p95 < 300ms", scope: scopeRequests, metric: "p95", percentile: 95, operator: "<", limit: 300},
+		{text: "p99.9<=2s", scope: scopeRequests, metric: "p99.9", percentile: 99.9, operator: "<=", limit: 2000},
+		{text: "p100 < 1", scope: scopeRequests, metric: "p100", percentile: 100, operator: "<", limit: 1},
+		{text: "  avg < 120.5  ", scope: scopeRequests, metric: "avg", operator: "<", limit: 120.5},
+		{text: "max <= 1.5s", scope: scopeRequests, metric: "max", operator: "<=", limit: 1500},
+		{text: "min >= 1ms", scope: scopeRequests, metric: "min", operator: ">=", limit: 1},
+		{text: "iterations: p50 < 1s", scope: scopeIterations, metric: "p50", percentile: 50, operator: "<", limit: 1000},
+		{text: "get-user : error_rate < 1%", scope: "get-user", metric: "error_rate", operator: "<", limit: 0.01},
+		{text: "error_rate <= 0.05", scope: scopeRequests, metric: "error_rate", operator: "<=", limit: 0.05},
+		{text: "rate > 50", scope: scopeRequests, metric: "rate", operator: ">", limit: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseThreshold(tt.text)
+			if err != nil {
+				t.Fatalf("parseThreshold: %v", err)
+			}
+			want := threshold{text: tt.text, scope: tt.scope, metric: tt.metric, percentile: tt.percentile, operator: tt.operator, limit: tt.limit}
+			if got != want {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseThresholdErrors(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{text: "", wantErr: "must look like"},
+		{text: "p95 = 300ms", wantErr: "must look like"},
+		{text: "p95 < -1ms", wantErr: "must look like"},
+		{text: "p95 < 300 minutes", wantErr: "must look like"},
+		{text: "p95 <", wantErr: "must look like"},
+		{text: "p0 < 1ms", wantErr: "above p0"},
+		{text: "p101 < 1ms", wantErr: "at most p100"},
+		{text: "median < 1ms", wantErr: "unknown metric median"},
+		{text: "rate > 5s", wantErr: "takes no unit"},
+		{text: "error_rate < 1ms", wantErr: "fraction or a percentage"},
+		{text: "p95 < 1%", wantErr: "ms or s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			_, err := parseThreshold(tt.text)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestThresholdEvaluate(t *testing.T) {
+	// 100 requests taking 1ms to 100ms, 5 of them failed. Percentiles are
+	// read back to three significant digits.
+	stats := newLoadStats()
+	for i := 1; i <= 100; i++ {
+		stats.record(i%20 == 0, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		text       string
+		stats      *loadStats
+		elapsed    time.Duration
+		wantActual float64
+		wantPassed bool
+	}{
+		{text: "p95 < 100ms", stats: stats, wantActual: 95, wantPassed: true},
+		{text: "p95 < 95ms", stats: stats, wantActual: 95},
+		{text: "p95 <= 95.1ms", stats: stats, wantActual: 95, wantPassed: true},
+		{text: "p50 > 49ms", stats: stats, wantActual: 50, wantPassed: true},
+		{text: "avg < 50ms", stats: stats, wantActual: 50.5},
+		{text: "avg >= 0.05s", stats: stats, wantActual: 50.5, wantPassed: true},
+		{text: "min < 2ms", stats: stats, wantActual: 1, wantPassed: true},
+		{text: "max <= 99ms", stats: stats, wantActual: 100},
+		{text: "error_rate < 5%", stats: stats, wantActual: 0.05},
+		{text: "error_rate <= 5%", stats: stats, wantActual: 0.05, wantPassed: true},
+		{text: "rate >= 10", stats: stats, elapsed: 10 * time.Second, wantActual: 10, wantPassed: true},
+		{text: "rate > 10", stats: stats, elapsed: 10 * time.Second, wantActual: 10},
+		{text: "rate > 0", stats: stats, wantActual: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			threshold, err := parseThreshold(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := threshold.evaluate(tt.stats, tt.elapsed)
+			if result.Actual == nil {
+				t.Fatal("no actual value")
+			}
+			if math.Abs(*result.Actual-tt.wantActual) > tt.wantActual/1000 || result.Passed != tt.wantPassed {
+				t.Errorf("actual %v, passed %v; want %v, %v", *result.Actual, result.Passed, tt.wantActual, tt.wantPassed)
+			}
+			if result.Threshold != tt.text || result.Scope != threshold.scope || result.Metric != threshold.metric {
+				t.Errorf("result %+v doesn't describe the threshold", result)
+			}
+		})
+	}
+}
+
+func TestThresholdEvaluateWithoutData(t *testing.T) {
+	threshold, _ := parseThreshold("get-user: p95 < 300ms")
+
+	for name, stats := range map[string]*loadStats{"no stats": nil, "nothing recorded": newLoadStats()} {
+		t.Run(name, func(t *testing.T) {
+			result := threshold.evaluate(stats, time.Minute)
+			if result.Passed || result.Actual != nil {
+				t.Errorf("got %+v, want a failure without an actual value", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// Curl generates a bash script that sends the flow's requests with curl in
+// run order and checks their responses, exiting with 1 if any check fails.
+// {{name}} placeholders become shell variables that default to the flow's
+// variables; names that aren't shell identifiers have other characters
+// replaced with _.
+func Curl(flow *models.Flow) ([]byte, error) {
+	steps, err := plan(flow)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/usr/bin/env bash\n")
+	fmt.Fprintf(&b, "# Generated from the flow %s by the Visual API Testing Platform.\n", shellQuote(oneLine(flow.Name)))
+	b.WriteString("# Variables can be overridden with environment variables of the same name.\n")
+	b.WriteString("set -uo pipefail\n\n")
+
+	declared := make(map[string]bool)
+	for _, v := range sortedVariables(flow.Variables) {
+		name := shellName(v.name)
+		if !declared[name] {
+			declared[name] = true
+			fmt.Fprintf(&b, ": \"${%s=%s}\"\n", name, shellEscape(v.value))
+		}
+	}
+	// placeholders for variables the flow doesn't define must come from the environment
+	for _, s := range steps {
+		if s.request == nil {
+			continue
+		}
+		texts := []string{s.request.url, s.request.body}
+		for _, h := range s.request.headers {
+			texts = append(texts, h.value)
+		}
+		for _, text := range texts {
+			for _, match := range templateVariable.FindAllStringSubmatch(text, -1) {
+				if name := shellName(match[1]); !declared[name] {
+					declared[name] = true
+					fmt.Fprintf(&b, ": \"${%s:?set %s to run this flow}\"\n", name, name)
+				}
+			}
+		}
+	}
+	b.WriteString(curlPreamble)
+
+	for _, s := range steps {
+		fmt.Fprintf(&b, "\n# %s (%s)\n", oneLine(s.label), s.id)
+		for _, note := range s.notes {
+			fmt.Fprintf(&b, "# Not exported: %s\n", note)
+		}
+		if r := s.request; r != nil {
+			fmt.Fprintf(&b, "send %s", shellQuote(s.id))
+			if r.method == "HEAD" {
+				b.WriteString(" --head")
+			} else {
+				fmt.Fprintf(&b, " -X %s", r.method)
+			}
+			fmt.Fprintf(&b, " %s", shellString(r.url))
+			hasContentType := false
+			for _, h := range r.headers {
+				hasContentType = hasContentType || strings.EqualFold(h.name, "Content-Type")
+				fmt.Fprintf(&b, " \\\n  -H %s", shellString(h.name+": "+h.value))
+			}
+			if r.body != "" {
+				if !hasContentType {
+					b.WriteString(" \\\n  -H 'Content-Type: application/json'")
+				}
+				fmt.Fprintf(&b, " \\\n  --data-raw %s", shellString(r.body))
+			}
+			b.WriteString("\n")
+		}
+		source := shellQuote(s.source)
+		for _, c := range s.checks {
+			switch c.kind {
+			case checkStatus:
+				fmt.Fprintf(&b, "expect_status %s", source)
+				for _, status := range c.statuses {
+					fmt.Fprintf(&b, " %s", status)
+				}
+				b.WriteString("\n")
+			case checkContentType:
+				fmt.Fprintf(&b, "expect_content_type %s %s\n", source, shellQuote(c.value))
+			case checkHeader:
+				fmt.Fprintf(&b, "expect_header %s %s %t\n", source, shellQuote(c.name), c.present)
+			case checkHeaderValue:
+				fmt.Fprintf(&b, "expect_header_value %s %s %s\n", source, shellQuote(c.name), shellQuote(c.value))
+			case checkMaxDuration:
+				fmt.Fprintf(&b, "expect_duration %s %g\n", source, c.limit)
+			case checkMaxSize:
+				fmt.Fprintf(&b, "expect_size %s %g\n", source, c.limit)
+			}
+		}
+	}
+
+	b.WriteString("\nif (( failures > 0 )); then\n  echo \"$failures check(s) failed\" >&2\n  exit 1\nfi\n")
+	return []byte(b.String()), nil
+}
+
+// curlPreamble declares the request and check helpers. Responses are kept in
+// a temporary directory: <node>.headers, <node>.body and <node>.meta holding
+// the status, duration and size.
+const curlPreamble = `
+failures=0
+responses=$(mktemp -d)
+trap 'rm -rf "$responses"' EXIT
+
+fail() {
+  echo "FAIL $*" >&2
+  failures=$((failures + 1))
+}
+
+send() {
+  local node=$1
+  shift
+  if ! curl -sS -D "$responses/$node.headers" -o "$responses/$node.body" \
+    -w '%{http_code} %{time_total} %{size_download}' "$@" >"$responses/$node.meta"; then
+    fail "$node: request failed"
+    rm -f "$responses/$node.meta"
+  fi
+}
+
+# meta <node> <field>: 1 status, 2 duration in seconds, 3 size in bytes
+meta() {
+  [[ -f "$responses/$1.meta" ]] && cut -d' ' -f"$2" "$responses/$1.meta"
+}
+
+# header_value <node> <name> prints the last value of a response header
+header_value() {
+  grep -i "^$2:" "$responses/$1.headers" 2>/dev/null | tail -n 1 | cut -d: -f2- | sed 's/^ *//' | tr -d '\r'
+}
+
+expect_status() {
+  local node=$1 status
+  shift
+  status=$(meta "$node" 1) || return 0
+  for expected in "$@"; do
+    if [[ $expected == "$status" || ( $expected == [1-5]xx && ${expected:0:1} == "${status:0:1}" ) ]]; then
+      return 0
+    fi
+  done
+  fail "$node: expected status $*, got $status"
+}
+
+expect_content_type() {
+  local actual
+  meta "$1" 1 >/dev/null || return 0
+  actual=$(header_value "$1" Content-Type | cut -d';' -f1 | tr -d ' ' | tr '[:upper:]' '[:lower:]')
+  [[ $actual == "$2" ]] || fail "$1: expected content type $2, got $actual"
+}
+
+expect_header() {
+  meta "$1" 1 >/dev/null || return 0
+  if grep -qi "^$2:" "$responses/$1.headers"; then
+    [[ $3 == true ]] || fail "$1: expected no header $2"
+  else
+    [[ $3 == false ]] || fail "$1: expected header $2"
+  fi
+}
+
+expect_header_value() {
+  local actual
+  meta "$1" 1 >/dev/null || return 0
+  actual=$(header_value "$1" "$2")
+  [[ $actual == "$3" ]] || fail "$1: expected header $2 to be $3, got $actual"
+}
+
+expect_duration() {
+  local seconds
+  seconds=$(meta "$1" 2) || return 0
+  awk -v s="$seconds" -v max="$2" 'BEGIN { exit !(s * 1000 <= max) }' ||
+    fail "$1: expected a response within $2 ms, took ${seconds}s"
+}
+
+expect_size() {
+  local size
+  size=$(meta "$1" 3) || return 0
+  (( size <= $2 )) || fail "$1: expected at most $2 bytes, got $size"
+}
+`
+
+var nonShellName = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// shellName turns a variable name into a shell identifier
+func shellName(name string) string {
+	return nonShellName.ReplaceAllString(name, "_")
+}
+
+// shellQuote single-quotes s for bash
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellEscape escapes s for use inside double quotes
+func shellEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(s)
+}
+
+// shellString double-quotes s for bash, turning {{name}} placeholders into
+// variable expansions
+func shellString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	last := 0
+	for _, match := range templateVariable.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(shellEscape(s[last:match[0]]))
+		b.WriteString("${" + shellName(s[match[2]:match[3]]) + "}")
+		last = match[1]
+	}
+	b.WriteString(shellEscape(s[last:]))
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,222 @@
+// Package exporter writes flows as portable documents, which the importer
+// package turns back into flows, and as code that runs them outside the
+// platform: Go tests, k6 scripts and curl scripts.
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/node"
+	"gopkg.in/yaml.v3"
+)
+
+// Identify flow documents; Version changes when the format does
+const (
+	DocumentFormat  = "visual-api-testing-platform/flow"
+	DocumentVersion = 1
+)
+
+// Document is a self-contained description of a flow. It has no database
+// IDs: library schemas are inlined in the nodes that use them and library
+// specs bound as contracts are embedded under Specs, keyed by name.
+type Document struct {
+	Format      string                 `json:"format"`
+	Version     int                    `json:"version"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Variables   map[string]interface{} `json:"variables,omitempty"`
+	Nodes       []Node                 `json:"nodes"`
+	Edges       []Edge                 `json:"edges"`
+	Specs       map[string]Spec        `json:"specs,omitempty"`
+}
+
+// Node is a flow node without its editor bookkeeping or last run state
+type Node struct {
+	ID       string                 `json:"id"`
+	Type     string                 `json:"type"`
+	Label    string                 `json:"label"`
+	Position models.Position        `json:"position"`
+	Config   map[string]interface{} `json:"config,omitempty"`
+}
+
+// Edge connects two nodes
+type Edge struct {
+	ID           string  `json:"id,omitempty"`
+	Source       string  `json:"source"`
+	Target       string  `json:"target"`
+	SourceHandle *string `json:"sourceHandle,omitempty"`
+	TargetHandle *string `json:"targetHandle,omitempty"`
+}
+
+// Spec is an OpenAPI document API node contracts refer to with "spec": name
+type Spec struct {
+	Description string      `json:"description,omitempty"`
+	Document    interface{} `json:"document"`
+}
+
+// Export builds the document of a flow, loading the library schemas and
+// specs its nodes reference
+func Export(ctx context.Context, flow *models.Flow, schemas node.SchemaStore, specs node.SpecStore) (*Document, error) {
+	doc := &Document{
+		Format:      DocumentFormat,
+		Version:     DocumentVersion,
+		Name:        flow.Name,
+		Description: flow.Description,
+		Tags:        flow.Tags,
+		Variables:   flow.Variables,
+		Nodes:       make([]Node, 0, len(flow.Nodes)),
+		Edges:       make([]Edge, 0, len(flow.Edges)),
+	}
+
+	for _, n := range flow.Nodes {
+		config, err := copyConfig(n.Data.Config)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", n.ID, err)
+		}
+
+		if id, ok := config["schemaId"].(string); ok {
+			schemaID, err := uuid.Parse(id)
+			if err != nil {
+				return nil, fmt.Errorf("node %s: invalid schemaId", n.ID)
+			}
+			schema, err := schemas.GetByID(ctx, schemaID)
+			if err != nil {
+				return nil, fmt.Errorf("node %s: failed to load schema %s: %w", n.ID, id, err)
+			}
+			delete(config, "schemaId")
+			config["schema"] = schema.Schema
+		}
+
+		if contract, ok := config["contract"].(map[string]interface{}); ok {
+			if id, ok := contract["specId"].(string); ok {
+				specID, err := uuid.Parse(id)
+				if err != nil {
+					return nil, fmt.Errorf("node %s: invalid contract.specId", n.ID)
+				}
+				spec, err := specs.GetByID(ctx, specID)
+				if err != nil {
+					return nil, fmt.Errorf("node %s: failed to load spec %s: %w", n.ID, id, err)
+				}
+				if doc.Specs == nil {
+					doc.Specs = make(map[string]Spec)
+				}
+				doc.Specs[spec.Name] = Spec{Description: spec.Description, Document: spec.Document}
+				delete(contract, "specId")
+				contract["spec"] = spec.Name
+			}
+		}
+
+		doc.Nodes = append(doc.Nodes, Node{
+			ID:       n.ID,
+			Type:     n.Data.Type,
+			Label:    n.Data.Label,
+			Position: n.Position,
+			Config:   config,
+		})
+	}
+
+	for _, e := range flow.Edges {
+		doc.Edges = append(doc.Edges, Edge{
+			ID:           e.ID,
+			Source:       e.Source,
+			Target:       e.Target,
+			SourceHandle: e.SourceHandle,
+			TargetHandle: e.TargetHandle,
+		})
+	}
+	return doc, nil
+}
+
+// copyConfig deep-copies a node config so that exporting doesn't change the flow
+func copyConfig(config map[string]interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var copied map[string]interface{}
+	if err := json.Unmarshal(encoded, &copied); err != nil {
+		return nil, err
+	}
+	if copied == nil {
+		copied = make(map[string]interface{})
+	}
+	return copied, nil
+}
+
+// Encode writes a document as indented JSON or as YAML
+func Encode(doc *Document, format string) ([]byte, error) {
+	encoded, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "json":
+		return append(encoded, '\n'), nil
+	case "yaml":
+		// Going through JSON keeps the field names and order
+		var root yaml.Node
+		if err := yaml.Unmarshal(encoded, &root); err != nil {
+			return nil, err
+		}
+		blockStyle(&root)
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&root); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported document format %s", format)
+}
+
+// blockStyle drops the flow style and quoting YAML kept from the JSON source
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		blockStyle(child)
+	}
+}
+
+// Decode reads a JSON or YAML document and checks its format and version
+func Decode(data []byte) (*Document, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid flow document: %w", err)
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flow document: %w", err)
+	}
+	var doc Document
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		return nil, fmt.Errorf("invalid flow document: %w", err)
+	}
+
+	if doc.Format != DocumentFormat {
+		return nil, fmt.Errorf("not a flow document: format must be %s", DocumentFormat)
+	}
+	if doc.Version < 1 || doc.Version > DocumentVersion {
+		return nil, fmt.Errorf("unsupported flow document version %d", doc.Version)
+	}
+	if doc.Name == "" {
+		return nil, fmt.Errorf("invalid flow document: name is required")
+	}
+	seen := make(map[string]bool, len(doc.Nodes))
+	for _, n := range doc.Nodes {
+		if n.ID == "" {
+			return nil, fmt.Errorf("invalid flow document: every node needs an id")
+		}
+		if seen[n.ID] {
+			return nil, fmt.Errorf("invalid flow document: duplicate node id %s", n.ID)
+		}
+		seen[n.ID] = true
+	}
+	return &doc, nil
+}
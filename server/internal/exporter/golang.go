@@ -0,0 +1,257 @@
+package exporter
+
+import (
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// GoTest generates a Go test that sends the flow's requests with net/http in
+// run order and checks their responses. Everything is declared inside the
+// test function so that several exported flows can share a package.
+func GoTest(flow *models.Flow) ([]byte, error) {
+	steps, err := plan(flow)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[checkKind]bool)
+	hasRequests := false
+	for _, s := range steps {
+		hasRequests = hasRequests || s.request != nil
+		for _, c := range s.checks {
+			used[c.kind] = true
+		}
+	}
+
+	imports := []string{"testing"}
+	if hasRequests {
+		imports = append(imports, "io", "net/http", "os", "regexp", "strings", "time")
+	}
+	if used[checkStatus] {
+		imports = append(imports, "strconv")
+	}
+	if used[checkContentType] {
+		imports = append(imports, "mime")
+	}
+	sort.Strings(imports)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Generated from the flow %q by the Visual API Testing Platform.\n", flow.Name)
+	b.WriteString("// Variables can be overridden with environment variables of the same name.\n\n")
+	b.WriteString("package flows\n\nimport (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(&b, "\t%q\n", imp)
+	}
+	b.WriteString(")\n\n")
+
+	fmt.Fprintf(&b, "func Test%s(t *testing.T) {\n", goIdentifier(flow.Name))
+	if hasRequests {
+		b.WriteString("variables := map[string]string{\n")
+		for _, v := range sortedVariables(flow.Variables) {
+			fmt.Fprintf(&b, "%q: %q,\n", v.name, v.value)
+		}
+		b.WriteString("}\n")
+		b.WriteString(goPreamble)
+		if used[checkStatus] {
+			b.WriteString(goExpectStatus)
+		}
+		if used[checkContentType] {
+			b.WriteString(goExpectContentType)
+		}
+		if used[checkHeader] || used[checkHeaderValue] {
+			b.WriteString(goExpectHeader)
+		}
+		if used[checkMaxDuration] {
+			b.WriteString(goExpectDuration)
+		}
+		if used[checkMaxSize] {
+			b.WriteString(goExpectSize)
+		}
+	}
+
+	for _, s := range steps {
+		fmt.Fprintf(&b, "\n// %s (%s)\n", oneLine(s.label), s.id)
+		for _, note := range s.notes {
+			fmt.Fprintf(&b, "// Not exported: %s\n", note)
+		}
+		if r := s.request; r != nil {
+			fmt.Fprintf(&b, "responses[%q] = send(%q, %q, %q, map[string]string{", s.id, s.id, r.method, r.url)
+			for _, h := range r.headers {
+				fmt.Fprintf(&b, "\n%q: %q,", h.name, h.value)
+			}
+			if len(r.headers) > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "}, %s)\n", strconv.Quote(r.body))
+		}
+		response := fmt.Sprintf("responses[%q]", s.source)
+		for _, c := range s.checks {
+			switch c.kind {
+			case checkStatus:
+				fmt.Fprintf(&b, "expectStatus(%q, %s", s.source, response)
+				for _, status := range c.statuses {
+					fmt.Fprintf(&b, ", %q", status)
+				}
+				b.WriteString(")\n")
+			case checkContentType:
+				fmt.Fprintf(&b, "expectContentType(%q, %s, %q)\n", s.source, response, c.value)
+			case checkHeader:
+				fmt.Fprintf(&b, "expectHeader(%q, %s, %q, %t, \"\")\n", s.source, response, c.name, c.present)
+			case checkHeaderValue:
+				fmt.Fprintf(&b, "expectHeader(%q, %s, %q, true, %q)\n", s.source, response, c.name, c.value)
+			case checkMaxDuration:
+				fmt.Fprintf(&b, "expectDuration(%q, %s, %g)\n", s.source, response, c.limit)
+			case checkMaxSize:
+				fmt.Fprintf(&b, "expectSize(%q, %s, %g)\n", s.source, response, c.limit)
+			}
+		}
+	}
+	b.WriteString("}\n")
+
+	return format.Source([]byte(b.String()))
+}
+
+// goIdentifier turns a flow name into the rest of a test function name
+func goIdentifier(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "Flow"
+	}
+	return "Flow" + b.String()
+}
+
+// goPreamble declares the request helpers. A failed request is reported and
+// leaves a nil response, which the checks skip, as later nodes still run.
+const goPreamble = `placeholder := regexp.MustCompile(` + "`" + `\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}` + "`" + `)
+expand := func(s string) string {
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		name := placeholder.FindStringSubmatch(m)[1]
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		if value, ok := variables[name]; ok {
+			return value
+		}
+		return m
+	})
+}
+
+type response struct {
+	status   int
+	header   http.Header
+	body     []byte
+	duration time.Duration
+}
+responses := make(map[string]*response)
+client := &http.Client{Timeout: 30 * time.Second}
+
+send := func(node, method, url string, headers map[string]string, body string) *response {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(expand(body))
+	}
+	req, err := http.NewRequest(method, expand(url), reader)
+	if err != nil {
+		t.Errorf("%s: %v", node, err)
+		return nil
+	}
+	for name, value := range headers {
+		req.Header.Set(name, expand(value))
+	}
+	if body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("%s: request failed: %v", node, err)
+		return nil
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Errorf("%s: failed to read response: %v", node, err)
+		return nil
+	}
+	return &response{status: resp.StatusCode, header: resp.Header, body: data, duration: time.Since(start)}
+}
+`
+
+const goExpectStatus = `
+expectStatus := func(node string, r *response, expected ...string) {
+	if r == nil {
+		return
+	}
+	code := strconv.Itoa(r.status)
+	for _, e := range expected {
+		if e == code || (strings.HasSuffix(e, "xx") && len(e) == 3 && e[0] == code[0]) {
+			return
+		}
+	}
+	t.Errorf("%s: expected status %s, got %d", node, strings.Join(expected, " or "), r.status)
+}
+`
+
+const goExpectContentType = `
+expectContentType := func(node string, r *response, expected string) {
+	if r == nil {
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.header.Get("Content-Type"))
+	if mediaType != expected {
+		t.Errorf("%s: expected content type %s, got %q", node, expected, r.header.Get("Content-Type"))
+	}
+}
+`
+
+const goExpectHeader = `
+expectHeader := func(node string, r *response, name string, present bool, value string) {
+	if r == nil {
+		return
+	}
+	values, ok := r.header[name]
+	switch {
+	case ok != present && present:
+		t.Errorf("%s: expected header %s", node, name)
+	case ok != present:
+		t.Errorf("%s: expected no header %s, got %q", node, name, strings.Join(values, ", "))
+	case value != "" && strings.Join(values, ", ") != value:
+		t.Errorf("%s: expected header %s to be %q, got %q", node, name, value, strings.Join(values, ", "))
+	}
+}
+`
+
+const goExpectDuration = `
+expectDuration := func(node string, r *response, maxMs float64) {
+	if r != nil && float64(r.duration.Milliseconds()) > maxMs {
+		t.Errorf("%s: expected a response within %g ms, took %d ms", node, maxMs, r.duration.Milliseconds())
+	}
+}
+`
+
+const goExpectSize = `
+expectSize := func(node string, r *response, maxBytes float64) {
+	if r != nil && float64(len(r.body)) > maxBytes {
+		t.Errorf("%s: expected at most %g bytes, got %d", node, maxBytes, len(r.body))
+	}
+}
+`
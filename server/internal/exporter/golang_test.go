@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+func TestGoTestCompiles(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	flows := map[string]*models.Flow{
+		"checks_test.go": checksFlow(),
+		"statuses_test.go": {
+			Name:  "Only statuses",
+			Nodes: []models.FlowNode{testNode("get", "api", map[string]interface{}{"method": "GET", "url": "https://api.test/"}), testNode("check", "verification", map[string]interface{}{"assertionType": "response", "status": 200.0})},
+			Edges: chain("get", "check"),
+		},
+		"headers_test.go": {
+			Name:  "Only headers",
+			Nodes: []models.FlowNode{testNode("get", "api", map[string]interface{}{"method": "GET", "url": "https://api.test/"}), testNode("check", "verification", map[string]interface{}{"assertionType": "response", "headers": map[string]interface{}{"etag": true}})},
+			Edges: chain("get", "check"),
+		},
+		"requests_test.go": {
+			Name:  "Requests only",
+			Nodes: []models.FlowNode{testNode("get", "api", map[string]interface{}{"method": "GET", "url": "https://api.test/"})},
+		},
+		"empty_test.go": {
+			Name:  "No requests",
+			Nodes: []models.FlowNode{testNode("wait", "delay", map[string]interface{}{"ms": 1.0})},
+		},
+	}
+
+	// Flows share a package, so every generated test must compile alongside the others
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module flows\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for file, flow := range flows {
+		source, err := GoTest(flow)
+		if err != nil {
+			t.Fatalf("%s: %v", flow.Name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), source, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goTool, "vet", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		checks, _ := os.ReadFile(filepath.Join(dir, "checks_test.go"))
+		t.Fatalf("go vet: %v\n%s\n%s", err, out, checks)
+	}
+}
+
+func TestGoIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"Checkout flow":    "FlowCheckoutFlow",
+		"user-signup v2":   "FlowUserSignupV2",
+		"ünïcode naming":   "FlowÜnïcodeNaming",
+		"---":              "Flow",
+		"already Camel":    "FlowAlreadyCamel",
+		"2 step / refresh": "Flow2StepRefresh",
+	}
+	for name, want := range tests {
+		if got := goIdentifier(name); got != want {
+			t.Errorf("goIdentifier(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGoTestQuotesValues(t *testing.T) {
+	source, err := GoTest(checksFlow())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"baseUrl": "https://api.test",`,
+		`"limit":   "5",`,
+		`"{\"name\":\"a \\\"quoted\\\" name\"}"`,
+		`expectStatus("create", responses["create"], "201", "2xx")`,
+	} {
+		if !strings.Contains(string(source), want) {
+			t.Errorf("generated test has no %s:\n%s", want, source)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// K6 generates a k6 script that sends the flow's requests in run order and
+// checks their responses. It runs one iteration; raise vus and duration in
+// its options to use it as a load test.
+func K6(flow *models.Flow) ([]byte, error) {
+	steps, err := plan(flow)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := make(map[string]string, len(flow.Variables))
+	for _, v := range sortedVariables(flow.Variables) {
+		variables[v.name] = v.value
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Generated from the flow %s by the Visual API Testing Platform.\n", jsString(flow.Name))
+	b.WriteString("// Run with: k6 run script.js. Variables can be overridden with -e name=value.\n")
+	b.WriteString(k6Preamble)
+	fmt.Fprintf(&b, "const variables = %s;\n", jsValue(variables))
+	b.WriteString(k6Helpers)
+
+	b.WriteString("\nexport default function () {\n  const responses = {};\n")
+	for _, s := range steps {
+		fmt.Fprintf(&b, "\n  // %s (%s)\n", oneLine(s.label), s.id)
+		for _, note := range s.notes {
+			fmt.Fprintf(&b, "  // Not exported: %s\n", note)
+		}
+		if r := s.request; r != nil {
+			headers := make(map[string]string, len(r.headers))
+			for _, h := range r.headers {
+				headers[h.name] = h.value
+			}
+			body := "null"
+			if r.body != "" {
+				body = jsString(r.body)
+			}
+			fmt.Fprintf(&b, "  responses[%s] = send(%s, %s, %s, %s);\n", jsString(s.id), jsString(r.method), jsString(r.url), jsValue(headers), body)
+		}
+		if len(s.checks) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "  check(responses[%s], {\n", jsString(s.source))
+		for _, c := range s.checks {
+			name := jsString(s.id + ": " + c.describe())
+			switch c.kind {
+			case checkStatus:
+				fmt.Fprintf(&b, "    %s: (r) => statusMatches(r.status, %s),\n", name, jsValue(c.statuses))
+			case checkContentType:
+				fmt.Fprintf(&b, "    %s: (r) => mediaType(r.headers['Content-Type']) === %s,\n", name, jsString(c.value))
+			case checkHeader:
+				operator := "!=="
+				if !c.present {
+					operator = "==="
+				}
+				fmt.Fprintf(&b, "    %s: (r) => r.headers[%s] %s undefined,\n", name, jsString(c.name), operator)
+			case checkHeaderValue:
+				fmt.Fprintf(&b, "    %s: (r) => r.headers[%s] === %s,\n", name, jsString(c.name), jsString(c.value))
+			case checkMaxDuration:
+				fmt.Fprintf(&b, "    %s: (r) => r.timings.duration <= %g,\n", name, c.limit)
+			case checkMaxSize:
+				fmt.Fprintf(&b, "    %s: (r) => (r.body || '').length <= %g,\n", name, c.limit)
+			}
+		}
+		b.WriteString("  });\n")
+	}
+	b.WriteString("}\n")
+	return []byte(b.String()), nil
+}
+
+const k6Preamble = `
+import http from 'k6/http';
+import { check } from 'k6';
+
+export const options = {
+  vus: 1,
+  iterations: 1,
+  thresholds: { checks: ['rate==1'] },
+};
+
+`
+
+const k6Helpers = `
+function expand(s) {
+  return s.replace(/\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}/g, (m, name) => {
+    if (name in __ENV) return __ENV[name];
+    if (name in variables) return variables[name];
+    return m;
+  });
+}
+
+function send(method, url, headers, body) {
+  const params = { headers: {} };
+  for (const name in headers) params.headers[name] = expand(headers[name]);
+  if (body !== null && !Object.keys(params.headers).some((h) => h.toLowerCase() === 'content-type')) {
+    params.headers['Content-Type'] = 'application/json';
+  }
+  return http.request(method, expand(url), body === null ? null : expand(body), params);
+}
+
+function statusMatches(status, expected) {
+  const code = String(status);
+  return expected.some((e) => e === code || (/^[1-5]xx$/.test(e) && e[0] === code[0]));
+}
+
+function mediaType(contentType) {
+  return (contentType || '').split(';')[0].trim().toLowerCase();
+}
+`
+
+// jsString writes s as a JavaScript string literal
+func jsString(s string) string {
+	return jsValue(s)
+}
+
+// jsValue writes v as a JavaScript literal. JSON is valid JavaScript, as
+// encoding/json escapes the line and paragraph separators.
+func jsValue(v interface{}) string {
+	encoded, _ := json.Marshal(v)
+	return string(encoded)
+}
+
+// oneLine keeps a label from breaking out of a line comment
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,306 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/node"
+)
+
+// templateVariable matches {{name}} placeholders like API nodes do
+var templateVariable = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// step is a node of a flow as code generators see it, in run order
+type step struct {
+	id    string
+	label string
+
+	// request is set for API nodes
+	request *httpRequest
+
+	// source is the API node whose response checks are about
+	source string
+	checks []check
+
+	// notes list what couldn't be exported, written as comments
+	notes []string
+}
+
+type httpRequest struct {
+	method  string
+	url     string
+	headers []header // sorted by name
+	body    string
+}
+
+type header struct {
+	name  string
+	value string
+}
+
+type checkKind int
+
+const (
+	checkStatus      checkKind = iota // statuses
+	checkContentType                  // value, a media type
+	checkHeader                       // name is present or absent
+	checkHeaderValue                  // name has value
+	checkMaxDuration                  // limit in milliseconds
+	checkMaxSize                      // limit in bytes
+)
+
+// check is a response check generators can express in every language
+type check struct {
+	kind     checkKind
+	statuses []string // codes such as "200" or classes such as "2xx"
+	name     string
+	value    string
+	present  bool
+	limit    float64
+}
+
+// describe names a check for failure messages and k6 check names
+func (c check) describe() string {
+	switch c.kind {
+	case checkStatus:
+		return "status is " + strings.Join(c.statuses, " or ")
+	case checkContentType:
+		return "content type is " + c.value
+	case checkHeader:
+		if c.present {
+			return "header " + c.name + " is present"
+		}
+		return "header " + c.name + " is absent"
+	case checkHeaderValue:
+		return "header " + c.name + " is " + c.value
+	case checkMaxDuration:
+		return fmt.Sprintf("duration is at most %g ms", c.limit)
+	case checkMaxSize:
+		return fmt.Sprintf("size is at most %g bytes", c.limit)
+	}
+	return ""
+}
+
+// plan orders a flow's nodes so that each comes after its dependencies and
+// translates them into steps
+func plan(flow *models.Flow) ([]step, error) {
+	nodes := make(map[string]models.FlowNode, len(flow.Nodes))
+	for _, n := range flow.Nodes {
+		nodes[n.ID] = n
+	}
+	upstream := make(map[string][]string)
+	pending := make(map[string]int)
+	for _, e := range flow.Edges {
+		if _, ok := nodes[e.Source]; !ok {
+			continue
+		}
+		if _, ok := nodes[e.Target]; !ok {
+			continue
+		}
+		upstream[e.Target] = append(upstream[e.Target], e.Source)
+		pending[e.Target]++
+	}
+
+	steps := make([]step, 0, len(flow.Nodes))
+	done := make(map[string]bool, len(flow.Nodes))
+	for len(steps) < len(flow.Nodes) {
+		progressed := false
+		for _, n := range flow.Nodes {
+			if done[n.ID] || pending[n.ID] > 0 {
+				continue
+			}
+			done[n.ID] = true
+			progressed = true
+			steps = append(steps, translate(n, upstream[n.ID], nodes))
+			for _, e := range flow.Edges {
+				if e.Source == n.ID {
+					pending[e.Target]--
+				}
+			}
+		}
+		if !progressed {
+			return nil, fmt.Errorf("flow contains a cycle")
+		}
+	}
+	return steps, nil
+}
+
+func translate(n models.FlowNode, upstream []string, nodes map[string]models.FlowNode) step {
+	s := step{id: n.ID, label: n.Data.Label}
+	if s.label == "" {
+		s.label = n.ID
+	}
+	config := n.Data.Config
+
+	switch node.NodeType(n.Data.Type) {
+	case node.NodeTypeAPI:
+		s.request = apiRequest(config)
+		if _, ok := config["contract"]; ok {
+			s.notes = append(s.notes, "contract validation")
+		}
+	case node.NodeTypeVerification:
+		translateVerification(&s, config, upstream, nodes)
+	default:
+		s.notes = append(s.notes, n.Data.Type+" node")
+	}
+	return s
+}
+
+func apiRequest(config map[string]interface{}) *httpRequest {
+	r := &httpRequest{}
+	r.method, _ = config["method"].(string)
+	r.url, _ = config["url"].(string)
+	r.body, _ = config["body"].(string)
+	if headers, ok := config["headers"].(map[string]interface{}); ok {
+		for name, value := range headers {
+			if str, ok := value.(string); ok {
+				r.headers = append(r.headers, header{name: name, value: str})
+			}
+		}
+		sort.Slice(r.headers, func(i, j int) bool { return r.headers[i].name < r.headers[j].name })
+	}
+	return r
+}
+
+// translateVerification turns response assertions, and field assertions on
+// the status and headers, into checks on the source API node's response
+func translateVerification(s *step, config map[string]interface{}, upstream []string, nodes map[string]models.FlowNode) {
+	assertionType, _ := config["assertionType"].(string)
+	if assertionType != "response" && assertionType != "assertions" {
+		if assertionType == "" {
+			assertionType = "equals"
+		}
+		s.notes = append(s.notes, assertionType+" assertion")
+		return
+	}
+
+	s.source, _ = config["source"].(string)
+	if s.source == "" && len(upstream) > 0 {
+		s.source = upstream[0]
+	}
+	if source, ok := nodes[s.source]; !ok || source.Data.Type != string(node.NodeTypeAPI) {
+		s.notes = append(s.notes, "checks on a node other than an API node")
+		s.source = ""
+		return
+	}
+
+	if assertionType == "response" {
+		translateResponseAssertion(s, config)
+	} else {
+		translateFieldAssertions(s, config)
+	}
+}
+
+func translateResponseAssertion(s *step, config map[string]interface{}) {
+	if spec, ok := config["status"]; ok && spec != nil {
+		if statuses := statusList(spec); len(statuses) > 0 {
+			s.checks = append(s.checks, check{kind: checkStatus, statuses: statuses})
+		}
+	}
+	if contentType, ok := config["contentType"].(string); ok && contentType != "" {
+		s.checks = append(s.checks, check{kind: checkContentType, value: strings.ToLower(contentType)})
+	}
+	if headers, ok := config["headers"].(map[string]interface{}); ok {
+		names := make([]string, 0, len(headers))
+		for name := range headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			canonical := http.CanonicalHeaderKey(name)
+			switch expected := headers[name].(type) {
+			case bool:
+				s.checks = append(s.checks, check{kind: checkHeader, name: canonical, present: expected})
+			case string:
+				s.checks = append(s.checks, check{kind: checkHeaderValue, name: canonical, value: expected})
+			default:
+				s.notes = append(s.notes, "header check on "+name)
+			}
+		}
+	}
+	if limit, ok := config["maxDurationMs"].(float64); ok {
+		s.checks = append(s.checks, check{kind: checkMaxDuration, limit: limit})
+	}
+	if limit, ok := config["maxSizeBytes"].(float64); ok {
+		s.checks = append(s.checks, check{kind: checkMaxSize, limit: limit})
+	}
+	if config["schema"] != nil || config["schemaId"] != nil {
+		s.notes = append(s.notes, "body schema check")
+	}
+}
+
+func translateFieldAssertions(s *step, config map[string]interface{}) {
+	list, _ := config["assertions"].([]interface{})
+	for _, item := range list {
+		a, _ := item.(map[string]interface{})
+		path, _ := a["path"].(string)
+		operator, _ := a["operator"].(string)
+		path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+
+		switch {
+		case path == "status" && operator == "eq":
+			if statuses := statusList(a["value"]); len(statuses) > 0 {
+				s.checks = append(s.checks, check{kind: checkStatus, statuses: statuses})
+				continue
+			}
+		case strings.HasPrefix(path, "headers.") && operator == "exists":
+			present, ok := a["value"].(bool)
+			if !ok && a["value"] == nil {
+				present, ok = true, true
+			}
+			if ok {
+				s.checks = append(s.checks, check{kind: checkHeader, name: http.CanonicalHeaderKey(path[len("headers."):]), present: present})
+				continue
+			}
+		case strings.HasPrefix(path, "headers.") && operator == "eq":
+			if value, ok := a["value"].(string); ok {
+				s.checks = append(s.checks, check{kind: checkHeaderValue, name: http.CanonicalHeaderKey(path[len("headers."):]), value: value})
+				continue
+			}
+		}
+		s.notes = append(s.notes, fmt.Sprintf("assertion %s %s", path, operator))
+	}
+}
+
+// statusList reads a status spec: a code, a class such as "2xx" or a list of either
+func statusList(spec interface{}) []string {
+	switch v := spec.(type) {
+	case float64:
+		return []string{fmt.Sprintf("%d", int(v))}
+	case string:
+		return []string{strings.ToLower(v)}
+	case []interface{}:
+		var statuses []string
+		for _, item := range v {
+			statuses = append(statuses, statusList(item)...)
+		}
+		return statuses
+	}
+	return nil
+}
+
+// variable is a flow variable's value as API nodes insert it into requests
+type variable struct {
+	name  string
+	value string
+}
+
+// sortedVariables returns the flow's variables sorted by name
+func sortedVariables(variables map[string]interface{}) []variable {
+	list := make([]variable, 0, len(variables))
+	for name, value := range variables {
+		str, ok := value.(string)
+		if !ok {
+			encoded, _ := json.Marshal(value)
+			str = string(encoded)
+		}
+		list = append(list, variable{name: name, value: str})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
@@ -0,0 +1,165 @@
+package exporter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+func testNode(id, nodeType string, config map[string]interface{}) models.FlowNode {
+	return models.FlowNode{
+		ID:   id,
+		Type: "custom",
+		Data: models.NodeData{ID: id, Type: nodeType, Label: id, Config: config},
+	}
+}
+
+func chain(ids ...string) []models.FlowEdge {
+	var edges []models.FlowEdge
+	for i := 1; i < len(ids); i++ {
+		edges = append(edges, models.FlowEdge{ID: ids[i-1] + "-" + ids[i], Source: ids[i-1], Target: ids[i]})
+	}
+	return edges
+}
+
+// checksFlow has an API node checked with every kind of check, and nodes and
+// checks that can't be exported
+func checksFlow() *models.Flow {
+	return &models.Flow{
+		Name:      "Every check",
+		Variables: map[string]interface{}{"baseUrl": "https://api.test", "limit": 5.0},
+		Nodes: []models.FlowNode{
+			testNode("create", "api", map[string]interface{}{
+				"method":   "POST",
+				"url":      "{{baseUrl}}/items?limit={{limit}}",
+				"headers":  map[string]interface{}{"Content-Type": "application/json", "X-Trace": "{{trace}}"},
+				"body":     `{"name":"a \"quoted\" name"}`,
+				"contract": map[string]interface{}{"specId": "4d8d3e9c-1a43-4f31-9d69-2a4a9f6c2d11"},
+			}),
+			testNode("response", "verification", map[string]interface{}{
+				"assertionType": "response",
+				"status":        []interface{}{201.0, "2XX"},
+				"contentType":   "Application/JSON",
+				"headers":       map[string]interface{}{"location": true, "x-debug": false, "cache-control": "no-store", "x-odd": 1.0},
+				"maxDurationMs": 500.0,
+				"maxSizeBytes":  2048.0,
+				"schemaId":      "0e7c1f0a-5b8e-4a47-9b43-6c3b2f1d9e20",
+			}),
+			testNode("fields", "verification", map[string]interface{}{
+				"assertionType": "assertions",
+				"source":        "create",
+				"assertions": []interface{}{
+					map[string]interface{}{"path": "$.status", "operator": "eq", "value": 201.0},
+					map[string]interface{}{"path": "headers.etag", "operator": "exists"},
+					map[string]interface{}{"path": "headers.vary", "operator": "eq", "value": "Accept"},
+					map[string]interface{}{"path": "$.body.id", "operator": "exists"},
+				},
+			}),
+			testNode("equals", "verification", map[string]interface{}{"expected": "x"}),
+			testNode("delay", "delay", map[string]interface{}{"ms": 10.0}),
+		},
+		Edges: chain("create", "response", "fields", "equals", "delay"),
+	}
+}
+
+func TestPlan(t *testing.T) {
+	steps, err := plan(checksFlow())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type summary struct {
+		id     string
+		source string
+		checks []check
+		notes  []string
+	}
+	var got []summary
+	for _, s := range steps {
+		got = append(got, summary{id: s.id, source: s.source, checks: s.checks, notes: s.notes})
+	}
+
+	want := []summary{
+		{id: "create", notes: []string{"contract validation"}},
+		{
+			id:     "response",
+			source: "create",
+			checks: []check{
+				{kind: checkStatus, statuses: []string{"201", "2xx"}},
+				{kind: checkContentType, value: "application/json"},
+				{kind: checkHeaderValue, name: "Cache-Control", value: "no-store"},
+				{kind: checkHeader, name: "Location", present: true},
+				{kind: checkHeader, name: "X-Debug", present: false},
+				{kind: checkMaxDuration, limit: 500},
+				{kind: checkMaxSize, limit: 2048},
+			},
+			notes: []string{"header check on x-odd", "body schema check"},
+		},
+		{
+			id:     "fields",
+			source: "create",
+			checks: []check{
+				{kind: checkStatus, statuses: []string{"201"}},
+				{kind: checkHeader, name: "Etag", present: true},
+				{kind: checkHeaderValue, name: "Vary", value: "Accept"},
+			},
+			notes: []string{"assertion body.id exists"},
+		},
+		{id: "equals", notes: []string{"equals assertion"}},
+		{id: "delay", notes: []string{"delay node"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("steps =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestPlanOrder(t *testing.T) {
+	flow := &models.Flow{
+		Nodes: []models.FlowNode{testNode("c", "delay", nil), testNode("b", "delay", nil), testNode("a", "delay", nil)},
+		Edges: []models.FlowEdge{{Source: "a", Target: "b"}, {Source: "b", Target: "c"}, {Source: "a", Target: "missing"}},
+	}
+	steps, err := plan(flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for _, s := range steps {
+		order = append(order, s.id)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %q, want %q", order, want)
+	}
+
+	flow.Edges = append(flow.Edges, models.FlowEdge{Source: "c", Target: "a"})
+	if _, err := plan(flow); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("error = %v, want a cycle error", err)
+	}
+}
+
+func TestNotExportedNotes(t *testing.T) {
+	generators := []struct {
+		name    string
+		comment string
+		fn      func(*models.Flow) ([]byte, error)
+	}{
+		{name: "go", comment: "// ", fn: GoTest},
+		{name: "k6", comment: "// ", fn: K6},
+		{name: "curl", comment: "# ", fn: Curl},
+	}
+
+	for _, g := range generators {
+		t.Run(g.name, func(t *testing.T) {
+			out, err := g.fn(checksFlow())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, note := range []string{"contract validation", "body schema check", "header check on x-odd", "assertion body.id exists", "equals assertion", "delay node"} {
+				if line := g.comment + "Not exported: " + note; !strings.Contains(string(out), line) {
+					t.Errorf("output has no %q line:\n%s", line, out)
+				}
+			}
+		})
+	}
+}
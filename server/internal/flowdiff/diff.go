@@ -0,0 +1,283 @@
+package flowdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// Diff describes the structural differences between two versions of a flow
+type Diff struct {
+	Metadata     []FieldChange     `json:"metadata"`
+	AddedNodes   []NodeRef         `json:"added_nodes"`
+	RemovedNodes []NodeRef         `json:"removed_nodes"`
+	ChangedNodes []NodeChange      `json:"changed_nodes"`
+	AddedEdges   []models.FlowEdge `json:"added_edges"`
+	RemovedEdges []models.FlowEdge `json:"removed_edges"`
+}
+
+// NodeRef identifies a node in a diff
+type NodeRef struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+// NodeChange lists the field changes of a node present in both versions
+type NodeChange struct {
+	NodeRef
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange represents a changed value at a dotted path.
+// Old is nil for added fields and New is nil for removed ones.
+type FieldChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// Compare computes the diff from before to after. Runtime node state
+// (status, output, error) is ignored; only the flow definition is compared.
+func Compare(before, after *models.Flow) *Diff {
+	d := &Diff{
+		Metadata:     []FieldChange{},
+		AddedNodes:   []NodeRef{},
+		RemovedNodes: []NodeRef{},
+		ChangedNodes: []NodeChange{},
+		AddedEdges:   []models.FlowEdge{},
+		RemovedEdges: []models.FlowEdge{},
+	}
+
+	if before.Name != after.Name {
+		d.Metadata = append(d.Metadata, FieldChange{Path: "name", Old: before.Name, New: after.Name})
+	}
+	if before.Description != after.Description {
+		d.Metadata = append(d.Metadata, FieldChange{Path: "description", Old: before.Description, New: after.Description})
+	}
+	if !sameStrings(before.Tags, after.Tags) {
+		d.Metadata = append(d.Metadata, FieldChange{Path: "tags", Old: before.Tags, New: after.Tags})
+	}
+	d.Metadata = append(d.Metadata, compareValues("variables", variables(before), variables(after))...)
+
+	beforeNodes := make(map[string]models.FlowNode, len(before.Nodes))
+	for _, n := range before.Nodes {
+		beforeNodes[n.ID] = n
+	}
+	afterNodes := make(map[string]models.FlowNode, len(after.Nodes))
+	for _, n := range after.Nodes {
+		afterNodes[n.ID] = n
+	}
+
+	// Walk nodes in their flow order so the diff is stable
+	for _, n := range after.Nodes {
+		old, ok := beforeNodes[n.ID]
+		if !ok {
+			d.AddedNodes = append(d.AddedNodes, refOf(n))
+			continue
+		}
+		if changes := compareNodes(old, n); len(changes) > 0 {
+			d.ChangedNodes = append(d.ChangedNodes, NodeChange{NodeRef: refOf(n), Changes: changes})
+		}
+	}
+	for _, n := range before.Nodes {
+		if _, ok := afterNodes[n.ID]; !ok {
+			d.RemovedNodes = append(d.RemovedNodes, refOf(n))
+		}
+	}
+
+	beforeEdges := make(map[string]bool, len(before.Edges))
+	for _, e := range before.Edges {
+		beforeEdges[edgeKey(e)] = true
+	}
+	afterEdges := make(map[string]bool, len(after.Edges))
+	for _, e := range after.Edges {
+		afterEdges[edgeKey(e)] = true
+		if !beforeEdges[edgeKey(e)] {
+			d.AddedEdges = append(d.AddedEdges, e)
+		}
+	}
+	for _, e := range before.Edges {
+		if !afterEdges[edgeKey(e)] {
+			d.RemovedEdges = append(d.RemovedEdges, e)
+		}
+	}
+
+	return d
+}
+
+// Empty reports whether the two versions are identical
+func (d *Diff) Empty() bool {
+	return len(d.Metadata) == 0 &&
+		len(d.AddedNodes) == 0 &&
+		len(d.RemovedNodes) == 0 &&
+		len(d.ChangedNodes) == 0 &&
+		len(d.AddedEdges) == 0 &&
+		len(d.RemovedEdges) == 0
+}
+
+// Summary returns a compact description of the diff suitable for audit logs
+func (d *Diff) Summary() map[string]interface{} {
+	metadata := make([]string, 0, len(d.Metadata))
+	for _, c := range d.Metadata {
+		metadata = append(metadata, c.Path)
+	}
+
+	return map[string]interface{}{
+		"description":      d.String(),
+		"metadata_changed": metadata,
+		"nodes_added":      nodeIDs(d.AddedNodes),
+		"nodes_removed":    nodeIDs(d.RemovedNodes),
+		"nodes_changed":    changedNodeIDs(d.ChangedNodes),
+		"edges_added":      len(d.AddedEdges),
+		"edges_removed":    len(d.RemovedEdges),
+	}
+}
+
+func compareNodes(before, after models.FlowNode) []FieldChange {
+	var changes []FieldChange
+
+	if before.Type != after.Type {
+		changes = append(changes, FieldChange{Path: "type", Old: before.Type, New: after.Type})
+	}
+	if before.Data.Label != after.Data.Label {
+		changes = append(changes, FieldChange{Path: "label", Old: before.Data.Label, New: after.Data.Label})
+	}
+	if before.Position != after.Position {
+		changes = append(changes, FieldChange{Path: "position", Old: before.Position, New: after.Position})
+	}
+
+	changes = append(changes, compareValues("config", normalize(before.Data.Config), normalize(after.Data.Config))...)
+	return changes
+}
+
+// compareValues recursively compares JSON-like values, descending into objects
+func compareValues(path string, before, after interface{}) []FieldChange {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+
+	if !beforeIsMap || !afterIsMap {
+		if reflect.DeepEqual(before, after) {
+			return nil
+		}
+		return []FieldChange{{Path: path, Old: before, New: after}}
+	}
+
+	keys := make(map[string]bool)
+	for k := range beforeMap {
+		keys[k] = true
+	}
+	for k := range afterMap {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []FieldChange
+	for _, k := range sorted {
+		changes = append(changes, compareValues(path+"."+k, beforeMap[k], afterMap[k])...)
+	}
+	return changes
+}
+
+// normalize round-trips a value through JSON so numbers and nested types compare consistently
+func normalize(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// variables returns the flow's variables for comparison; none and an empty set are equal
+func variables(flow *models.Flow) interface{} {
+	if flow.Variables == nil {
+		return map[string]interface{}{}
+	}
+	return normalize(flow.Variables)
+}
+
+func refOf(n models.FlowNode) NodeRef {
+	return NodeRef{ID: n.ID, Type: n.Type, Label: n.Data.Label}
+}
+
+func edgeKey(e models.FlowEdge) string {
+	var sourceHandle, targetHandle string
+	if e.SourceHandle != nil {
+		sourceHandle = *e.SourceHandle
+	}
+	if e.TargetHandle != nil {
+		targetHandle = *e.TargetHandle
+	}
+	return strings.Join([]string{e.Source, sourceHandle, e.Target, targetHandle}, "\x00")
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func nodeIDs(refs []NodeRef) []string {
+	ids := make([]string, 0, len(refs))
+	for _, r := range refs {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func changedNodeIDs(changes []NodeChange) []string {
+	ids := make([]string, 0, len(changes))
+	for _, c := range changes {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+// String returns a one-line human readable description of the diff
+func (d *Diff) String() string {
+	if d.Empty() {
+		return "no changes"
+	}
+
+	var parts []string
+	if len(d.Metadata) > 0 {
+		parts = append(parts, fmt.Sprintf("%d metadata field(s) changed", len(d.Metadata)))
+	}
+	if len(d.AddedNodes) > 0 {
+		parts = append(parts, fmt.Sprintf("%d node(s) added", len(d.AddedNodes)))
+	}
+	if len(d.RemovedNodes) > 0 {
+		parts = append(parts, fmt.Sprintf("%d node(s) removed", len(d.RemovedNodes)))
+	}
+	if len(d.ChangedNodes) > 0 {
+		parts = append(parts, fmt.Sprintf("%d node(s) changed", len(d.ChangedNodes)))
+	}
+	if len(d.AddedEdges) > 0 {
+		parts = append(parts, fmt.Sprintf("%d edge(s) added", len(d.AddedEdges)))
+	}
+	if len(d.RemovedEdges) > 0 {
+		parts = append(parts, fmt.Sprintf("%d edge(s) removed", len(d.RemovedEdges)))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,177 @@
+package flowdiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+func node(id, label string, x float64, config map[string]interface{}) models.FlowNode {
+	return models.FlowNode{
+		ID:       id,
+		Type:     "custom",
+		Position: models.Position{X: x, Y: 100},
+		Data:     models.NodeData{ID: id, Type: "api", Label: label, Config: config},
+	}
+}
+
+func edge(id, source, target string, sourceHandle string) models.FlowEdge {
+	e := models.FlowEdge{ID: id, Source: source, Target: target}
+	if sourceHandle != "" {
+		e.SourceHandle = &sourceHandle
+	}
+	return e
+}
+
+func baseFlow() *models.Flow {
+	return &models.Flow{
+		Name:        "Checkout",
+		Description: "Buy a thing",
+		Tags:        []string{"smoke", "shop"},
+		Variables:   map[string]interface{}{"baseUrl": "https://shop.test", "auth": map[string]interface{}{"token": "a", "user": "u"}},
+		Nodes: []models.FlowNode{
+			node("login", "Log in", 100, map[string]interface{}{"method": "POST", "url": "{{baseUrl}}/login"}),
+			node("cart", "Add to cart", 300, map[string]interface{}{"method": "POST", "headers": map[string]interface{}{"X-A": "1", "X-B": "2"}, "retries": 2}),
+			node("check", "Check cart", 500, map[string]interface{}{"assertionType": "response", "status": 201}),
+			node("wait", "Wait", 700, map[string]interface{}{"ms": 10}),
+		},
+		Edges: []models.FlowEdge{
+			edge("e1", "login", "cart", ""),
+			edge("e2", "cart", "check", ""),
+			edge("e3", "check", "wait", "true"),
+		},
+	}
+}
+
+func TestCompareUnchanged(t *testing.T) {
+	before := baseFlow()
+	after := baseFlow()
+
+	// Run state, edge IDs and number types aren't part of the definition
+	after.Nodes[0].Data.Status = "success"
+	after.Nodes[0].Data.Output = map[string]interface{}{"status": 200.0}
+	after.Nodes[1].Data.Error = "timeout"
+	after.Nodes[1].Data.Config["retries"] = 2.0
+	after.Edges[0].ID = "reactflow__edge-login-cart"
+
+	d := Compare(before, after)
+	if !d.Empty() || d.String() != "no changes" {
+		t.Errorf("diff = %+v, want none", d)
+	}
+
+	// No variables and an empty set are the same
+	before.Variables, after.Variables = nil, map[string]interface{}{}
+	if d := Compare(before, after); !d.Empty() {
+		t.Errorf("metadata = %+v, want no changes", d.Metadata)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	before := baseFlow()
+	after := baseFlow()
+
+	after.Name = "Checkout v2"
+	after.Tags = []string{"shop", "smoke"}
+	after.Variables = map[string]interface{}{"baseUrl": "https://shop.test", "auth": map[string]interface{}{"token": "b"}, "timeout": 30}
+
+	after.Nodes[0].Data.Label = "Sign in"
+	after.Nodes[0].Position.X = 120
+	after.Nodes[1].Data.Config = map[string]interface{}{"method": "PUT", "headers": map[string]interface{}{"X-A": "1", "X-C": "3"}, "retries": 2}
+	after.Nodes[2].Type = "verification"
+	// wait is replaced by report
+	after.Nodes = append(after.Nodes[:3], node("report", "Report", 700, nil))
+	after.Edges = []models.FlowEdge{
+		edge("e1", "login", "cart", ""),
+		edge("e2", "cart", "check", ""),
+		edge("e4", "check", "report", "false"),
+	}
+
+	d := Compare(before, after)
+
+	wantMetadata := []FieldChange{
+		{Path: "name", Old: "Checkout", New: "Checkout v2"},
+		{Path: "tags", Old: []string{"smoke", "shop"}, New: []string{"shop", "smoke"}},
+		{Path: "variables.auth.token", Old: "a", New: "b"},
+		{Path: "variables.auth.user", Old: "u", New: nil},
+		{Path: "variables.timeout", Old: nil, New: 30.0},
+	}
+	if !reflect.DeepEqual(d.Metadata, wantMetadata) {
+		t.Errorf("metadata =\n%+v\nwant\n%+v", d.Metadata, wantMetadata)
+	}
+
+	wantChanged := []NodeChange{
+		{NodeRef: NodeRef{ID: "login", Type: "custom", Label: "Sign in"}, Changes: []FieldChange{
+			{Path: "label", Old: "Log in", New: "Sign in"},
+			{Path: "position", Old: models.Position{X: 100, Y: 100}, New: models.Position{X: 120, Y: 100}},
+		}},
+		{NodeRef: NodeRef{ID: "cart", Type: "custom", Label: "Add to cart"}, Changes: []FieldChange{
+			{Path: "config.headers.X-B", Old: "2", New: nil},
+			{Path: "config.headers.X-C", Old: nil, New: "3"},
+			{Path: "config.method", Old: "POST", New: "PUT"},
+		}},
+		{NodeRef: NodeRef{ID: "check", Type: "verification", Label: "Check cart"}, Changes: []FieldChange{
+			{Path: "type", Old: "custom", New: "verification"},
+		}},
+	}
+	if !reflect.DeepEqual(d.ChangedNodes, wantChanged) {
+		t.Errorf("changed nodes =\n%+v\nwant\n%+v", d.ChangedNodes, wantChanged)
+	}
+
+	if want := []NodeRef{{ID: "report", Type: "custom", Label: "Report"}}; !reflect.DeepEqual(d.AddedNodes, want) {
+		t.Errorf("added nodes = %+v, want %+v", d.AddedNodes, want)
+	}
+	if want := []NodeRef{{ID: "wait", Type: "custom", Label: "Wait"}}; !reflect.DeepEqual(d.RemovedNodes, want) {
+		t.Errorf("removed nodes = %+v, want %+v", d.RemovedNodes, want)
+	}
+	if len(d.AddedEdges) != 1 || d.AddedEdges[0].ID != "e4" {
+		t.Errorf("added edges = %+v, want e4", d.AddedEdges)
+	}
+	if len(d.RemovedEdges) != 1 || d.RemovedEdges[0].ID != "e3" {
+		t.Errorf("removed edges = %+v, want e3", d.RemovedEdges)
+	}
+
+	wantString := "5 metadata field(s) changed, 1 node(s) added, 1 node(s) removed, 3 node(s) changed, 1 edge(s) added, 1 edge(s) removed"
+	if d.String() != wantString {
+		t.Errorf("String() = %q, want %q", d.String(), wantString)
+	}
+
+	wantSummary := map[string]interface{}{
+		"description":      wantString,
+		"metadata_changed": []string{"name", "tags", "variables.auth.token", "variables.auth.user", "variables.timeout"},
+		"nodes_added":      []string{"report"},
+		"nodes_removed":    []string{"wait"},
+		"nodes_changed":    []string{"login", "cart", "check"},
+		"edges_added":      1,
+		"edges_removed":    1,
+	}
+	if summary := d.Summary(); !reflect.DeepEqual(summary, wantSummary) {
+		t.Errorf("summary =\n%v\nwant\n%v", summary, wantSummary)
+	}
+}
+
+func TestCompareEdgeHandles(t *testing.T) {
+	tests := []struct {
+		name    string
+		before  models.FlowEdge
+		after   models.FlowEdge
+		changed bool
+	}{
+		{name: "same endpoints, new ID", before: edge("a", "x", "y", ""), after: edge("b", "x", "y", ""), changed: false},
+		{name: "same handle", before: edge("a", "x", "y", "true"), after: edge("a", "x", "y", "true"), changed: false},
+		{name: "handle added", before: edge("a", "x", "y", ""), after: edge("a", "x", "y", "true"), changed: true},
+		{name: "handle changed", before: edge("a", "x", "y", "true"), after: edge("a", "x", "y", "false"), changed: true},
+		{name: "target changed", before: edge("a", "x", "y", ""), after: edge("a", "x", "z", ""), changed: true},
+		{name: "reversed", before: edge("a", "x", "y", ""), after: edge("a", "y", "x", ""), changed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Compare(&models.Flow{Edges: []models.FlowEdge{tt.before}}, &models.Flow{Edges: []models.FlowEdge{tt.after}})
+			changed := len(d.AddedEdges) == 1 && len(d.RemovedEdges) == 1
+			if changed != tt.changed || (!changed && !d.Empty()) {
+				t.Errorf("added %+v, removed %+v, want changed %v", d.AddedEdges, d.RemovedEdges, tt.changed)
+			}
+		})
+	}
+}
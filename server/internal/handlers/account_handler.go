@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/auth"
+	"github.com/visual-api-testing-platform/server/internal/mail"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+const (
+	passwordResetTTL       = time.Hour
+	emailVerificationTTL   = 48 * time.Hour
+	accountConfirmationTTL = 15 * time.Minute
+)
+
+// AccountHandler handles profile management, password reset and email verification
+type AccountHandler struct {
+	userRepo  accountUserStore
+	tokenRepo userTokenStore
+	mailer    mail.Sender
+	appURL    string
+}
+
+// NewAccountHandler creates a new account handler.
+// appURL is the frontend base URL used to build links in emails.
+func NewAccountHandler(
+	userRepo *repository.UserRepository,
+	tokenRepo *repository.UserTokenRepository,
+	mailer mail.Sender,
+	appURL string,
+) *AccountHandler {
+	return &AccountHandler{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		mailer:    mailer,
+		appURL:    strings.TrimSuffix(appURL, "/"),
+	}
+}
+
+// GetMe handles GET /api/me
+func (h *AccountHandler) GetMe(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateMe handles PUT /api/me
+func (h *AccountHandler) UpdateMe(c *gin.Context) {
+	var req models.UpdateProfileRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if req.Email != nil && !strings.EqualFold(*req.Email, user.Email) {
+		if !h.reauthenticate(c, user, req.CurrentPassword, req.ConfirmationToken) {
+			return
+		}
+
+		if _, err := h.userRepo.GetByEmail(ctx, *req.Email); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email is already in use"})
+			return
+		}
+
+		if err := h.userRepo.UpdateEmail(ctx, user.ID, *req.Email); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		user.Email = *req.Email
+		user.EmailVerifiedAt = nil
+
+		if err := h.SendVerificationEmail(ctx, user); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
+		}
+	}
+
+	if req.Name != nil && *req.Name != user.Name {
+		if strings.TrimSpace(*req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		if err := h.userRepo.UpdateName(ctx, user.ID, *req.Name); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		user.Name = *req.Name
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangePassword handles PUT /api/me/password
+func (h *AccountHandler) ChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !h.reauthenticate(c, user, req.CurrentPassword, req.ConfirmationToken) {
+		return
+	}
+
+	if err := h.userRepo.UpdatePassword(c.Request.Context(), user.ID, req.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Outstanding reset links must not outlive a password change
+	if err := h.tokenRepo.DeleteByUser(c.Request.Context(), user.ID, models.TokenPurposePasswordReset); err != nil {
+		log.Printf("Failed to revoke password reset tokens: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated"})
+}
+
+// DeleteMe handles DELETE /api/me
+func (h *AccountHandler) DeleteMe(c *gin.Context) {
+	var req models.DeleteAccountRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !h.reauthenticate(c, user, req.Password, req.ConfirmationToken) {
+		return
+	}
+
+	if err := h.userRepo.Delete(c.Request.Context(), user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+}
+
+// RequestConfirmation handles POST /api/me/confirmation.
+// Accounts without a password (SSO-only) can't re-enter one before changing
+// their email or password or deleting the account, so they confirm with a
+// short-lived token emailed to their current address instead.
+func (h *AccountHandler) RequestConfirmation(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.PasswordHash != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Confirm account changes with your current password"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// Only the most recent confirmation stays valid
+	if err := h.tokenRepo.DeleteByUser(ctx, user.ID, models.TokenPurposeAccountConfirmation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation email"})
+		return
+	}
+
+	token, err := h.issueToken(ctx, user.ID, models.TokenPurposeAccountConfirmation, user.Email, accountConfirmationTTL)
+	if err == nil {
+		err = h.mailer.Send(ctx, mail.Message{
+			To:      user.Email,
+			Subject: "Confirm your account change",
+			Body: fmt.Sprintf(
+				"Hi %s,\n\nUse this code to confirm changing your email or password, or deleting your account. It expires in %s.\n\n%s\n\nIf you didn't request this, someone may be using your session; sign out everywhere and contact your administrator.\n",
+				user.Name, accountConfirmationTTL, token,
+			),
+		})
+	}
+	if err != nil {
+		log.Printf("Failed to send account confirmation email: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation email sent"})
+}
+
+// ResendVerification handles POST /api/me/email/verification
+func (h *AccountHandler) ResendVerification(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Email already verified"})
+		return
+	}
+
+	if err := h.SendVerificationEmail(c.Request.Context(), user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+// ForgotPassword handles POST /api/auth/password/forgot.
+// It always responds 202 so the endpoint cannot be used to discover accounts.
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if user, err := h.userRepo.GetByEmail(c.Request.Context(), req.Email); err == nil {
+		token, err := h.issueToken(c.Request.Context(), user.ID, models.TokenPurposePasswordReset, user.Email, passwordResetTTL)
+		if err == nil {
+			err = h.mailer.Send(c.Request.Context(), mail.Message{
+				To:      user.Email,
+				Subject: "Reset your password",
+				Body: fmt.Sprintf(
+					"Hi %s,\n\nUse the link below to reset your password. It expires in %s.\n\n%s\n\nIf you didn't request this, you can ignore this email.\n",
+					user.Name, passwordResetTTL, h.link("/reset-password", token),
+				),
+			})
+		}
+		if err != nil {
+			log.Printf("Failed to send password reset email: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists, a reset email has been sent"})
+}
+
+// ResetPassword handles POST /api/auth/password/reset
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	token, err := h.tokenRepo.Consume(ctx, auth.HashToken(req.Token), models.TokenPurposePasswordReset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	if err := h.userRepo.UpdatePassword(ctx, token.UserID, req.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.tokenRepo.DeleteByUser(ctx, token.UserID, models.TokenPurposePasswordReset); err != nil {
+		log.Printf("Failed to revoke password reset tokens: %v", err)
+	}
+
+	// Receiving the reset email proves ownership of the address
+	if _, err := h.userRepo.MarkEmailVerified(ctx, token.UserID, token.Email); err != nil {
+		log.Printf("Failed to mark email verified: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// VerifyEmail handles POST /api/auth/email/verify
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.tokenRepo.Consume(c.Request.Context(), auth.HashToken(req.Token), models.TokenPurposeEmailVerification)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	verified, err := h.userRepo.MarkEmailVerified(c.Request.Context(), token.UserID, token.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !verified {
+		// The user changed their email after this link was sent
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token does not match the current email address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// SendVerificationEmail issues a verification token for the user's current email and mails it
+func (h *AccountHandler) SendVerificationEmail(ctx context.Context, user *models.User) error {
+	// Only the most recent link stays valid
+	if err := h.tokenRepo.DeleteByUser(ctx, user.ID, models.TokenPurposeEmailVerification); err != nil {
+		return err
+	}
+
+	token, err := h.issueToken(ctx, user.ID, models.TokenPurposeEmailVerification, user.Email, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n",
+			user.Name, h.link("/verify-email", token),
+		),
+	})
+}
+
+// issueToken creates a random single-use token and stores its digest
+func (h *AccountHandler) issueToken(ctx context.Context, userID uuid.UUID, purpose, email string, ttl time.Duration) (string, error) {
+	raw, err := auth.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	token := &models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := h.tokenRepo.Create(ctx, token, auth.HashToken(raw)); err != nil {
+		return "", err
+	}
+
+	return raw, nil
+}
+
+func (h *AccountHandler) link(path, token string) string {
+	return h.appURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// reauthenticate checks the current password, or for accounts without one the
+// emailed confirmation token, before a sensitive account change. It writes an
+// error response if the check fails.
+func (h *AccountHandler) reauthenticate(c *gin.Context, user *models.User, password, confirmationToken string) bool {
+	if user.PasswordHash != "" {
+		if !h.userRepo.VerifyPassword(user, password) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+			return false
+		}
+		return true
+	}
+
+	if confirmationToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "confirmation_token required; request one with POST /api/me/confirmation"})
+		return false
+	}
+
+	// The token must have been sent to this user's current address
+	token, err := h.tokenRepo.Consume(c.Request.Context(), auth.HashToken(confirmationToken), models.TokenPurposeAccountConfirmation)
+	if err != nil || token.UserID != user.ID || !strings.EqualFold(token.Email, user.Email) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired confirmation token"})
+		return false
+	}
+	return true
+}
+
+// currentUser loads the authenticated user, writing an error response if it fails
+func (h *AccountHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+
+	return user, true
+}
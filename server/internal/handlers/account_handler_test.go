@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/mail"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+type accountTest struct {
+	users   *fakeUserStore
+	tokens  *fakeTokenStore
+	mailDir string
+	router  *gin.Engine
+}
+
+// testUserHeader stands in for the JWT middleware in these tests
+const testUserHeader = "X-Test-User"
+
+func newAccountTest(t *testing.T, users ...*models.User) *accountTest {
+	t.Helper()
+	a := &accountTest{
+		users:   newFakeUserStore(users...),
+		tokens:  newFakeTokenStore(),
+		mailDir: t.TempDir(),
+	}
+	h := &AccountHandler{
+		userRepo:  a.users,
+		tokenRepo: a.tokens,
+		mailer:    mail.NewFileSender(a.mailDir, "noreply@example.com"),
+		appURL:    "http://localhost:5173",
+	}
+
+	a.router = gin.New()
+	a.router.POST("/api/auth/password/forgot", h.ForgotPassword)
+	a.router.POST("/api/auth/password/reset", h.ResetPassword)
+	a.router.POST("/api/auth/email/verify", h.VerifyEmail)
+
+	me := a.router.Group("/api/me", func(c *gin.Context) {
+		if id, err := uuid.Parse(c.GetHeader(testUserHeader)); err == nil {
+			c.Set("user_id", id)
+		}
+	})
+	me.PUT("", h.UpdateMe)
+	me.DELETE("", h.DeleteMe)
+	me.PUT("/password", h.ChangePassword)
+	me.POST("/email/verification", h.ResendVerification)
+	me.POST("/confirmation", h.RequestConfirmation)
+	return a
+}
+
+func (a *accountTest) do(t *testing.T, method, path string, user *models.User, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var payload []byte
+	switch b := body.(type) {
+	case nil:
+	case string:
+		payload = []byte(b)
+	default:
+		payload, _ = json.Marshal(b)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if user != nil {
+		req.Header.Set(testUserHeader, user.ID.String())
+	}
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+	return w
+}
+
+var mailedToken = regexp.MustCompile(`token=(\S+)`)
+
+// takeMail returns the token in the only message the FileSender wrote, and
+// removes the message
+func (a *accountTest) takeMail(t *testing.T, to string) string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(a.mailDir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("%d messages were sent, want 1", len(files))
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(files[0])
+
+	message := string(raw)
+	if !strings.Contains(message, "To: "+to+"\r\n") {
+		t.Fatalf("message isn't addressed to %s:\n%s", to, message)
+	}
+
+	// Links carry the token in the query; confirmation codes are on a line of their own
+	if match := mailedToken.FindStringSubmatch(message); match != nil {
+		token, err := url.QueryUnescape(match[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	_, body, _ := strings.Cut(message, "\r\n\r\n")
+	lines := strings.Split(body, "\r\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "Use this code") && i+2 < len(lines) {
+			return lines[i+2]
+		}
+	}
+	t.Fatalf("no token in message:\n%s", message)
+	return ""
+}
+
+func (a *accountTest) expectNoMail(t *testing.T) {
+	t.Helper()
+	if files, _ := filepath.Glob(filepath.Join(a.mailDir, "*.eml")); len(files) != 0 {
+		t.Fatalf("%d messages were sent, want none", len(files))
+	}
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status %d, want %d: %s", w.Code, status, w.Body)
+	}
+}
+
+func newPasswordUser(email string) *models.User {
+	return &models.User{ID: uuid.New(), Email: email, Name: "Test", Role: "editor", PasswordHash: "hash:old-password"}
+}
+
+func TestPasswordReset(t *testing.T) {
+	user := newPasswordUser("alice@example.com")
+	a := newAccountTest(t, user)
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/password/forgot", nil, gin.H{"email": "alice@example.com"}), http.StatusAccepted)
+	token := a.takeMail(t, "alice@example.com")
+
+	reset := gin.H{"token": token, "new_password": "new-password"}
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/password/reset", nil, reset), http.StatusOK)
+
+	stored, _ := a.users.GetByID(context.Background(), user.ID)
+	if !a.users.VerifyPassword(stored, "new-password") {
+		t.Error("password wasn't changed")
+	}
+	if stored.EmailVerifiedAt == nil {
+		t.Error("resetting through the emailed link didn't verify the address")
+	}
+
+	// Tokens are single use
+	reset["new_password"] = "another-password"
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/password/reset", nil, reset), http.StatusBadRequest)
+	stored, _ = a.users.GetByID(context.Background(), user.ID)
+	if !a.users.VerifyPassword(stored, "new-password") {
+		t.Error("a used token changed the password again")
+	}
+}
+
+func TestPasswordResetUnknownEmail(t *testing.T) {
+	a := newAccountTest(t, newPasswordUser("alice@example.com"))
+
+	// Same response as for an existing account, but nothing is sent
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/password/forgot", nil, gin.H{"email": "nobody@example.com"}), http.StatusAccepted)
+	a.expectNoMail(t)
+}
+
+func TestPasswordResetExpiredToken(t *testing.T) {
+	user := newPasswordUser("alice@example.com")
+	a := newAccountTest(t, user)
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/password/forgot", nil, gin.H{"email": "alice@example.com"}), http.StatusAccepted)
+	token := a.takeMail(t, "alice@example.com")
+	a.tokens.expire()
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/password/reset", nil, gin.H{"token": token, "new_password": "new-password"}), http.StatusBadRequest)
+	stored, _ := a.users.GetByID(context.Background(), user.ID)
+	if !a.users.VerifyPassword(stored, "old-password") {
+		t.Error("an expired token changed the password")
+	}
+}
+
+func TestPasswordChangeRevokesResetTokens(t *testing.T) {
+	user := newPasswordUser("alice@example.com")
+	a := newAccountTest(t, user)
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/password/forgot", nil, gin.H{"email": "alice@example.com"}), http.StatusAccepted)
+	token := a.takeMail(t, "alice@example.com")
+
+	change := gin.H{"current_password": "old-password", "new_password": "changed-password"}
+	expectStatus(t, a.do(t, http.MethodPut, "/api/me/password", user, change), http.StatusOK)
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/password/reset", nil, gin.H{"token": token, "new_password": "new-password"}), http.StatusBadRequest)
+}
+
+func TestEmailVerification(t *testing.T) {
+	user := newPasswordUser("alice@example.com")
+	a := newAccountTest(t, user)
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/me/email/verification", user, nil), http.StatusAccepted)
+	first := a.takeMail(t, "alice@example.com")
+
+	// Only the most recent link stays valid
+	expectStatus(t, a.do(t, http.MethodPost, "/api/me/email/verification", user, nil), http.StatusAccepted)
+	second := a.takeMail(t, "alice@example.com")
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/email/verify", nil, gin.H{"token": first}), http.StatusBadRequest)
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/email/verify", nil, gin.H{"token": second}), http.StatusOK)
+	stored, _ := a.users.GetByID(context.Background(), user.ID)
+	if stored.EmailVerifiedAt == nil {
+		t.Error("email wasn't verified")
+	}
+
+	// Tokens are single use
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/email/verify", nil, gin.H{"token": second}), http.StatusBadRequest)
+
+	// Verified addresses don't get another link
+	expectStatus(t, a.do(t, http.MethodPost, "/api/me/email/verification", user, nil), http.StatusOK)
+	a.expectNoMail(t)
+}
+
+func TestEmailVerificationExpiredToken(t *testing.T) {
+	user := newPasswordUser("alice@example.com")
+	a := newAccountTest(t, user)
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/me/email/verification", user, nil), http.StatusAccepted)
+	token := a.takeMail(t, "alice@example.com")
+	a.tokens.expire()
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/email/verify", nil, gin.H{"token": token}), http.StatusBadRequest)
+	stored, _ := a.users.GetByID(context.Background(), user.ID)
+	if stored.EmailVerifiedAt != nil {
+		t.Error("an expired token verified the email")
+	}
+}
+
+func TestEmailChangeSendsVerification(t *testing.T) {
+	user := newPasswordUser("alice@example.com")
+	a := newAccountTest(t, user)
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/me/email/verification", user, nil), http.StatusAccepted)
+	oldToken := a.takeMail(t, "alice@example.com")
+
+	update := gin.H{"email": "alice@new.example.com", "current_password": "wrong"}
+	expectStatus(t, a.do(t, http.MethodPut, "/api/me", user, update), http.StatusUnauthorized)
+	a.expectNoMail(t)
+
+	update["current_password"] = "old-password"
+	expectStatus(t, a.do(t, http.MethodPut, "/api/me", user, update), http.StatusOK)
+	newToken := a.takeMail(t, "alice@new.example.com")
+
+	// A link sent to the previous address no longer verifies the account
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/email/verify", nil, gin.H{"token": oldToken}), http.StatusBadRequest)
+	expectStatus(t, a.do(t, http.MethodPost, "/api/auth/email/verify", nil, gin.H{"token": newToken}), http.StatusOK)
+}
+
+func TestPasswordlessAccountRequiresConfirmation(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "sso@example.com", Name: "SSO", Role: "editor"}
+	a := newAccountTest(t, user)
+
+	// Without a confirmation token a session alone can't change or delete the account
+	expectStatus(t, a.do(t, http.MethodDelete, "/api/me", user, gin.H{}), http.StatusUnauthorized)
+	expectStatus(t, a.do(t, http.MethodPut, "/api/me/password", user, gin.H{"new_password": "new-password"}), http.StatusUnauthorized)
+	expectStatus(t, a.do(t, http.MethodPut, "/api/me", user, gin.H{"email": "attacker@example.com"}), http.StatusUnauthorized)
+	expectStatus(t, a.do(t, http.MethodDelete, "/api/me", user, gin.H{"confirmation_token": "guessed"}), http.StatusUnauthorized)
+
+	stored, err := a.users.GetByID(context.Background(), user.ID)
+	if err != nil || stored.Email != "sso@example.com" || stored.PasswordHash != "" {
+		t.Fatalf("account changed without confirmation: %+v, %v", stored, err)
+	}
+
+	// The emailed token confirms one change
+	expectStatus(t, a.do(t, http.MethodPost, "/api/me/confirmation", user, nil), http.StatusAccepted)
+	token := a.takeMail(t, "sso@example.com")
+
+	setPassword := gin.H{"confirmation_token": token, "new_password": "new-password"}
+	expectStatus(t, a.do(t, http.MethodPut, "/api/me/password", user, setPassword), http.StatusOK)
+	stored, _ = a.users.GetByID(context.Background(), user.ID)
+	if !a.users.VerifyPassword(stored, "new-password") {
+		t.Error("password wasn't set")
+	}
+
+	// Once the account has a password it confirms with that instead
+	expectStatus(t, a.do(t, http.MethodPost, "/api/me/confirmation", user, nil), http.StatusBadRequest)
+	expectStatus(t, a.do(t, http.MethodDelete, "/api/me", user, gin.H{"confirmation_token": token}), http.StatusUnauthorized)
+}
+
+func TestPasswordlessAccountConfirmationExpiresAndIsSingleUse(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "sso@example.com", Name: "SSO", Role: "editor"}
+	other := &models.User{ID: uuid.New(), Email: "other@example.com", Name: "Other", Role: "editor"}
+	a := newAccountTest(t, user, other)
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/me/confirmation", user, nil), http.StatusAccepted)
+	token := a.takeMail(t, "sso@example.com")
+
+	// Another user's session can't use it
+	expectStatus(t, a.do(t, http.MethodDelete, "/api/me", other, gin.H{"confirmation_token": token}), http.StatusUnauthorized)
+	if a.users.count() != 2 {
+		t.Fatal("an account was deleted with someone else's token")
+	}
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/me/confirmation", user, nil), http.StatusAccepted)
+	token = a.takeMail(t, "sso@example.com")
+	a.tokens.expire()
+	expectStatus(t, a.do(t, http.MethodDelete, "/api/me", user, gin.H{"confirmation_token": token}), http.StatusUnauthorized)
+
+	expectStatus(t, a.do(t, http.MethodPost, "/api/me/confirmation", user, nil), http.StatusAccepted)
+	token = a.takeMail(t, "sso@example.com")
+	expectStatus(t, a.do(t, http.MethodPut, "/api/me", user, gin.H{"email": "sso@new.example.com", "confirmation_token": token}), http.StatusOK)
+	a.takeMail(t, "sso@new.example.com") // verification of the new address
+	expectStatus(t, a.do(t, http.MethodDelete, "/api/me", user, gin.H{"confirmation_token": token}), http.StatusUnauthorized)
+	if a.users.count() != 2 {
+		t.Fatal("a used token deleted the account")
+	}
+}
+
+func TestDeleteMe(t *testing.T) {
+	user := newPasswordUser("alice@example.com")
+	a := newAccountTest(t, user)
+
+	expectStatus(t, a.do(t, http.MethodDelete, "/api/me", user, "{not json"), http.StatusBadRequest)
+	expectStatus(t, a.do(t, http.MethodDelete, "/api/me", user, gin.H{"password": "wrong"}), http.StatusUnauthorized)
+	if a.users.count() != 1 {
+		t.Fatal("account was deleted")
+	}
+
+	expectStatus(t, a.do(t, http.MethodDelete, "/api/me", user, gin.H{"password": "old-password"}), http.StatusOK)
+	if a.users.count() != 0 {
+		t.Error("account wasn't deleted")
+	}
+}
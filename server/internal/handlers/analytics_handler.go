@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// maxStatsWindow bounds how far back run analytics can look
+const maxStatsWindow = 365 * 24 * time.Hour
+
+// AnalyticsHandler handles run analytics HTTP requests
+type AnalyticsHandler struct {
+	analyticsRepo  *repository.AnalyticsRepository
+	flowRepo       *repository.FlowRepository
+	quarantineRepo *repository.QuarantineRepository
+}
+
+// NewAnalyticsHandler creates a new analytics handler
+func NewAnalyticsHandler(
+	analyticsRepo *repository.AnalyticsRepository,
+	flowRepo *repository.FlowRepository,
+	quarantineRepo *repository.QuarantineRepository,
+) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsRepo:  analyticsRepo,
+		flowRepo:       flowRepo,
+		quarantineRepo: quarantineRepo,
+	}
+}
+
+// GetFlowStats handles GET /api/flows/:id/stats?window=30d
+func (h *AnalyticsHandler) GetFlowStats(c *gin.Context) {
+	window := c.DefaultQuery("window", "30d")
+	duration, err := parseWindow(window)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	to := time.Now().UTC()
+	stats, err := h.analyticsRepo.FlowStats(c.Request.Context(), flow.ID, to.Add(-duration), to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	stats.Window = window
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetFlakyNodes handles GET /api/flows/:id/flaky?window=14d.
+// Every node with pass/fail results in the window is scored; flaky marks the ones
+// over the threshold.
+func (h *AnalyticsHandler) GetFlakyNodes(c *gin.Context) {
+	window := c.DefaultQuery("window", "14d")
+	duration, err := parseWindow(window)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	nodes, err := h.analyticsRepo.FlakyNodes(ctx, flow.ID, time.Now().UTC().Add(-duration))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	quarantines, err := h.quarantineRepo.ListByFlowID(ctx, flow.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	quarantined := make(map[string]bool, len(quarantines))
+	for _, q := range quarantines {
+		quarantined[q.NodeID] = true
+	}
+	for i := range nodes {
+		nodes[i].Quarantined = quarantined[nodes[i].NodeID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flow_id":     flow.ID,
+		"window":      window,
+		"nodes":       nodes,
+		"quarantined": quarantines,
+	})
+}
+
+// parseWindow parses a look-back window such as 24h, 7d or 4w
+func parseWindow(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("window must look like 24h, 30d or 4w")
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("window must look like 24h, 30d or 4w")
+	}
+
+	var unit time.Duration
+	switch s[len(s)-1] {
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("window must look like 24h, 30d or 4w")
+	}
+
+	window := time.Duration(n) * unit
+	if window > maxStatsWindow {
+		return 0, fmt.Errorf("window cannot be longer than 365d")
+	}
+	return window, nil
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/openapi"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// APISpecHandler handles the shared OpenAPI spec library.
+// Every user can read the library; viewers cannot change it.
+type APISpecHandler struct {
+	specRepo  *repository.APISpecRepository
+	userRepo  *repository.UserRepository
+	auditRepo *repository.AuditRepository
+}
+
+// NewAPISpecHandler creates a new API spec handler
+func NewAPISpecHandler(
+	specRepo *repository.APISpecRepository,
+	userRepo *repository.UserRepository,
+	auditRepo *repository.AuditRepository,
+) *APISpecHandler {
+	return &APISpecHandler{
+		specRepo:  specRepo,
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+	}
+}
+
+// specRequest carries the document either as a JSON object or as a string
+// holding JSON or YAML
+type specRequest struct {
+	Name        string      `json:"name" binding:"required"`
+	Description string      `json:"description"`
+	Spec        interface{} `json:"spec" binding:"required"`
+}
+
+// operationSummary lists an operation API nodes can bind to
+type operationSummary struct {
+	OperationID string   `json:"operation_id,omitempty"`
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Summary     string   `json:"summary,omitempty"`
+	StatusCodes []string `json:"status_codes"`
+}
+
+// ListSpecs handles GET /api/specs
+func (h *APISpecHandler) ListSpecs(c *gin.Context) {
+	specs, err := h.specRepo.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, specs)
+}
+
+// GetSpec handles GET /api/specs/:id
+func (h *APISpecHandler) GetSpec(c *gin.Context) {
+	spec, ok := h.loadSpec(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, spec)
+}
+
+// ListOperations handles GET /api/specs/:id/operations
+func (h *APISpecHandler) ListOperations(c *gin.Context) {
+	spec, ok := h.loadSpec(c)
+	if !ok {
+		return
+	}
+
+	doc, err := openapi.Parse(spec.Document)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid spec: " + err.Error()})
+		return
+	}
+
+	operations := make([]operationSummary, 0, len(doc.Operations()))
+	for _, op := range doc.Operations() {
+		operations = append(operations, operationSummary{
+			OperationID: op.ID,
+			Method:      op.Method,
+			Path:        op.Path,
+			Summary:     op.Summary,
+			StatusCodes: op.StatusCodes(),
+		})
+	}
+
+	c.JSON(http.StatusOK, operations)
+}
+
+// CreateSpec handles POST /api/specs
+func (h *APISpecHandler) CreateSpec(c *gin.Context) {
+	userID, ok := requireEditor(c, h.userRepo, "spec")
+	if !ok {
+		return
+	}
+
+	var req specRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	document, doc, ok := parseSpecDocument(c, req.Spec)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	spec := &models.APISpec{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Description: req.Description,
+		Title:       doc.Title,
+		APIVersion:  doc.Version,
+		Document:    document,
+		CreatedBy:   &userID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := h.specRepo.Create(c.Request.Context(), spec); err != nil {
+		if errors.Is(err, repository.ErrSpecNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, models.AuditActionSpecCreate, models.AuditResourceSpec, spec.ID.String(), map[string]interface{}{
+		"name":        spec.Name,
+		"api_version": spec.APIVersion,
+	})
+
+	c.JSON(http.StatusCreated, spec)
+}
+
+// UpdateSpec handles PUT /api/specs/:id
+func (h *APISpecHandler) UpdateSpec(c *gin.Context) {
+	if _, ok := requireEditor(c, h.userRepo, "spec"); !ok {
+		return
+	}
+
+	var req specRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	document, doc, ok := parseSpecDocument(c, req.Spec)
+	if !ok {
+		return
+	}
+
+	spec, ok := h.loadSpec(c)
+	if !ok {
+		return
+	}
+
+	spec.Name = req.Name
+	spec.Description = req.Description
+	spec.Title = doc.Title
+	spec.APIVersion = doc.Version
+	spec.Document = document
+
+	if err := h.specRepo.Update(c.Request.Context(), spec); err != nil {
+		if errors.Is(err, repository.ErrSpecNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, models.AuditActionSpecUpdate, models.AuditResourceSpec, spec.ID.String(), map[string]interface{}{
+		"name":        spec.Name,
+		"api_version": spec.APIVersion,
+	})
+
+	c.JSON(http.StatusOK, spec)
+}
+
+// DeleteSpec handles DELETE /api/specs/:id.
+// API nodes bound to the spec fail until their contract is updated.
+func (h *APISpecHandler) DeleteSpec(c *gin.Context) {
+	if _, ok := requireEditor(c, h.userRepo, "spec"); !ok {
+		return
+	}
+
+	spec, ok := h.loadSpec(c)
+	if !ok {
+		return
+	}
+
+	if err := h.specRepo.Delete(c.Request.Context(), spec.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, models.AuditActionSpecDelete, models.AuditResourceSpec, spec.ID.String(), map[string]interface{}{
+		"name": spec.Name,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Spec deleted successfully"})
+}
+
+// loadSpec loads the spec from the :id param, writing an error response if
+// it doesn't exist
+func (h *APISpecHandler) loadSpec(c *gin.Context) (*models.APISpec, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid spec ID"})
+		return nil, false
+	}
+
+	spec, err := h.specRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return nil, false
+	}
+
+	return spec, true
+}
+
+// parseSpecDocument decodes a spec given as an object or as JSON or YAML text
+// and checks it is a usable OpenAPI 3 document, writing an error response if not
+func parseSpecDocument(c *gin.Context, raw interface{}) (interface{}, *openapi.Document, bool) {
+	document := raw
+	if text, ok := raw.(string); ok {
+		decoded, err := openapi.Decode([]byte(text))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid spec: " + err.Error()})
+			return nil, nil, false
+		}
+		document = decoded
+	}
+
+	doc, err := openapi.Parse(document)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid spec: " + err.Error()})
+		return nil, nil, false
+	}
+
+	return document, doc, true
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// AuditHandler handles audit log HTTP requests
+type AuditHandler struct {
+	auditRepo *repository.AuditRepository
+	userRepo  *repository.UserRepository
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(auditRepo *repository.AuditRepository, userRepo *repository.UserRepository) *AuditHandler {
+	return &AuditHandler{
+		auditRepo: auditRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// ListEvents handles GET /api/audit.
+// Admins can query every event; other users only see events they performed.
+func (h *AuditHandler) ListEvents(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	filter := models.AuditFilter{
+		Action:       c.Query("action"),
+		ResourceType: c.Query("resource_type"),
+		ResourceID:   c.Query("resource_id"),
+	}
+
+	if actor := c.Query("actor"); actor != "" {
+		actorID, err := uuid.Parse(actor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid actor ID"})
+			return
+		}
+		filter.ActorID = &actorID
+	}
+
+	if user.Role != models.RoleAdmin {
+		if filter.ActorID != nil && *filter.ActorID != user.ID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can view other users' events"})
+			return
+		}
+		filter.ActorID = &user.ID
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.To = &t
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	events, err := h.auditRepo.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// recordAudit appends an audit event for the current request, attributed to the
+// authenticated user, whose email the repository records alongside the ID.
+// Failures are logged rather than surfaced so auditing never blocks the action
+// itself.
+func recordAudit(
+	c *gin.Context,
+	auditRepo *repository.AuditRepository,
+	action, resourceType, resourceID string,
+	metadata map[string]interface{},
+) {
+	event := &models.AuditEvent{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Metadata:     metadata,
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		id := userID.(uuid.UUID)
+		event.ActorID = &id
+	}
+
+	writeAudit(c, auditRepo, event)
+}
+
+// writeAudit fills in request details and stores the event
+func writeAudit(c *gin.Context, auditRepo auditStore, event *models.AuditEvent) {
+	event.IPAddress = c.ClientIP()
+	event.UserAgent = c.Request.UserAgent()
+
+	if err := auditRepo.Create(c.Request.Context(), event); err != nil {
+		log.Printf("Failed to record audit event %s: %v", event.Action, err)
+	}
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,17 +13,37 @@ import (
 	"github.com/visual-api-testing-platform/server/internal/repository"
 )
 
+// Account lockout policy: after maxLoginFailures failed logins within
+// loginFailureWindow, the account is locked for loginLockoutDuration
+const (
+	maxLoginFailures     = 5
+	loginFailureWindow   = 15 * time.Minute
+	loginLockoutDuration = 15 * time.Minute
+)
+
 // AuthHandler handles authentication-related HTTP requests
 type AuthHandler struct {
-	userRepo  *repository.UserRepository
-	jwtSecret string
+	userRepo         authUserStore
+	loginAttemptRepo loginAttemptStore
+	auditRepo        auditStore
+	accountHandler   *AccountHandler
+	jwtSecret        string
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(userRepo *repository.UserRepository, jwtSecret string) *AuthHandler {
+func NewAuthHandler(
+	userRepo *repository.UserRepository,
+	loginAttemptRepo *repository.LoginAttemptRepository,
+	auditRepo *repository.AuditRepository,
+	accountHandler *AccountHandler,
+	jwtSecret string,
+) *AuthHandler {
 	return &AuthHandler{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:         userRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		auditRepo:        auditRepo,
+		accountHandler:   accountHandler,
+		jwtSecret:        jwtSecret,
 	}
 }
 
@@ -52,7 +74,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	token, err := h.generateToken(user.ID)
+	if err := h.accountHandler.SendVerificationEmail(c.Request.Context(), user); err != nil {
+		log.Printf("Failed to send verification email: %v", err)
+	}
+
+	token, err := h.issueToken(c, user, "register")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -73,18 +99,40 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	email := strings.ToLower(req.Email)
+
+	// Check account lockout before touching the password
+	failures, lastFailure, err := h.loginAttemptRepo.CountRecentFailures(
+		c.Request.Context(), email, time.Now().Add(-loginFailureWindow),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return
+	}
+	if failures >= maxLoginFailures && lastFailure != nil {
+		if retryAfter := time.Until(lastFailure.Add(loginLockoutDuration)); retryAfter > 0 {
+			h.recordLoginAttempt(c, email, nil, false, models.LoginReasonLocked)
+			abortTooManyRequests(c, retryAfter)
+			return
+		}
+	}
+
 	user, err := h.userRepo.GetByEmail(c.Request.Context(), req.Email)
 	if err != nil {
+		h.recordLoginAttempt(c, email, nil, false, models.LoginReasonUnknownUser)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
 	if !h.userRepo.VerifyPassword(user, req.Password) {
+		h.recordLoginAttempt(c, email, &user.ID, false, models.LoginReasonInvalidCredentials)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	token, err := h.generateToken(user.ID)
+	h.recordLoginAttempt(c, email, &user.ID, true, "")
+
+	token, err := h.issueToken(c, user, "password")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -96,6 +144,58 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+// recordLoginAttempt writes a login attempt to the login attempts table and the audit log.
+// Failures are logged rather than surfaced so auditing never blocks a login.
+func (h *AuthHandler) recordLoginAttempt(c *gin.Context, email string, userID *uuid.UUID, success bool, reason string) {
+	event := &models.AuditEvent{
+		ActorID:      userID,
+		ActorEmail:   email,
+		Action:       models.AuditActionLogin,
+		ResourceType: models.AuditResourceUser,
+		Metadata:     map[string]interface{}{"method": "password"},
+	}
+	if userID != nil {
+		event.ResourceID = userID.String()
+	}
+	if !success {
+		event.Action = models.AuditActionLoginFailed
+		event.Metadata["reason"] = reason
+	}
+	writeAudit(c, h.auditRepo, event)
+
+	attempt := &models.LoginAttempt{
+		Email:     email,
+		UserID:    userID,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Success:   success,
+		Reason:    reason,
+	}
+
+	if err := h.loginAttemptRepo.Create(c.Request.Context(), attempt); err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	}
+}
+
+// issueToken generates a JWT for the user and records its creation in the audit log
+func (h *AuthHandler) issueToken(c *gin.Context, user *models.User, method string) (string, error) {
+	token, err := h.generateToken(user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	writeAudit(c, h.auditRepo, &models.AuditEvent{
+		ActorID:      &user.ID,
+		ActorEmail:   user.Email,
+		Action:       models.AuditActionTokenCreated,
+		ResourceType: models.AuditResourceUser,
+		ResourceID:   user.ID.String(),
+		Metadata:     map[string]interface{}{"method": method},
+	})
+
+	return token, nil
+}
+
 // generateToken generates a JWT token
 func (h *AuthHandler) generateToken(userID uuid.UUID) (string, error) {
 	claims := jwt.MapClaims{
@@ -158,4 +258,3 @@ func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
-
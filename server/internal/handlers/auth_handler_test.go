@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+type loginTest struct {
+	attempts *fakeLoginAttemptStore
+	router   *gin.Engine
+}
+
+func newLoginTest(users ...*models.User) *loginTest {
+	l := &loginTest{attempts: &fakeLoginAttemptStore{}}
+	h := &AuthHandler{
+		userRepo:         newFakeUserStore(users...),
+		loginAttemptRepo: l.attempts,
+		auditRepo:        &fakeAuditStore{},
+		jwtSecret:        "test-secret",
+	}
+	l.router = gin.New()
+	l.router.POST("/api/auth/login", h.Login)
+	return l
+}
+
+func (l *loginTest) login(email, password string) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(models.LoginRequest{Email: email, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	l.router.ServeHTTP(w, req)
+	return w
+}
+
+// fail makes n failed logins, each of which must be refused as invalid
+func (l *loginTest) fail(t *testing.T, email string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		expectStatus(t, l.login(email, "wrong-password"), http.StatusUnauthorized)
+	}
+}
+
+// expectLocked checks the login was refused by the lockout, with a
+// Retry-After of at most the given duration
+func expectLocked(t *testing.T, w *httptest.ResponseRecorder, within time.Duration) {
+	t.Helper()
+	expectStatus(t, w, http.StatusTooManyRequests)
+	seconds, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || seconds < 1 || time.Duration(seconds)*time.Second > within {
+		t.Fatalf("Retry-After = %q, want at most %v", w.Header().Get("Retry-After"), within)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	user := newPasswordUser("ada@example.com")
+	l := newLoginTest(user)
+
+	l.fail(t, "ada@example.com", maxLoginFailures)
+
+	// Locked, even with the right password, until the last failure is
+	// loginLockoutDuration old
+	expectLocked(t, l.login("ada@example.com", "old-password"), loginLockoutDuration)
+	expectLocked(t, l.login("ADA@example.com", "old-password"), loginLockoutDuration)
+
+	// Retries while locked don't extend the lockout
+	l.attempts.age(loginLockoutDuration - time.Minute)
+	expectLocked(t, l.login("ada@example.com", "old-password"), time.Minute)
+
+	l.attempts.age(time.Minute)
+	expectStatus(t, l.login("ada@example.com", "old-password"), http.StatusOK)
+
+	// A successful login clears the failures
+	l.fail(t, "ada@example.com", maxLoginFailures-1)
+	expectStatus(t, l.login("ada@example.com", "old-password"), http.StatusOK)
+
+	want := []string{"invalid_credentials", "invalid_credentials", "invalid_credentials", "invalid_credentials", "invalid_credentials",
+		"locked", "locked", "locked", "success",
+		"invalid_credentials", "invalid_credentials", "invalid_credentials", "invalid_credentials", "success"}
+	if got := l.attempts.reasons(); !reflect.DeepEqual(got, want) {
+		t.Errorf("attempts = %q, want %q", got, want)
+	}
+}
+
+func TestLoginLockoutWindow(t *testing.T) {
+	l := newLoginTest(newPasswordUser("ada@example.com"))
+
+	// Failures older than the window don't count
+	l.fail(t, "ada@example.com", maxLoginFailures-1)
+	l.attempts.age(loginFailureWindow)
+	l.fail(t, "ada@example.com", maxLoginFailures-1)
+	expectStatus(t, l.login("ada@example.com", "old-password"), http.StatusOK)
+}
+
+func TestLoginLockoutPerAccount(t *testing.T) {
+	l := newLoginTest(newPasswordUser("ada@example.com"), newPasswordUser("grace@example.com"))
+
+	// Unknown accounts lock too, so guessing doesn't reveal which exist
+	l.fail(t, "nobody@example.com", maxLoginFailures)
+	expectLocked(t, l.login("nobody@example.com", "old-password"), loginLockoutDuration)
+
+	l.fail(t, "ada@example.com", maxLoginFailures)
+	expectLocked(t, l.login("ada@example.com", "old-password"), loginLockoutDuration)
+
+	expectStatus(t, l.login("grace@example.com", "old-password"), http.StatusOK)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/coverage"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/openapi"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// maxCoverageRuns bounds how many recent runs a coverage report inspects
+const maxCoverageRuns = 500
+
+// CoverageHandler reports how much of a library spec the user's flows exercise
+type CoverageHandler struct {
+	specRepo    *repository.APISpecRepository
+	flowRepo    *repository.FlowRepository
+	testRunRepo *repository.TestRunRepository
+}
+
+// NewCoverageHandler creates a new coverage handler
+func NewCoverageHandler(
+	specRepo *repository.APISpecRepository,
+	flowRepo *repository.FlowRepository,
+	testRunRepo *repository.TestRunRepository,
+) *CoverageHandler {
+	return &CoverageHandler{
+		specRepo:    specRepo,
+		flowRepo:    flowRepo,
+		testRunRepo: testRunRepo,
+	}
+}
+
+// GetCoverage handles GET /api/coverage?specId=&runs=7d.
+// The user's flows are matched against the spec's operations; with runs, the
+// requests their runs in that window sent count too.
+func (h *CoverageHandler) GetCoverage(c *gin.Context) {
+	specID, err := uuid.Parse(c.Query("specId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "specId must be a valid spec ID"})
+		return
+	}
+
+	var runsWindow time.Duration
+	if window := c.Query("runs"); window != "" {
+		if runsWindow, err = parseWindow(window); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "runs: " + err.Error()})
+			return
+		}
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	spec, err := h.specRepo.GetByID(ctx, specID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+	doc, err := openapi.Parse(spec.Document)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid spec: " + err.Error()})
+		return
+	}
+
+	flows, err := h.flowRepo.GetByUserID(ctx, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	analyzer := coverage.New(spec.ID, doc)
+	flowsByID := make(map[uuid.UUID]*models.Flow, len(flows))
+	for i := range flows {
+		flowsByID[flows[i].ID] = &flows[i]
+		analyzer.AddFlow(&flows[i])
+	}
+
+	if runsWindow > 0 {
+		runs, err := h.testRunRepo.GetRecentByUserID(ctx, userID.(uuid.UUID), time.Now().UTC().Add(-runsWindow), maxCoverageRuns)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i := range runs {
+			if flow, ok := flowsByID[runs[i].FlowID]; ok {
+				analyzer.AddRun(flow, &runs[i])
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, analyzer.Report())
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/visual-api-testing-platform/server/internal/exporter"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// ExportHandler writes flows as portable documents and as code
+type ExportHandler struct {
+	flowRepo   *repository.FlowRepository
+	schemaRepo *repository.SchemaRepository
+	specRepo   *repository.APISpecRepository
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(
+	flowRepo *repository.FlowRepository,
+	schemaRepo *repository.SchemaRepository,
+	specRepo *repository.APISpecRepository,
+) *ExportHandler {
+	return &ExportHandler{
+		flowRepo:   flowRepo,
+		schemaRepo: schemaRepo,
+		specRepo:   specRepo,
+	}
+}
+
+// exportFormat is how a format is downloaded
+type exportFormat struct {
+	extension   string
+	contentType string
+}
+
+var exportFormats = map[string]exportFormat{
+	"json": {".flow.json", "application/json"},
+	"yaml": {".flow.yaml", "application/yaml"},
+	"go":   {"_test.go", "text/x-go; charset=utf-8"},
+	"k6":   {".k6.js", "application/javascript"},
+	"curl": {".sh", "application/x-sh"},
+}
+
+// ExportFlow handles GET /api/flows/:id/export?format=json|yaml|go|k6|curl.
+// json and yaml give the flow document POST /api/flows/import takes; the
+// other formats give code that runs the flow outside the platform.
+func (h *ExportHandler) ExportFlow(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	out, ok := exportFormats[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of json, yaml, go, k6 or curl"})
+		return
+	}
+
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	var data []byte
+	var err error
+	switch format {
+	case "json", "yaml":
+		var doc *exporter.Document
+		doc, err = exporter.Export(c.Request.Context(), flow, h.schemaRepo, h.specRepo)
+		if err == nil {
+			data, err = exporter.Encode(doc, format)
+		}
+	case "go":
+		data, err = exporter.GoTest(flow)
+	case "k6":
+		data, err = exporter.K6(flow)
+	case "curl":
+		data, err = exporter.Curl(flow)
+	}
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to export flow: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s"`, exportFileName(flow), out.extension))
+	c.Data(http.StatusOK, out.contentType, data)
+}
+
+var nonFileName = regexp.MustCompile(`[^a-z0-9]+`)
+
+// exportFileName names downloads after the flow, e.g. user_signup for "User signup"
+func exportFileName(flow *models.Flow) string {
+	name := strings.Trim(nonFileName.ReplaceAllString(strings.ToLower(flow.Name), "_"), "_")
+	if name == "" {
+		name = "flow"
+	}
+	return name
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// fakeAuditStore keeps audit events in memory
+type fakeAuditStore struct {
+	mu     sync.Mutex
+	events []models.AuditEvent
+}
+
+func (s *fakeAuditStore) Create(ctx context.Context, event *models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, *event)
+	return nil
+}
+
+func (s *fakeAuditStore) actions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var actions []string
+	for _, event := range s.events {
+		actions = append(actions, event.Action)
+	}
+	return actions
+}
+
+// fakeUserStore keeps users in memory. Setting one of the err fields makes
+// the matching lookup fail as a database error would.
+type fakeUserStore struct {
+	mu         sync.Mutex
+	users      map[uuid.UUID]*models.User
+	identities map[string]uuid.UUID // issuer + " " + subject -> user
+
+	errGetByOIDCIdentity error
+	errGetByEmail        error
+}
+
+func newFakeUserStore(users ...*models.User) *fakeUserStore {
+	s := &fakeUserStore{users: make(map[uuid.UUID]*models.User), identities: make(map[string]uuid.UUID)}
+	for _, user := range users {
+		s.users[user.ID] = user
+	}
+	return s
+}
+
+func (s *fakeUserStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.errGetByEmail != nil {
+		return nil, s.errGetByEmail
+	}
+	for _, user := range s.users {
+		if strings.EqualFold(user.Email, email) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (s *fakeUserStore) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (s *fakeUserStore) UpdateName(ctx context.Context, userID uuid.UUID, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID].Name = name
+	return nil
+}
+
+func (s *fakeUserStore) UpdateEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID].Email = email
+	s.users[userID].EmailVerifiedAt = nil
+	return nil
+}
+
+// Create stores a readable stand-in for the bcrypt hash
+func (s *fakeUserStore) Create(ctx context.Context, user *models.User, password string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user.PasswordHash = "hash:" + password
+	copied := *user
+	s.users[user.ID] = &copied
+	return nil
+}
+
+// UpdatePassword stores a readable stand-in for the bcrypt hash
+func (s *fakeUserStore) UpdatePassword(ctx context.Context, userID uuid.UUID, password string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID].PasswordHash = "hash:" + password
+	return nil
+}
+
+func (s *fakeUserStore) MarkEmailVerified(ctx context.Context, userID uuid.UUID, email string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[userID]
+	if !ok || !strings.EqualFold(user.Email, email) {
+		return false, nil
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return true, nil
+}
+
+func (s *fakeUserStore) Delete(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, userID)
+	return nil
+}
+
+func (s *fakeUserStore) VerifyPassword(user *models.User, password string) bool {
+	return user.PasswordHash != "" && user.PasswordHash == "hash:"+password
+}
+
+func (s *fakeUserStore) GetByOIDCIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.errGetByOIDCIdentity != nil {
+		return nil, s.errGetByOIDCIdentity
+	}
+	id, ok := s.identities[issuer+" "+subject]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *s.users[id]
+	return &copied, nil
+}
+
+func (s *fakeUserStore) LinkOIDCIdentity(ctx context.Context, userID uuid.UUID, issuer, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identities[issuer+" "+subject] = userID
+	return nil
+}
+
+func (s *fakeUserStore) CreateExternal(ctx context.Context, user *models.User, issuer, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *user
+	s.users[user.ID] = &copied
+	s.identities[issuer+" "+subject] = user.ID
+	return nil
+}
+
+func (s *fakeUserStore) UpdateRole(ctx context.Context, userID uuid.UUID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID].Role = role
+	return nil
+}
+
+func (s *fakeUserStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users)
+}
+
+func (s *fakeUserStore) linkedUser(issuer, subject string) (uuid.UUID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.identities[issuer+" "+subject]
+	return id, ok
+}
+
+// fakeTokenStore keeps user tokens in memory with the same expiry and
+// single-use rules as the user_tokens queries
+type fakeTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*models.UserToken // token hash -> token
+}
+
+func newFakeTokenStore() *fakeTokenStore {
+	return &fakeTokenStore{tokens: make(map[string]*models.UserToken)}
+}
+
+func (s *fakeTokenStore) Create(ctx context.Context, token *models.UserToken, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.ID == uuid.Nil {
+		token.ID = uuid.New()
+	}
+	token.CreatedAt = time.Now()
+	copied := *token
+	s.tokens[tokenHash] = &copied
+	return nil
+}
+
+func (s *fakeTokenStore) Consume(ctx context.Context, tokenHash, purpose string) (*models.UserToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	token, ok := s.tokens[tokenHash]
+	if !ok || token.Purpose != purpose || token.UsedAt != nil || !token.ExpiresAt.After(now) {
+		return nil, pgx.ErrNoRows
+	}
+	token.UsedAt = &now
+	copied := *token
+	return &copied, nil
+}
+
+func (s *fakeTokenStore) DeleteByUser(ctx context.Context, userID uuid.UUID, purpose string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, token := range s.tokens {
+		if token.UserID == userID && token.Purpose == purpose {
+			delete(s.tokens, hash)
+		}
+	}
+	return nil
+}
+
+// expire makes every outstanding token past its expiry
+func (s *fakeTokenStore) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range s.tokens {
+		token.ExpiresAt = time.Now().Add(-time.Second)
+	}
+}
+
+// fakeLoginAttemptStore keeps login attempts in memory and counts failures
+// with the same rules as the login_attempts query
+type fakeLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts []models.LoginAttempt
+}
+
+func (s *fakeLoginAttemptStore) Create(ctx context.Context, attempt *models.LoginAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if attempt.ID == uuid.Nil {
+		attempt.ID = uuid.New()
+	}
+	attempt.CreatedAt = time.Now()
+	s.attempts = append(s.attempts, *attempt)
+	return nil
+}
+
+func (s *fakeLoginAttemptStore) CountRecentFailures(ctx context.Context, email string, since time.Time) (int, *time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var lastSuccess time.Time
+	for _, attempt := range s.attempts {
+		if attempt.Email == email && attempt.Success && attempt.CreatedAt.After(lastSuccess) {
+			lastSuccess = attempt.CreatedAt
+		}
+	}
+
+	var count int
+	var lastFailure *time.Time
+	for _, attempt := range s.attempts {
+		if attempt.Email != email || attempt.Success || attempt.Reason == models.LoginReasonLocked ||
+			!attempt.CreatedAt.After(since) || !attempt.CreatedAt.After(lastSuccess) {
+			continue
+		}
+		count++
+		if lastFailure == nil || attempt.CreatedAt.After(*lastFailure) {
+			createdAt := attempt.CreatedAt
+			lastFailure = &createdAt
+		}
+	}
+	return count, lastFailure, nil
+}
+
+// age moves every attempt back in time by d
+func (s *fakeLoginAttemptStore) age(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.attempts {
+		s.attempts[i].CreatedAt = s.attempts[i].CreatedAt.Add(-d)
+	}
+}
+
+func (s *fakeLoginAttemptStore) reasons() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var reasons []string
+	for _, attempt := range s.attempts {
+		reason := attempt.Reason
+		if attempt.Success {
+			reason = "success"
+		}
+		reasons = append(reasons, reason)
+	}
+	return reasons
+}
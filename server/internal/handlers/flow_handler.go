@@ -1,23 +1,32 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/flowdiff"
 	"github.com/visual-api-testing-platform/server/internal/models"
 	"github.com/visual-api-testing-platform/server/internal/repository"
 )
 
 // FlowHandler handles flow-related HTTP requests
 type FlowHandler struct {
-	flowRepo *repository.FlowRepository
+	flowRepo  *repository.FlowRepository
+	auditRepo *repository.AuditRepository
 }
 
 // NewFlowHandler creates a new flow handler
-func NewFlowHandler(flowRepo *repository.FlowRepository) *FlowHandler {
-	return &FlowHandler{flowRepo: flowRepo}
+func NewFlowHandler(flowRepo *repository.FlowRepository, auditRepo *repository.AuditRepository) *FlowHandler {
+	return &FlowHandler{
+		flowRepo:  flowRepo,
+		auditRepo: auditRepo,
+	}
 }
 
 // CreateFlow handles POST /api/flows
@@ -28,6 +37,8 @@ func (h *FlowHandler) CreateFlow(c *gin.Context) {
 		Tags        []string               `json:"tags"`
 		Nodes       []models.FlowNode      `json:"nodes"`
 		Edges       []models.FlowEdge      `json:"edges"`
+		Variables   map[string]interface{} `json:"variables"`
+		Message     string                 `json:"message"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -49,14 +60,22 @@ func (h *FlowHandler) CreateFlow(c *gin.Context) {
 		Tags:        req.Tags,
 		Nodes:       req.Nodes,
 		Edges:       req.Edges,
+		Variables:   req.Variables,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
-	if err := h.flowRepo.Create(c.Request.Context(), flow); err != nil {
+	if err := h.flowRepo.Create(c.Request.Context(), flow, flow.UserID, req.Message); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	setFlowETag(c, flow)
+
+	recordAudit(c, h.auditRepo, models.AuditActionFlowCreate, models.AuditResourceFlow, flow.ID.String(), map[string]interface{}{
+		"name":  flow.Name,
+		"nodes": len(flow.Nodes),
+		"edges": len(flow.Edges),
+	})
 
 	c.JSON(http.StatusCreated, flow)
 } 
@@ -75,10 +94,13 @@ func (h *FlowHandler) GetFlow(c *gin.Context) {
 		return
 	}
 
+	setFlowETag(c, flow)
 	c.JSON(http.StatusOK, flow)
 }
 
-// ListFlows handles GET /api/flows
+// ListFlows handles GET /api/flows.
+// It returns a page of flow summaries matching the filters, plus tag counts
+// across all matches; full node data comes from GetFlow.
 func (h *FlowHandler) ListFlows(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -86,16 +108,68 @@ func (h *FlowHandler) ListFlows(c *gin.Context) {
 		return
 	}
 
-	flows, err := h.flowRepo.GetByUserID(c.Request.Context(), userID.(uuid.UUID))
+	opts := models.FlowListOptions{
+		Query:       strings.TrimSpace(c.Query("q")),
+		Tags:        c.QueryArray("tag"),
+		HasNodeType: c.Query("hasNodeType"),
+		URLContains: c.Query("urlContains"),
+		Sort:        c.DefaultQuery("sort", models.FlowSortUpdatedAt),
+		Cursor:      c.Query("cursor"),
+	}
+
+	switch opts.Sort {
+	case models.FlowSortUpdatedAt, models.FlowSortCreatedAt, models.FlowSortName:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of updated_at, created_at, name"})
+		return
+	}
+
+	// Names read naturally A-Z; timestamps default to newest first
+	order := c.Query("order")
+	switch order {
+	case "":
+		opts.Desc = opts.Sort != models.FlowSortName
+	case "asc", "desc":
+		opts.Desc = order == "desc"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		opts.Limit = n
+	}
+
+	flows, nextCursor, err := h.flowRepo.ListSummaries(c.Request.Context(), userID.(uuid.UUID), opts)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, flows)
+	tagFacets, err := h.flowRepo.TagFacets(c.Request.Context(), userID.(uuid.UUID), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flows":       flows,
+		"next_cursor": nextCursor,
+		"facets":      gin.H{"tags": tagFacets},
+	})
 }
 
-// UpdateFlow handles PUT /api/flows/:id
+// UpdateFlow handles PUT /api/flows/:id.
+// The If-Match header must carry the ETag of the version being edited.
 func (h *FlowHandler) UpdateFlow(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -103,6 +177,11 @@ func (h *FlowHandler) UpdateFlow(c *gin.Context) {
 		return
 	}
 
+	expectedVersion, ok := parseIfMatch(c)
+	if !ok {
+		return
+	}
+
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
@@ -110,11 +189,13 @@ func (h *FlowHandler) UpdateFlow(c *gin.Context) {
 	}
 
 	var req struct {
-		Name        string            `json:"name"`
-		Description string            `json:"description"`
-		Tags        []string          `json:"tags"`
-		Nodes       []models.FlowNode `json:"nodes"`
-		Edges       []models.FlowEdge `json:"edges"`
+		Name        string                 `json:"name"`
+		Description string                 `json:"description"`
+		Tags        []string               `json:"tags"`
+		Nodes       []models.FlowNode      `json:"nodes"`
+		Edges       []models.FlowEdge      `json:"edges"`
+		Variables   map[string]interface{} `json:"variables"`
+		Message     string                 `json:"message"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -134,6 +215,8 @@ func (h *FlowHandler) UpdateFlow(c *gin.Context) {
 		return
 	}
 
+	before := *flow
+
 	if req.Name != "" {
 		flow.Name = req.Name
 	}
@@ -149,12 +232,22 @@ func (h *FlowHandler) UpdateFlow(c *gin.Context) {
 	if req.Edges != nil {
 		flow.Edges = req.Edges
 	}
+	if req.Variables != nil {
+		flow.Variables = req.Variables
+	}
 	flow.UpdatedAt = time.Now()
+	flow.Version = expectedVersion
 
-	if err := h.flowRepo.Update(c.Request.Context(), flow); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.flowRepo.Update(c.Request.Context(), flow, userID.(uuid.UUID), req.Message); err != nil {
+		if !respondVersionConflict(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
+	setFlowETag(c, flow)
+
+	recordAudit(c, h.auditRepo, models.AuditActionFlowUpdate, models.AuditResourceFlow, flow.ID.String(),
+		flowdiff.Compare(&before, flow).Summary())
 
 	c.JSON(http.StatusOK, flow)
 }
@@ -190,6 +283,269 @@ func (h *FlowHandler) DeleteFlow(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditRepo, models.AuditActionFlowDelete, models.AuditResourceFlow, id.String(), map[string]interface{}{
+		"name": flow.Name,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Flow deleted"})
 }
 
+// setFlowETag exposes the flow version as a strong ETag
+func setFlowETag(c *gin.Context, flow *models.Flow) {
+	c.Header("ETag", fmt.Sprintf("%q", strconv.Itoa(flow.Version)))
+}
+
+// parseIfMatch reads the expected flow version from the If-Match header,
+// writing an error response if it is missing or malformed
+func parseIfMatch(c *gin.Context) (int, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header with the flow version is required"})
+		return 0, false
+	}
+
+	// Accept "3", W/"3" and a bare 3
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "If-Match must be a flow version ETag"})
+		return 0, false
+	}
+
+	return version, true
+}
+
+// respondVersionConflict writes a 409 response if err is a version conflict
+func respondVersionConflict(c *gin.Context, err error) bool {
+	var conflict *repository.VersionConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+
+	c.Header("ETag", fmt.Sprintf("%q", strconv.Itoa(conflict.Current)))
+	c.JSON(http.StatusConflict, gin.H{
+		"error":           "Flow has been modified since it was loaded",
+		"current_version": conflict.Current,
+	})
+	return true
+}
+
+// nodePatch holds the node fields that can be changed individually.
+// Config keys are merged into the existing config; a null value removes the key.
+type nodePatch struct {
+	Type     *string                `json:"type"`
+	Position *models.Position       `json:"position"`
+	Label    *string                `json:"label"`
+	Config   map[string]interface{} `json:"config"`
+}
+
+// CreateNode handles POST /api/flows/:id/nodes.
+// Like PUT /api/flows/:id it requires If-Match and creates a new flow version.
+func (h *FlowHandler) CreateNode(c *gin.Context) {
+	var req struct {
+		Message string `json:"message"`
+		models.FlowNode
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	node := req.FlowNode
+	if node.ID == "" || node.Type == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Node id and type are required"})
+		return
+	}
+	if node.Data.ID == "" {
+		node.Data.ID = node.ID
+	}
+	if node.Data.Type == "" {
+		node.Data.Type = node.Type
+	}
+	if node.Data.Config == nil {
+		node.Data.Config = make(map[string]interface{})
+	}
+
+	flow, ok := h.flowForNodeEdit(c)
+	if !ok {
+		return
+	}
+
+	if findNode(flow, node.ID) >= 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A node with this ID already exists"})
+		return
+	}
+
+	before := *flow
+	flow.Nodes = append(append([]models.FlowNode{}, flow.Nodes...), node)
+
+	if !h.saveNodeEdit(c, &before, flow, req.Message) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, node)
+}
+
+// UpdateNode handles PATCH /api/flows/:id/nodes/:nodeId
+func (h *FlowHandler) UpdateNode(c *gin.Context) {
+	var req struct {
+		nodePatch
+		Message string `json:"message"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flow, ok := h.flowForNodeEdit(c)
+	if !ok {
+		return
+	}
+
+	i := findNode(flow, c.Param("nodeId"))
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+
+	before := *flow
+	flow.Nodes = append([]models.FlowNode{}, flow.Nodes...)
+	node := flow.Nodes[i]
+
+	if req.Type != nil {
+		node.Type = *req.Type
+		node.Data.Type = *req.Type
+	}
+	if req.Position != nil {
+		node.Position = *req.Position
+	}
+	if req.Label != nil {
+		node.Data.Label = *req.Label
+	}
+	if req.Config != nil {
+		config := make(map[string]interface{}, len(node.Data.Config))
+		for k, v := range node.Data.Config {
+			config[k] = v
+		}
+		for k, v := range req.Config {
+			if v == nil {
+				delete(config, k)
+			} else {
+				config[k] = v
+			}
+		}
+		node.Data.Config = config
+	}
+	flow.Nodes[i] = node
+
+	if !h.saveNodeEdit(c, &before, flow, req.Message) {
+		return
+	}
+
+	c.JSON(http.StatusOK, node)
+}
+
+// DeleteNode handles DELETE /api/flows/:id/nodes/:nodeId.
+// Edges connected to the node are removed with it.
+func (h *FlowHandler) DeleteNode(c *gin.Context) {
+	flow, ok := h.flowForNodeEdit(c)
+	if !ok {
+		return
+	}
+
+	nodeID := c.Param("nodeId")
+	i := findNode(flow, nodeID)
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+
+	before := *flow
+
+	nodes := make([]models.FlowNode, 0, len(flow.Nodes)-1)
+	nodes = append(nodes, flow.Nodes[:i]...)
+	flow.Nodes = append(nodes, flow.Nodes[i+1:]...)
+
+	edges := make([]models.FlowEdge, 0, len(flow.Edges))
+	for _, e := range flow.Edges {
+		if e.Source != nodeID && e.Target != nodeID {
+			edges = append(edges, e)
+		}
+	}
+	flow.Edges = edges
+
+	if !h.saveNodeEdit(c, &before, flow, c.Query("message")) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Node deleted", "version": flow.Version})
+}
+
+// flowForNodeEdit loads the flow for a single-node edit, checking ownership and
+// the If-Match precondition. It writes an error response on failure.
+func (h *FlowHandler) flowForNodeEdit(c *gin.Context) (*models.Flow, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flow ID"})
+		return nil, false
+	}
+
+	expectedVersion, ok := parseIfMatch(c)
+	if !ok {
+		return nil, false
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	flow, err := h.flowRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flow not found"})
+		return nil, false
+	}
+
+	if flow.UserID != userID.(uuid.UUID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update this flow"})
+		return nil, false
+	}
+
+	// Fail fast instead of applying the edit to a newer version than the client saw
+	if flow.Version != expectedVersion {
+		respondVersionConflict(c, &repository.VersionConflictError{Expected: expectedVersion, Current: flow.Version})
+		return nil, false
+	}
+
+	return flow, true
+}
+
+// saveNodeEdit stores a flow changed by a single-node edit as a new version and audits it
+func (h *FlowHandler) saveNodeEdit(c *gin.Context, before, flow *models.Flow, message string) bool {
+	flow.UpdatedAt = time.Now()
+
+	if err := h.flowRepo.Update(c.Request.Context(), flow, c.MustGet("user_id").(uuid.UUID), message); err != nil {
+		if !respondVersionConflict(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return false
+	}
+	setFlowETag(c, flow)
+
+	recordAudit(c, h.auditRepo, models.AuditActionFlowUpdate, models.AuditResourceFlow, flow.ID.String(),
+		flowdiff.Compare(before, flow).Summary())
+	return true
+}
+
+// findNode returns the index of the node with the given ID, or -1
+func findNode(flow *models.Flow, nodeID string) int {
+	for i := range flow.Nodes {
+		if flow.Nodes[i].ID == nodeID {
+			return i
+		}
+	}
+	return -1
+}
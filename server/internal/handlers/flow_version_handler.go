@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/flowdiff"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// FlowVersionHandler handles flow version history HTTP requests
+type FlowVersionHandler struct {
+	flowRepo    *repository.FlowRepository
+	versionRepo *repository.FlowVersionRepository
+	auditRepo   *repository.AuditRepository
+}
+
+// NewFlowVersionHandler creates a new flow version handler
+func NewFlowVersionHandler(
+	flowRepo *repository.FlowRepository,
+	versionRepo *repository.FlowVersionRepository,
+	auditRepo *repository.AuditRepository,
+) *FlowVersionHandler {
+	return &FlowVersionHandler{
+		flowRepo:    flowRepo,
+		versionRepo: versionRepo,
+		auditRepo:   auditRepo,
+	}
+}
+
+// ListVersions handles GET /api/flows/:id/versions
+func (h *FlowVersionHandler) ListVersions(c *gin.Context) {
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	versions, err := h.versionRepo.ListByFlowID(c.Request.Context(), flow.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// GetVersion handles GET /api/flows/:id/versions/:version
+func (h *FlowVersionHandler) GetVersion(c *gin.Context) {
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	version, err := h.versionRepo.GetByVersion(c.Request.Context(), flow.ID, number)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// DiffVersions handles GET /api/flows/:id/diff?from=&to=.
+// to defaults to the current version and from to the version before it.
+func (h *FlowVersionHandler) DiffVersions(c *gin.Context) {
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	to := flow.Version
+	if s := c.Query("to"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a version number"})
+			return
+		}
+		to = n
+	}
+
+	from := to - 1
+	if s := c.Query("from"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a version number"})
+			return
+		}
+		from = n
+	}
+
+	ctx := c.Request.Context()
+
+	fromVersion, err := h.versionRepo.GetByVersion(ctx, flow.ID, from)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Version %d not found", from)})
+		return
+	}
+	toVersion, err := h.versionRepo.GetByVersion(ctx, flow.ID, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Version %d not found", to)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": from,
+		"to":   to,
+		"diff": flowdiff.Compare(fromVersion.AsFlow(), toVersion.AsFlow()),
+	})
+}
+
+// RestoreVersion handles POST /api/flows/:id/versions/:version/restore.
+// Restoring never rewrites history: the old definition is saved as a new version.
+func (h *FlowVersionHandler) RestoreVersion(c *gin.Context) {
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	version, err := h.versionRepo.GetByVersion(ctx, flow.ID, number)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+
+	before := *flow
+
+	flow.Name = version.Name
+	flow.Description = version.Description
+	flow.Tags = version.Tags
+	flow.Nodes = version.Nodes
+	flow.Edges = version.Edges
+	flow.Variables = version.Variables
+	flow.UpdatedAt = time.Now()
+
+	message := fmt.Sprintf("Restored from version %d", number)
+	if err := h.flowRepo.Update(ctx, flow, c.MustGet("user_id").(uuid.UUID), message); err != nil {
+		if !respondVersionConflict(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	setFlowETag(c, flow)
+
+	metadata := flowdiff.Compare(&before, flow).Summary()
+	metadata["restored_from"] = number
+	metadata["version"] = flow.Version
+	recordAudit(c, h.auditRepo, models.AuditActionFlowRestore, models.AuditResourceFlow, flow.ID.String(), metadata)
+
+	c.JSON(http.StatusOK, flow)
+}
+
+// loadOwnedFlow loads the flow from the :id param and checks it belongs to the
+// authenticated user, writing an error response if not
+func loadOwnedFlow(c *gin.Context, flowRepo *repository.FlowRepository) (*models.Flow, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flow ID"})
+		return nil, false
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	flow, err := flowRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flow not found"})
+		return nil, false
+	}
+
+	if flow.UserID != userID.(uuid.UUID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to access this flow"})
+		return nil, false
+	}
+
+	return flow, true
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/exporter"
+	"github.com/visual-api-testing-platform/server/internal/importer"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/openapi"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// ImportHandler generates flows from API descriptions and captured requests
+type ImportHandler struct {
+	flowRepo  *repository.FlowRepository
+	specRepo  *repository.APISpecRepository
+	userRepo  *repository.UserRepository
+	auditRepo *repository.AuditRepository
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(
+	flowRepo *repository.FlowRepository,
+	specRepo *repository.APISpecRepository,
+	userRepo *repository.UserRepository,
+	auditRepo *repository.AuditRepository,
+) *ImportHandler {
+	return &ImportHandler{
+		flowRepo:  flowRepo,
+		specRepo:  specRepo,
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+	}
+}
+
+// ImportFlow handles POST /api/flows/import.
+// The body is a flow document from GET /api/flows/:id/export, as JSON or YAML.
+// Embedded specs are bound to the library spec of the same name, which is
+// created if there is none; creating specs needs an editor. A library spec
+// whose document differs from the embedded one is a 409 conflict.
+func (h *ImportHandler) ImportFlow(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doc, err := exporter.Decode(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	specIDs, ok := h.librarySpecs(c, doc.Specs)
+	if !ok {
+		return
+	}
+
+	flow, err := importer.FromDocument(doc, specIDs)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if name := c.Query("name"); name != "" {
+		flow.Name = name
+	}
+
+	if !h.createFlows(c, []*models.Flow{flow}, "document", "Imported from a flow document") {
+		return
+	}
+	setFlowETag(c, flow)
+
+	c.JSON(http.StatusCreated, flow)
+}
+
+// librarySpecs finds or creates the library spec for each embedded spec,
+// writing an error response if that fails
+func (h *ImportHandler) librarySpecs(c *gin.Context, specs map[string]exporter.Spec) (map[string]uuid.UUID, bool) {
+	ids := make(map[string]uuid.UUID, len(specs))
+	if len(specs) == 0 {
+		return ids, true
+	}
+
+	library, err := h.specRepo.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	for _, listed := range library {
+		embedded, ok := specs[listed.Name]
+		if !ok {
+			continue
+		}
+		// Binding to a different document would validate against the wrong contract
+		spec, err := h.specRepo.GetByID(c.Request.Context(), listed.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		if !reflect.DeepEqual(spec.Document, embedded.Document) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": fmt.Sprintf("The library spec %s differs from the one in the document; rename the spec in the document or update the library spec", spec.Name),
+			})
+			return nil, false
+		}
+		ids[spec.Name] = spec.ID
+	}
+
+	for name, embedded := range specs {
+		if _, ok := ids[name]; ok {
+			continue
+		}
+		userID, ok := requireEditor(c, h.userRepo, "spec")
+		if !ok {
+			return nil, false
+		}
+		doc, err := openapi.Parse(embedded.Document)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid spec %s: %v", name, err)})
+			return nil, false
+		}
+
+		now := time.Now()
+		spec := &models.APISpec{
+			ID:          uuid.New(),
+			Name:        name,
+			Description: embedded.Description,
+			Title:       doc.Title,
+			APIVersion:  doc.Version,
+			Document:    embedded.Document,
+			CreatedBy:   &userID,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := h.specRepo.Create(c.Request.Context(), spec); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		recordAudit(c, h.auditRepo, models.AuditActionSpecCreate, models.AuditResourceSpec, spec.ID.String(), map[string]interface{}{
+			"name":        spec.Name,
+			"api_version": spec.APIVersion,
+			"source":      "document",
+		})
+		ids[name] = spec.ID
+	}
+	return ids, true
+}
+
+// ImportOpenAPI handles POST /api/import/openapi.
+// The document is given as spec (an object, or JSON or YAML text) or as the
+// spec_id of a library spec, in which case the API nodes are bound to it.
+func (h *ImportHandler) ImportOpenAPI(c *gin.Context) {
+	var req struct {
+		Spec    interface{} `json:"spec"`
+		SpecID  string      `json:"spec_id"`
+		Name    string      `json:"name"`
+		GroupBy string      `json:"group_by"`
+		BaseURL string      `json:"base_url"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.GroupBy != "" && req.GroupBy != "tag" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be empty or tag"})
+		return
+	}
+
+	opts := importer.OpenAPIOptions{
+		Name:       req.Name,
+		GroupByTag: req.GroupBy == "tag",
+		BaseURL:    req.BaseURL,
+	}
+
+	var doc *openapi.Document
+	switch {
+	case req.SpecID != "":
+		id, err := uuid.Parse(req.SpecID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid spec ID"})
+			return
+		}
+		spec, err := h.specRepo.GetByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+			return
+		}
+		if doc, err = openapi.Parse(spec.Document); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid spec: " + err.Error()})
+			return
+		}
+		opts.SpecID = &spec.ID
+	case req.Spec != nil:
+		var ok bool
+		if _, doc, ok = parseSpecDocument(c, req.Spec); !ok {
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "spec or spec_id is required"})
+		return
+	}
+
+	flows, skipped := importer.FromOpenAPI(doc, opts)
+	h.respondImported(c, flows, skipped, "openapi", "Imported from OpenAPI")
+}
+
+// ImportPostman handles POST /api/import/postman.
+// The collection, and the optional environment whose values override the
+// collection variables, are given as objects or as JSON text.
+func (h *ImportHandler) ImportPostman(c *gin.Context) {
+	var req struct {
+		Collection  json.RawMessage `json:"collection" binding:"required"`
+		Environment json.RawMessage `json:"environment"`
+		Name        string          `json:"name"`
+		GroupBy     string          `json:"group_by"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.GroupBy != "" && req.GroupBy != "folder" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be empty or folder"})
+		return
+	}
+
+	flows, skipped, err := importer.FromPostman(importDocument(req.Collection), importDocument(req.Environment), importer.PostmanOptions{
+		Name:          req.Name,
+		GroupByFolder: req.GroupBy == "folder",
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respondImported(c, flows, skipped, "postman", "Imported from Postman")
+}
+
+// ImportHAR handles POST /api/import/har.
+// The capture is given as an object or as JSON text.
+func (h *ImportHandler) ImportHAR(c *gin.Context) {
+	var req struct {
+		HAR           json.RawMessage `json:"har" binding:"required"`
+		Name          string          `json:"name"`
+		IncludeStatic bool            `json:"include_static"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flow, skipped, err := importer.FromHAR(importDocument(req.HAR), importer.HAROptions{
+		Name:          req.Name,
+		IncludeStatic: req.IncludeStatic,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respondImported(c, []*models.Flow{flow}, skipped, "har", "Imported from a HAR capture")
+}
+
+// ImportCurl handles POST /api/import/curl
+func (h *ImportHandler) ImportCurl(c *gin.Context) {
+	var req struct {
+		Command string `json:"command" binding:"required"`
+		Name    string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flow, skipped, err := importer.FromCurl(req.Command, req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respondImported(c, []*models.Flow{flow}, skipped, "curl", "Imported from curl")
+}
+
+// respondImported stores imported flows and responds with them and what was
+// skipped, or with 422 when nothing could be imported
+func (h *ImportHandler) respondImported(c *gin.Context, flows []*models.Flow, skipped []string, source, message string) {
+	if skipped == nil {
+		skipped = []string{}
+	}
+
+	var importable []*models.Flow
+	for _, flow := range flows {
+		if len(flow.Nodes) > 0 {
+			importable = append(importable, flow)
+		}
+	}
+	if len(importable) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Nothing to import", "skipped": skipped})
+		return
+	}
+
+	if !h.createFlows(c, importable, source, message) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"flows": importable, "skipped": skipped})
+}
+
+// importDocument returns the JSON document in raw, unwrapping it when it was
+// sent as a string
+func importDocument(raw json.RawMessage) []byte {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []byte(text)
+	}
+	return raw
+}
+
+// createFlows stores generated flows for the authenticated user, writing an
+// error response if that fails
+func (h *ImportHandler) createFlows(c *gin.Context, flows []*models.Flow, source, message string) bool {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return false
+	}
+
+	now := time.Now()
+	for _, flow := range flows {
+		flow.ID = uuid.New()
+		flow.UserID = userID.(uuid.UUID)
+		flow.CreatedAt = now
+		flow.UpdatedAt = now
+	}
+
+	// All or nothing, so a failed import can simply be retried
+	if err := h.flowRepo.CreateAll(c.Request.Context(), flows, userID.(uuid.UUID), message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	for _, flow := range flows {
+		recordAudit(c, h.auditRepo, models.AuditActionFlowCreate, models.AuditResourceFlow, flow.ID.String(), map[string]interface{}{
+			"name":   flow.Name,
+			"nodes":  len(flow.Nodes),
+			"edges":  len(flow.Edges),
+			"source": source,
+		})
+	}
+	return true
+}
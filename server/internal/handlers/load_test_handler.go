@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/engine"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// runningLoadRun tracks an in-flight load test so it can be cancelled and its
+// live metrics read
+type runningLoadRun struct {
+	cancel  context.CancelFunc
+	ownerID uuid.UUID
+	flowID  uuid.UUID
+	metrics *models.LoadMetrics
+}
+
+// LoadTestHandler handles load test HTTP requests
+type LoadTestHandler struct {
+	loadRunRepo    *repository.LoadRunRepository
+	flowRepo       *repository.FlowRepository
+	quarantineRepo *repository.QuarantineRepository
+	auditRepo      *repository.AuditRepository
+	flowRunner     *engine.FlowRunner
+
+	mu      sync.Mutex
+	running map[uuid.UUID]*runningLoadRun
+}
+
+// NewLoadTestHandler creates a new load test handler
+func NewLoadTestHandler(
+	loadRunRepo *repository.LoadRunRepository,
+	flowRepo *repository.FlowRepository,
+	quarantineRepo *repository.QuarantineRepository,
+	auditRepo *repository.AuditRepository,
+	flowRunner *engine.FlowRunner,
+) *LoadTestHandler {
+	return &LoadTestHandler{
+		loadRunRepo:    loadRunRepo,
+		flowRepo:       flowRepo,
+		quarantineRepo: quarantineRepo,
+		auditRepo:      auditRepo,
+		flowRunner:     flowRunner,
+		running:        make(map[uuid.UUID]*runningLoadRun),
+	}
+}
+
+// StartLoadTest handles POST /api/flows/:id/load.
+// The load test runs in the background; its progress is streamed over
+// /api/ws?loadRunId= and its result read from /api/load-runs/:id.
+func (h *LoadTestHandler) StartLoadTest(c *gin.Context) {
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		models.LoadConfig
+		Environment string                 `json:"environment"`
+		Tags        []string               `json:"tags"`
+		Variables   map[string]interface{} `json:"variables"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := engine.ValidateLoadConfig(flow, req.LoadConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	quarantined, err := h.quarantineRepo.NodeIDs(ctx, flow.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags := req.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	loadRun := &models.LoadRun{
+		ID:          uuid.New(),
+		FlowID:      flow.ID,
+		FlowName:    flow.Name,
+		FlowVersion: flow.Version,
+		Status:      models.ExecutionStatusRunning,
+		Config:      req.LoadConfig,
+		Thresholds:  []models.ThresholdResult{},
+		Environment: req.Environment,
+		Tags:        tags,
+		StartedAt:   time.Now(),
+	}
+
+	// One load test per flow at a time, so they don't skew each other
+	runCtx, cancel := context.WithCancel(context.Background())
+	h.mu.Lock()
+	for _, run := range h.running {
+		if run.flowID == flow.ID {
+			h.mu.Unlock()
+			cancel()
+			c.JSON(http.StatusConflict, gin.H{"error": "A load test of this flow is already running"})
+			return
+		}
+	}
+	run := &runningLoadRun{cancel: cancel, ownerID: flow.UserID, flowID: flow.ID}
+	h.running[loadRun.ID] = run
+	h.mu.Unlock()
+
+	if err := h.loadRunRepo.Create(ctx, loadRun); err != nil {
+		h.mu.Lock()
+		delete(h.running, loadRun.ID)
+		h.mu.Unlock()
+		cancel()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := engine.LoadOptions{
+		LoadRunID:        loadRun.ID,
+		Config:           req.LoadConfig,
+		Environment:      req.Environment,
+		Tags:             tags,
+		QuarantinedNodes: quarantined,
+		Variables:        req.Variables,
+		Progress: func(metrics *models.LoadMetrics) {
+			h.mu.Lock()
+			run.metrics = metrics
+			h.mu.Unlock()
+		},
+	}
+
+	// Execute the load test in a goroutine
+	go func() {
+		defer func() {
+			h.mu.Lock()
+			delete(h.running, loadRun.ID)
+			h.mu.Unlock()
+			cancel()
+		}()
+
+		result, err := h.flowRunner.ExecuteLoad(runCtx, flow, opts)
+		if err != nil && result.Status != models.ExecutionStatusCancelled {
+			log.Printf("Load test %s of flow %s failed: %v", loadRun.ID, flow.ID, err)
+		}
+
+		// The run context may already be cancelled, so use a fresh one
+		if err := h.loadRunRepo.Update(context.Background(), result); err != nil {
+			log.Printf("Failed to save load run %s: %v", loadRun.ID, err)
+		}
+	}()
+
+	recordAudit(c, h.auditRepo, models.AuditActionLoadRunStart, models.AuditResourceLoadRun, loadRun.ID.String(), map[string]interface{}{
+		"flow_id":   flow.ID.String(),
+		"flow_name": flow.Name,
+		"vus":       req.VUs,
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "Load test started",
+		"flow_id":     flow.ID,
+		"load_run_id": loadRun.ID,
+	})
+}
+
+// ListLoadRuns handles GET /api/flows/:id/load-runs?limit=
+func (h *LoadTestHandler) ListLoadRuns(c *gin.Context) {
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	limit := 20
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+
+	loadRuns, err := h.loadRunRepo.GetByFlowID(c.Request.Context(), flow.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i := range loadRuns {
+		loadRuns[i].FlowName = flow.Name
+		h.overlayLiveMetrics(&loadRuns[i])
+	}
+
+	c.JSON(http.StatusOK, loadRuns)
+}
+
+// GetLoadRun handles GET /api/load-runs/:id.
+// While the load test runs, its metrics are the latest live ones.
+func (h *LoadTestHandler) GetLoadRun(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid load run ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	loadRun, err := h.loadRunRepo.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Load run not found"})
+		return
+	}
+
+	flow, err := h.flowRepo.GetByID(ctx, loadRun.FlowID)
+	if err != nil || flow.UserID != userID.(uuid.UUID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Load run not found"})
+		return
+	}
+	loadRun.FlowName = flow.Name
+	h.overlayLiveMetrics(loadRun)
+
+	c.JSON(http.StatusOK, loadRun)
+}
+
+// CancelLoadRun handles POST /api/load-runs/:id/cancel
+func (h *LoadTestHandler) CancelLoadRun(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid load run ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	h.mu.Lock()
+	run, ok := h.running[id]
+	h.mu.Unlock()
+
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "Load run is not running"})
+		return
+	}
+
+	if run.ownerID != userID.(uuid.UUID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to cancel this load run"})
+		return
+	}
+
+	run.cancel()
+
+	recordAudit(c, h.auditRepo, models.AuditActionLoadRunCancel, models.AuditResourceLoadRun, id.String(), nil)
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Cancellation requested"})
+}
+
+// overlayLiveMetrics replaces the stored metrics of a running load test with
+// its latest live ones
+func (h *LoadTestHandler) overlayLiveMetrics(loadRun *models.LoadRun) {
+	if loadRun.Status != models.ExecutionStatusRunning {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if run, ok := h.running[loadRun.ID]; ok && run.metrics != nil {
+		loadRun.Metrics = run.metrics
+	}
+}
@@ -15,13 +15,16 @@ import (
 // NodeHandler handles node-related HTTP requests
 type NodeHandler struct {
 	flowRepo   *repository.FlowRepository
+	specRepo    *repository.APISpecRepository
 	nodeFactory *node.NodeFactory
 }
 
-// NewNodeHandler creates a new node handler
-func NewNodeHandler(flowRepo *repository.FlowRepository) *NodeHandler {
+// NewNodeHandler creates a new node handler. specRepo lets API nodes validate
+// their contract when executed on their own.
+func NewNodeHandler(flowRepo *repository.FlowRepository, specRepo *repository.APISpecRepository) *NodeHandler {
 	return &NodeHandler{
 		flowRepo:    flowRepo,
+		specRepo:    specRepo,
 		nodeFactory: node.NewNodeFactory(),
 	}
 }
@@ -114,19 +117,30 @@ func (h *NodeHandler) ExecuteNode(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
+	assertions := &node.Assertions{}
+	ctx = node.WithExecution(ctx, node.Execution{
+		Run:        node.RunInfo{FlowID: flow.ID, FlowName: flow.Name, FlowVersion: flow.Version, StartedAt: time.Now()},
+		NodeID:     targetNode.ID,
+		Specs:      h.specRepo,
+		Assertions: assertions,
+		Variables:  flow.Variables,
+	})
+
 	output, err := apiNode.Execute(ctx, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":  err.Error(),
-			"output": nil,
+			"error":      err.Error(),
+			"output":     output,
+			"assertions": assertions.Results(),
 		})
 		return
 	}
 
 	// Return the output
 	c.JSON(http.StatusOK, gin.H{
-		"output": output,
-		"status": "success",
+		"output":     output,
+		"status":     "success",
+		"assertions": assertions.Results(),
 	})
 }
 
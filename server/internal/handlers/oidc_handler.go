@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/visual-api-testing-platform/server/internal/auth"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcLoginTTL    = 10 * time.Minute
+)
+
+// pendingLogin holds the PKCE verifier and nonce of a login in progress
+type pendingLogin struct {
+	codeVerifier string
+	nonce        string
+	expiresAt    time.Time
+}
+
+// rejectedIdentity is why a verified identity can't sign in. Its reason is
+// safe to show to the user, unlike other provisioning errors.
+type rejectedIdentity struct {
+	reason string
+}
+
+func (e *rejectedIdentity) Error() string {
+	return e.reason
+}
+
+// OIDCHandler handles single sign-on through an OpenID Connect provider
+type OIDCHandler struct {
+	provider      *auth.OIDCProvider
+	userRepo      oidcUserStore
+	authHandler   *AuthHandler
+	postLoginURL  string
+	secureCookies bool
+
+	mu      sync.Mutex
+	pending map[string]pendingLogin
+}
+
+// NewOIDCHandler creates a new OIDC handler.
+// If postLoginURL is set, the callback redirects there with the token in the URL fragment
+// instead of returning JSON.
+func NewOIDCHandler(
+	provider *auth.OIDCProvider,
+	userRepo *repository.UserRepository,
+	authHandler *AuthHandler,
+	postLoginURL string,
+) *OIDCHandler {
+	return &OIDCHandler{
+		provider:      provider,
+		userRepo:      userRepo,
+		authHandler:   authHandler,
+		postLoginURL:  postLoginURL,
+		secureCookies: strings.HasPrefix(postLoginURL, "https://"),
+		pending:       make(map[string]pendingLogin),
+	}
+}
+
+// Login handles GET /api/auth/oidc/login
+func (h *OIDCHandler) Login(c *gin.Context) {
+	state, err := auth.RandomToken(24)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	nonce, err := auth.RandomToken(24)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	verifier, challenge, err := auth.NewPKCE()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	authURL, err := h.provider.AuthCodeURL(c.Request.Context(), state, nonce, challenge)
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	h.mu.Lock()
+	now := time.Now()
+	for s, p := range h.pending {
+		if now.After(p.expiresAt) {
+			delete(h.pending, s)
+		}
+	}
+	h.pending[state] = pendingLogin{
+		codeVerifier: verifier,
+		nonce:        nonce,
+		expiresAt:    now.Add(oidcLoginTTL),
+	}
+	h.mu.Unlock()
+
+	// Bind the state to this browser to prevent login CSRF
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(oidcLoginTTL.Seconds()), "/api/auth/oidc", "", h.secureCookies, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback handles GET /api/auth/oidc/callback
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Identity provider error: " + errCode})
+		return
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "state and code query parameters required"})
+		return
+	}
+
+	cookieState, err := c.Cookie(oidcStateCookie)
+	if err != nil || cookieState != state {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid login state"})
+		return
+	}
+	c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc", "", h.secureCookies, true)
+
+	h.mu.Lock()
+	login, ok := h.pending[state]
+	delete(h.pending, state)
+	h.mu.Unlock()
+
+	if !ok || time.Now().After(login.expiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login expired, please try again"})
+		return
+	}
+
+	// Exchange and provisioning errors can reveal IdP and database details,
+	// so they are logged and audited rather than returned
+	identity, err := h.provider.Exchange(c.Request.Context(), code, login.codeVerifier, login.nonce)
+	if err != nil {
+		log.Printf("OIDC token exchange failed: %v", err)
+		h.recordFailure(c, "", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign-in with the identity provider failed"})
+		return
+	}
+
+	user, err := h.provisionUser(c, identity)
+	if err != nil {
+		h.recordFailure(c, identity.Email, err)
+		var rejected *rejectedIdentity
+		if errors.As(err, &rejected) {
+			c.JSON(http.StatusForbidden, gin.H{"error": rejected.reason})
+			return
+		}
+		log.Printf("OIDC provisioning of %s failed: %v", identity.Subject, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	writeAudit(c, h.authHandler.auditRepo, &models.AuditEvent{
+		ActorID:      &user.ID,
+		ActorEmail:   user.Email,
+		Action:       models.AuditActionLogin,
+		ResourceType: models.AuditResourceUser,
+		ResourceID:   user.ID.String(),
+		Metadata:     map[string]interface{}{"method": "oidc", "issuer": identity.Issuer},
+	})
+
+	token, err := h.authHandler.issueToken(c, user, "oidc")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	if h.postLoginURL != "" {
+		c.Redirect(http.StatusFound, h.postLoginURL+"#token="+url.QueryEscape(token))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Token: token,
+		User:  *user,
+	})
+}
+
+// provisionUser finds the user linked to the identity, links an existing account by email,
+// or creates a new account. The role is refreshed from the IdP groups on every login.
+// Identities that can't sign in are reported as *rejectedIdentity.
+func (h *OIDCHandler) provisionUser(c *gin.Context, identity *auth.Identity) (*models.User, error) {
+	ctx := c.Request.Context()
+	role := h.provider.RoleForGroups(identity.Groups)
+
+	user, err := h.userRepo.GetByOIDCIdentity(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		if identity.Email == "" {
+			return nil, &rejectedIdentity{"identity provider did not return an email address"}
+		}
+		// Linking by email is only safe when the IdP vouches for the address
+		if !identity.EmailVerified {
+			return nil, &rejectedIdentity{"email address is not verified by the identity provider"}
+		}
+
+		user, err = h.userRepo.GetByEmail(ctx, identity.Email)
+		switch {
+		case err == nil:
+			if err := h.userRepo.LinkOIDCIdentity(ctx, user.ID, identity.Issuer, identity.Subject); err != nil {
+				return nil, err
+			}
+		case !errors.Is(err, pgx.ErrNoRows):
+			return nil, err
+		default:
+			name := identity.Name
+			if name == "" {
+				name = identity.Email
+			}
+			user = &models.User{
+				ID:    uuid.New(),
+				Email: identity.Email,
+				Name:  name,
+				Role:  role,
+			}
+			if err := h.userRepo.CreateExternal(ctx, user, identity.Issuer, identity.Subject); err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+	}
+
+	if role != "" && role != user.Role {
+		if err := h.userRepo.UpdateRole(ctx, user.ID, role); err != nil {
+			return nil, err
+		}
+		user.Role = role
+	}
+
+	return user, nil
+}
+
+// recordFailure writes a failed SSO login to the audit log
+func (h *OIDCHandler) recordFailure(c *gin.Context, email string, err error) {
+	writeAudit(c, h.authHandler.auditRepo, &models.AuditEvent{
+		ActorEmail:   email,
+		Action:       models.AuditActionLoginFailed,
+		ResourceType: models.AuditResourceUser,
+		Metadata:     map[string]interface{}{"method": "oidc", "reason": err.Error()},
+	})
+}
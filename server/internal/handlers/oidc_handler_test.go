@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/auth"
+	"github.com/visual-api-testing-platform/server/internal/auth/oidctest"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type oidcTest struct {
+	idp    *oidctest.Server
+	users  *fakeUserStore
+	audit  *fakeAuditStore
+	router *gin.Engine
+}
+
+func newOIDCTest(t *testing.T, users *fakeUserStore) *oidcTest {
+	t.Helper()
+	idp := oidctest.NewServer("visual-api-testing")
+	t.Cleanup(idp.Close)
+
+	provider := auth.NewOIDCProvider(auth.OIDCConfig{
+		Issuer:      idp.Issuer(),
+		ClientID:    idp.ClientID,
+		RedirectURL: "http://localhost:8080/api/auth/oidc/callback",
+		GroupRoles:  map[string]string{"qa-admins": "admin", "qa": "editor"},
+		DefaultRole: "viewer",
+	})
+
+	audit := &fakeAuditStore{}
+	h := &OIDCHandler{
+		provider:    provider,
+		userRepo:    users,
+		authHandler: &AuthHandler{auditRepo: audit, jwtSecret: "test-secret"},
+		pending:     make(map[string]pendingLogin),
+	}
+
+	router := gin.New()
+	router.GET("/api/auth/oidc/login", h.Login)
+	router.GET("/api/auth/oidc/callback", h.Callback)
+
+	return &oidcTest{idp: idp, users: users, audit: audit, router: router}
+}
+
+// signIn goes through Login, the IdP and Callback as a browser would
+func (o *oidcTest) signIn(t *testing.T, claims jwt.MapClaims) *httptest.ResponseRecorder {
+	t.Helper()
+
+	login := httptest.NewRecorder()
+	o.router.ServeHTTP(login, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	if login.Code != http.StatusFound {
+		t.Fatalf("login returned %d: %s", login.Code, login.Body)
+	}
+
+	authURL := login.Header().Get("Location")
+	code, err := o.idp.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := url.Parse(authURL)
+
+	callback := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?"+url.Values{
+		"state": {parsed.Query().Get("state")},
+		"code":  {code},
+	}.Encode(), nil)
+	for _, cookie := range login.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	o.router.ServeHTTP(w, callback)
+	return w
+}
+
+func signedInUser(t *testing.T, w *httptest.ResponseRecorder) models.User {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback returned %d: %s", w.Code, w.Body)
+	}
+	var resp models.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Token == "" {
+		t.Error("callback returned no token")
+	}
+	return resp.User
+}
+
+func TestOIDCCallbackProvisionsNewUser(t *testing.T) {
+	o := newOIDCTest(t, newFakeUserStore())
+
+	user := signedInUser(t, o.signIn(t, jwt.MapClaims{
+		"sub":            "alice",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice",
+		"groups":         []string{"qa-admins"},
+	}))
+
+	if user.Email != "alice@example.com" || user.Name != "Alice" || user.Role != "admin" {
+		t.Errorf("provisioned %+v", user)
+	}
+	if id, ok := o.users.linkedUser(o.idp.Issuer(), "alice"); !ok || id != user.ID {
+		t.Errorf("identity linked to %v, want %v", id, user.ID)
+	}
+
+	// The next login finds the same user through the identity
+	again := signedInUser(t, o.signIn(t, jwt.MapClaims{"sub": "alice", "email": "alice@example.com"}))
+	if again.ID != user.ID || o.users.count() != 1 {
+		t.Errorf("second login signed in %v with %d users, want %v with 1", again.ID, o.users.count(), user.ID)
+	}
+	if again.Role != "viewer" {
+		t.Errorf("role = %s, want viewer refreshed from the groups", again.Role)
+	}
+}
+
+func TestOIDCCallbackLinksVerifiedEmail(t *testing.T) {
+	existing := &models.User{ID: uuid.New(), Email: "bob@example.com", Name: "Bob", Role: "editor", PasswordHash: "hash"}
+	o := newOIDCTest(t, newFakeUserStore(existing))
+
+	user := signedInUser(t, o.signIn(t, jwt.MapClaims{
+		"sub":            "bob-at-idp",
+		"email":          "Bob@example.com",
+		"email_verified": true,
+		"groups":         []string{"qa"},
+	}))
+
+	if user.ID != existing.ID {
+		t.Errorf("signed in %v, want the existing account %v", user.ID, existing.ID)
+	}
+	if id, ok := o.users.linkedUser(o.idp.Issuer(), "bob-at-idp"); !ok || id != existing.ID {
+		t.Error("identity wasn't linked to the existing account")
+	}
+	if o.users.count() != 1 {
+		t.Errorf("%d users, want 1", o.users.count())
+	}
+}
+
+func TestOIDCCallbackRejectsUnverifiedEmail(t *testing.T) {
+	existing := &models.User{ID: uuid.New(), Email: "carol@example.com", Name: "Carol", Role: "admin", PasswordHash: "hash"}
+
+	for name, verified := range map[string]interface{}{"omitted": nil, "false": false} {
+		t.Run(name, func(t *testing.T) {
+			o := newOIDCTest(t, newFakeUserStore(existing))
+
+			w := o.signIn(t, jwt.MapClaims{"sub": "attacker", "email": "carol@example.com", "email_verified": verified})
+
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("callback returned %d, want 403: %s", w.Code, w.Body)
+			}
+			if _, ok := o.users.linkedUser(o.idp.Issuer(), "attacker"); ok {
+				t.Error("unverified identity was linked")
+			}
+			if o.users.count() != 1 {
+				t.Errorf("%d users, want 1", o.users.count())
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackDatabaseErrors(t *testing.T) {
+	existing := &models.User{ID: uuid.New(), Email: "dave@example.com", Name: "Dave", Role: "editor"}
+	dbErr := errors.New("connection reset by peer")
+
+	tests := []struct {
+		name  string
+		setup func(users *fakeUserStore)
+	}{
+		{name: "identity lookup", setup: func(users *fakeUserStore) { users.errGetByOIDCIdentity = dbErr }},
+		{name: "email lookup", setup: func(users *fakeUserStore) { users.errGetByEmail = dbErr }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newFakeUserStore(existing)
+			tt.setup(users)
+			o := newOIDCTest(t, users)
+
+			w := o.signIn(t, jwt.MapClaims{"sub": "dave", "email": "dave@example.com", "email_verified": true})
+
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("callback returned %d, want 500: %s", w.Code, w.Body)
+			}
+			if strings.Contains(w.Body.String(), dbErr.Error()) {
+				t.Errorf("response leaks the database error: %s", w.Body)
+			}
+			if users.count() != 1 {
+				t.Errorf("%d users, want no new account", users.count())
+			}
+			if _, ok := users.linkedUser(o.idp.Issuer(), "dave"); ok {
+				t.Error("identity was linked despite the error")
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackHidesExchangeErrors(t *testing.T) {
+	o := newOIDCTest(t, newFakeUserStore())
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.idp.SigningKey = otherKey
+
+	w := o.signIn(t, jwt.MapClaims{"sub": "eve", "email": "eve@example.com", "email_verified": true})
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("callback returned %d, want 401: %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "signature") {
+		t.Errorf("response leaks verification details: %s", w.Body)
+	}
+	if actions := o.audit.actions(); len(actions) != 1 || actions[0] != models.AuditActionLoginFailed {
+		t.Errorf("audit actions = %v, want a failed login", actions)
+	}
+}
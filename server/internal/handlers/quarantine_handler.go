@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// QuarantineHandler handles node quarantine HTTP requests
+type QuarantineHandler struct {
+	quarantineRepo *repository.QuarantineRepository
+	flowRepo       *repository.FlowRepository
+	auditRepo      *repository.AuditRepository
+}
+
+// NewQuarantineHandler creates a new quarantine handler
+func NewQuarantineHandler(
+	quarantineRepo *repository.QuarantineRepository,
+	flowRepo *repository.FlowRepository,
+	auditRepo *repository.AuditRepository,
+) *QuarantineHandler {
+	return &QuarantineHandler{
+		quarantineRepo: quarantineRepo,
+		flowRepo:       flowRepo,
+		auditRepo:      auditRepo,
+	}
+}
+
+// QuarantineNode handles PUT /api/flows/:id/nodes/:nodeId/quarantine
+func (h *QuarantineHandler) QuarantineNode(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	nodeID := c.Param("nodeId")
+	if findNode(flow, nodeID) < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	quarantine := &models.NodeQuarantine{
+		FlowID:        flow.ID,
+		NodeID:        nodeID,
+		Reason:        req.Reason,
+		QuarantinedBy: &userID,
+	}
+
+	if err := h.quarantineRepo.Set(c.Request.Context(), quarantine); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, models.AuditActionNodeQuarantine, models.AuditResourceFlow, flow.ID.String(), map[string]interface{}{
+		"node_id": nodeID,
+		"reason":  req.Reason,
+	})
+
+	c.JSON(http.StatusOK, quarantine)
+}
+
+// ReleaseNode handles DELETE /api/flows/:id/nodes/:nodeId/quarantine
+func (h *QuarantineHandler) ReleaseNode(c *gin.Context) {
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	nodeID := c.Param("nodeId")
+	released, err := h.quarantineRepo.Delete(c.Request.Context(), flow.ID, nodeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !released {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node is not quarantined"})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, models.AuditActionNodeRelease, models.AuditResourceFlow, flow.ID.String(), map[string]interface{}{
+		"node_id": nodeID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Node released from quarantine"})
+}
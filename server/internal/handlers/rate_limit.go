@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/ratelimit"
+)
+
+// RateLimitMiddleware throttles requests per authenticated user, falling back to
+// the client IP for anonymous requests
+func RateLimitMiddleware(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID, exists := c.Get("user_id"); exists {
+			key = "user:" + userID.(uuid.UUID).String()
+		}
+
+		if ok, retryAfter := limiter.Allow(key); !ok {
+			abortTooManyRequests(c, retryAfter)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// abortTooManyRequests responds with 429 and a Retry-After header in whole seconds
+func abortTooManyRequests(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many requests",
+		"retry_after": seconds,
+	})
+	c.Abort()
+}
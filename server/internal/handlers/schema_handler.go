@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/jsonschema"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// SchemaHandler handles the shared JSON Schema library.
+// Every user can read the library; viewers cannot change it.
+type SchemaHandler struct {
+	schemaRepo *repository.SchemaRepository
+	userRepo   *repository.UserRepository
+	auditRepo  *repository.AuditRepository
+}
+
+// NewSchemaHandler creates a new schema handler
+func NewSchemaHandler(
+	schemaRepo *repository.SchemaRepository,
+	userRepo *repository.UserRepository,
+	auditRepo *repository.AuditRepository,
+) *SchemaHandler {
+	return &SchemaHandler{
+		schemaRepo: schemaRepo,
+		userRepo:   userRepo,
+		auditRepo:  auditRepo,
+	}
+}
+
+type schemaRequest struct {
+	Name        string      `json:"name" binding:"required"`
+	Description string      `json:"description"`
+	Schema      interface{} `json:"schema" binding:"required"`
+}
+
+// ListSchemas handles GET /api/schemas
+func (h *SchemaHandler) ListSchemas(c *gin.Context) {
+	schemas, err := h.schemaRepo.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas)
+}
+
+// GetSchema handles GET /api/schemas/:id
+func (h *SchemaHandler) GetSchema(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema ID"})
+		return
+	}
+
+	schema, err := h.schemaRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schema)
+}
+
+// CreateSchema handles POST /api/schemas
+func (h *SchemaHandler) CreateSchema(c *gin.Context) {
+	userID, ok := requireEditor(c, h.userRepo, "schema")
+	if !ok {
+		return
+	}
+
+	var req schemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := jsonschema.Compile(req.Schema); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema: " + err.Error()})
+		return
+	}
+
+	now := time.Now()
+	schema := &models.Schema{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Description: req.Description,
+		Schema:      req.Schema,
+		CreatedBy:   &userID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := h.schemaRepo.Create(c.Request.Context(), schema); err != nil {
+		if errors.Is(err, repository.ErrSchemaNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, models.AuditActionSchemaCreate, models.AuditResourceSchema, schema.ID.String(), map[string]interface{}{
+		"name": schema.Name,
+	})
+
+	c.JSON(http.StatusCreated, schema)
+}
+
+// UpdateSchema handles PUT /api/schemas/:id
+func (h *SchemaHandler) UpdateSchema(c *gin.Context) {
+	if _, ok := requireEditor(c, h.userRepo, "schema"); !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema ID"})
+		return
+	}
+
+	var req schemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := jsonschema.Compile(req.Schema); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	schema, err := h.schemaRepo.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found"})
+		return
+	}
+
+	schema.Name = req.Name
+	schema.Description = req.Description
+	schema.Schema = req.Schema
+
+	if err := h.schemaRepo.Update(ctx, schema); err != nil {
+		if errors.Is(err, repository.ErrSchemaNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, models.AuditActionSchemaUpdate, models.AuditResourceSchema, schema.ID.String(), map[string]interface{}{
+		"name": schema.Name,
+	})
+
+	c.JSON(http.StatusOK, schema)
+}
+
+// DeleteSchema handles DELETE /api/schemas/:id.
+// Verification nodes that still reference the schema fail until they are updated.
+func (h *SchemaHandler) DeleteSchema(c *gin.Context) {
+	if _, ok := requireEditor(c, h.userRepo, "schema"); !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	schema, err := h.schemaRepo.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found"})
+		return
+	}
+
+	if err := h.schemaRepo.Delete(ctx, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, models.AuditActionSchemaDelete, models.AuditResourceSchema, id.String(), map[string]interface{}{
+		"name": schema.Name,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Schema deleted successfully"})
+}
+
+// requireEditor checks that the authenticated user may change a shared
+// library, writing an error response if not
+func requireEditor(c *gin.Context, userRepo *repository.UserRepository, library string) (uuid.UUID, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return uuid.Nil, false
+	}
+
+	user, err := userRepo.GetByID(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return uuid.Nil, false
+	}
+
+	if user.Role == models.RoleViewer {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Viewers cannot change the " + library + " library"})
+		return uuid.Nil, false
+	}
+
+	return user.ID, true
+}
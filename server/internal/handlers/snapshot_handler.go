@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/visual-api-testing-platform/server/internal/engine"
+	"github.com/visual-api-testing-platform/server/internal/jsondiff"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/node"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// SnapshotHandler handles snapshot assertion HTTP requests
+type SnapshotHandler struct {
+	snapshotRepo *repository.SnapshotRepository
+	flowRepo     *repository.FlowRepository
+	testRunRepo  *repository.TestRunRepository
+	auditRepo    *repository.AuditRepository
+}
+
+// NewSnapshotHandler creates a new snapshot handler
+func NewSnapshotHandler(
+	snapshotRepo *repository.SnapshotRepository,
+	flowRepo *repository.FlowRepository,
+	testRunRepo *repository.TestRunRepository,
+	auditRepo *repository.AuditRepository,
+) *SnapshotHandler {
+	return &SnapshotHandler{
+		snapshotRepo: snapshotRepo,
+		flowRepo:     flowRepo,
+		testRunRepo:  testRunRepo,
+		auditRepo:    auditRepo,
+	}
+}
+
+// GetSnapshot handles GET /api/flows/:id/nodes/:nodeId/snapshot
+func (h *SnapshotHandler) GetSnapshot(c *gin.Context) {
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	snapshot, err := h.snapshotRepo.Get(c.Request.Context(), flow.ID, c.Param("nodeId"))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// ApproveSnapshot handles POST /api/flows/:id/nodes/:nodeId/snapshot/approve.
+// The new snapshot is the value the node asserted on in the given test run,
+// rebuilt from the outputs of the nodes it depends on, or an explicit value.
+func (h *SnapshotHandler) ApproveSnapshot(c *gin.Context) {
+	var req struct {
+		TestRunID *uuid.UUID  `json:"test_run_id"`
+		Value     interface{} `json:"value"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.TestRunID == nil) == (req.Value == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either test_run_id or value"})
+		return
+	}
+
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	nodeID := c.Param("nodeId")
+	if findNode(flow, nodeID) < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	snapshot := &models.NodeSnapshot{
+		FlowID: flow.ID,
+		NodeID: nodeID,
+		Value:  jsondiff.Normalize(req.Value),
+	}
+
+	if req.TestRunID != nil {
+		testRun, err := h.testRunRepo.GetByID(ctx, *req.TestRunID)
+		if err != nil || testRun.FlowID != flow.ID {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Test run not found"})
+			return
+		}
+		if testRun.CompactedAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Node outputs of this test run were removed by retention"})
+			return
+		}
+
+		outputs := make(map[string]map[string]interface{}, len(testRun.NodeResults))
+		for id, result := range testRun.NodeResults {
+			if output, ok := result.Output.(map[string]interface{}); ok && result.Status == models.ExecutionStatusSuccess {
+				outputs[id] = output
+			}
+		}
+
+		input, err := engine.NodeInput(flow, nodeID, outputs)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		snapshot.Value = jsondiff.Normalize(node.AssertionSubject(input))
+		snapshot.SourceTestRunID = &testRun.ID
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	snapshot.ApprovedBy = &userID
+
+	if err := h.snapshotRepo.Save(ctx, snapshot); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	metadata := map[string]interface{}{"node_id": nodeID}
+	if snapshot.SourceTestRunID != nil {
+		metadata["test_run_id"] = snapshot.SourceTestRunID.String()
+	}
+	recordAudit(c, h.auditRepo, models.AuditActionSnapshotApprove, models.AuditResourceFlow, flow.ID.String(), metadata)
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// DeleteSnapshot handles DELETE /api/flows/:id/nodes/:nodeId/snapshot.
+// The next run of the node records a new snapshot.
+func (h *SnapshotHandler) DeleteSnapshot(c *gin.Context) {
+	flow, ok := loadOwnedFlow(c, h.flowRepo)
+	if !ok {
+		return
+	}
+
+	nodeID := c.Param("nodeId")
+	deleted, err := h.snapshotRepo.Delete(c.Request.Context(), flow.ID, nodeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, models.AuditActionSnapshotDelete, models.AuditResourceFlow, flow.ID.String(), map[string]interface{}{
+		"node_id": nodeID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Snapshot deleted"})
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// The authentication handlers depend on these instead of the repositories so
+// they can be exercised without a database. The repositories satisfy them.
+
+// auditStore appends audit events
+type auditStore interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+}
+
+// authUserStore finds and creates users signing in with a password.
+// Lookups return pgx.ErrNoRows when nothing matches.
+type authUserStore interface {
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Create(ctx context.Context, user *models.User, password string) error
+	VerifyPassword(user *models.User, password string) bool
+}
+
+// loginAttemptStore records password login attempts and counts the recent
+// failures the account lockout is based on
+type loginAttemptStore interface {
+	Create(ctx context.Context, attempt *models.LoginAttempt) error
+	CountRecentFailures(ctx context.Context, email string, since time.Time) (int, *time.Time, error)
+}
+
+// accountUserStore reads and updates user accounts.
+// Lookups return pgx.ErrNoRows when nothing matches.
+type accountUserStore interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateName(ctx context.Context, userID uuid.UUID, name string) error
+	UpdateEmail(ctx context.Context, userID uuid.UUID, email string) error
+	UpdatePassword(ctx context.Context, userID uuid.UUID, password string) error
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID, email string) (bool, error)
+	Delete(ctx context.Context, userID uuid.UUID) error
+	VerifyPassword(user *models.User, password string) bool
+}
+
+// userTokenStore keeps the digests of emailed single-use tokens
+type userTokenStore interface {
+	Create(ctx context.Context, token *models.UserToken, tokenHash string) error
+	Consume(ctx context.Context, tokenHash, purpose string) (*models.UserToken, error)
+	DeleteByUser(ctx context.Context, userID uuid.UUID, purpose string) error
+}
+
+// oidcUserStore finds, links and provisions the users signing in with OIDC.
+// Lookups return pgx.ErrNoRows when nothing matches.
+type oidcUserStore interface {
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByOIDCIdentity(ctx context.Context, issuer, subject string) (*models.User, error)
+	LinkOIDCIdentity(ctx context.Context, userID uuid.UUID, issuer, subject string) error
+	CreateExternal(ctx context.Context, user *models.User, issuer, subject string) error
+	UpdateRole(ctx context.Context, userID uuid.UUID, role string) error
+}
@@ -2,31 +2,57 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/visual-api-testing-platform/server/internal/repository"
+	"github.com/jackc/pgx/v5"
 	"github.com/visual-api-testing-platform/server/internal/engine"
+	"github.com/visual-api-testing-platform/server/internal/jsondiff"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/repository"
+	"github.com/visual-api-testing-platform/server/internal/rundiff"
 )
 
+// runningTestRun tracks an in-flight execution so it can be cancelled
+type runningTestRun struct {
+	cancel  context.CancelFunc
+	ownerID uuid.UUID
+}
+
 // TestRunHandler handles test run-related HTTP requests
 type TestRunHandler struct {
-	testRunRepo *repository.TestRunRepository
-	flowRepo    *repository.FlowRepository
-	flowRunner  *engine.FlowRunner
+	testRunRepo    *repository.TestRunRepository
+	flowRepo       *repository.FlowRepository
+	quarantineRepo *repository.QuarantineRepository
+	auditRepo      *repository.AuditRepository
+	flowRunner     *engine.FlowRunner
+
+	mu      sync.Mutex
+	running map[uuid.UUID]runningTestRun
 }
 
 // NewTestRunHandler creates a new test run handler
 func NewTestRunHandler(
 	testRunRepo *repository.TestRunRepository,
 	flowRepo *repository.FlowRepository,
+	quarantineRepo *repository.QuarantineRepository,
+	auditRepo *repository.AuditRepository,
 	flowRunner *engine.FlowRunner,
 ) *TestRunHandler {
 	return &TestRunHandler{
-		testRunRepo: testRunRepo,
-		flowRepo:    flowRepo,
-		flowRunner:  flowRunner,
+		testRunRepo:    testRunRepo,
+		flowRepo:       flowRepo,
+		quarantineRepo: quarantineRepo,
+		auditRepo:      auditRepo,
+		flowRunner:     flowRunner,
+		running:        make(map[uuid.UUID]runningTestRun),
 	}
 }
 
@@ -38,30 +64,125 @@ func (h *TestRunHandler) RunFlow(c *gin.Context) {
 		return
 	}
 
+	// The body is optional; it labels the run for later filtering and
+	// passes variables to custom script assertions
+	var req struct {
+		TriggerSource string                 `json:"trigger_source"`
+		Environment   string                 `json:"environment"`
+		Tags          []string               `json:"tags"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.TriggerSource == "" {
+		req.TriggerSource = models.TriggerSourceManual
+	}
+	if !models.ValidTriggerSource(req.TriggerSource) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "trigger_source must be one of manual, api, schedule, ci"})
+		return
+	}
+
 	flow, err := h.flowRepo.GetByID(c.Request.Context(), flowID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Flow not found"})
 		return
 	}
 
+	quarantined, err := h.quarantineRepo.NodeIDs(c.Request.Context(), flow.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := engine.RunOptions{
+		TriggerSource:    req.TriggerSource,
+		Environment:      req.Environment,
+		Tags:             req.Tags,
+		QuarantinedNodes: quarantined,
+		Variables:        req.Variables,
+	}
+
+	testRunID := uuid.New()
+	opts.TestRunID = testRunID
+	ctx, cancel := context.WithCancel(context.Background())
+
+	h.mu.Lock()
+	h.running[testRunID] = runningTestRun{cancel: cancel, ownerID: flow.UserID}
+	h.mu.Unlock()
+
 	// Execute flow in a goroutine
 	go func() {
-		ctx := context.Background()
-		testRun, err := h.flowRunner.ExecuteFlow(ctx, flow)
-		if err != nil {
+		defer func() {
+			h.mu.Lock()
+			delete(h.running, testRunID)
+			h.mu.Unlock()
+			cancel()
+		}()
+
+		testRun, err := h.flowRunner.ExecuteFlow(ctx, flow, opts)
+		if testRun == nil {
+			log.Printf("Flow %s execution failed: %v", flowID, err)
 			return
 		}
 
-		// Save test run to database
-		h.testRunRepo.Create(ctx, testRun)
+		// Save test run to database, including cancelled and timed out runs.
+		// The run context may already be cancelled, so use a fresh one.
+		if err := h.testRunRepo.Create(context.Background(), testRun); err != nil {
+			log.Printf("Failed to save test run %s: %v", testRun.ID, err)
+		}
 	}()
 
+	recordAudit(c, h.auditRepo, models.AuditActionTestRunStart, models.AuditResourceTestRun, testRunID.String(), map[string]interface{}{
+		"flow_id":   flowID.String(),
+		"flow_name": flow.Name,
+	})
+
 	c.JSON(http.StatusAccepted, gin.H{
-		"message": "Flow execution started",
-		"flow_id": flowID,
+		"message":     "Flow execution started",
+		"flow_id":     flowID,
+		"test_run_id": testRunID,
 	})
 }
 
+// CancelTestRun handles POST /api/test-runs/:id/cancel
+func (h *TestRunHandler) CancelTestRun(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid test run ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	h.mu.Lock()
+	run, ok := h.running[id]
+	h.mu.Unlock()
+
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "Test run is not running"})
+		return
+	}
+
+	if run.ownerID != userID.(uuid.UUID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to cancel this test run"})
+		return
+	}
+
+	run.cancel()
+
+	recordAudit(c, h.auditRepo, models.AuditActionTestRunCancel, models.AuditResourceTestRun, id.String(), nil)
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Cancellation requested"})
+}
+
 // GetTestRun handles GET /api/test-runs/:id
 func (h *TestRunHandler) GetTestRun(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
@@ -79,7 +200,7 @@ func (h *TestRunHandler) GetTestRun(c *gin.Context) {
 	c.JSON(http.StatusOK, testRun)
 }
 
-// GetTestRunsByFlow handles GET /api/flows/:id/test-runs
+// GetTestRunsByFlow handles GET /api/flows/:id/test-runs?limit=
 func (h *TestRunHandler) GetTestRunsByFlow(c *gin.Context) {
 	flowID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -87,7 +208,17 @@ func (h *TestRunHandler) GetTestRunsByFlow(c *gin.Context) {
 		return
 	}
 
-	testRuns, err := h.testRunRepo.GetByFlowID(c.Request.Context(), flowID, 50)
+	limit := 50
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+
+	testRuns, err := h.testRunRepo.GetByFlowID(c.Request.Context(), flowID, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -96,3 +227,139 @@ func (h *TestRunHandler) GetTestRunsByFlow(c *gin.Context) {
 	c.JSON(http.StatusOK, testRuns)
 }
 
+// ListTestRuns handles GET /api/test-runs.
+// It lists runs across the user's flows, newest first, without node results.
+func (h *TestRunHandler) ListTestRuns(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	filter := models.TestRunFilter{
+		UserID:        userID.(uuid.UUID),
+		Status:        models.ExecutionStatus(c.Query("status")),
+		TriggerSource: c.Query("trigger_source"),
+		Environment:   c.Query("environment"),
+		Tag:           c.Query("tag"),
+		Cursor:        c.Query("cursor"),
+	}
+
+	if s := c.Query("flow_id"); s != "" {
+		flowID, err := uuid.Parse(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flow ID"})
+			return
+		}
+		filter.FlowID = &flowID
+	}
+	if s := c.Query("from"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.From = &t
+	}
+	if s := c.Query("to"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.To = &t
+	}
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	testRuns, nextCursor, err := h.testRunRepo.List(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"test_runs":   testRuns,
+		"next_cursor": nextCursor,
+	})
+}
+
+// CompareTestRuns handles GET /api/test-runs/:id/compare/:otherId?ignore=.
+// It compares run :id with the later run :otherId. Pass last-success as :id to
+// compare with the newest successful run of the same flow before :otherId.
+// ignore takes output path patterns (repeatable or comma-separated) such as
+// data.**.createdAt or headers.Date that are left out of the output diff.
+func (h *TestRunHandler) CompareTestRuns(c *gin.Context) {
+	to, ok := h.ownedTestRun(c, c.Param("otherId"))
+	if !ok {
+		return
+	}
+
+	var from *models.TestRun
+	if c.Param("id") == "last-success" {
+		run, err := h.testRunRepo.GetLastSuccessful(c.Request.Context(), to.FlowID, to.StartedAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "No earlier successful run of this flow"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		from = run
+	} else {
+		from, ok = h.ownedTestRun(c, c.Param("id"))
+		if !ok {
+			return
+		}
+	}
+
+	var patterns []string
+	for _, value := range c.QueryArray("ignore") {
+		patterns = append(patterns, strings.Split(value, ",")...)
+	}
+
+	c.JSON(http.StatusOK, rundiff.Compare(from, to, jsondiff.NewFilter(patterns, nil)))
+}
+
+// ownedTestRun loads a test run and checks its flow belongs to the
+// authenticated user, writing an error response if not
+func (h *TestRunHandler) ownedTestRun(c *gin.Context, param string) (*models.TestRun, bool) {
+	id, err := uuid.Parse(param)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid test run ID"})
+		return nil, false
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	ctx := c.Request.Context()
+
+	testRun, err := h.testRunRepo.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Test run not found"})
+		return nil, false
+	}
+
+	flow, err := h.flowRepo.GetByID(ctx, testRun.FlowID)
+	if err != nil || flow.UserID != userID.(uuid.UUID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Test run not found"})
+		return nil, false
+	}
+
+	return testRun, true
+}
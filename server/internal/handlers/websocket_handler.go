@@ -26,11 +26,15 @@ func NewWebSocketHandler(hub *engine.ExecutionHub) *WebSocketHandler {
 	return &WebSocketHandler{hub: hub}
 }
 
-// HandleWebSocket handles WebSocket connections
+// HandleWebSocket handles WebSocket connections for the updates of a test run
+// (testRunId) or a load run (loadRunId)
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 	testRunIDStr := c.Query("testRunId")
 	if testRunIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "testRunId query parameter required"})
+		testRunIDStr = c.Query("loadRunId")
+	}
+	if testRunIDStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "testRunId or loadRunId query parameter required"})
 		return
 	}
 
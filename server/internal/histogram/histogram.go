@@ -0,0 +1,185 @@
+// Package histogram records latencies in an HDR (high dynamic range)
+// histogram: values are counted in buckets whose width grows with the value,
+// so that any recorded value can be read back with a bounded relative error
+// while memory stays constant however many values are recorded.
+package histogram
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Histogram counts values from 1 to a highest trackable value to a number of
+// significant decimal digits. It is not safe for concurrent use.
+type Histogram struct {
+	highest                     int64
+	subBucketHalfCountMagnitude int // log2 of half the sub-bucket count
+	subBucketHalfCount          int
+	subBucketMask               int64
+	leadingZeroCountBase        int
+
+	counts     []int64
+	totalCount int64
+	min        int64
+	max        int64
+	sum        float64
+}
+
+// New creates a histogram tracking values from 1 to highest with the given
+// number of significant digits, from 1 to 5
+func New(highest int64, digits int) *Histogram {
+	if digits < 1 {
+		digits = 1
+	}
+	if digits > 5 {
+		digits = 5
+	}
+	if highest < 2 {
+		highest = 2
+	}
+
+	// Enough sub-buckets that neighbouring values at the top of a bucket
+	// differ by less than one unit in the last significant digit
+	largestSingleUnit := 2 * int64(math.Pow10(digits))
+	subBucketCountMagnitude := int(math.Ceil(math.Log2(float64(largestSingleUnit))))
+	h := &Histogram{
+		highest:                     highest,
+		subBucketHalfCountMagnitude: subBucketCountMagnitude - 1,
+		subBucketHalfCount:          1 << (subBucketCountMagnitude - 1),
+		subBucketMask:               int64(1)<<subBucketCountMagnitude - 1,
+		min:                         math.MaxInt64,
+	}
+	h.leadingZeroCountBase = 64 - h.subBucketHalfCountMagnitude - 1
+
+	bucketCount := 1
+	for smallestUntrackable := int64(1) << subBucketCountMagnitude; smallestUntrackable <= highest; smallestUntrackable <<= 1 {
+		bucketCount++
+		if smallestUntrackable > math.MaxInt64/2 {
+			break
+		}
+	}
+	h.counts = make([]int64, (bucketCount+1)*h.subBucketHalfCount)
+	return h
+}
+
+// NewLatency creates a histogram of durations in microseconds, up to an hour,
+// to three significant digits
+func NewLatency() *Histogram {
+	return New(int64(time.Hour/time.Microsecond), 3)
+}
+
+// Record counts a value. Values below 1 count as 1 and values above the
+// highest trackable value count as that value.
+func (h *Histogram) Record(value int64) {
+	if value < 1 {
+		value = 1
+	}
+	if value > h.highest {
+		value = h.highest
+	}
+	h.counts[h.index(value)]++
+	h.totalCount++
+	h.sum += float64(value)
+	if value < h.min {
+		h.min = value
+	}
+	if value > h.max {
+		h.max = value
+	}
+}
+
+// RecordDuration counts a duration in microseconds, for latency histograms
+func (h *Histogram) RecordDuration(d time.Duration) {
+	h.Record(d.Microseconds())
+}
+
+// Merge adds the counts of another histogram created with the same parameters
+func (h *Histogram) Merge(other *Histogram) {
+	if other.totalCount == 0 {
+		return
+	}
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+	h.totalCount += other.totalCount
+	h.sum += other.sum
+	if other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+}
+
+// Count returns how many values were recorded
+func (h *Histogram) Count() int64 {
+	return h.totalCount
+}
+
+// Min returns the smallest recorded value, or 0 when there are none
+func (h *Histogram) Min() int64 {
+	if h.totalCount == 0 {
+		return 0
+	}
+	return h.min
+}
+
+// Max returns the largest recorded value, or 0 when there are none
+func (h *Histogram) Max() int64 {
+	return h.max
+}
+
+// Mean returns the mean of the recorded values, or 0 when there are none
+func (h *Histogram) Mean() float64 {
+	if h.totalCount == 0 {
+		return 0
+	}
+	return h.sum / float64(h.totalCount)
+}
+
+// Percentile returns the value at or below which the given percentage of
+// recorded values fall, to the histogram's precision, or 0 when there are none
+func (h *Histogram) Percentile(percentile float64) int64 {
+	if h.totalCount == 0 {
+		return 0
+	}
+	percentile = math.Max(0, math.Min(100, percentile))
+	target := int64(math.Ceil(percentile / 100 * float64(h.totalCount)))
+	if target < 1 {
+		target = 1
+	}
+
+	var cumulative int64
+	for i, count := range h.counts {
+		cumulative += count
+		if cumulative >= target {
+			// Report the top of the bucket, but never more than was recorded
+			value := h.highestEquivalent(i)
+			if value > h.max {
+				value = h.max
+			}
+			return value
+		}
+	}
+	return h.max
+}
+
+// index finds the counts slot of a value
+func (h *Histogram) index(value int64) int {
+	bucket := h.leadingZeroCountBase - bits.LeadingZeros64(uint64(value|h.subBucketMask))
+	subBucket := int(value >> uint(bucket))
+	return (bucket+1)<<uint(h.subBucketHalfCountMagnitude) + subBucket - h.subBucketHalfCount
+}
+
+// highestEquivalent returns the largest value counted in a slot
+func (h *Histogram) highestEquivalent(index int) int64 {
+	bucket := index>>uint(h.subBucketHalfCountMagnitude) - 1
+	subBucket := index&(h.subBucketHalfCount-1) + h.subBucketHalfCount
+	if bucket < 0 {
+		subBucket -= h.subBucketHalfCount
+		bucket = 0
+	}
+	lowest := int64(subBucket) << uint(bucket)
+	return lowest + int64(1)<<uint(bucket) - 1
+}
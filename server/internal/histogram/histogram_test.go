@@ -0,0 +1,147 @@
+package histogram
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// withinPrecision reports whether got is value to three significant digits
+func withinPrecision(got, value int64) bool {
+	return math.Abs(float64(got-value)) <= float64(value)/1000+1
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int64
+		want   map[float64]int64 // percentile -> value
+	}{
+		{name: "empty", want: map[float64]int64{0: 0, 50: 0, 100: 0}},
+		{name: "single value", values: []int64{42}, want: map[float64]int64{0: 42, 50: 42, 99.9: 42, 100: 42}},
+		{
+			name:   "one to a hundred",
+			values: sequence(1, 100),
+			want:   map[float64]int64{1: 1, 50: 50, 90: 90, 99: 99, 100: 100},
+		},
+		{
+			name:   "one to a million",
+			values: sequence(1, 1000000),
+			want:   map[float64]int64{50: 500000, 90: 900000, 99: 990000, 99.9: 999000},
+		},
+		{
+			// The slow tail decides the high percentiles
+			name:   "outliers",
+			values: append(repeat(1000, 990), repeat(2000000, 10)...),
+			want:   map[float64]int64{50: 1000, 99: 1000, 99.5: 2000000, 100: 2000000},
+		},
+		{name: "out of range percentiles are clamped", values: []int64{5, 10}, want: map[float64]int64{-10: 5, 150: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewLatency()
+			for _, v := range tt.values {
+				h.Record(v)
+			}
+			for percentile, value := range tt.want {
+				if got := h.Percentile(percentile); !withinPrecision(got, value) {
+					t.Errorf("p%v = %d, want %d", percentile, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	tests := []struct {
+		name      string
+		highest   int64
+		values    []int64
+		wantCount int64
+		wantMin   int64
+		wantMax   int64
+		wantMean  float64
+	}{
+		{name: "empty", highest: 1000},
+		{name: "values", highest: 1000, values: []int64{10, 20, 30}, wantCount: 3, wantMin: 10, wantMax: 30, wantMean: 20},
+		{name: "below one counts as one", highest: 1000, values: []int64{0, -5}, wantCount: 2, wantMin: 1, wantMax: 1, wantMean: 1},
+		{name: "above highest counts as highest", highest: 1000, values: []int64{5000}, wantCount: 1, wantMin: 1000, wantMax: 1000, wantMean: 1000},
+		{name: "largest value", highest: math.MaxInt64, values: []int64{math.MaxInt64}, wantCount: 1, wantMin: math.MaxInt64, wantMax: math.MaxInt64, wantMean: math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(tt.highest, 3)
+			for _, v := range tt.values {
+				h.Record(v)
+			}
+			if h.Count() != tt.wantCount || h.Min() != tt.wantMin || h.Max() != tt.wantMax || h.Mean() != tt.wantMean {
+				t.Errorf("count %d, min %d, max %d, mean %v; want %d, %d, %d, %v",
+					h.Count(), h.Min(), h.Max(), h.Mean(), tt.wantCount, tt.wantMin, tt.wantMax, tt.wantMean)
+			}
+			if tt.wantCount > 0 && !withinPrecision(h.Percentile(100), tt.wantMax) {
+				t.Errorf("p100 = %d, want %d", h.Percentile(100), tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	for _, digits := range []int{1, 2, 3, 4} {
+		h := New(int64(time.Hour/time.Microsecond), digits)
+		tolerance := math.Pow10(-digits)
+		for value := int64(1); value < int64(time.Hour/time.Microsecond); value = value*3 + 1 {
+			h.Record(value)
+			got := h.Percentile(100)
+			if rel := math.Abs(float64(got-value)) / float64(value); rel > tolerance {
+				t.Errorf("%d digits: recorded %d, read back %d", digits, value, got)
+			}
+			h = New(int64(time.Hour/time.Microsecond), digits)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := NewLatency(), NewLatency()
+	for _, v := range sequence(1, 50) {
+		a.Record(v)
+	}
+	for _, v := range sequence(51, 100) {
+		b.Record(v)
+	}
+	a.Merge(b)
+	a.Merge(NewLatency())
+
+	if a.Count() != 100 || a.Min() != 1 || a.Max() != 100 || a.Mean() != 50.5 {
+		t.Errorf("merged count %d, min %d, max %d, mean %v", a.Count(), a.Min(), a.Max(), a.Mean())
+	}
+	if got := a.Percentile(75); got != 75 {
+		t.Errorf("p75 = %d, want 75", got)
+	}
+}
+
+func TestRecordDuration(t *testing.T) {
+	h := NewLatency()
+	h.RecordDuration(1500 * time.Microsecond)
+	h.RecordDuration(2 * time.Hour)
+	if h.Min() != 1500 || h.Max() != int64(time.Hour/time.Microsecond) {
+		t.Errorf("min %d, max %d", h.Min(), h.Max())
+	}
+}
+
+func sequence(from, to int64) []int64 {
+	values := make([]int64, 0, to-from+1)
+	for v := from; v <= to; v++ {
+		values = append(values, v)
+	}
+	return values
+}
+
+func repeat(value int64, n int) []int64 {
+	values := make([]int64, n)
+	for i := range values {
+		values[i] = value
+	}
+	return values
+}
@@ -0,0 +1,371 @@
+package importer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// curlValueOptions take a value that doesn't affect the request
+var curlValueOptions = map[string]bool{
+	"-o": true, "--output": true, "-m": true, "--max-time": true, "--connect-timeout": true,
+	"-w": true, "--write-out": true, "--retry": true, "-x": true, "--proxy": true,
+	"--cacert": true, "--capath": true, "-E": true, "--cert": true, "--key": true,
+	"-r": true, "--range": true, "--resolve": true, "--connect-to": true, "-c": true,
+	"--cookie-jar": true, "--limit-rate": true, "--max-redirs": true, "-K": true, "--config": true,
+}
+
+// curlShortValueFlags are the single-letter options that take a value, which
+// may be attached, as in -XPOST
+const curlShortValueFlags = "XHdubAeFTomwxrEKc"
+
+// FromCurl generates a flow from one or more curl commands, as copied from
+// browser developer tools, chaining them as API nodes in order. Commands are
+// separated by newlines, ; or &&. Options that can't be imported are
+// returned with the reason.
+func FromCurl(commands, name string) (*models.Flow, []string, error) {
+	words, err := shellWords(commands)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var requests []request
+	var skipped []string
+	for _, command := range splitCommands(words) {
+		if program := command[0]; program != "curl" && program != "curl.exe" && !strings.HasSuffix(program, "/curl") {
+			continue
+		}
+		r, notes, err := parseCurl(command[1:])
+		if err != nil {
+			return nil, nil, fmt.Errorf("command %d: %w", len(requests)+1, err)
+		}
+		requests = append(requests, r)
+		skipped = append(skipped, notes...)
+	}
+	if len(requests) == 0 {
+		return nil, nil, fmt.Errorf("no curl command found")
+	}
+
+	if name == "" {
+		name = "curl import"
+	}
+	flow, unsupported := sequentialFlow(name, "Imported from curl commands", []string{"curl"}, requests)
+	return flow, append(skipped, unsupported...), nil
+}
+
+// parseCurl reads the request of one curl command from its arguments
+func parseCurl(args []string) (request, []string, error) {
+	var (
+		r        request
+		notes    []string
+		data     []string
+		method   string
+		toQuery  bool
+		formData []formField
+	)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		option, value, attached := splitCurlOption(arg)
+		takesValue := option != "" && (curlValueOptions[option] || curlTakesValue(option))
+		if takesValue && !attached {
+			if i+1 >= len(args) {
+				return request{}, nil, fmt.Errorf("option %s needs a value", option)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch option {
+		case "":
+			if r.url == "" {
+				r.url = arg
+			}
+		case "--url":
+			r.url = value
+		case "-X", "--request":
+			method = strings.ToUpper(value)
+		case "-H", "--header":
+			if name, v, ok := strings.Cut(value, ":"); ok && !isSkippedHeader(strings.TrimSpace(name)) {
+				r.header(strings.TrimSpace(name), strings.TrimSpace(v))
+			}
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			if strings.HasPrefix(value, "@") && option != "--data-raw" {
+				notes = append(notes, fmt.Sprintf("%s: data from file %s is not supported", option, value[1:]))
+				continue
+			}
+			data = append(data, value)
+		case "--data-urlencode":
+			data = append(data, curlURLEncode(value))
+		case "--json":
+			data = append(data, value)
+			r.header("Content-Type", "application/json")
+			r.header("Accept", "application/json")
+		case "-F", "--form", "--form-string":
+			key, v, _ := strings.Cut(value, "=")
+			if option != "--form-string" && (strings.HasPrefix(v, "@") || strings.HasPrefix(v, "<")) {
+				notes = append(notes, fmt.Sprintf("%s: file field %s is not supported", option, key))
+				continue
+			}
+			formData = append(formData, formField{name: key, value: v})
+		case "-u", "--user":
+			r.header("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+		case "-A", "--user-agent":
+			r.header("User-Agent", value)
+		case "-e", "--referer":
+			r.header("Referer", value)
+		case "-b", "--cookie":
+			if strings.Contains(value, "=") {
+				r.header("Cookie", value)
+			} else {
+				notes = append(notes, fmt.Sprintf("%s: cookies from file %s are not supported", option, value))
+			}
+		case "-G", "--get":
+			toQuery = true
+		case "-I", "--head":
+			method = "HEAD"
+		case "-T", "--upload-file":
+			notes = append(notes, fmt.Sprintf("%s: uploading %s is not supported", option, value))
+		}
+	}
+
+	if r.url == "" {
+		return request{}, nil, fmt.Errorf("no URL")
+	}
+	if !strings.Contains(r.url, "://") {
+		r.url = "http://" + r.url
+	}
+
+	body := strings.Join(data, "&")
+	switch {
+	case toQuery && body != "":
+		separator := "?"
+		if strings.Contains(r.url, "?") {
+			separator = "&"
+		}
+		r.url += separator + body
+	case len(formData) > 0:
+		encoded, contentType := multipartBody(formData)
+		r.body = encoded
+		r.header("Content-Type", contentType)
+	case body != "":
+		r.body = body
+		r.header("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	switch {
+	case method != "":
+		r.method = method
+	case toQuery:
+		r.method = "GET"
+	case r.body != "":
+		r.method = "POST"
+	default:
+		r.method = "GET"
+	}
+	if u, err := url.Parse(r.url); err == nil {
+		r.name = r.method + " " + u.Path
+	}
+	return r, notes, nil
+}
+
+// splitCurlOption splits an argument into its option and any attached value:
+// --data=x, -XPOST or -H'Accept: */*'. The option is empty for plain arguments.
+func splitCurlOption(arg string) (string, string, bool) {
+	if !strings.HasPrefix(arg, "-") || arg == "-" {
+		return "", "", false
+	}
+	if strings.HasPrefix(arg, "--") {
+		if option, value, ok := strings.Cut(arg, "="); ok {
+			return option, value, true
+		}
+		return arg, "", false
+	}
+	// grouped short flags such as -sSL end at the first one taking a value
+	for i := 1; i < len(arg); i++ {
+		if strings.IndexByte(curlShortValueFlags, arg[i]) >= 0 {
+			if i+1 < len(arg) {
+				return "-" + string(arg[i]), arg[i+1:], true
+			}
+			return "-" + string(arg[i]), "", false
+		}
+	}
+	return "-" + string(arg[len(arg)-1]), "", false
+}
+
+func curlTakesValue(option string) bool {
+	switch option {
+	case "--url", "--request", "--header", "--data", "--data-raw", "--data-binary", "--data-ascii",
+		"--data-urlencode", "--json", "--form", "--form-string", "--user", "--user-agent",
+		"--referer", "--cookie", "--upload-file":
+		return true
+	}
+	return len(option) == 2 && strings.IndexByte(curlShortValueFlags, option[1]) >= 0
+}
+
+// curlURLEncode encodes a --data-urlencode value: content, name=content or =content
+func curlURLEncode(value string) string {
+	if name, content, ok := strings.Cut(value, "="); ok {
+		if name == "" {
+			return url.QueryEscape(content)
+		}
+		return name + "=" + url.QueryEscape(content)
+	}
+	return url.QueryEscape(value)
+}
+
+// commandSeparator marks the end of a command in the output of shellWords
+const commandSeparator = "\x00"
+
+// shellWords splits text into words as a POSIX shell would, handling single,
+// double and $'...' quotes, backslash escapes and line continuations.
+// Unquoted newlines, ; and && become commandSeparator words.
+func shellWords(text string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	flush := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(text) {
+				i++
+				if text[i] == '\r' && i+1 < len(text) && text[i+1] == '\n' {
+					i++
+				}
+				if text[i] != '\n' && text[i] != '\r' {
+					word.WriteByte(text[i])
+					inWord = true
+				}
+			}
+		case c == '\'':
+			end := strings.IndexByte(text[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(text[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+		case c == '$' && i+1 < len(text) && text[i+1] == '\'':
+			value, n, err := ansiCQuoted(text[i+2:])
+			if err != nil {
+				return nil, err
+			}
+			word.WriteString(value)
+			inWord = true
+			// skip $' and the body, leaving i on the closing quote
+			i += n + 1
+		case c == '"':
+			i++
+			for ; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' && i+1 < len(text) && strings.IndexByte("\"\\$`\n", text[i+1]) >= 0 {
+					i++
+					if text[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(text[i])
+			}
+			if i >= len(text) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\r':
+			flush()
+		case c == '\n' || c == ';':
+			flush()
+			words = append(words, commandSeparator)
+		case c == '&' && i+1 < len(text) && text[i+1] == '&':
+			flush()
+			words = append(words, commandSeparator)
+			i++
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	flush()
+	return words, nil
+}
+
+// ansiCQuoted decodes the body of a $'...' string, returning the value and
+// the number of bytes read including the closing quote
+func ansiCQuoted(text string) (string, int, error) {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '\'' {
+			return b.String(), i + 1, nil
+		}
+		if c != '\\' || i+1 >= len(text) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch text[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'x':
+			end := i + 1
+			for end < len(text) && end < i+3 && strings.IndexByte("0123456789abcdefABCDEF", text[end]) >= 0 {
+				end++
+			}
+			if n, err := strconv.ParseUint(text[i+1:end], 16, 8); err == nil {
+				b.WriteByte(byte(n))
+				i = end - 1
+			} else {
+				b.WriteString(`\x`)
+			}
+		case 'u':
+			end := i + 1
+			for end < len(text) && end < i+5 && strings.IndexByte("0123456789abcdefABCDEF", text[end]) >= 0 {
+				end++
+			}
+			if n, err := strconv.ParseUint(text[i+1:end], 16, 32); err == nil {
+				b.WriteRune(rune(n))
+				i = end - 1
+			} else {
+				b.WriteString(`\u`)
+			}
+		default:
+			b.WriteByte(text[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated $' quote")
+}
+
+// splitCommands groups words into commands at commandSeparator words
+func splitCommands(words []string) [][]string {
+	var commands [][]string
+	var current []string
+	for _, w := range words {
+		if w == commandSeparator {
+			if len(current) > 0 {
+				commands = append(commands, current)
+			}
+			current = nil
+			continue
+		}
+		current = append(current, w)
+	}
+	if len(current) > 0 {
+		commands = append(commands, current)
+	}
+	return commands
+}
@@ -0,0 +1,250 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShellWords(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "plain", text: "curl -s https://api.test/a", want: []string{"curl", "-s", "https://api.test/a"}},
+		{name: "single quotes", text: `curl -H 'Accept: */*' 'a b'`, want: []string{"curl", "-H", "Accept: */*", "a b"}},
+		{name: "single quotes keep backslashes", text: `'a\"b'`, want: []string{`a\"b`}},
+		{name: "double quotes with escapes", text: `"say \"hi\" \$HOME \\ \n"`, want: []string{`say "hi" $HOME \ \n`}},
+		{name: "ANSI-C quotes", text: `$'line\nnext\x41é\'' x`, want: []string{"line\nnextAé'", "x"}},
+		{name: "adjacent quoted parts", text: `--data-raw '{"a":'"1"'}'`, want: []string{"--data-raw", `{"a":1}`}},
+		{name: "backslash escape", text: `a\ b \'c`, want: []string{"a b", "'c"}},
+		{name: "line continuation", text: "curl \\\n  -X POST \\\r\n  url", want: []string{"curl", "-X", "POST", "url"}},
+		{name: "empty quotes", text: `curl -d ''`, want: []string{"curl", "-d", ""}},
+		{
+			name: "command separators",
+			text: "curl a; curl b && curl c\ncurl d",
+			want: []string{"curl", "a", commandSeparator, "curl", "b", commandSeparator, "curl", "c", commandSeparator, "curl", "d"},
+		},
+		{name: "quoted separators", text: `curl 'a;b' "c&&d"`, want: []string{"curl", "a;b", "c&&d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shellWords(tt.text)
+			if err != nil {
+				t.Fatalf("shellWords: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellWordsErrors(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{text: `curl 'unterminated`, wantErr: "unterminated single quote"},
+		{text: `curl "unterminated`, wantErr: "unterminated double quote"},
+		{text: `curl $'unterminated`, wantErr: "unterminated $' quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if _, err := shellWords(tt.text); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCurl(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		want      request
+		wantNotes []string
+	}{
+		{
+			name:    "GET by default",
+			command: "curl https://api.test/users",
+			want:    request{name: "GET /users", method: "GET", url: "https://api.test/users"},
+		},
+		{
+			name:    "scheme added",
+			command: "curl api.test/users",
+			want:    request{name: "GET /users", method: "GET", url: "http://api.test/users"},
+		},
+		{
+			name:    "data makes a POST",
+			command: `curl https://api.test/users -d 'name=a b'`,
+			want: request{name: "POST /users", method: "POST", url: "https://api.test/users", body: "name=a b",
+				headers: map[string]interface{}{"Content-Type": "application/x-www-form-urlencoded"}},
+		},
+		{
+			name:    "-X wins over the data method",
+			command: `curl -X PUT https://api.test/users/1 -H 'Content-Type: application/json' --data-raw '{"name":"a"}'`,
+			want: request{name: "PUT /users/1", method: "PUT", url: "https://api.test/users/1", body: `{"name":"a"}`,
+				headers: map[string]interface{}{"Content-Type": "application/json"}},
+		},
+		{
+			name:    "attached values",
+			command: `curl -XPATCH --data={} '-HAccept: */*' --url=https://api.test/x`,
+			want: request{name: "PATCH /x", method: "PATCH", url: "https://api.test/x", body: "{}",
+				headers: map[string]interface{}{"Accept": "*/*", "Content-Type": "application/x-www-form-urlencoded"}},
+		},
+		{
+			name:    "grouped short flags",
+			command: `curl -sSLX DELETE https://api.test/x`,
+			want:    request{name: "DELETE /x", method: "DELETE", url: "https://api.test/x"},
+		},
+		{
+			name:    "several data options are joined",
+			command: `curl https://api.test/f -d a=1 --data-urlencode 'q=x y&z' -d b=2`,
+			want: request{name: "POST /f", method: "POST", url: "https://api.test/f", body: "a=1&q=x+y%26z&b=2",
+				headers: map[string]interface{}{"Content-Type": "application/x-www-form-urlencoded"}},
+		},
+		{
+			name:    "-G moves data to the query",
+			command: `curl -G https://api.test/search?x=1 -d q=a`,
+			want:    request{name: "GET /search", method: "GET", url: "https://api.test/search?x=1&q=a"},
+		},
+		{
+			name:    "user becomes basic auth",
+			command: `curl -u 'alice:s3cret' https://api.test/me`,
+			want: request{name: "GET /me", method: "GET", url: "https://api.test/me",
+				headers: map[string]interface{}{"Authorization": "Basic YWxpY2U6czNjcmV0"}},
+		},
+		{
+			name:    "first header of a name wins",
+			command: `curl -H 'X-A: 1' -H 'x-a: 2' -H 'Host: ignored' -H 'Accept-Encoding: gzip' https://api.test/`,
+			want: request{name: "GET /", method: "GET", url: "https://api.test/",
+				headers: map[string]interface{}{"X-A": "1"}},
+		},
+		{
+			name:    "json",
+			command: `curl --json '{"a":1}' https://api.test/j`,
+			want: request{name: "POST /j", method: "POST", url: "https://api.test/j", body: `{"a":1}`,
+				headers: map[string]interface{}{"Content-Type": "application/json", "Accept": "application/json"}},
+		},
+		{
+			name:    "head",
+			command: `curl -I https://api.test/h`,
+			want:    request{name: "HEAD /h", method: "HEAD", url: "https://api.test/h"},
+		},
+		{
+			name:    "data from a file is skipped",
+			command: `curl -d @body.json https://api.test/u`,
+			want:    request{name: "GET /u", method: "GET", url: "https://api.test/u"},
+			wantNotes: []string{
+				"-d: data from file body.json is not supported",
+			},
+		},
+		{
+			name:    "data-raw keeps a leading @",
+			command: `curl --data-raw @literal https://api.test/u`,
+			want: request{name: "POST /u", method: "POST", url: "https://api.test/u", body: "@literal",
+				headers: map[string]interface{}{"Content-Type": "application/x-www-form-urlencoded"}},
+		},
+		{
+			name:    "ignored options and their values",
+			command: `curl -o out.txt --max-time 5 -w '%{http_code}' https://api.test/v`,
+			want:    request{name: "GET /v", method: "GET", url: "https://api.test/v"},
+		},
+		{
+			name:    "cookies",
+			command: `curl -b 'a=1; b=2' -c jar.txt -b cookies.txt https://api.test/c`,
+			want: request{name: "GET /c", method: "GET", url: "https://api.test/c",
+				headers: map[string]interface{}{"Cookie": "a=1; b=2"}},
+			wantNotes: []string{"-b: cookies from file cookies.txt are not supported"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			words, err := shellWords(tt.command)
+			if err != nil {
+				t.Fatalf("shellWords: %v", err)
+			}
+			got, notes, err := parseCurl(words[1:])
+			if err != nil {
+				t.Fatalf("parseCurl: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(notes, tt.wantNotes) {
+				t.Errorf("notes = %q, want %q", notes, tt.wantNotes)
+			}
+		})
+	}
+}
+
+func TestParseCurlForm(t *testing.T) {
+	words, _ := shellWords(`curl -F name=alice -F 'avatar=@me.png' --form-string 'bio=@home' https://api.test/f`)
+	got, notes, err := parseCurl(words[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantBody, wantType := multipartBody([]formField{{name: "name", value: "alice"}, {name: "bio", value: "@home"}})
+	if got.method != "POST" || got.body != wantBody || got.headers["Content-Type"] != wantType {
+		t.Errorf("got %+v, want a POST of the text fields", got)
+	}
+	if want := []string{"-F: file field avatar is not supported"}; !reflect.DeepEqual(notes, want) {
+		t.Errorf("notes = %q, want %q", notes, want)
+	}
+}
+
+func TestParseCurlErrors(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"-s"}, wantErr: "no URL"},
+		{args: []string{"https://api.test", "-H"}, wantErr: "option -H needs a value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			if _, _, err := parseCurl(tt.args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFromCurl(t *testing.T) {
+	commands := "curl https://api.test/login -d u=a && \\\n" +
+		"echo done; curl -X PUT https://api.test/items/1\n" +
+		"/usr/bin/curl -X TRACE https://api.test/"
+
+	flow, skipped, err := FromCurl(commands, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flow.Name != "curl import" {
+		t.Errorf("name = %q", flow.Name)
+	}
+
+	var ids []string
+	for _, n := range flow.Nodes {
+		ids = append(ids, n.ID)
+	}
+	if want := []string{"post-login", "put-items-1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("nodes = %q, want %q", ids, want)
+	}
+	if len(flow.Edges) != 1 || flow.Edges[0].Source != "post-login" || flow.Edges[0].Target != "put-items-1" {
+		t.Errorf("edges = %+v, want post-login -> put-items-1", flow.Edges)
+	}
+	if want := []string{"TRACE /: unsupported method TRACE"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped = %q, want %q", skipped, want)
+	}
+
+	if _, _, err := FromCurl("echo hello", ""); err == nil {
+		t.Error("expected an error without curl commands")
+	}
+}
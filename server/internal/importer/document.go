@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/exporter"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// FromDocument recreates a flow from an exported flow document. specIDs maps
+// the names of the document's specs to the library specs API node contracts
+// are bound to.
+func FromDocument(doc *exporter.Document, specIDs map[string]uuid.UUID) (*models.Flow, error) {
+	flow := &models.Flow{
+		Name:        doc.Name,
+		Description: doc.Description,
+		Tags:        doc.Tags,
+		Variables:   doc.Variables,
+		Nodes:       make([]models.FlowNode, 0, len(doc.Nodes)),
+		Edges:       make([]models.FlowEdge, 0, len(doc.Edges)),
+	}
+	if flow.Tags == nil {
+		flow.Tags = []string{}
+	}
+	if flow.Variables == nil {
+		flow.Variables = make(map[string]interface{})
+	}
+
+	for _, n := range doc.Nodes {
+		config := make(map[string]interface{}, len(n.Config))
+		for k, v := range n.Config {
+			config[k] = v
+		}
+		if contract, ok := config["contract"].(map[string]interface{}); ok {
+			if name, ok := contract["spec"].(string); ok {
+				id, found := specIDs[name]
+				if !found {
+					return nil, fmt.Errorf("node %s: unknown spec %s", n.ID, name)
+				}
+				binding := map[string]interface{}{"specId": id.String()}
+				for k, v := range contract {
+					if k != "spec" {
+						binding[k] = v
+					}
+				}
+				config["contract"] = binding
+			}
+		}
+		flow.Nodes = append(flow.Nodes, newNode(n.ID, n.Type, n.Label, config, n.Position.X, n.Position.Y))
+	}
+
+	for _, e := range doc.Edges {
+		id := e.ID
+		if id == "" {
+			id = "edge-" + e.Source + "-" + e.Target
+		}
+		flow.Edges = append(flow.Edges, models.FlowEdge{
+			ID:           id,
+			Source:       e.Source,
+			Target:       e.Target,
+			SourceHandle: e.SourceHandle,
+			TargetHandle: e.TargetHandle,
+		})
+	}
+	return flow, nil
+}
@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/exporter"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+type fakeSchemaStore map[uuid.UUID]*models.Schema
+
+func (s fakeSchemaStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Schema, error) {
+	if schema, ok := s[id]; ok {
+		return schema, nil
+	}
+	return nil, fmt.Errorf("schema not found")
+}
+
+type fakeSpecStore map[uuid.UUID]*models.APISpec
+
+func (s fakeSpecStore) GetByID(ctx context.Context, id uuid.UUID) (*models.APISpec, error) {
+	if spec, ok := s[id]; ok {
+		return spec, nil
+	}
+	return nil, fmt.Errorf("spec not found")
+}
+
+func (s fakeSpecStore) GetUpdatedAt(ctx context.Context, id uuid.UUID) (time.Time, error) {
+	if spec, ok := s[id]; ok {
+		return spec.UpdatedAt, nil
+	}
+	return time.Time{}, fmt.Errorf("spec not found")
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+	schemaID := uuid.New()
+	specID := uuid.New()
+	schemas := fakeSchemaStore{schemaID: {ID: schemaID, Name: "User", Schema: map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"id"},
+	}}}
+	specs := fakeSpecStore{specID: {ID: specID, Name: "Users API", Description: "v1", Document: map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]interface{}{"title": "Users", "version": "1"},
+		"paths":   map[string]interface{}{},
+	}}}
+
+	handle := "true"
+	flow := &models.Flow{
+		Name:        "Users",
+		Description: "Create and fetch a user",
+		Tags:        []string{"users", "smoke"},
+		Variables:   map[string]interface{}{"baseUrl": "https://api.test", "retries": 3.0, "user": map[string]interface{}{"name": "a: b"}},
+		Nodes: []models.FlowNode{
+			newNode("create", "api", "Create user", map[string]interface{}{
+				"method":   "POST",
+				"url":      "{{baseUrl}}/users",
+				"headers":  map[string]interface{}{"Content-Type": "application/json"},
+				"body":     "{\n  \"name\": \"{{user.name}}\"\n}",
+				"contract": map[string]interface{}{"specId": specID.String(), "operationId": "createUser", "validateRequest": true},
+			}, 250, 100),
+			newNode("check", "verification", "Check user", map[string]interface{}{
+				"assertionType": "response",
+				"status":        201.0,
+				"schemaId":      schemaID.String(),
+			}, 250, 260),
+			newNode("wait", "delay", "Wait", map[string]interface{}{"ms": 1.5}, 520.5, -40),
+		},
+		Edges: []models.FlowEdge{
+			{ID: "e1", Source: "create", Target: "check"},
+			{ID: "e2", Source: "check", Target: "wait", SourceHandle: &handle},
+		},
+	}
+	// Run state is not exported
+	flow.Nodes[0].Data.Status = "success"
+	flow.Nodes[0].Data.Output = map[string]interface{}{"status": 201.0}
+
+	// Library schemas come back inlined, and contracts bound to the spec the
+	// import maps the embedded spec's name to
+	importedSpecID := uuid.New()
+	want := &models.Flow{
+		Name:        flow.Name,
+		Description: flow.Description,
+		Tags:        flow.Tags,
+		Variables:   flow.Variables,
+		Nodes: []models.FlowNode{
+			newNode("create", "api", "Create user", map[string]interface{}{
+				"method":   "POST",
+				"url":      "{{baseUrl}}/users",
+				"headers":  map[string]interface{}{"Content-Type": "application/json"},
+				"body":     "{\n  \"name\": \"{{user.name}}\"\n}",
+				"contract": map[string]interface{}{"specId": importedSpecID.String(), "operationId": "createUser", "validateRequest": true},
+			}, 250, 100),
+			newNode("check", "verification", "Check user", map[string]interface{}{
+				"assertionType": "response",
+				"status":        201.0,
+				"schema":        map[string]interface{}{"type": "object", "required": []interface{}{"id"}},
+			}, 250, 260),
+			newNode("wait", "delay", "Wait", map[string]interface{}{"ms": 1.5}, 520.5, -40),
+		},
+		Edges: flow.Edges,
+	}
+
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			doc, err := exporter.Export(context.Background(), flow, schemas, specs)
+			if err != nil {
+				t.Fatalf("Export: %v", err)
+			}
+			encoded, err := exporter.Encode(doc, format)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			decoded, err := exporter.Decode(encoded)
+			if err != nil {
+				t.Fatalf("Decode: %v\n%s", err, encoded)
+			}
+			if spec, ok := decoded.Specs["Users API"]; !ok || spec.Description != "v1" {
+				t.Errorf("specs = %+v, want the Users API spec embedded", decoded.Specs)
+			}
+
+			got, err := FromDocument(decoded, map[string]uuid.UUID{"Users API": importedSpecID})
+			if err != nil {
+				t.Fatalf("FromDocument: %v", err)
+			}
+			if !reflect.DeepEqual(got.Nodes, want.Nodes) {
+				t.Errorf("nodes =\n%+v\nwant\n%+v\ndocument:\n%s", got.Nodes, want.Nodes, encoded)
+			}
+			if !reflect.DeepEqual(got.Edges, want.Edges) {
+				t.Errorf("edges = %+v, want %+v", got.Edges, want.Edges)
+			}
+			if !reflect.DeepEqual(got.Variables, want.Variables) {
+				t.Errorf("variables = %#v, want %#v", got.Variables, want.Variables)
+			}
+			if got.Name != want.Name || got.Description != want.Description || !reflect.DeepEqual(got.Tags, want.Tags) {
+				t.Errorf("got %q %q %q, want %q %q %q", got.Name, got.Description, got.Tags, want.Name, want.Description, want.Tags)
+			}
+		})
+	}
+
+	// Exporting works on a copy of the node configs
+	if _, ok := flow.Nodes[1].Data.Config["schemaId"]; !ok {
+		t.Error("Export changed the flow's node config")
+	}
+}
+
+func TestFromDocumentUnknownSpec(t *testing.T) {
+	doc := &exporter.Document{
+		Name:  "x",
+		Nodes: []exporter.Node{{ID: "a", Type: "api", Config: map[string]interface{}{"contract": map[string]interface{}{"spec": "Missing"}}}},
+	}
+	if _, err := FromDocument(doc, nil); err == nil {
+		t.Error("expected an error for a contract bound to an unknown spec")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "not a document", data: "- 1\n- 2\n"},
+		{name: "wrong format", data: `{"format": "other", "version": 1, "name": "x"}`},
+		{name: "future version", data: `{"format": "visual-api-testing-platform/flow", "version": 2, "name": "x"}`},
+		{name: "no name", data: `{"format": "visual-api-testing-platform/flow", "version": 1}`},
+		{name: "node without id", data: `{"format": "visual-api-testing-platform/flow", "version": 1, "name": "x", "nodes": [{"type": "api"}]}`},
+		{name: "duplicate ids", data: `{"format": "visual-api-testing-platform/flow", "version": 1, "name": "x", "nodes": [{"id": "a"}, {"id": "a"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := exporter.Decode([]byte(tt.data)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
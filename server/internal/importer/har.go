@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// HAROptions configures FromHAR
+type HAROptions struct {
+	// Name names the flow and defaults to the title of the capture's first page
+	Name string
+
+	// IncludeStatic keeps requests for documents, scripts, stylesheets, images,
+	// fonts and media, which are otherwise skipped
+	IncludeStatic bool
+}
+
+type harFile struct {
+	Log struct {
+		Pages []struct {
+			Title string `json:"title"`
+		} `json:"pages"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	ResourceType string `json:"_resourceType"`
+	Request      struct {
+		Method   string         `json:"method"`
+		URL      string         `json:"url"`
+		Headers  []harNameValue `json:"headers"`
+		PostData *struct {
+			MimeType string         `json:"mimeType"`
+			Text     string         `json:"text"`
+			Params   []harNameValue `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// staticResourceTypes are the browser resource types skipped by default
+var staticResourceTypes = map[string]bool{
+	"document":   true,
+	"script":     true,
+	"stylesheet": true,
+	"image":      true,
+	"font":       true,
+	"media":      true,
+	"manifest":   true,
+}
+
+// FromHAR generates a flow from an HTTP Archive, chaining its requests as
+// API nodes in the order they were captured. Skipped requests are returned
+// with the reason.
+func FromHAR(data []byte, opts HAROptions) (*models.Flow, []string, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, nil, fmt.Errorf("invalid HAR file: %w", err)
+	}
+	if har.Log.Entries == nil {
+		return nil, nil, fmt.Errorf("invalid HAR file: no log entries")
+	}
+
+	var skipped []string
+	requests := make([]request, 0, len(har.Log.Entries))
+	for _, entry := range har.Log.Entries {
+		r := request{method: entry.Request.Method, url: entry.Request.URL}
+		r.name = r.method + " " + r.url
+		if u, err := url.Parse(r.url); err == nil {
+			r.name = r.method + " " + u.Path
+		}
+		if !opts.IncludeStatic && isStatic(entry) {
+			skipped = append(skipped, r.name+": static resource")
+			continue
+		}
+
+		for _, h := range entry.Request.Headers {
+			if !isSkippedHeader(h.Name) {
+				r.header(h.Name, h.Value)
+			}
+		}
+		if post := entry.Request.PostData; post != nil {
+			r.body = post.Text
+			if r.body == "" && len(post.Params) > 0 {
+				fields := make([]string, 0, len(post.Params))
+				for _, p := range post.Params {
+					fields = append(fields, url.QueryEscape(p.Name)+"="+url.QueryEscape(p.Value))
+				}
+				r.body = strings.Join(fields, "&")
+			}
+			if post.MimeType != "" {
+				r.header("Content-Type", post.MimeType)
+			}
+		}
+		requests = append(requests, r)
+	}
+
+	name := opts.Name
+	if name == "" && len(har.Log.Pages) > 0 {
+		name = har.Log.Pages[0].Title
+	}
+	if name == "" {
+		name = "HAR capture"
+	}
+
+	flow, unsupported := sequentialFlow(name, "Imported from a HAR capture", []string{"har"}, requests)
+	return flow, append(skipped, unsupported...), nil
+}
+
+// isStatic tells whether an entry loaded a page resource rather than calling
+// an API, by its browser resource type or else, for GET requests, its
+// response content type
+func isStatic(entry harEntry) bool {
+	if entry.ResourceType != "" {
+		return staticResourceTypes[entry.ResourceType]
+	}
+	if entry.Request.Method != "GET" {
+		return false
+	}
+	mimeType := strings.ToLower(entry.Response.Content.MimeType)
+	for _, prefix := range []string{"text/html", "text/css", "image/", "font/", "audio/", "video/", "application/javascript", "text/javascript"} {
+		if strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const harFixture = `{
+	"log": {
+		"pages": [{"title": "Shop checkout"}],
+		"entries": [
+			{"_resourceType": "document", "request": {"method": "GET", "url": "https://shop.test/checkout", "headers": []}},
+			{"_resourceType": "script", "request": {"method": "GET", "url": "https://shop.test/app.js", "headers": []}},
+			{
+				"_resourceType": "fetch",
+				"request": {
+					"method": "POST",
+					"url": "https://shop.test/api/cart?x=1",
+					"headers": [
+						{"name": ":authority", "value": "shop.test"},
+						{"name": "Accept", "value": "application/json"},
+						{"name": "accept-encoding", "value": "gzip"},
+						{"name": "Content-Length", "value": "9"}
+					],
+					"postData": {"mimeType": "application/json", "text": "{\"sku\":1}"}
+				}
+			},
+			{"request": {"method": "GET", "url": "https://shop.test/logo.png", "headers": []}, "response": {"content": {"mimeType": "image/png"}}},
+			{"request": {"method": "GET", "url": "https://shop.test/site.css", "headers": []}, "response": {"content": {"mimeType": "text/css; charset=utf-8"}}},
+			{"request": {"method": "GET", "url": "https://shop.test/api/cart", "headers": []}, "response": {"content": {"mimeType": "application/json"}}},
+			{
+				"request": {
+					"method": "POST",
+					"url": "https://shop.test/login",
+					"headers": [],
+					"postData": {"mimeType": "application/x-www-form-urlencoded", "params": [{"name": "user", "value": "a b"}, {"name": "pass", "value": "&x"}]}
+				},
+				"response": {"content": {"mimeType": "text/html"}}
+			}
+		]
+	}
+}`
+
+func TestFromHAR(t *testing.T) {
+	flow, skipped, err := FromHAR([]byte(harFixture), HAROptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flow.Name != "Shop checkout" {
+		t.Errorf("name = %q", flow.Name)
+	}
+
+	want := []nodeSummary{
+		{
+			ID: "post-api-cart", Label: "POST /api/cart", Method: "POST", URL: "https://shop.test/api/cart?x=1",
+			Headers: map[string]interface{}{"Accept": "application/json", "Content-Type": "application/json"}, Body: `{"sku":1}`,
+		},
+		{ID: "get-api-cart", Label: "GET /api/cart", Method: "GET", URL: "https://shop.test/api/cart"},
+		// A POST is never static, whatever its response
+		{
+			ID: "post-login", Label: "POST /login", Method: "POST", URL: "https://shop.test/login",
+			Headers: map[string]interface{}{"Content-Type": "application/x-www-form-urlencoded"}, Body: "user=a+b&pass=%26x",
+		},
+	}
+	if got := summarize(flow.Nodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("nodes =\n%+v\nwant\n%+v", got, want)
+	}
+
+	wantSkipped := []string{
+		"GET /checkout: static resource",
+		"GET /app.js: static resource",
+		"GET /logo.png: static resource",
+		"GET /site.css: static resource",
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped = %q, want %q", skipped, wantSkipped)
+	}
+}
+
+func TestFromHARIncludeStatic(t *testing.T) {
+	flow, skipped, err := FromHAR([]byte(harFixture), HAROptions{Name: "Everything", IncludeStatic: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flow.Name != "Everything" {
+		t.Errorf("name = %q", flow.Name)
+	}
+	if len(flow.Nodes) != 7 || len(skipped) != 0 {
+		t.Errorf("got %d nodes and skipped %q, want all 7 requests", len(flow.Nodes), skipped)
+	}
+}
+
+func TestIsStatic(t *testing.T) {
+	tests := []struct {
+		resourceType string
+		method       string
+		mimeType     string
+		want         bool
+	}{
+		{resourceType: "xhr", method: "GET", mimeType: "text/html", want: false},
+		{resourceType: "image", method: "GET", want: true},
+		{resourceType: "font", method: "GET", want: true},
+		{method: "GET", mimeType: "application/json", want: false},
+		{method: "GET", mimeType: "Text/HTML; charset=utf-8", want: true},
+		{method: "GET", mimeType: "application/javascript", want: true},
+		{method: "GET", mimeType: "font/woff2", want: true},
+		{method: "GET", mimeType: "video/mp4", want: true},
+		{method: "POST", mimeType: "text/html", want: false},
+		{method: "GET", want: false},
+	}
+
+	for _, tt := range tests {
+		var entry harEntry
+		entry.ResourceType = tt.resourceType
+		entry.Request.Method = tt.method
+		entry.Response.Content.MimeType = tt.mimeType
+		if got := isStatic(entry); got != tt.want {
+			t.Errorf("isStatic(%q, %s, %q) = %v, want %v", tt.resourceType, tt.method, tt.mimeType, got, tt.want)
+		}
+	}
+}
+
+func TestFromHARErrors(t *testing.T) {
+	tests := []struct {
+		data    string
+		wantErr string
+	}{
+		{data: "not json", wantErr: "invalid HAR file"},
+		{data: `{"log": {}}`, wantErr: "no log entries"},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := FromHAR([]byte(tt.data), HAROptions{}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("FromHAR(%q) error = %v, want %q", tt.data, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+// Package importer generates flows from API descriptions such as OpenAPI
+// documents and from captured requests: Postman collections, HAR files and
+// curl commands. Generated flows have no ID or owner yet; the caller stores them.
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/node"
+	"github.com/visual-api-testing-platform/server/internal/openapi"
+)
+
+// untaggedGroup collects operations without tags when grouping by tag
+const untaggedGroup = "untagged"
+
+// OpenAPIOptions configures FromOpenAPI
+type OpenAPIOptions struct {
+	// Name names the flow and defaults to the document title. Flows grouped
+	// by tag are named "<name> - <tag>".
+	Name string
+
+	// GroupByTag generates one flow per tag, grouping operations under their
+	// first tag, instead of one flow for the whole document
+	GroupByTag bool
+
+	// BaseURL is the value of the baseUrl variable and defaults to the
+	// document's first server
+	BaseURL string
+
+	// SpecID binds every API node's contract to this library spec when set
+	SpecID *uuid.UUID
+}
+
+// FromOpenAPI generates flows with an API node per operation, each followed
+// by a verification node asserting the documented success status and
+// response schema. Request URLs start with {{baseUrl}}, and credentials for
+// the operations' security schemes come from variables of the scheme's name.
+// Operations whose method API nodes can't send are skipped and returned by name.
+func FromOpenAPI(doc *openapi.Document, opts OpenAPIOptions) ([]*models.Flow, []string) {
+	name := opts.Name
+	if name == "" {
+		name = doc.Title
+	}
+	if name == "" {
+		name = "Imported API"
+	}
+	baseURL := opts.BaseURL
+	if servers := doc.Servers(); baseURL == "" && len(servers) > 0 {
+		baseURL = servers[0]
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	var groups, skipped []string
+	operations := make(map[string][]*openapi.Operation)
+	for _, op := range doc.Operations() {
+		if !node.SupportedMethods[op.Method] {
+			skipped = append(skipped, op.Name())
+			continue
+		}
+		group := ""
+		if opts.GroupByTag {
+			group = untaggedGroup
+			if len(op.Tags) > 0 {
+				group = op.Tags[0]
+			}
+		}
+		if _, ok := operations[group]; !ok {
+			groups = append(groups, group)
+		}
+		operations[group] = append(operations[group], op)
+	}
+
+	flows := make([]*models.Flow, 0, len(groups))
+	for _, group := range groups {
+		flow := &models.Flow{
+			Name:        name,
+			Description: fmt.Sprintf("Generated from %s", describeDocument(doc)),
+			Tags:        []string{"openapi"},
+			Variables:   map[string]interface{}{"baseUrl": baseURL},
+		}
+		if group != "" {
+			flow.Name = name + " - " + group
+			flow.Tags = append(flow.Tags, group)
+		}
+
+		b := &openAPIBuilder{doc: doc, flow: flow, specID: opts.SpecID, nodeIDs: make(map[string]bool)}
+		for i, op := range operations[group] {
+			b.addOperation(op, float64(i))
+		}
+		flows = append(flows, flow)
+	}
+	return flows, skipped
+}
+
+func describeDocument(doc *openapi.Document) string {
+	title := doc.Title
+	if title == "" {
+		title = "an OpenAPI document"
+	}
+	if doc.Version != "" {
+		title += " " + doc.Version
+	}
+	return title
+}
+
+// openAPIBuilder adds the nodes of one generated flow
+type openAPIBuilder struct {
+	doc     *openapi.Document
+	flow    *models.Flow
+	specID  *uuid.UUID
+	nodeIDs map[string]bool
+}
+
+func (b *openAPIBuilder) addOperation(op *openapi.Operation, row float64) {
+	label := op.Summary
+	if label == "" {
+		label = op.Method + " " + op.Path
+	}
+
+	apiID := uniqueID(b.nodeIDs, slug(op.Name()))
+	config := b.requestConfig(op)
+	if b.specID != nil {
+		binding := map[string]interface{}{"specId": b.specID.String()}
+		if op.ID != "" {
+			binding["operationId"] = op.ID
+		} else {
+			binding["method"], binding["path"] = op.Method, op.Path
+		}
+		config["contract"] = binding
+	}
+	b.flow.Nodes = append(b.flow.Nodes, newNode(apiID, string(node.NodeTypeAPI), label, config, 100, 100+row*160))
+
+	checkID := uniqueID(b.nodeIDs, apiID+"-check")
+	b.flow.Nodes = append(b.flow.Nodes, newNode(checkID, string(node.NodeTypeVerification), "Verify "+label, b.checkConfig(op), 450, 100+row*160))
+	b.flow.Edges = append(b.flow.Edges, models.FlowEdge{ID: "edge-" + apiID + "-" + checkID, Source: apiID, Target: checkID})
+}
+
+// requestConfig builds the API node config: the URL with example path and
+// required query parameters, required headers, credentials and an example body
+func (b *openAPIBuilder) requestConfig(op *openapi.Operation) map[string]interface{} {
+	path := op.Path
+	headers := make(map[string]interface{})
+	var query []string
+
+	for _, p := range op.Parameters() {
+		if !p.Required {
+			continue
+		}
+		example := p.Example
+		if example == nil {
+			example = b.doc.Example(p.Schema)
+		}
+		value := formatValue(example)
+		switch p.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(value))
+		case "query":
+			query = append(query, url.QueryEscape(p.Name)+"="+url.QueryEscape(value))
+		case "header":
+			headers[p.Name] = value
+		case "cookie":
+			appendCookie(headers, p.Name+"="+value)
+		}
+	}
+
+	for _, scheme := range op.Security() {
+		placeholder := "{{" + b.credentialVariable(scheme.Name) + "}}"
+		switch {
+		case scheme.Type == "apiKey" && scheme.In == "header":
+			headers[scheme.Param] = placeholder
+		case scheme.Type == "apiKey" && scheme.In == "query":
+			query = append(query, url.QueryEscape(scheme.Param)+"="+placeholder)
+		case scheme.Type == "apiKey" && scheme.In == "cookie":
+			appendCookie(headers, scheme.Param+"="+placeholder)
+		case scheme.Type == "http" && scheme.Scheme == "basic":
+			headers["Authorization"] = "Basic " + placeholder
+		case scheme.Type == "http" && scheme.Scheme != "" && scheme.Scheme != "bearer":
+			headers["Authorization"] = strings.ToUpper(scheme.Scheme[:1]) + scheme.Scheme[1:] + " " + placeholder
+		default:
+			headers["Authorization"] = "Bearer " + placeholder
+		}
+	}
+
+	target := "{{baseUrl}}" + path
+	if len(query) > 0 {
+		target += "?" + strings.Join(query, "&")
+	}
+	config := map[string]interface{}{
+		"method": op.Method,
+		"url":    target,
+	}
+
+	if body := op.RequestBody(); body != nil {
+		example := body.Example
+		if example == nil {
+			example = b.doc.Example(body.Schema)
+		}
+		if text, ok := example.(string); ok && !strings.Contains(body.MediaType, "json") {
+			config["body"] = text
+		} else if encoded, err := json.MarshalIndent(example, "", "  "); err == nil {
+			config["body"] = string(encoded)
+		}
+		headers["Content-Type"] = body.MediaType
+	}
+
+	if len(headers) > 0 {
+		config["headers"] = headers
+	}
+	return config
+}
+
+// checkConfig builds a response assertion for the documented success status
+// and, for JSON responses, its schema
+func (b *openAPIBuilder) checkConfig(op *openapi.Operation) map[string]interface{} {
+	code, body := op.SuccessResponse()
+	config := map[string]interface{}{"assertionType": "response"}
+	if status, err := strconv.Atoi(code); err == nil {
+		config["status"] = float64(status)
+	} else {
+		config["status"] = "2xx"
+	}
+	if body != nil && body.Schema != nil && strings.Contains(body.MediaType, "json") {
+		config["schema"] = b.doc.Standalone(body.Schema)
+	}
+	return config
+}
+
+// credentialVariable declares the flow variable holding a security scheme's
+// credential and returns its name
+func (b *openAPIBuilder) credentialVariable(scheme string) string {
+	name := nonIdentifier.ReplaceAllString(scheme, "_")
+	if _, ok := b.flow.Variables[name]; !ok {
+		b.flow.Variables[name] = ""
+	}
+	return name
+}
+
+func newNode(id, nodeType, label string, config map[string]interface{}, x, y float64) models.FlowNode {
+	return models.FlowNode{
+		ID:       id,
+		Type:     "custom",
+		Position: models.Position{X: x, Y: y},
+		Data: models.NodeData{
+			ID:     id,
+			Type:   nodeType,
+			Label:  label,
+			Config: config,
+		},
+	}
+}
+
+func appendCookie(headers map[string]interface{}, cookie string) {
+	if existing, ok := headers["Cookie"].(string); ok && existing != "" {
+		cookie = existing + "; " + cookie
+	}
+	headers["Cookie"] = cookie
+}
+
+// formatValue renders an example value for a URL or header
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = formatValue(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+var (
+	nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+	nonSlug       = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// slug turns an operation name into a node ID, e.g. "GET /pets/{id}" into get-pets-id
+func slug(name string) string {
+	s := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if s == "" {
+		s = "operation"
+	}
+	return s
+}
+
+func uniqueID(taken map[string]bool, base string) string {
+	id := base
+	for i := 2; taken[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	taken[id] = true
+	return id
+}
@@ -0,0 +1,342 @@
+package importer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/openapi"
+)
+
+const petStore = `
+openapi: 3.0.3
+info: {title: Pet Store, version: 1.2.0}
+servers:
+  - url: https://{env}.pets.test/v1/
+    variables: {env: {default: api}}
+security:
+  - bearerAuth: []
+components:
+  securitySchemes:
+    bearerAuth: {type: http, scheme: bearer}
+    apiKeyHeader: {type: apiKey, in: header, name: X-API-Key}
+    basic-auth: {type: http, scheme: Basic}
+    queryKey: {type: apiKey, in: query, name: api_key}
+    cookieKey: {type: apiKey, in: cookie, name: session}
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id: {type: integer, readOnly: true}
+        name: {type: string}
+        tag: {type: string, enum: [dog, cat]}
+        age: {type: integer, minimum: 1}
+        born: {type: string, format: date}
+paths:
+  /health:
+    get:
+      security: []
+      responses:
+        "200": {description: ok, content: {text/plain: {schema: {type: string}}}}
+  /login:
+    post:
+      operationId: login
+      tags: [auth]
+      security: [{queryKey: [], cookieKey: []}]
+      parameters:
+        - {name: X-Request-Id, in: header, required: true, schema: {type: string, format: uuid}}
+      requestBody:
+        content:
+          application/x-www-form-urlencoded:
+            example: user=a&pass=b
+      responses:
+        "204": {description: signed in}
+  /pets:
+    get:
+      operationId: listPets
+      summary: List pets
+      tags: [pets]
+      parameters:
+        - {name: limit, in: query, required: true, schema: {type: integer, minimum: 1}}
+        - {name: offset, in: query, schema: {type: integer}}
+      responses:
+        "200":
+          description: ok
+          content: {application/json: {schema: {type: array, items: {$ref: "#/components/schemas/Pet"}}}}
+        default: {description: error}
+    post:
+      operationId: createPet
+      tags: [pets]
+      security: [{apiKeyHeader: []}]
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/Pet"}
+      responses:
+        "201":
+          description: created
+          content: {application/json: {schema: {$ref: "#/components/schemas/Pet"}}}
+        "400": {description: invalid pet}
+  /pets/{petId}:
+    parameters:
+      - {name: petId, in: path, required: true, schema: {type: integer}, example: 42}
+    get:
+      operationId: getPet
+      tags: [pets]
+      responses:
+        2XX: {description: ok}
+    delete:
+      operationId: deletePet
+      tags: [admin, pets]
+      security: [{basic-auth: []}]
+      responses:
+        "204": {description: deleted}
+    trace:
+      operationId: tracePet
+      responses:
+        "200": {description: ok}
+`
+
+func parsePetStore(t *testing.T) *openapi.Document {
+	t.Helper()
+	decoded, err := openapi.Decode([]byte(petStore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi.Parse(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func nodeByID(flow *models.Flow, id string) *models.FlowNode {
+	for i := range flow.Nodes {
+		if flow.Nodes[i].ID == id {
+			return &flow.Nodes[i]
+		}
+	}
+	return nil
+}
+
+func TestFromOpenAPI(t *testing.T) {
+	flows, skipped := FromOpenAPI(parsePetStore(t), OpenAPIOptions{})
+	if want := []string{"tracePet"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped = %q, want %q", skipped, want)
+	}
+	if len(flows) != 1 {
+		t.Fatalf("got %d flows, want 1", len(flows))
+	}
+	flow := flows[0]
+
+	if flow.Name != "Pet Store" || flow.Description != "Generated from Pet Store 1.2.0" || !reflect.DeepEqual(flow.Tags, []string{"openapi"}) {
+		t.Errorf("got %q %q %q", flow.Name, flow.Description, flow.Tags)
+	}
+
+	// baseUrl comes from the first server, and each security scheme gets a
+	// variable for its credential
+	wantVariables := map[string]interface{}{
+		"baseUrl":      "https://api.pets.test/v1",
+		"bearerAuth":   "",
+		"apiKeyHeader": "",
+		"basic_auth":   "",
+		"queryKey":     "",
+		"cookieKey":    "",
+	}
+	if !reflect.DeepEqual(flow.Variables, wantVariables) {
+		t.Errorf("variables = %v, want %v", flow.Variables, wantVariables)
+	}
+
+	// An API node and a verification node per operation, in document order
+	var ids []string
+	for _, n := range flow.Nodes {
+		ids = append(ids, n.ID+" "+n.Data.Type)
+	}
+	wantIDs := []string{
+		"get-health api", "get-health-check verification",
+		"login api", "login-check verification",
+		"listpets api", "listpets-check verification",
+		"createpet api", "createpet-check verification",
+		"getpet api", "getpet-check verification",
+		"deletepet api", "deletepet-check verification",
+	}
+	if !reflect.DeepEqual(ids, wantIDs) {
+		t.Errorf("nodes = %q, want %q", ids, wantIDs)
+	}
+	for i, e := range flow.Edges {
+		if api, check := flow.Nodes[2*i].ID, flow.Nodes[2*i+1].ID; e.Source != api || e.Target != check {
+			t.Errorf("edge %d = %s -> %s, want %s -> %s", i, e.Source, e.Target, api, check)
+		}
+	}
+	if len(flow.Edges) != 6 {
+		t.Errorf("got %d edges, want 6", len(flow.Edges))
+	}
+
+	requests := []struct {
+		id     string
+		label  string
+		config map[string]interface{}
+	}{
+		{
+			id:     "get-health",
+			label:  "GET /health",
+			config: map[string]interface{}{"method": "GET", "url": "{{baseUrl}}/health"},
+		},
+		{
+			id:    "login",
+			label: "POST /login",
+			config: map[string]interface{}{
+				"method": "POST",
+				"url":    "{{baseUrl}}/login?api_key={{queryKey}}",
+				"headers": map[string]interface{}{
+					"X-Request-Id": "00000000-0000-4000-8000-000000000000",
+					"Cookie":       "session={{cookieKey}}",
+					"Content-Type": "application/x-www-form-urlencoded",
+				},
+				"body": "user=a&pass=b",
+			},
+		},
+		{
+			id:    "listpets",
+			label: "List pets",
+			config: map[string]interface{}{
+				"method":  "GET",
+				"url":     "{{baseUrl}}/pets?limit=1",
+				"headers": map[string]interface{}{"Authorization": "Bearer {{bearerAuth}}"},
+			},
+		},
+		{
+			id:    "createpet",
+			label: "POST /pets",
+			config: map[string]interface{}{
+				"method":  "POST",
+				"url":     "{{baseUrl}}/pets",
+				"headers": map[string]interface{}{"X-API-Key": "{{apiKeyHeader}}", "Content-Type": "application/json"},
+				"body":    "{\n  \"age\": 1,\n  \"born\": \"2024-01-01\",\n  \"name\": \"string\",\n  \"tag\": \"dog\"\n}",
+			},
+		},
+		{
+			id:    "getpet",
+			label: "GET /pets/{petId}",
+			config: map[string]interface{}{
+				"method":  "GET",
+				"url":     "{{baseUrl}}/pets/42",
+				"headers": map[string]interface{}{"Authorization": "Bearer {{bearerAuth}}"},
+			},
+		},
+		{
+			id:    "deletepet",
+			label: "DELETE /pets/{petId}",
+			config: map[string]interface{}{
+				"method":  "DELETE",
+				"url":     "{{baseUrl}}/pets/42",
+				"headers": map[string]interface{}{"Authorization": "Basic {{basic_auth}}"},
+			},
+		},
+	}
+	for _, r := range requests {
+		n := nodeByID(flow, r.id)
+		if n == nil {
+			t.Errorf("no node %s", r.id)
+			continue
+		}
+		if n.Data.Label != r.label {
+			t.Errorf("%s: label = %q, want %q", r.id, n.Data.Label, r.label)
+		}
+		if !reflect.DeepEqual(n.Data.Config, r.config) {
+			t.Errorf("%s: config =\n%#v\nwant\n%#v", r.id, n.Data.Config, r.config)
+		}
+	}
+
+	// Verification nodes check the lowest documented 2xx status, and the
+	// response schema of JSON responses, with references copied in
+	checks := map[string]interface{}{
+		"get-health-check": 200.0,
+		"login-check":      204.0,
+		"listpets-check":   200.0,
+		"createpet-check":  201.0,
+		"getpet-check":     "2xx",
+		"deletepet-check":  204.0,
+	}
+	for id, status := range checks {
+		n := nodeByID(flow, id)
+		if n == nil {
+			t.Errorf("no node %s", id)
+			continue
+		}
+		if n.Data.Config["assertionType"] != "response" || n.Data.Config["status"] != status {
+			t.Errorf("%s: config = %v, want a response assertion on status %v", id, n.Data.Config, status)
+		}
+		_, hasSchema := n.Data.Config["schema"]
+		if wantSchema := id == "listpets-check" || id == "createpet-check"; hasSchema != wantSchema {
+			t.Errorf("%s: has schema = %v, want %v", id, hasSchema, wantSchema)
+		}
+	}
+
+	schema, _ := nodeByID(flow, "createpet-check").Data.Config["schema"].(map[string]interface{})
+	defs, _ := schema["$defs"].(map[string]interface{})
+	if schema["$ref"] != "#/$defs/Pet" || defs["Pet"] == nil {
+		encoded, _ := json.Marshal(schema)
+		t.Errorf("createpet-check schema = %s, want a standalone reference to Pet", encoded)
+	}
+}
+
+func TestFromOpenAPIGroupByTag(t *testing.T) {
+	flows, _ := FromOpenAPI(parsePetStore(t), OpenAPIOptions{Name: "Pets", GroupByTag: true, BaseURL: "http://localhost:8080/"})
+
+	type group struct {
+		name      string
+		tags      []string
+		nodes     []string
+		variables map[string]interface{}
+	}
+	var got []group
+	for _, f := range flows {
+		g := group{name: f.Name, tags: f.Tags, variables: f.Variables}
+		for _, n := range f.Nodes {
+			if n.Data.Type == "api" {
+				g.nodes = append(g.nodes, n.ID)
+			}
+		}
+		got = append(got, g)
+	}
+
+	// Operations go to the flow of their first tag, and each flow only
+	// declares the credentials its operations use
+	base := "http://localhost:8080"
+	want := []group{
+		{name: "Pets - untagged", tags: []string{"openapi", "untagged"}, nodes: []string{"get-health"},
+			variables: map[string]interface{}{"baseUrl": base}},
+		{name: "Pets - auth", tags: []string{"openapi", "auth"}, nodes: []string{"login"},
+			variables: map[string]interface{}{"baseUrl": base, "queryKey": "", "cookieKey": ""}},
+		{name: "Pets - pets", tags: []string{"openapi", "pets"}, nodes: []string{"listpets", "createpet", "getpet"},
+			variables: map[string]interface{}{"baseUrl": base, "bearerAuth": "", "apiKeyHeader": ""}},
+		{name: "Pets - admin", tags: []string{"openapi", "admin"}, nodes: []string{"deletepet"},
+			variables: map[string]interface{}{"baseUrl": base, "basic_auth": ""}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("flows =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestFromOpenAPIContracts(t *testing.T) {
+	specID := uuid.New()
+	flows, _ := FromOpenAPI(parsePetStore(t), OpenAPIOptions{SpecID: &specID})
+
+	tests := map[string]map[string]interface{}{
+		"get-health": {"specId": specID.String(), "method": "GET", "path": "/health"},
+		"createpet":  {"specId": specID.String(), "operationId": "createPet"},
+	}
+	for id, want := range tests {
+		if got := nodeByID(flows[0], id).Data.Config["contract"]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: contract = %v, want %v", id, got, want)
+		}
+	}
+	if _, ok := nodeByID(flows[0], "createpet-check").Data.Config["contract"]; ok {
+		t.Error("verification nodes should not be bound to the contract")
+	}
+}
@@ -0,0 +1,474 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// PostmanOptions configures FromPostman
+type PostmanOptions struct {
+	// Name names the flow and defaults to the collection name. Flows grouped
+	// by folder are named "<name> - <folder>".
+	Name string
+
+	// GroupByFolder generates one flow per top-level folder, plus one for
+	// requests outside folders, instead of one flow for the whole collection
+	GroupByFolder bool
+}
+
+type postmanCollection struct {
+	Info struct {
+		Name        string          `json:"name"`
+		Schema      string          `json:"schema"`
+		Description json.RawMessage `json:"description"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanVariable `json:"variable"`
+	Auth     *postmanAuth      `json:"auth"`
+}
+
+// postmanItem is a request, or a folder when it has items
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Request json.RawMessage `json:"request"`
+	Item    []postmanItem   `json:"item"`
+	Auth    *postmanAuth    `json:"auth"`
+}
+
+type postmanRequest struct {
+	Method string          `json:"method"`
+	URL    json.RawMessage `json:"url"`
+	Header json.RawMessage `json:"header"`
+	Body   *postmanBody    `json:"body"`
+	Auth   *postmanAuth    `json:"auth"`
+}
+
+type postmanURL struct {
+	Raw      string         `json:"raw"`
+	Query    []postmanParam `json:"query"`
+	Variable []postmanParam `json:"variable"`
+}
+
+// postmanParam is a header, query parameter, form field or path variable
+type postmanParam struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Type     string      `json:"type"`
+	Disabled bool        `json:"disabled"`
+}
+
+type postmanBody struct {
+	Mode       string         `json:"mode"`
+	Raw        string         `json:"raw"`
+	URLEncoded []postmanParam `json:"urlencoded"`
+	FormData   []postmanParam `json:"formdata"`
+	GraphQL    *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+	Disabled bool `json:"disabled"`
+}
+
+// postmanAuth holds the auth type and its parameters, a list of key-value
+// pairs in v2.1 collections and an object in v2.0 ones
+type postmanAuth struct {
+	Type   string
+	params map[string]json.RawMessage
+}
+
+func (a *postmanAuth) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.params); err != nil {
+		return err
+	}
+	json.Unmarshal(a.params["type"], &a.Type)
+	return nil
+}
+
+// param returns an auth parameter such as the bearer token
+func (a *postmanAuth) param(key string) string {
+	raw := a.params[a.Type]
+	var list []postmanParam
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for _, p := range list {
+			if p.Key == key {
+				return formatValue(p.Value)
+			}
+		}
+		return ""
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(raw, &fields)
+	return formatValue(fields[key])
+}
+
+type postmanVariable struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Disabled bool        `json:"disabled"`
+}
+
+type postmanEnvironment struct {
+	Values []struct {
+		Key     string      `json:"key"`
+		Value   interface{} `json:"value"`
+		Enabled *bool       `json:"enabled"`
+	} `json:"values"`
+}
+
+// postmanGroup collects the requests of one generated flow
+type postmanGroup struct {
+	folder   string
+	tags     []string
+	requests []request
+}
+
+// FromPostman generates flows from a Postman v2.0 or v2.1 collection, with
+// the collection's requests chained as API nodes in order. Collection
+// variables, overridden by the optional environment's, become flow variables;
+// Postman's {{name}} placeholders work as they do in Postman. Folder names
+// become flow tags. Requests and parts of requests that can't be imported
+// are returned with the reason.
+func FromPostman(collection, environment []byte, opts PostmanOptions) ([]*models.Flow, []string, error) {
+	var c postmanCollection
+	if err := json.Unmarshal(collection, &c); err != nil {
+		return nil, nil, fmt.Errorf("invalid collection: %w", err)
+	}
+	if c.Info.Schema != "" && !strings.Contains(c.Info.Schema, "v2.") {
+		return nil, nil, fmt.Errorf("unsupported collection schema %s, export the collection as v2.1", c.Info.Schema)
+	}
+	if c.Item == nil {
+		return nil, nil, fmt.Errorf("invalid collection: no items")
+	}
+
+	variables := make(map[string]interface{})
+	for _, v := range c.Variable {
+		if !v.Disabled && v.Key != "" {
+			variables[v.Key] = v.Value
+		}
+	}
+	if len(environment) > 0 {
+		var env postmanEnvironment
+		if err := json.Unmarshal(environment, &env); err != nil {
+			return nil, nil, fmt.Errorf("invalid environment: %w", err)
+		}
+		for _, v := range env.Values {
+			if (v.Enabled == nil || *v.Enabled) && v.Key != "" {
+				variables[v.Key] = v.Value
+			}
+		}
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = c.Info.Name
+	}
+	if name == "" {
+		name = "Postman collection"
+	}
+
+	p := &postmanImporter{}
+	root := &postmanGroup{}
+	groups := []*postmanGroup{root}
+	for _, item := range c.Item {
+		if !item.isFolder() {
+			p.addItem(root, item, "", c.Auth)
+			continue
+		}
+		group := root
+		if opts.GroupByFolder {
+			group = &postmanGroup{folder: item.Name}
+			groups = append(groups, group)
+		}
+		p.addItem(group, item, "", c.Auth)
+	}
+
+	description := postmanDescription(c.Info.Description)
+	var flows []*models.Flow
+	for _, g := range groups {
+		if len(g.requests) == 0 {
+			continue
+		}
+		flowName := name
+		if g.folder != "" {
+			flowName = name + " - " + g.folder
+		}
+		flow, skipped := sequentialFlow(flowName, description, append([]string{"postman"}, g.tags...), g.requests)
+		p.skipped = append(p.skipped, skipped...)
+		if len(flow.Nodes) == 0 {
+			continue
+		}
+		for k, v := range variables {
+			flow.Variables[k] = v
+		}
+		flows = append(flows, flow)
+	}
+	return flows, p.skipped, nil
+}
+
+func (item postmanItem) isFolder() bool {
+	return len(item.Request) == 0 || string(item.Request) == "null"
+}
+
+func postmanDescription(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var object struct {
+		Content string `json:"content"`
+	}
+	json.Unmarshal(raw, &object)
+	return object.Content
+}
+
+// postmanImporter converts collection items, collecting what it skips
+type postmanImporter struct {
+	skipped []string
+}
+
+func (p *postmanImporter) skip(format string, args ...interface{}) {
+	p.skipped = append(p.skipped, fmt.Sprintf(format, args...))
+}
+
+// addItem adds a request, or the requests of a folder depth first, to group.
+// folder is the path of the folders containing item and auth the inherited auth.
+func (p *postmanImporter) addItem(group *postmanGroup, item postmanItem, folder string, auth *postmanAuth) {
+	if item.Auth != nil && item.Auth.Type != "inherit" {
+		auth = item.Auth
+	}
+	if !item.isFolder() {
+		if r, ok := p.convertRequest(item, folder, auth); ok {
+			group.requests = append(group.requests, r)
+		}
+		return
+	}
+
+	group.tags = appendTag(group.tags, item.Name)
+	path := item.Name
+	if folder != "" {
+		path = folder + " / " + item.Name
+	}
+	for _, child := range item.Item {
+		p.addItem(group, child, path, auth)
+	}
+}
+
+func appendTag(tags []string, tag string) []string {
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+func (p *postmanImporter) convertRequest(item postmanItem, folder string, auth *postmanAuth) (request, bool) {
+	name := item.Name
+	if folder != "" {
+		name = folder + " / " + item.Name
+	}
+
+	var req postmanRequest
+	var address string
+	if err := json.Unmarshal(item.Request, &address); err == nil {
+		req.URL, _ = json.Marshal(address)
+	} else if err := json.Unmarshal(item.Request, &req); err != nil {
+		p.skip("%s: invalid request: %v", name, err)
+		return request{}, false
+	}
+
+	r := request{name: item.Name, method: req.Method}
+	if r.method == "" {
+		r.method = "GET"
+	}
+	r.url = postmanAddress(req.URL)
+	if r.url == "" {
+		p.skip("%s: no URL", name)
+		return request{}, false
+	}
+
+	var headers []postmanParam
+	var text string
+	if err := json.Unmarshal(req.Header, &headers); err != nil && json.Unmarshal(req.Header, &text) == nil {
+		for _, line := range strings.Split(text, "\n") {
+			if key, value, ok := strings.Cut(line, ":"); ok {
+				headers = append(headers, postmanParam{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)})
+			}
+		}
+	}
+	for _, h := range headers {
+		if !h.Disabled && h.Key != "" && !isSkippedHeader(h.Key) {
+			r.header(h.Key, formatValue(h.Value))
+		}
+	}
+
+	if req.Auth != nil && req.Auth.Type != "inherit" {
+		auth = req.Auth
+	}
+	p.applyAuth(&r, name, auth)
+
+	if req.Body != nil && !req.Body.Disabled {
+		p.applyBody(&r, name, req.Body)
+	}
+	return r, true
+}
+
+// postmanAddress reads a URL given as a string or as an object, dropping
+// disabled query parameters and filling in path variables such as :id
+func postmanAddress(raw json.RawMessage) string {
+	var address string
+	if err := json.Unmarshal(raw, &address); err != nil {
+		var u postmanURL
+		if json.Unmarshal(raw, &u) != nil {
+			return ""
+		}
+		address = u.Raw
+		if u.Query != nil {
+			address, _, _ = strings.Cut(address, "?")
+			var query []string
+			for _, q := range u.Query {
+				if q.Disabled || q.Key == "" {
+					continue
+				}
+				query = append(query, q.Key+"="+formatValue(q.Value))
+			}
+			if len(query) > 0 {
+				address += "?" + strings.Join(query, "&")
+			}
+		}
+		for _, v := range u.Variable {
+			if value := formatValue(v.Value); v.Key != "" && value != "" {
+				address = pathVariable(v.Key).ReplaceAllString(address, "/"+strings.ReplaceAll(value, "$", "$$")+"$1")
+			}
+		}
+	}
+
+	address = strings.TrimSpace(address)
+	if address != "" && !strings.Contains(address, "://") && !strings.HasPrefix(address, "{{") {
+		address = "http://" + address
+	}
+	return address
+}
+
+func pathVariable(name string) *regexp.Regexp {
+	return regexp.MustCompile(`/:` + regexp.QuoteMeta(name) + `([/?#]|$)`)
+}
+
+func (p *postmanImporter) applyAuth(r *request, name string, auth *postmanAuth) {
+	if auth == nil {
+		return
+	}
+	switch auth.Type {
+	case "noauth", "inherit", "":
+	case "bearer":
+		r.header("Authorization", "Bearer "+auth.param("token"))
+	case "basic":
+		credentials := auth.param("username") + ":" + auth.param("password")
+		if strings.Contains(credentials, "{{") {
+			p.skip("%s: basic auth credentials use variables; set the Authorization header instead", name)
+			return
+		}
+		r.header("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	case "apikey":
+		key, value := auth.param("key"), auth.param("value")
+		if auth.param("in") == "query" {
+			separator := "?"
+			if strings.Contains(r.url, "?") {
+				separator = "&"
+			}
+			r.url += separator + key + "=" + value
+		} else {
+			r.header(key, value)
+		}
+	default:
+		p.skip("%s: %s auth is not supported", name, auth.Type)
+	}
+}
+
+// postmanLanguages maps raw body languages to content types
+var postmanLanguages = map[string]string{
+	"json":       "application/json",
+	"xml":        "application/xml",
+	"html":       "text/html",
+	"javascript": "application/javascript",
+	"text":       "text/plain",
+}
+
+func (p *postmanImporter) applyBody(r *request, name string, body *postmanBody) {
+	switch body.Mode {
+	case "raw":
+		if body.Raw == "" {
+			return
+		}
+		r.body = body.Raw
+		contentType, ok := postmanLanguages[body.Options.Raw.Language]
+		if !ok {
+			contentType = "text/plain"
+		}
+		r.header("Content-Type", contentType)
+	case "urlencoded":
+		var fields []string
+		for _, f := range body.URLEncoded {
+			if !f.Disabled && f.Key != "" {
+				fields = append(fields, escapeKeepingVariables(f.Key)+"="+escapeKeepingVariables(formatValue(f.Value)))
+			}
+		}
+		r.body = strings.Join(fields, "&")
+		r.header("Content-Type", "application/x-www-form-urlencoded")
+	case "formdata":
+		var fields []formField
+		for _, f := range body.FormData {
+			if f.Disabled || f.Key == "" {
+				continue
+			}
+			if f.Type == "file" {
+				p.skip("%s: file field %s is not supported", name, f.Key)
+				continue
+			}
+			fields = append(fields, formField{name: f.Key, value: formatValue(f.Value)})
+		}
+		encoded, contentType := multipartBody(fields)
+		r.body = encoded
+		r.header("Content-Type", contentType)
+	case "graphql":
+		if body.GraphQL == nil {
+			return
+		}
+		payload := map[string]interface{}{"query": body.GraphQL.Query}
+		var variables interface{}
+		if json.Unmarshal([]byte(body.GraphQL.Variables), &variables) == nil && variables != nil {
+			payload["variables"] = variables
+		}
+		encoded, _ := json.Marshal(payload)
+		r.body = string(encoded)
+		r.header("Content-Type", "application/json")
+	case "file":
+		p.skip("%s: file bodies are not supported", name)
+	}
+}
+
+var placeholder = regexp.MustCompile(`\{\{[^{}]+\}\}`)
+
+// escapeKeepingVariables form-encodes s but leaves {{name}} placeholders intact
+func escapeKeepingVariables(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, match := range placeholder.FindAllStringIndex(s, -1) {
+		b.WriteString(url.QueryEscape(s[last:match[0]]))
+		b.WriteString(s[match[0]:match[1]])
+		last = match[1]
+	}
+	b.WriteString(url.QueryEscape(s[last:]))
+	return b.String()
+}
@@ -0,0 +1,270 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+const postmanFixture = `{
+	"info": {"name": "Shop", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json", "description": "Shop API"},
+	"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]},
+	"variable": [
+		{"key": "baseUrl", "value": "https://shop.test"},
+		{"key": "token", "value": "collection-token"},
+		{"key": "unused", "value": "x", "disabled": true}
+	],
+	"item": [
+		{"name": "Health", "request": "{{baseUrl}}/health"},
+		{
+			"name": "Users",
+			"item": [
+				{
+					"name": "Get user",
+					"request": {
+						"method": "GET",
+						"url": {
+							"raw": "{{baseUrl}}/users/:id?verbose=1&debug=1",
+							"query": [{"key": "verbose", "value": "1"}, {"key": "debug", "value": "1", "disabled": true}],
+							"variable": [{"key": "id", "value": "42"}]
+						},
+						"header": [
+							{"key": "Accept", "value": "application/json"},
+							{"key": "X-Off", "value": "1", "disabled": true},
+							{"key": "Content-Length", "value": "10"}
+						]
+					}
+				},
+				{
+					"name": "Admin",
+					"auth": {"type": "apikey", "apikey": [{"key": "key", "value": "api_key"}, {"key": "value", "value": "{{adminKey}}"}, {"key": "in", "value": "query"}]},
+					"item": [
+						{"name": "Delete user", "request": {"method": "DELETE", "url": "{{baseUrl}}/users/42"}}
+					]
+				}
+			]
+		},
+		{
+			"name": "Orders",
+			"item": [
+				{
+					"name": "Create order",
+					"request": {
+						"method": "POST",
+						"url": "{{baseUrl}}/orders",
+						"auth": {"type": "noauth"},
+						"body": {"mode": "raw", "raw": "{\"sku\":\"{{sku}}\"}", "options": {"raw": {"language": "json"}}}
+					}
+				}
+			]
+		}
+	]
+}`
+
+const postmanEnvironmentFixture = `{
+	"values": [
+		{"key": "baseUrl", "value": "https://staging.shop.test", "enabled": true},
+		{"key": "sku", "value": "A-1"},
+		{"key": "token", "value": "disabled-token", "enabled": false}
+	]
+}`
+
+// nodeSummary is the part of an imported API node the tests compare
+type nodeSummary struct {
+	ID      string
+	Label   string
+	Method  string
+	URL     string
+	Headers map[string]interface{}
+	Body    string
+}
+
+func summarize(nodes []models.FlowNode) []nodeSummary {
+	var out []nodeSummary
+	for _, n := range nodes {
+		s := nodeSummary{ID: n.ID, Label: n.Data.Label}
+		s.Method, _ = n.Data.Config["method"].(string)
+		s.URL, _ = n.Data.Config["url"].(string)
+		s.Headers, _ = n.Data.Config["headers"].(map[string]interface{})
+		s.Body, _ = n.Data.Config["body"].(string)
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestFromPostman(t *testing.T) {
+	flows, skipped, err := FromPostman([]byte(postmanFixture), nil, PostmanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("skipped = %q", skipped)
+	}
+	if len(flows) != 1 {
+		t.Fatalf("got %d flows, want 1", len(flows))
+	}
+
+	flow := flows[0]
+	if flow.Name != "Shop" || flow.Description != "Shop API" {
+		t.Errorf("name = %q, description = %q", flow.Name, flow.Description)
+	}
+	if want := []string{"postman", "Users", "Admin", "Orders"}; !reflect.DeepEqual(flow.Tags, want) {
+		t.Errorf("tags = %q, want %q", flow.Tags, want)
+	}
+
+	bearer := map[string]interface{}{"Authorization": "Bearer {{token}}"}
+	want := []nodeSummary{
+		{ID: "health", Label: "Health", Method: "GET", URL: "{{baseUrl}}/health", Headers: bearer},
+		{
+			ID: "get-user", Label: "Get user", Method: "GET", URL: "{{baseUrl}}/users/42?verbose=1",
+			Headers: map[string]interface{}{"Accept": "application/json", "Authorization": "Bearer {{token}}"},
+		},
+		{
+			ID: "delete-user", Label: "Delete user", Method: "DELETE", URL: "{{baseUrl}}/users/42?api_key={{adminKey}}",
+		},
+		{
+			ID: "create-order", Label: "Create order", Method: "POST", URL: "{{baseUrl}}/orders",
+			Headers: map[string]interface{}{"Content-Type": "application/json"}, Body: `{"sku":"{{sku}}"}`,
+		},
+	}
+	if got := summarize(flow.Nodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("nodes =\n%+v\nwant\n%+v", got, want)
+	}
+	if len(flow.Edges) != 3 {
+		t.Errorf("got %d edges, want 3", len(flow.Edges))
+	}
+
+	wantVariables := map[string]interface{}{"baseUrl": "https://shop.test", "token": "collection-token"}
+	if !reflect.DeepEqual(flow.Variables, wantVariables) {
+		t.Errorf("variables = %v, want %v", flow.Variables, wantVariables)
+	}
+}
+
+func TestFromPostmanGroupByFolder(t *testing.T) {
+	flows, _, err := FromPostman([]byte(postmanFixture), nil, PostmanOptions{Name: "Store", GroupByFolder: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type group struct {
+		name  string
+		tags  []string
+		nodes []string
+	}
+	var got []group
+	for _, f := range flows {
+		g := group{name: f.Name, tags: f.Tags}
+		for _, n := range f.Nodes {
+			g.nodes = append(g.nodes, n.ID)
+		}
+		got = append(got, g)
+	}
+
+	// Nested folders stay in the flow of their top-level folder
+	want := []group{
+		{name: "Store", tags: []string{"postman"}, nodes: []string{"health"}},
+		{name: "Store - Users", tags: []string{"postman", "Users", "Admin"}, nodes: []string{"get-user", "delete-user"}},
+		{name: "Store - Orders", tags: []string{"postman", "Orders"}, nodes: []string{"create-order"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("flows =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestFromPostmanEnvironment(t *testing.T) {
+	flows, _, err := FromPostman([]byte(postmanFixture), []byte(postmanEnvironmentFixture), PostmanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Enabled environment values override collection variables; disabled ones don't
+	want := map[string]interface{}{
+		"baseUrl": "https://staging.shop.test",
+		"token":   "collection-token",
+		"sku":     "A-1",
+	}
+	if got := flows[0].Variables; !reflect.DeepEqual(got, want) {
+		t.Errorf("variables = %v, want %v", got, want)
+	}
+}
+
+func TestFromPostmanBodiesAndAuth(t *testing.T) {
+	collection := `{
+		"info": {"name": "Bodies"},
+		"item": [
+			{"name": "Form", "request": {"method": "POST", "url": "https://api.test/form", "body": {"mode": "urlencoded", "urlencoded": [
+				{"key": "name", "value": "a b&c"}, {"key": "id", "value": "{{id}}"}, {"key": "off", "value": "1", "disabled": true}
+			]}}},
+			{"name": "GraphQL", "request": {"method": "POST", "url": "https://api.test/graphql", "body": {"mode": "graphql", "graphql": {
+				"query": "{ me { id } }", "variables": "{\"a\": 1}"
+			}}}},
+			{"name": "Basic", "request": {"url": "https://api.test/basic", "auth": {"type": "basic", "basic": {"username": "alice", "password": "s3cret"}}}},
+			{"name": "Basic variables", "request": {"url": "https://api.test/basic", "auth": {"type": "basic", "basic": [
+				{"key": "username", "value": "{{user}}"}, {"key": "password", "value": "x"}
+			]}}},
+			{"name": "Header key", "request": {"url": "https://api.test/key", "auth": {"type": "apikey", "apikey": [
+				{"key": "key", "value": "X-Key"}, {"key": "value", "value": "k"}
+			]}}},
+			{"name": "Digest", "request": {"url": "https://api.test/digest", "auth": {"type": "digest"}}},
+			{"name": "Upload", "request": {"method": "POST", "url": "https://api.test/upload", "body": {"mode": "file"}}},
+			{"name": "Trace", "request": {"method": "TRACE", "url": "https://api.test/"}},
+			{"name": "No URL", "request": {"method": "GET"}}
+		]
+	}`
+
+	flows, skipped, err := FromPostman([]byte(collection), nil, PostmanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := map[string]interface{}{"Content-Type": "application/x-www-form-urlencoded"}
+	jsonType := map[string]interface{}{"Content-Type": "application/json"}
+	want := []nodeSummary{
+		{ID: "form", Label: "Form", Method: "POST", URL: "https://api.test/form", Headers: form, Body: "name=a+b%26c&id={{id}}"},
+		{ID: "graphql", Label: "GraphQL", Method: "POST", URL: "https://api.test/graphql", Headers: jsonType, Body: `{"query":"{ me { id } }","variables":{"a":1}}`},
+		{ID: "basic", Label: "Basic", Method: "GET", URL: "https://api.test/basic", Headers: map[string]interface{}{"Authorization": "Basic YWxpY2U6czNjcmV0"}},
+		{ID: "basic-variables", Label: "Basic variables", Method: "GET", URL: "https://api.test/basic"},
+		{ID: "header-key", Label: "Header key", Method: "GET", URL: "https://api.test/key", Headers: map[string]interface{}{"X-Key": "k"}},
+		{ID: "digest", Label: "Digest", Method: "GET", URL: "https://api.test/digest"},
+		{ID: "upload", Label: "Upload", Method: "POST", URL: "https://api.test/upload"},
+	}
+	if got := summarize(flows[0].Nodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("nodes =\n%+v\nwant\n%+v", got, want)
+	}
+
+	wantSkipped := []string{
+		"Basic variables: basic auth credentials use variables; set the Authorization header instead",
+		"Digest: digest auth is not supported",
+		"Upload: file bodies are not supported",
+		"No URL: no URL",
+		"Trace: unsupported method TRACE",
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped =\n%q\nwant\n%q", skipped, wantSkipped)
+	}
+}
+
+func TestFromPostmanErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		collection  string
+		environment string
+		wantErr     string
+	}{
+		{name: "not JSON", collection: "{", wantErr: "invalid collection"},
+		{name: "v1 schema", collection: `{"info": {"schema": "https://schema.getpostman.com/json/collection/v1.0.0/collection.json"}, "item": []}`, wantErr: "unsupported collection schema"},
+		{name: "no items", collection: `{"info": {"name": "x"}}`, wantErr: "no items"},
+		{name: "bad environment", collection: `{"item": []}`, environment: "[", wantErr: "invalid environment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := FromPostman([]byte(tt.collection), []byte(tt.environment), PostmanOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package importer
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/node"
+)
+
+// formBoundary separates the parts of generated multipart bodies. It is fixed
+// so that re-importing the same request gives the same flow.
+const formBoundary = "FlowImportFormBoundary7MA4YWxkTrZu0gW"
+
+// request is one HTTP request read from a collection, capture or command
+type request struct {
+	name    string
+	method  string
+	url     string
+	headers map[string]interface{}
+	body    string
+}
+
+// header sets a request header unless one with the same name is already set
+func (r *request) header(name, value string) {
+	if r.headers == nil {
+		r.headers = make(map[string]interface{})
+	}
+	for existing := range r.headers {
+		if strings.EqualFold(existing, name) {
+			return
+		}
+	}
+	r.headers[name] = value
+}
+
+// sequentialFlow chains requests as API nodes in order, laid out top to bottom.
+// Requests with methods API nodes can't send are left out and returned by name.
+func sequentialFlow(name, description string, tags []string, requests []request) (*models.Flow, []string) {
+	flow := &models.Flow{
+		Name:        name,
+		Description: description,
+		Tags:        tags,
+		Variables:   make(map[string]interface{}),
+	}
+
+	var skipped []string
+	nodeIDs := make(map[string]bool)
+	previous := ""
+	for _, r := range requests {
+		method := strings.ToUpper(r.method)
+		if !node.SupportedMethods[method] {
+			skipped = append(skipped, r.name+": unsupported method "+r.method)
+			continue
+		}
+
+		label := r.name
+		if label == "" {
+			label = method + " " + r.url
+		}
+		config := map[string]interface{}{
+			"method": method,
+			"url":    r.url,
+		}
+		if len(r.headers) > 0 {
+			config["headers"] = r.headers
+		}
+		if r.body != "" {
+			config["body"] = r.body
+		}
+
+		id := uniqueID(nodeIDs, slug(label))
+		flow.Nodes = append(flow.Nodes, newNode(id, string(node.NodeTypeAPI), label, config, 250, 100+float64(len(flow.Nodes))*160))
+		if previous != "" {
+			flow.Edges = append(flow.Edges, models.FlowEdge{ID: "edge-" + previous + "-" + id, Source: previous, Target: id})
+		}
+		previous = id
+	}
+	return flow, skipped
+}
+
+// formField is one field of a multipart form
+type formField struct {
+	name  string
+	value string
+}
+
+// multipartBody encodes text form fields, returning the body and its content type
+func multipartBody(fields []formField) (string, string) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	w.SetBoundary(formBoundary)
+	for _, f := range fields {
+		w.WriteField(f.name, f.value)
+	}
+	w.Close()
+	return buf.String(), w.FormDataContentType()
+}
+
+// skippedHeaders are set by the HTTP client and not copied from captures.
+// Accept-Encoding is left to the client so that it decompresses responses.
+var skippedHeaders = map[string]bool{
+	"Accept-Encoding":   true,
+	"Content-Length":    true,
+	"Host":              true,
+	"Connection":        true,
+	"Transfer-Encoding": true,
+}
+
+func isSkippedHeader(name string) bool {
+	return strings.HasPrefix(name, ":") || skippedHeaders[http.CanonicalHeaderKey(name)]
+}
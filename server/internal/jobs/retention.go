@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/visual-api-testing-platform/server/internal/repository"
+)
+
+// AuditRetention returns a job that deletes audit events older than the retention period
+func AuditRetention(auditRepo *repository.AuditRepository, retention time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error {
+		deleted, err := auditRepo.DeleteBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			log.Printf("Audit retention: deleted %d events older than %s", deleted, retention)
+		}
+		return nil
+	}
+}
+
+// TestRunRetention returns a job that compacts test runs outside the retention
+// policy: runs beyond the newest keepRuns per flow and older than keepFor lose
+// their node outputs but keep status and durations. A zero value disables that rule.
+func TestRunRetention(testRunRepo *repository.TestRunRepository, keepRuns int, keepFor time.Duration) func(context.Context) error {
+	const batchSize = 500
+
+	return func(ctx context.Context) error {
+		var keepSince time.Time
+		if keepFor > 0 {
+			keepSince = time.Now().Add(-keepFor)
+		}
+
+		var total int64
+		for {
+			compacted, err := testRunRepo.CompactExpired(ctx, keepRuns, keepSince, batchSize)
+			if err != nil {
+				return err
+			}
+			total += compacted
+			if compacted < batchSize {
+				break
+			}
+		}
+
+		if total > 0 {
+			log.Printf("Test run retention: compacted %d runs", total)
+		}
+		return nil
+	}
+}
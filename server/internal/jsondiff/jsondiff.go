@@ -0,0 +1,305 @@
+// Package jsondiff computes structural differences between decoded JSON values.
+//
+// Paths use dots for object keys and brackets for array indexes, for example
+// data.items[0].id. Filter patterns use the same syntax where a segment may be
+// a glob (* matches any key, X-* matches keys with that prefix), [*] matches
+// any array index and ** matches any number of segments.
+package jsondiff
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind describes how a value changed
+type Kind string
+
+const (
+	KindAdded   Kind = "added"
+	KindRemoved Kind = "removed"
+	KindChanged Kind = "changed"
+	KindType    Kind = "type_changed" // the JSON type differs at a type-only path
+)
+
+// Change represents a difference at a path.
+// Old is nil for added values and New is nil for removed ones.
+type Change struct {
+	Path string      `json:"path"`
+	Kind Kind        `json:"kind"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Filter holds compiled path patterns. Ignored paths are skipped entirely;
+// at type-only paths only the JSON type of the values is compared.
+type Filter struct {
+	ignore   [][]string
+	typeOnly [][]string
+}
+
+// NewFilter compiles ignore and type-only patterns. Empty patterns are skipped.
+func NewFilter(ignore, typeOnly []string) *Filter {
+	return &Filter{
+		ignore:   compilePatterns(ignore),
+		typeOnly: compilePatterns(typeOnly),
+	}
+}
+
+func compilePatterns(patterns []string) [][]string {
+	var compiled [][]string
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		compiled = append(compiled, splitPath(p))
+	}
+	return compiled
+}
+
+// Ignored reports whether path matches one of the filter's ignore patterns
+func (f *Filter) Ignored(path string) bool {
+	return f.ignored(splitPath(path))
+}
+
+func (f *Filter) ignored(segments []string) bool {
+	return f != nil && matchAny(f.ignore, segments)
+}
+
+func (f *Filter) typeOnlyAt(segments []string) bool {
+	return f != nil && matchAny(f.typeOnly, segments)
+}
+
+func matchAny(patterns [][]string, segments []string) bool {
+	for _, p := range patterns {
+		if matchSegments(p, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// TypeOf returns the JSON type name of a decoded value
+func TypeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+// Compare returns the changes from before to after, sorted by path. Values
+// whose path is ignored by filter are skipped along with everything below them;
+// filter may be nil.
+func Compare(before, after interface{}, filter *Filter) []Change {
+	changes := []Change{}
+	compare(nil, Normalize(before), Normalize(after), filter, &changes)
+	return changes
+}
+
+func compare(segments []string, before, after interface{}, filter *Filter, changes *[]Change) {
+	if len(segments) > 0 && filter.ignored(segments) {
+		return
+	}
+
+	if len(segments) > 0 && filter.typeOnlyAt(segments) {
+		if TypeOf(before) != TypeOf(after) {
+			*changes = append(*changes, Change{Path: joinPath(segments), Kind: KindType, Old: TypeOf(before), New: TypeOf(after)})
+		}
+		return
+	}
+
+	switch b := before.(type) {
+	case map[string]interface{}:
+		if a, ok := after.(map[string]interface{}); ok {
+			compareObjects(segments, b, a, filter, changes)
+			return
+		}
+	case []interface{}:
+		if a, ok := after.([]interface{}); ok {
+			compareArrays(segments, b, a, filter, changes)
+			return
+		}
+	}
+
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+
+	change := Change{Path: joinPath(segments), Kind: KindChanged, Old: before, New: after}
+	if before == nil {
+		change.Kind = KindAdded
+	} else if after == nil {
+		change.Kind = KindRemoved
+	}
+	*changes = append(*changes, change)
+}
+
+func compareObjects(segments []string, before, after map[string]interface{}, filter *Filter, changes *[]Change) {
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		child := append(segments[:len(segments):len(segments)], k)
+		oldValue, hadOld := before[k]
+		newValue, hasNew := after[k]
+
+		switch {
+		case !hadOld:
+			if !filter.ignored(child) {
+				*changes = append(*changes, Change{Path: joinPath(child), Kind: KindAdded, New: newValue})
+			}
+		case !hasNew:
+			if !filter.ignored(child) {
+				*changes = append(*changes, Change{Path: joinPath(child), Kind: KindRemoved, Old: oldValue})
+			}
+		default:
+			compare(child, oldValue, newValue, filter, changes)
+		}
+	}
+}
+
+func compareArrays(segments []string, before, after []interface{}, filter *Filter, changes *[]Change) {
+	n := len(before)
+	if len(after) > n {
+		n = len(after)
+	}
+
+	for i := 0; i < n; i++ {
+		child := append(segments[:len(segments):len(segments)], "["+strconv.Itoa(i)+"]")
+
+		switch {
+		case i >= len(before):
+			if !filter.ignored(child) {
+				*changes = append(*changes, Change{Path: joinPath(child), Kind: KindAdded, New: after[i]})
+			}
+		case i >= len(after):
+			if !filter.ignored(child) {
+				*changes = append(*changes, Change{Path: joinPath(child), Kind: KindRemoved, Old: before[i]})
+			}
+		default:
+			compare(child, before[i], after[i], filter, changes)
+		}
+	}
+}
+
+// Normalize round-trips a value through JSON so numbers, structs and typed maps
+// and slices compare consistently
+func Normalize(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// splitPath splits a.b[0].c into a, b, [0], c
+func splitPath(p string) []string {
+	var segments []string
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() > 0 {
+			segments = append(segments, current.String())
+			current.Reset()
+		}
+	}
+
+	for i := 0; i < len(p); i++ {
+		switch p[i] {
+		case '.':
+			flush()
+		case '[':
+			flush()
+			end := strings.IndexByte(p[i:], ']')
+			if end < 0 {
+				current.WriteString(p[i:])
+				i = len(p)
+				continue
+			}
+			segments = append(segments, p[i:i+end+1])
+			i += end
+		default:
+			current.WriteByte(p[i])
+		}
+	}
+	flush()
+
+	return segments
+}
+
+func joinPath(segments []string) string {
+	var b strings.Builder
+	for _, s := range segments {
+		if b.Len() > 0 && !strings.HasPrefix(s, "[") {
+			b.WriteByte('.')
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// matchSegments matches a split path against a split pattern
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 || !matchSegment(pattern[0], segments[0]) {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+func matchSegment(pattern, segment string) bool {
+	isIndex := strings.HasPrefix(segment, "[")
+	switch {
+	case pattern == "[*]":
+		return isIndex
+	case strings.HasPrefix(pattern, "["):
+		return pattern == segment
+	case isIndex:
+		return pattern == "*"
+	}
+
+	ok, err := path.Match(pattern, segment)
+	return err == nil && ok
+}
@@ -0,0 +1,207 @@
+package jsondiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareFilter(t *testing.T) {
+	before := map[string]interface{}{
+		"id":        1,
+		"updatedAt": "2024-01-01",
+		"session":   "s1",
+		"headers":   map[string]interface{}{"X-Request-Id": "a", "X-Trace": "t1", "Content-Type": "application/json"},
+		"items": []interface{}{
+			map[string]interface{}{"id": 1, "etag": "a", "price": 10},
+			map[string]interface{}{"id": 2, "etag": "b", "price": 20},
+		},
+		"meta": map[string]interface{}{"token": "x", "count": 2},
+	}
+	after := map[string]interface{}{
+		"id":        1,
+		"updatedAt": "2024-02-02",
+		"headers":   map[string]interface{}{"X-Request-Id": "b", "X-Trace": "t2", "Content-Type": "text/xml"},
+		"items": []interface{}{
+			map[string]interface{}{"id": 1, "etag": "c", "price": 10},
+			map[string]interface{}{"id": 2, "etag": "d", "price": "20"},
+			map[string]interface{}{"id": 3, "etag": "e", "price": 30},
+		},
+		"meta": map[string]interface{}{"token": 5, "count": 3},
+	}
+
+	contentType := Change{Path: "headers.Content-Type", Kind: KindChanged, Old: "application/json", New: "text/xml"}
+	requestID := Change{Path: "headers.X-Request-Id", Kind: KindChanged, Old: "a", New: "b"}
+	trace := Change{Path: "headers.X-Trace", Kind: KindChanged, Old: "t1", New: "t2"}
+	etag0 := Change{Path: "items[0].etag", Kind: KindChanged, Old: "a", New: "c"}
+	etag1 := Change{Path: "items[1].etag", Kind: KindChanged, Old: "b", New: "d"}
+	price1 := Change{Path: "items[1].price", Kind: KindChanged, Old: 20.0, New: "20"}
+	item2 := Change{Path: "items[2]", Kind: KindAdded, New: map[string]interface{}{"id": 3.0, "etag": "e", "price": 30.0}}
+	count := Change{Path: "meta.count", Kind: KindChanged, Old: 2.0, New: 3.0}
+	token := Change{Path: "meta.token", Kind: KindChanged, Old: "x", New: 5.0}
+	session := Change{Path: "session", Kind: KindRemoved, Old: "s1"}
+	updatedAt := Change{Path: "updatedAt", Kind: KindChanged, Old: "2024-01-01", New: "2024-02-02"}
+
+	tests := []struct {
+		name     string
+		ignore   []string
+		typeOnly []string
+		want     []Change
+	}{
+		{
+			name: "no filter",
+			want: []Change{contentType, requestID, trace, etag0, etag1, price1, item2, count, token, session, updatedAt},
+		},
+		{
+			name:   "empty patterns are skipped",
+			ignore: []string{"", "  "},
+			want:   []Change{contentType, requestID, trace, etag0, etag1, price1, item2, count, token, session, updatedAt},
+		},
+		{
+			name:   "changed key",
+			ignore: []string{"updatedAt"},
+			want:   []Change{contentType, requestID, trace, etag0, etag1, price1, item2, count, token, session},
+		},
+		{
+			name:   "removed key",
+			ignore: []string{"session"},
+			want:   []Change{contentType, requestID, trace, etag0, etag1, price1, item2, count, token, updatedAt},
+		},
+		{
+			name:   "key glob",
+			ignore: []string{"headers.X-*"},
+			want:   []Change{contentType, etag0, etag1, price1, item2, count, token, session, updatedAt},
+		},
+		{
+			name:   "object and everything below it",
+			ignore: []string{"headers"},
+			want:   []Change{etag0, etag1, price1, item2, count, token, session, updatedAt},
+		},
+		{
+			// The added item is reported whole, at a path the pattern doesn't match
+			name:   "field of every array item",
+			ignore: []string{"items[*].etag"},
+			want:   []Change{contentType, requestID, trace, price1, item2, count, token, session, updatedAt},
+		},
+		{
+			name:   "one array item",
+			ignore: []string{"items[1]"},
+			want:   []Change{contentType, requestID, trace, etag0, item2, count, token, session, updatedAt},
+		},
+		{
+			name:   "field of one array item",
+			ignore: []string{"items[0].etag"},
+			want:   []Change{contentType, requestID, trace, etag1, price1, item2, count, token, session, updatedAt},
+		},
+		{
+			name:   "every array item",
+			ignore: []string{"items[*]"},
+			want:   []Change{contentType, requestID, trace, count, token, session, updatedAt},
+		},
+		{
+			name:   "star matches array indexes",
+			ignore: []string{"items.*.price"},
+			want:   []Change{contentType, requestID, trace, etag0, etag1, item2, count, token, session, updatedAt},
+		},
+		{
+			name:   "any depth",
+			ignore: []string{"**.etag", "**.X-Trace"},
+			want:   []Change{contentType, requestID, price1, item2, count, token, session, updatedAt},
+		},
+		{
+			name:   "index pattern doesn't match keys",
+			ignore: []string{"[*]", "meta[*]"},
+			want:   []Change{contentType, requestID, trace, etag0, etag1, price1, item2, count, token, session, updatedAt},
+		},
+		{
+			name:     "type-only keys",
+			typeOnly: []string{"meta.*"},
+			want: []Change{contentType, requestID, trace, etag0, etag1, price1, item2,
+				{Path: "meta.token", Kind: KindType, Old: "string", New: "number"}, session, updatedAt},
+		},
+		{
+			name:     "type-only field of every array item",
+			typeOnly: []string{"items[*].price", "items[*].etag"},
+			want: []Change{contentType, requestID, trace,
+				{Path: "items[1].price", Kind: KindType, Old: "number", New: "string"}, item2, count, token, session, updatedAt},
+		},
+		{
+			// Values added or removed at a type-only path are still reported
+			name:     "type-only added item",
+			typeOnly: []string{"items[2]"},
+			want: []Change{contentType, requestID, trace, etag0, etag1, price1,
+				{Path: "items[2]", Kind: KindAdded, New: map[string]interface{}{"id": 3.0, "etag": "e", "price": 30.0}}, count, token, session, updatedAt},
+		},
+		{
+			name:     "ignore wins over type-only",
+			ignore:   []string{"meta.token"},
+			typeOnly: []string{"meta.*"},
+			want:     []Change{contentType, requestID, trace, etag0, etag1, price1, item2, session, updatedAt},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(before, after, NewFilter(tt.ignore, tt.typeOnly))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareFilterRoot(t *testing.T) {
+	// The root is never filtered, only what is below it
+	got := Compare(map[string]interface{}{"a": 1}, map[string]interface{}{"a": 2}, NewFilter([]string{"**"}, nil))
+	if len(got) != 0 {
+		t.Errorf("changes = %+v, want none", got)
+	}
+	got = Compare(1, 2, NewFilter([]string{"**"}, nil))
+	if want := []Change{{Path: "", Kind: KindChanged, Old: 1.0, New: 2.0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %+v, want %+v", got, want)
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "data.id", path: "data.id", want: true},
+		{pattern: "data.id", path: "data.id2", want: false},
+		{pattern: "data.id", path: "data", want: false},
+		{pattern: "data", path: "data.id", want: false},
+		{pattern: "$.data.id", path: "data.id", want: false},
+		{pattern: "data.*", path: "data.id", want: true},
+		{pattern: "data.*", path: "data.id.x", want: false},
+		{pattern: "data.*", path: "data[0]", want: true},
+		{pattern: "data[*]", path: "data[12]", want: true},
+		{pattern: "data[*]", path: "data.id", want: false},
+		{pattern: "data[1]", path: "data[1]", want: true},
+		{pattern: "data[1]", path: "data[10]", want: false},
+		{pattern: "data[*].id", path: "data[3].id", want: true},
+		{pattern: "data[*].id", path: "data[3].name", want: false},
+		{pattern: "data[*].id", path: "data[3][0].id", want: false},
+		{pattern: "data[*][*]", path: "data[3][0]", want: true},
+		{pattern: "headers.X-*", path: "headers.X-Request-Id", want: true},
+		{pattern: "headers.X-*", path: "headers.Content-Type", want: false},
+		{pattern: "**.id", path: "id", want: true},
+		{pattern: "**.id", path: "data.items[2].id", want: true},
+		{pattern: "**.id", path: "data.items[2].id.x", want: false},
+		{pattern: "data.**", path: "data", want: true},
+		{pattern: "data.**", path: "data.items[0]", want: true},
+		{pattern: "data.**.updatedAt", path: "data.a.b.updatedAt", want: true},
+		{pattern: "data.**.updatedAt", path: "meta.updatedAt", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := NewFilter([]string{tt.pattern}, nil).Ignored(tt.path); got != tt.want {
+			t.Errorf("%q ignores %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+
+	var filter *Filter
+	if filter.Ignored("data") {
+		t.Error("a nil filter ignores paths")
+	}
+}
@@ -0,0 +1,232 @@
+// Package jsonpath evaluates a subset of JSONPath against decoded JSON values.
+//
+// Supported syntax: the root $ (optional), child access .name or ['name'],
+// array indexes [0] and [-1] (from the end), wildcards .* and [*], and
+// recursive descent ..name or ..*. A path without $ is read as a dot path
+// from the root, so data.items[0].id and $.data.items[0].id are equivalent.
+package jsonpath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type stepKind int
+
+const (
+	stepChild stepKind = iota
+	stepIndex
+	stepWildcard
+)
+
+type step struct {
+	kind      stepKind
+	name      string
+	index     int
+	recursive bool // applies to the current value and all of its descendants
+}
+
+// Path is a compiled JSONPath expression
+type Path struct {
+	raw   string
+	steps []step
+}
+
+// Parse compiles a JSONPath expression
+func Parse(expr string) (*Path, error) {
+	p := &Path{raw: expr}
+	s := strings.TrimSpace(expr)
+	if s == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	if strings.HasPrefix(s, "$") {
+		s = s[1:]
+	} else if s[0] != '.' && s[0] != '[' {
+		s = "." + s
+	}
+
+	for len(s) > 0 {
+		recursive := false
+		switch {
+		case strings.HasPrefix(s, ".."):
+			recursive = true
+			s = s[2:]
+		case s[0] == '.':
+			s = s[1:]
+		case s[0] == '[':
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", expr, s[0])
+		}
+
+		if len(s) == 0 {
+			return nil, fmt.Errorf("invalid path %q: ends with a dot", expr)
+		}
+
+		var st step
+		var err error
+		if s[0] == '[' {
+			st, s, err = parseBracket(expr, s)
+		} else {
+			st, s, err = parseName(expr, s)
+		}
+		if err != nil {
+			return nil, err
+		}
+		st.recursive = recursive
+		p.steps = append(p.steps, st)
+	}
+
+	return p, nil
+}
+
+func parseName(expr, s string) (step, string, error) {
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		end = len(s)
+	}
+	name := s[:end]
+	if name == "" {
+		return step{}, "", fmt.Errorf("invalid path %q: empty name", expr)
+	}
+	if name == "*" {
+		return step{kind: stepWildcard}, s[end:], nil
+	}
+	return step{kind: stepChild, name: name}, s[end:], nil
+}
+
+func parseBracket(expr, s string) (step, string, error) {
+	// Quoted names may contain ] so they are scanned separately
+	if len(s) > 1 && (s[1] == '\'' || s[1] == '"') {
+		quote := s[1]
+		end := strings.IndexByte(s[2:], quote)
+		if end < 0 || len(s) < end+4 || s[end+3] != ']' {
+			return step{}, "", fmt.Errorf("invalid path %q: unterminated name", expr)
+		}
+		return step{kind: stepChild, name: s[2 : end+2]}, s[end+4:], nil
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return step{}, "", fmt.Errorf("invalid path %q: missing ]", expr)
+	}
+	inner := strings.TrimSpace(s[1:end])
+	rest := s[end+1:]
+
+	if inner == "*" {
+		return step{kind: stepWildcard}, rest, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return step{}, "", fmt.Errorf("invalid path %q: unsupported selector [%s]", expr, inner)
+	}
+	return step{kind: stepIndex, index: index}, rest, nil
+}
+
+// String returns the expression the path was parsed from
+func (p *Path) String() string {
+	return p.raw
+}
+
+// Definite reports whether the path can match at most one value, i.e. it has
+// no wildcards or recursive descent
+func (p *Path) Definite() bool {
+	for _, st := range p.steps {
+		if st.kind == stepWildcard || st.recursive {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the values the path matches in root. Wildcards and recursive
+// descent visit array elements in order and object members by sorted key.
+func (p *Path) Get(root interface{}) []interface{} {
+	current := []interface{}{root}
+	for _, st := range p.steps {
+		var next []interface{}
+		for _, value := range current {
+			if st.recursive {
+				for _, v := range descendants(value) {
+					next = append(next, st.apply(v)...)
+				}
+			} else {
+				next = append(next, st.apply(value)...)
+			}
+		}
+		current = next
+		if len(current) == 0 {
+			break
+		}
+	}
+	return current
+}
+
+// Lookup returns the single value a definite path matches and whether it exists
+func (p *Path) Lookup(root interface{}) (interface{}, bool) {
+	values := p.Get(root)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+func (st step) apply(value interface{}) []interface{} {
+	switch st.kind {
+	case stepChild:
+		if m, ok := value.(map[string]interface{}); ok {
+			if v, ok := m[st.name]; ok {
+				return []interface{}{v}
+			}
+		}
+	case stepIndex:
+		if a, ok := value.([]interface{}); ok {
+			i := st.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i >= 0 && i < len(a) {
+				return []interface{}{a[i]}
+			}
+		}
+	case stepWildcard:
+		switch v := value.(type) {
+		case map[string]interface{}:
+			out := make([]interface{}, 0, len(v))
+			for _, key := range sortedKeys(v) {
+				out = append(out, v[key])
+			}
+			return out
+		case []interface{}:
+			return append([]interface{}{}, v...)
+		}
+	}
+	return nil
+}
+
+// descendants returns value followed by every value nested inside it
+func descendants(value interface{}) []interface{} {
+	out := []interface{}{value}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			out = append(out, descendants(v[key])...)
+		}
+	case []interface{}:
+		for _, child := range v {
+			out = append(out, descendants(child)...)
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,143 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const document = `{
+	"status": 200,
+	"data": {
+		"items": [
+			{"id": 1, "name": "first", "tags": ["a"]},
+			{"id": 2, "name": "second", "tags": []},
+			{"id": 3, "name": "third"}
+		],
+		"odd.key": "dotted",
+		"it's": "quoted",
+		"a]b": "bracket",
+		"owner": {"id": 9}
+	}
+}`
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseAndGet(t *testing.T) {
+	root := decode(t, document)
+
+	tests := []struct {
+		expr     string
+		want     []interface{}
+		definite bool
+	}{
+		{expr: "$", want: []interface{}{root}, definite: true},
+		{expr: "$.status", want: []interface{}{200.0}, definite: true},
+		{expr: "status", want: []interface{}{200.0}, definite: true},
+		{expr: "data.items[0].id", want: []interface{}{1.0}, definite: true},
+		{expr: "$.data.items[0].id", want: []interface{}{1.0}, definite: true},
+		{expr: " $.data.items[ 1 ].id ", want: []interface{}{2.0}, definite: true},
+		{expr: "[0]", want: nil, definite: true},
+
+		// Quoted names
+		{expr: "$.data['odd.key']", want: []interface{}{"dotted"}, definite: true},
+		{expr: `$.data["it's"]`, want: []interface{}{"quoted"}, definite: true},
+		{expr: "$['data']['a]b']", want: []interface{}{"bracket"}, definite: true},
+
+		// Negative indexes count from the end
+		{expr: "$.data.items[-1].name", want: []interface{}{"third"}, definite: true},
+		{expr: "$.data.items[-3].name", want: []interface{}{"first"}, definite: true},
+		{expr: "$.data.items[-4].name", want: nil, definite: true},
+		{expr: "$.data.items[3]", want: nil, definite: true},
+
+		// Wildcards
+		{expr: "$.data.items[*].id", want: []interface{}{1.0, 2.0, 3.0}},
+		{expr: "$.data.items.*.name", want: []interface{}{"first", "second", "third"}},
+		{expr: "$.data.owner.*", want: []interface{}{9.0}},
+
+		// Recursive descent
+		{expr: "$..id", want: []interface{}{1.0, 2.0, 3.0, 9.0}},
+		{expr: "$.data..tags[0]", want: []interface{}{"a"}},
+		{expr: "$.data.owner..*", want: []interface{}{9.0}},
+		{expr: "$..missing", want: nil},
+
+		// Missing members and type mismatches match nothing
+		{expr: "$.data.missing.id", want: nil, definite: true},
+		{expr: "$.status.id", want: nil, definite: true},
+		{expr: "$.data[0]", want: nil, definite: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			path, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := path.Get(root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get = %#v, want %#v", got, tt.want)
+			}
+			if path.Definite() != tt.definite {
+				t.Errorf("Definite = %v, want %v", path.Definite(), tt.definite)
+			}
+			if path.String() != tt.expr {
+				t.Errorf("String = %q, want %q", path.String(), tt.expr)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	root := decode(t, `{"a": null}`)
+
+	path, _ := Parse("$.a")
+	if value, ok := path.Lookup(root); !ok || value != nil {
+		t.Errorf("Lookup($.a) = %v, %v; want null, true", value, ok)
+	}
+	path, _ = Parse("$.b")
+	if _, ok := path.Lookup(root); ok {
+		t.Error("Lookup($.b) found a value")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: "", wantErr: "empty path"},
+		{expr: "   ", wantErr: "empty path"},
+		{expr: "$.", wantErr: "ends with a dot"},
+		{expr: "$..", wantErr: "ends with a dot"},
+		{expr: "$.a.", wantErr: "ends with a dot"},
+		{expr: "$x", wantErr: "unexpected"},
+		{expr: "$.a..[", wantErr: "missing ]"},
+		{expr: "$.a[0", wantErr: "missing ]"},
+		{expr: "$.a['b]", wantErr: "unterminated name"},
+		{expr: "$.a['b'", wantErr: "unterminated name"},
+		{expr: "$.a['b'x", wantErr: "unterminated name"},
+		{expr: "$.a[b]", wantErr: "unsupported selector"},
+		{expr: "$.a[1:2]", wantErr: "unsupported selector"},
+		{expr: "$.a[?(@.b)]", wantErr: "unsupported selector"},
+		{expr: "$.a[0]b", wantErr: "unexpected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if err == nil {
+				t.Fatal("Parse succeeded")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q doesn't mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
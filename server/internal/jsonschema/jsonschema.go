@@ -0,0 +1,631 @@
+// Package jsonschema validates decoded JSON values against a subset of JSON
+// Schema draft 2020-12.
+//
+// Supported keywords: type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, uniqueItems,
+// minProperties, maxProperties, minLength, maxLength, pattern, format,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, allOf,
+// anyOf, oneOf, not and $ref to a JSON pointer within the same document
+// (e.g. #/$defs/user, or # for the whole document). Other keywords are ignored.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxRefDepth bounds nested $ref resolution so recursive schemas cannot loop forever
+const maxRefDepth = 64
+
+// maxValidationSteps bounds the subschemas one Validate call evaluates, so a
+// schema whose combinators fan out cannot stall the caller
+const maxValidationSteps = 1_000_000
+
+// Violation describes one way an instance fails a schema
+type Violation struct {
+	InstancePath string `json:"instance_path"` // JSON pointer into the instance; "" is the root
+	SchemaPath   string `json:"schema_path"`   // JSON pointer to the failing keyword
+	Keyword      string `json:"keyword"`
+	Message      string `json:"message"`
+}
+
+// Schema is a compiled schema
+type Schema struct {
+	root     interface{} // the document $refs resolve against
+	entry    interface{} // the schema instances are validated against
+	entryRef string      // the JSON pointer to entry, for schema paths
+	checked  map[string]bool
+	patterns map[string]*regexp.Regexp
+}
+
+var validTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+// Compile checks a decoded JSON schema and prepares it for validation. It
+// fails on unknown types, invalid patterns and $refs that don't resolve.
+func Compile(schema interface{}) (*Schema, error) {
+	s := newSchema(schema)
+	s.entry, s.entryRef = schema, "#"
+	if err := s.check(schema, "#"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CompileRef compiles the subschema ref points to within document, e.g.
+// "#/components/schemas/Pet" in an OpenAPI document. $refs inside it resolve
+// against the whole document.
+func CompileRef(document interface{}, ref string) (*Schema, error) {
+	s := newSchema(document)
+	entry, err := s.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	s.entry, s.entryRef = entry, ref
+	s.checked[ref] = true
+	if err := s.check(entry, ref); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newSchema(root interface{}) *Schema {
+	return &Schema{
+		root:     root,
+		checked:  make(map[string]bool),
+		patterns: make(map[string]*regexp.Regexp),
+	}
+}
+
+// check walks every subschema, validating the keywords Validate relies on
+func (s *Schema) check(schema interface{}, path string) error {
+	if _, ok := schema.(bool); ok {
+		return nil
+	}
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: schema must be an object or a boolean", path)
+	}
+
+	if t, ok := m["type"]; ok {
+		names, err := typeNames(t)
+		if err != nil {
+			return fmt.Errorf("%s/type: %w", path, err)
+		}
+		for _, name := range names {
+			if !validTypes[name] {
+				return fmt.Errorf("%s/type: unknown type %q", path, name)
+			}
+		}
+	}
+
+	if p, ok := m["pattern"]; ok {
+		pattern, ok := p.(string)
+		if !ok {
+			return fmt.Errorf("%s/pattern: must be a string", path)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%s/pattern: %w", path, err)
+		}
+		s.patterns[pattern] = re
+	}
+
+	if r, ok := m["$ref"]; ok {
+		ref, ok := r.(string)
+		if !ok {
+			return fmt.Errorf("%s/$ref: must be a string", path)
+		}
+		target, err := s.resolve(ref)
+		if err != nil {
+			return fmt.Errorf("%s/$ref: %w", path, err)
+		}
+		// Referenced schemas may live outside the keywords walked below
+		if !s.checked[ref] {
+			s.checked[ref] = true
+			if err := s.check(target, ref); err != nil {
+				return err
+			}
+		}
+	}
+
+	if req, ok := m["required"]; ok {
+		list, ok := req.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s/required: must be an array", path)
+		}
+		for _, name := range list {
+			if _, ok := name.(string); !ok {
+				return fmt.Errorf("%s/required: must contain strings", path)
+			}
+		}
+	}
+
+	if e, ok := m["enum"]; ok {
+		if _, ok := e.([]interface{}); !ok {
+			return fmt.Errorf("%s/enum: must be an array", path)
+		}
+	}
+
+	for _, keyword := range []string{"properties", "$defs", "definitions"} {
+		if v, ok := m[keyword]; ok {
+			children, ok := v.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s/%s: must be an object", path, keyword)
+			}
+			for _, name := range sortedKeys(children) {
+				if err := s.check(children[name], path+"/"+keyword+"/"+escapePointer(name)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, keyword := range []string{"items", "additionalProperties", "not"} {
+		if v, ok := m[keyword]; ok {
+			if err := s.check(v, path+"/"+keyword); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		if v, ok := m[keyword]; ok {
+			list, ok := v.([]interface{})
+			if !ok || len(list) == 0 {
+				return fmt.Errorf("%s/%s: must be a non-empty array", path, keyword)
+			}
+			for i, sub := range list {
+				if err := s.check(sub, fmt.Sprintf("%s/%s/%d", path, keyword, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolve follows a $ref to a JSON pointer in the schema document
+func (s *Schema) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("only references within the document are supported: %q", ref)
+	}
+	pointer, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference %q", ref)
+	}
+	if pointer == "" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("unsupported reference %q", ref)
+	}
+
+	current := s.root
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("reference %q does not resolve", ref)
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("reference %q does not resolve", ref)
+			}
+			current = v[i]
+		default:
+			return nil, fmt.Errorf("reference %q does not resolve", ref)
+		}
+	}
+	return current, nil
+}
+
+// Validate returns every violation of the schema by instance, which must be a
+// decoded JSON value. An empty result means the instance is valid.
+func (s *Schema) Validate(instance interface{}) []Violation {
+	v := &validator{schema: s, state: &validation{refs: make(map[refKey]*refResult), cycleLevel: math.MaxInt}}
+	v.validate(s.entry, instance, "", s.entryRef, 0)
+	if v.state.steps > maxValidationSteps {
+		// Results cut short by the budget can't be trusted, e.g. anyOf
+		// branches that stopped early look like matches
+		return []Violation{{SchemaPath: s.entryRef, Keyword: "$ref", Message: "schema is too complex to validate"}}
+	}
+	if v.violations == nil {
+		return []Violation{}
+	}
+	return v.violations
+}
+
+type validator struct {
+	schema     *Schema
+	state      *validation
+	violations []Violation
+}
+
+// validation is the state shared by the validators of one Validate call
+type validation struct {
+	steps int
+
+	// refs memoises the violations of each $ref target at each instance
+	// location. Without it a recursive $ref under anyOf or oneOf is
+	// evaluated exponentially many times.
+	refs map[refKey]*refResult
+
+	// active counts the refs being evaluated; cycleLevel is the lowest level
+	// of those a cycle returned to while evaluating the current one
+	active     int
+	cycleLevel int
+}
+
+type refKey struct {
+	ref          string
+	instancePath string
+}
+
+type refResult struct {
+	done       bool // false while the target is being evaluated
+	level      int  // the value of active while it is
+	violations []Violation
+}
+
+func (v *validator) fail(instancePath, schemaPath, keyword, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{
+		InstancePath: instancePath,
+		SchemaPath:   schemaPath + "/" + keyword,
+		Keyword:      keyword,
+		Message:      fmt.Sprintf(format, args...),
+	})
+}
+
+// sub validates instance against a subschema in isolation and returns its violations
+func (v *validator) sub(schema, instance interface{}, instancePath, schemaPath string, depth int) []Violation {
+	child := &validator{schema: v.schema, state: v.state}
+	child.validate(schema, instance, instancePath, schemaPath, depth)
+	return child.violations
+}
+
+func (v *validator) validate(schema, instance interface{}, ip, sp string, depth int) {
+	v.state.steps++
+	if v.state.steps > maxValidationSteps {
+		return
+	}
+
+	if b, ok := schema.(bool); ok {
+		if !b {
+			v.violations = append(v.violations, Violation{InstancePath: ip, SchemaPath: sp, Keyword: "false", Message: "no value is allowed here"})
+		}
+		return
+	}
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if r, ok := m["$ref"].(string); ok {
+		if depth >= maxRefDepth {
+			v.fail(ip, sp, "$ref", "maximum reference depth exceeded")
+			return
+		}
+		v.validateRef(r, instance, ip, sp, depth)
+	}
+
+	if t, ok := m["type"]; ok {
+		names, _ := typeNames(t)
+		matched := false
+		for _, name := range names {
+			if hasType(instance, name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(ip, sp, "type", "expected %s, got %s", strings.Join(names, " or "), typeOf(instance))
+			// Other keywords would only repeat the mismatch
+			return
+		}
+	}
+
+	if e, ok := m["enum"].([]interface{}); ok {
+		found := false
+		for _, option := range e {
+			if equal(instance, option) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(ip, sp, "enum", "must be one of %s", formatJSON(e))
+		}
+	}
+
+	if c, ok := m["const"]; ok && !equal(instance, c) {
+		v.fail(ip, sp, "const", "must be %s", formatJSON(c))
+	}
+
+	switch value := instance.(type) {
+	case map[string]interface{}:
+		v.validateObject(m, value, ip, sp, depth)
+	case []interface{}:
+		v.validateArray(m, value, ip, sp, depth)
+	case string:
+		v.validateString(m, value, ip, sp)
+	case float64:
+		v.validateNumber(m, value, ip, sp)
+	}
+
+	if all, ok := m["allOf"].([]interface{}); ok {
+		for i, sub := range all {
+			v.validate(sub, instance, ip, fmt.Sprintf("%s/allOf/%d", sp, i), depth)
+		}
+	}
+
+	if anyOf, ok := m["anyOf"].([]interface{}); ok {
+		matched := false
+		for i, sub := range anyOf {
+			if len(v.sub(sub, instance, ip, fmt.Sprintf("%s/anyOf/%d", sp, i), depth)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(ip, sp, "anyOf", "must match at least one schema")
+		}
+	}
+
+	if oneOf, ok := m["oneOf"].([]interface{}); ok {
+		matches := 0
+		for i, sub := range oneOf {
+			if len(v.sub(sub, instance, ip, fmt.Sprintf("%s/oneOf/%d", sp, i), depth)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(ip, sp, "oneOf", "must match exactly one schema, matched %d", matches)
+		}
+	}
+
+	if not, ok := m["not"]; ok {
+		if len(v.sub(not, instance, ip, sp+"/not", depth)) == 0 {
+			v.fail(ip, sp, "not", "must not match the schema")
+		}
+	}
+}
+
+// validateRef validates instance against the schema ref points to. A ref
+// met again at the same instance location without consuming any of the
+// instance is a cycle that can never be decided, e.g. a schema that is
+// anyOf itself.
+func (v *validator) validateRef(ref string, instance interface{}, ip, sp string, depth int) {
+	key := refKey{ref: ref, instancePath: ip}
+	if result, ok := v.state.refs[key]; ok {
+		if !result.done {
+			if result.level < v.state.cycleLevel {
+				v.state.cycleLevel = result.level
+			}
+			v.fail(ip, sp, "$ref", "reference %q refers to itself without consuming the instance", ref)
+			return
+		}
+		v.violations = append(v.violations, result.violations...)
+		return
+	}
+
+	target, err := v.schema.resolve(ref)
+	if err != nil {
+		v.fail(ip, sp, "$ref", "%s", err)
+		return
+	}
+
+	v.state.active++
+	result := &refResult{level: v.state.active}
+	v.state.refs[key] = result
+	outerCycleLevel := v.state.cycleLevel
+	v.state.cycleLevel = math.MaxInt
+
+	result.violations = v.sub(target, instance, ip, ref, depth+1)
+	result.done = true
+	v.violations = append(v.violations, result.violations...)
+
+	// A result that relied on a cycle back to an enclosing ref holds only
+	// while that ref is being evaluated, so it isn't kept
+	if v.state.cycleLevel < result.level {
+		delete(v.state.refs, key)
+	}
+	if v.state.cycleLevel > outerCycleLevel {
+		v.state.cycleLevel = outerCycleLevel
+	}
+	v.state.active--
+}
+
+func (v *validator) validateObject(m map[string]interface{}, value map[string]interface{}, ip, sp string, depth int) {
+	if required, ok := m["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := value[name]; !ok {
+				v.fail(ip, sp, "required", "missing required property %q", name)
+			}
+		}
+	}
+
+	properties, _ := m["properties"].(map[string]interface{})
+	for _, name := range sortedKeys(value) {
+		child := ip + "/" + escapePointer(name)
+		if propSchema, ok := properties[name]; ok {
+			v.validate(propSchema, value[name], child, sp+"/properties/"+escapePointer(name), depth)
+			continue
+		}
+		if additional, ok := m["additionalProperties"]; ok {
+			if b, ok := additional.(bool); ok {
+				if !b {
+					v.fail(ip, sp, "additionalProperties", "property %q is not allowed", name)
+				}
+				continue
+			}
+			v.validate(additional, value[name], child, sp+"/additionalProperties", depth)
+		}
+	}
+
+	if n, ok := number(m["minProperties"]); ok && float64(len(value)) < n {
+		v.fail(ip, sp, "minProperties", "must have at least %v properties, has %d", n, len(value))
+	}
+	if n, ok := number(m["maxProperties"]); ok && float64(len(value)) > n {
+		v.fail(ip, sp, "maxProperties", "must have at most %v properties, has %d", n, len(value))
+	}
+}
+
+func (v *validator) validateArray(m map[string]interface{}, value []interface{}, ip, sp string, depth int) {
+	if items, ok := m["items"]; ok {
+		for i, item := range value {
+			v.validate(items, item, ip+"/"+strconv.Itoa(i), sp+"/items", depth)
+		}
+	}
+
+	if n, ok := number(m["minItems"]); ok && float64(len(value)) < n {
+		v.fail(ip, sp, "minItems", "must have at least %v items, has %d", n, len(value))
+	}
+	if n, ok := number(m["maxItems"]); ok && float64(len(value)) > n {
+		v.fail(ip, sp, "maxItems", "must have at most %v items, has %d", n, len(value))
+	}
+
+	if unique, _ := m["uniqueItems"].(bool); unique {
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if equal(value[i], value[j]) {
+					v.fail(ip, sp, "uniqueItems", "items %d and %d are equal", i, j)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) validateString(m map[string]interface{}, value, ip, sp string) {
+	length := float64(utf8.RuneCountInString(value))
+	if n, ok := number(m["minLength"]); ok && length < n {
+		v.fail(ip, sp, "minLength", "must be at least %v characters, is %v", n, length)
+	}
+	if n, ok := number(m["maxLength"]); ok && length > n {
+		v.fail(ip, sp, "maxLength", "must be at most %v characters, is %v", n, length)
+	}
+
+	if pattern, ok := m["pattern"].(string); ok {
+		if re := v.schema.patterns[pattern]; re != nil && !re.MatchString(value) {
+			v.fail(ip, sp, "pattern", "must match %q", pattern)
+		}
+	}
+
+	if format, ok := m["format"].(string); ok {
+		if check, known := formats[format]; known && !check(value) {
+			v.fail(ip, sp, "format", "must be a valid %s", format)
+		}
+	}
+}
+
+func (v *validator) validateNumber(m map[string]interface{}, value float64, ip, sp string) {
+	if n, ok := number(m["minimum"]); ok && value < n {
+		v.fail(ip, sp, "minimum", "must be >= %v", n)
+	}
+	if n, ok := number(m["maximum"]); ok && value > n {
+		v.fail(ip, sp, "maximum", "must be <= %v", n)
+	}
+	if n, ok := number(m["exclusiveMinimum"]); ok && value <= n {
+		v.fail(ip, sp, "exclusiveMinimum", "must be > %v", n)
+	}
+	if n, ok := number(m["exclusiveMaximum"]); ok && value >= n {
+		v.fail(ip, sp, "exclusiveMaximum", "must be < %v", n)
+	}
+	if n, ok := number(m["multipleOf"]); ok && n > 0 {
+		if q := value / n; math.Abs(q-math.Round(q)) > 1e-9 {
+			v.fail(ip, sp, "multipleOf", "must be a multiple of %v", n)
+		}
+	}
+}
+
+// typeNames reads the type keyword, a name or a list of names
+func typeNames(t interface{}) ([]string, error) {
+	switch v := t.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("type names must be strings")
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	}
+	return nil, fmt.Errorf("type must be a string or an array")
+}
+
+func hasType(instance interface{}, name string) bool {
+	if name == "integer" {
+		n, ok := instance.(float64)
+		return ok && n == math.Trunc(n)
+	}
+	return typeOf(instance) == name
+}
+
+func typeOf(instance interface{}) string {
+	switch instance.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", instance)
+}
+
+func number(v interface{}) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,174 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func compile(t *testing.T, schema string) *Schema {
+	t.Helper()
+	s, err := Compile(decode(t, schema))
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return s
+}
+
+func keywords(violations []Violation) string {
+	names := make([]string, 0, len(violations))
+	for _, v := range violations {
+		names = append(names, v.InstancePath+":"+v.Keyword)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestValidate(t *testing.T) {
+	const user = `{
+		"type": "object",
+		"required": ["id", "email"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"email": {"type": "string", "format": "email"},
+			"role": {"enum": ["admin", "viewer"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true, "maxItems": 2}
+		},
+		"additionalProperties": false
+	}`
+
+	tests := []struct {
+		name     string
+		schema   string
+		instance string
+		want     string // instance path and keyword of each violation
+	}{
+		{name: "valid", schema: user, instance: `{"id": 1, "email": "a@example.com", "tags": ["x"]}`},
+		{name: "wrong root type", schema: user, instance: `[]`, want: ":type"},
+		{name: "missing required", schema: user, instance: `{"id": 1}`, want: ":required"},
+		{name: "nested failures", schema: user, instance: `{"id": 0.5, "email": "nope", "role": "owner"}`, want: "/email:format,/id:type,/role:enum"},
+		{name: "additional property", schema: user, instance: `{"id": 1, "email": "a@example.com", "extra": true}`, want: ":additionalProperties"},
+		{name: "array items", schema: user, instance: `{"id": 1, "email": "a@example.com", "tags": ["x", "x", 3]}`, want: "/tags/2:type,/tags:maxItems,/tags:uniqueItems"},
+		{name: "oneOf matches two", schema: `{"oneOf": [{"type": "number"}, {"minimum": 0}]}`, instance: `1`, want: ":oneOf"},
+		{name: "not", schema: `{"not": {"type": "string"}}`, instance: `"x"`, want: ":not"},
+		{name: "false schema", schema: `{"properties": {"a": false}}`, instance: `{"a": 1}`, want: "/a:false"},
+		{name: "ref to defs", schema: `{"$ref": "#/$defs/id", "$defs": {"id": {"type": "string"}}}`, instance: `1`, want: ":type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := keywords(compile(t, tt.schema).Validate(decode(t, tt.instance)))
+			if got != tt.want {
+				t.Errorf("violations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRecursiveRefs(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		instance string
+		want     string
+	}{
+		{
+			name:     "root ref",
+			schema:   `{"type": "object", "properties": {"name": {"type": "string"}, "children": {"type": "array", "items": {"$ref": "#"}}}}`,
+			instance: `{"name": "a", "children": [{"name": "b", "children": [{"name": 3}]}]}`,
+			want:     "/children/0/children/0/name:type",
+		},
+		{
+			name:     "recursive anyOf",
+			schema:   `{"$ref": "#/$defs/a", "$defs": {"a": {"anyOf": [{"$ref": "#/$defs/a"}, {"$ref": "#/$defs/a"}]}}}`,
+			instance: `1`,
+			want:     ":anyOf",
+		},
+		{
+			name:     "recursive anyOf with a base case",
+			schema:   `{"$ref": "#/$defs/a", "$defs": {"a": {"anyOf": [{"$ref": "#/$defs/a"}, {"type": "number"}]}}}`,
+			instance: `1`,
+		},
+		{
+			// b fails on the cycle back to a while a is being evaluated, but
+			// holds on its own because a matches the number
+			name:     "mutual recursion",
+			schema:   `{"allOf": [{"$ref": "#/$defs/a"}, {"$ref": "#/$defs/b"}], "$defs": {"a": {"anyOf": [{"$ref": "#/$defs/b"}, {"type": "number"}]}, "b": {"anyOf": [{"$ref": "#/$defs/a"}, {"type": "string"}]}}}`,
+			instance: `1`,
+		},
+		{
+			name:     "fan-out over a deep instance",
+			schema:   `{"$ref": "#/$defs/node", "$defs": {"node": {"oneOf": [{"type": "number"}, {"type": "array", "items": {"anyOf": [{"$ref": "#/$defs/node"}, {"$ref": "#/$defs/node"}]}}]}}}`,
+			instance: strings.Repeat("[", 40) + "1" + strings.Repeat("]", 40),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := compile(t, tt.schema)
+			instance := decode(t, tt.instance)
+
+			done := make(chan string, 1)
+			go func() { done <- keywords(schema.Validate(instance)) }()
+			select {
+			case got := <-done:
+				if got != tt.want {
+					t.Errorf("violations = %q, want %q", got, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("validation didn't finish")
+			}
+		})
+	}
+}
+
+func TestValidateStepBudget(t *testing.T) {
+	schema := compile(t, `{"type": "array", "items": {"type": "array", "items": {"type": "number"}}}`)
+
+	row := make([]interface{}, 1000)
+	for i := range row {
+		row[i] = 1.0
+	}
+	instance := make([]interface{}, maxValidationSteps/len(row)+1)
+	for i := range instance {
+		instance[i] = row
+	}
+
+	violations := schema.Validate(instance)
+	if len(violations) != 1 || !strings.Contains(violations[0].Message, "too complex") {
+		t.Errorf("violations = %+v, want the budget to be exceeded", violations)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		schema  string
+		wantErr string
+	}{
+		{schema: `{"type": "float"}`, wantErr: "unknown type"},
+		{schema: `{"pattern": "("}`, wantErr: "/pattern"},
+		{schema: `{"$ref": "#/$defs/missing"}`, wantErr: "does not resolve"},
+		{schema: `{"$ref": "other.json#/a"}`, wantErr: "within the document"},
+		{schema: `{"$ref": "#a"}`, wantErr: "unsupported reference"},
+		{schema: `{"anyOf": []}`, wantErr: "non-empty array"},
+		{schema: `{"properties": {"a": 1}}`, wantErr: "#/properties/a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			_, err := Compile(decode(t, tt.schema))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
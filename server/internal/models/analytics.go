@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DurationStats summarizes a set of durations in milliseconds
+type DurationStats struct {
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P95  float64 `json:"p95_ms"`
+	P99  float64 `json:"p99_ms"`
+}
+
+// FlowStats describes the health of a flow over a time window.
+// Pass rate is passed / (passed + failed); cancelled runs are not counted against it.
+type FlowStats struct {
+	FlowID      uuid.UUID     `json:"flow_id"`
+	Window      string        `json:"window"`
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	Runs        int           `json:"runs"`
+	Passed      int           `json:"passed"`
+	Failed      int           `json:"failed"`
+	Cancelled   int           `json:"cancelled"`
+	PassRate    *float64      `json:"pass_rate"` // nil when no run passed or failed
+	Duration    DurationStats `json:"duration"`
+	Nodes       []NodeStats   `json:"nodes"`
+	MostFailing []NodeStats   `json:"most_failing"`
+	Daily       []DailyStats  `json:"daily"`
+}
+
+// NodeStats describes the results of one node across the runs in a window
+type NodeStats struct {
+	NodeID      string        `json:"node_id"`
+	Label       string        `json:"label,omitempty"`
+	Type        string        `json:"type,omitempty"`
+	Runs        int           `json:"runs"`
+	Passed      int           `json:"passed"`
+	Failed      int           `json:"failed"`
+	Skipped     int           `json:"skipped"`
+	FailureRate float64       `json:"failure_rate"`
+	Duration    DurationStats `json:"duration"`
+}
+
+// DailyStats is one day of a flow's run history
+type DailyStats struct {
+	Date     time.Time `json:"date"`
+	Runs     int       `json:"runs"`
+	Passed   int       `json:"passed"`
+	Failed   int       `json:"failed"`
+	PassRate *float64  `json:"pass_rate"`
+	MeanMs   float64   `json:"mean_ms"`
+	P95Ms    float64   `json:"p95_ms"`
+}
+
+// FlakyNode scores how often a node flips between passing and failing while the
+// flow definition stays the same. Score is flips / transitions, where a
+// transition is a pair of consecutive runs on the same flow version.
+type FlakyNode struct {
+	NodeID        string     `json:"node_id"`
+	Label         string     `json:"label,omitempty"`
+	Type          string     `json:"type,omitempty"`
+	Runs          int        `json:"runs"`
+	Failures      int        `json:"failures"`
+	Transitions   int        `json:"transitions"`
+	Flips         int        `json:"flips"`
+	Score         float64    `json:"score"`
+	Flaky         bool       `json:"flaky"`
+	Quarantined   bool       `json:"quarantined"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+}
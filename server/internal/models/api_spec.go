@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APISpec is an OpenAPI 3 document in the workspace's spec library. API nodes
+// bind to one of its operations with the contract config.
+type APISpec struct {
+	ID          uuid.UUID   `json:"id" db:"id"`
+	Name        string      `json:"name" db:"name"`
+	Description string      `json:"description" db:"description"`
+	Title       string      `json:"title" db:"title"`             // info.title of the document
+	APIVersion  string      `json:"api_version" db:"api_version"` // info.version of the document
+	Document    interface{} `json:"document,omitempty" db:"document"`
+	CreatedBy   *uuid.UUID  `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent represents an append-only record of a user or system action
+type AuditEvent struct {
+	ID           uuid.UUID              `json:"id" db:"id"`
+	ActorID      *uuid.UUID             `json:"actor_id,omitempty" db:"actor_id"`
+	ActorEmail   string                 `json:"actor_email,omitempty" db:"actor_email"`
+	Action       string                 `json:"action" db:"action"`
+	ResourceType string                 `json:"resource_type" db:"resource_type"`
+	ResourceID   string                 `json:"resource_id,omitempty" db:"resource_id"`
+	IPAddress    string                 `json:"ip_address" db:"ip_address"`
+	UserAgent    string                 `json:"user_agent" db:"user_agent"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty" db:"metadata"`
+	CreatedAt    time.Time              `json:"created_at" db:"created_at"`
+}
+
+// AuditFilter narrows an audit event query; zero values are ignored
+type AuditFilter struct {
+	ActorID      *uuid.UUID
+	Action       string
+	ResourceType string
+	ResourceID   string
+	From         *time.Time
+	To           *time.Time
+	Limit        int
+}
+
+// Audit actions
+const (
+	AuditActionFlowCreate      = "flow.create"
+	AuditActionFlowUpdate      = "flow.update"
+	AuditActionFlowDelete      = "flow.delete"
+	AuditActionFlowRestore     = "flow.restore"
+	AuditActionNodeQuarantine  = "node.quarantine"
+	AuditActionNodeRelease     = "node.release"
+	AuditActionSnapshotApprove = "snapshot.approve"
+	AuditActionSnapshotDelete  = "snapshot.delete"
+	AuditActionSchemaCreate    = "schema.create"
+	AuditActionSchemaUpdate    = "schema.update"
+	AuditActionSchemaDelete    = "schema.delete"
+	AuditActionSpecCreate      = "spec.create"
+	AuditActionSpecUpdate      = "spec.update"
+	AuditActionSpecDelete      = "spec.delete"
+	AuditActionTestRunStart    = "test_run.start"
+	AuditActionTestRunCancel   = "test_run.cancel"
+	AuditActionLoadRunStart    = "load_run.start"
+	AuditActionLoadRunCancel   = "load_run.cancel"
+	AuditActionLogin           = "auth.login"
+	AuditActionLoginFailed     = "auth.login_failed"
+	AuditActionTokenCreated    = "auth.token_created"
+)
+
+// Audit resource types
+const (
+	AuditResourceFlow    = "flow"
+	AuditResourceTestRun = "test_run"
+	AuditResourceUser    = "user"
+	AuditResourceSchema  = "schema"
+	AuditResourceSpec    = "spec"
+	AuditResourceLoadRun = "load_run"
+)
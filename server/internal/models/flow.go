@@ -15,16 +15,18 @@ type Flow struct {
 	Tags        []string               `json:"tags" db:"tags"`
 	Nodes       []FlowNode             `json:"nodes" db:"nodes"`
 	Edges       []FlowEdge             `json:"edges" db:"edges"`
+	Variables   map[string]interface{} `json:"variables" db:"variables"`
+	Version     int                    `json:"version" db:"version"`
 	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at" db:"updated_at"`
 }
 
 // FlowNode represents a node in a flow
 type FlowNode struct {
-	ID       string                 `json:"id"`
-	Type     string                 `json:"type"`
-	Position Position               `json:"position"`
-	Data     NodeData               `json:"data"`
+	ID       string   `json:"id"`
+	Type     string   `json:"type"`
+	Position Position `json:"position"`
+	Data     NodeData `json:"data"`
 }
 
 // Position represents node position in the editor
@@ -35,9 +37,9 @@ type Position struct {
 
 // FlowEdge represents a connection between nodes
 type FlowEdge struct {
-	ID          string  `json:"id"`
-	Source      string  `json:"source"`
-	Target      string  `json:"target"`
+	ID           string  `json:"id"`
+	Source       string  `json:"source"`
+	Target       string  `json:"target"`
 	SourceHandle *string `json:"sourceHandle,omitempty"`
 	TargetHandle *string `json:"targetHandle,omitempty"`
 }
@@ -53,3 +55,44 @@ type NodeData struct {
 	Error  string                 `json:"error,omitempty"`
 }
 
+// FlowSummary is a lightweight projection of a flow for list views
+type FlowSummary struct {
+	ID            uuid.UUID        `json:"id"`
+	UserID        uuid.UUID        `json:"user_id"`
+	Name          string           `json:"name"`
+	Description   string           `json:"description"`
+	Tags          []string         `json:"tags"`
+	Version       int              `json:"version"`
+	NodeCount     int              `json:"node_count"`
+	NodeCounts    map[string]int   `json:"node_counts"` // node type -> count
+	EdgeCount     int              `json:"edge_count"`
+	LastRunStatus *ExecutionStatus `json:"last_run_status,omitempty"`
+	LastRunAt     *time.Time       `json:"last_run_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+// Flow list sort fields
+const (
+	FlowSortUpdatedAt = "updated_at"
+	FlowSortCreatedAt = "created_at"
+	FlowSortName      = "name"
+)
+
+// FlowListOptions controls filtering, sorting and pagination of flow summaries
+type FlowListOptions struct {
+	Query       string   // full-text search over names, descriptions, node labels and URLs
+	Tags        []string // flows must have every tag
+	HasNodeType string   // flows must contain a node of this type
+	URLContains string   // flows must contain a node whose URL contains this substring
+	Sort        string   // one of the FlowSort constants, defaults to updated_at
+	Desc        bool
+	Limit       int
+	Cursor      string // opaque cursor from a previous page
+}
+
+// TagFacet is the number of matching flows that carry a tag
+type TagFacet struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
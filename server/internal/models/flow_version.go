@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FlowVersion represents an immutable snapshot of a flow taken on every save
+type FlowVersion struct {
+	ID          uuid.UUID              `json:"id" db:"id"`
+	FlowID      uuid.UUID              `json:"flow_id" db:"flow_id"`
+	Version     int                    `json:"version" db:"version"`
+	Name        string                 `json:"name" db:"name"`
+	Description string                 `json:"description" db:"description"`
+	Tags        []string               `json:"tags" db:"tags"`
+	Nodes       []FlowNode             `json:"nodes,omitempty" db:"nodes"`
+	Edges       []FlowEdge             `json:"edges,omitempty" db:"edges"`
+	Variables   map[string]interface{} `json:"variables,omitempty" db:"variables"`
+	NodeCount   int                    `json:"node_count" db:"node_count"`
+	AuthorID    *uuid.UUID             `json:"author_id,omitempty" db:"author_id"`
+	Message     string                 `json:"message,omitempty" db:"message"`
+	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+}
+
+// NewFlowVersion snapshots a flow's definition. Runtime node state
+// (status, output, error) is not part of a version.
+func NewFlowVersion(flow *Flow, authorID uuid.UUID, message string) *FlowVersion {
+	nodes := make([]FlowNode, len(flow.Nodes))
+	for i, n := range flow.Nodes {
+		n.Data.Status = ""
+		n.Data.Output = nil
+		n.Data.Error = ""
+		nodes[i] = n
+	}
+
+	return &FlowVersion{
+		ID:          uuid.New(),
+		FlowID:      flow.ID,
+		Version:     flow.Version,
+		Name:        flow.Name,
+		Description: flow.Description,
+		Tags:        flow.Tags,
+		Nodes:       nodes,
+		Edges:       flow.Edges,
+		Variables:   flow.Variables,
+		NodeCount:   len(nodes),
+		AuthorID:    &authorID,
+		Message:     message,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// AsFlow returns the version as a flow definition, e.g. for diffing or restoring
+func (v *FlowVersion) AsFlow() *Flow {
+	return &Flow{
+		ID:          v.FlowID,
+		Name:        v.Name,
+		Description: v.Description,
+		Tags:        v.Tags,
+		Nodes:       v.Nodes,
+		Edges:       v.Edges,
+		Variables:   v.Variables,
+		Version:     v.Version,
+	}
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoadRun is a load test of a flow: virtual users executing it repeatedly
+// while request rates, error rates and latencies are measured
+type LoadRun struct {
+	ID          uuid.UUID         `json:"id" db:"id"`
+	FlowID      uuid.UUID         `json:"flow_id" db:"flow_id"`
+	FlowName    string            `json:"flow_name,omitempty"`
+	FlowVersion int               `json:"flow_version" db:"flow_version"`
+	Status      ExecutionStatus   `json:"status" db:"status"`
+	Config      LoadConfig        `json:"config" db:"config"`
+	Metrics     *LoadMetrics      `json:"metrics,omitempty" db:"metrics"`
+	Thresholds  []ThresholdResult `json:"thresholds" db:"thresholds"`
+	Error       string            `json:"error,omitempty" db:"error"`
+	Environment string            `json:"environment,omitempty" db:"environment"`
+	Tags        []string          `json:"tags" db:"tags"`
+	StartedAt   time.Time         `json:"started_at" db:"started_at"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty" db:"completed_at"`
+	DurationMs  *int              `json:"duration_ms,omitempty" db:"duration_ms"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+}
+
+// LoadConfig describes how a load test runs. Durations are Go durations such
+// as 30s or 5m. Exactly one of Duration and Iterations is set.
+type LoadConfig struct {
+	VUs int `json:"vus"` // virtual users
+
+	// RampUp spreads the start of the virtual users over this duration, one at
+	// a time or, with RampUpSteps, in that many equal batches
+	RampUp      string `json:"ramp_up,omitempty"`
+	RampUpSteps int    `json:"ramp_up_steps,omitempty"`
+
+	// Duration is how long virtual users keep starting iterations once ramp-up
+	// is over; Iterations is how many each virtual user runs
+	Duration   string `json:"duration,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+
+	// ThinkTime is the pause between a virtual user's iterations, random
+	// between ThinkTime and ThinkTimeMax when that is set
+	ThinkTime    string `json:"think_time,omitempty"`
+	ThinkTimeMax string `json:"think_time_max,omitempty"`
+
+	// Thresholds fail the run when breached, e.g. "p95 < 300ms",
+	// "error_rate < 1%" or "get-user: p99 <= 1s"
+	Thresholds []string `json:"thresholds,omitempty"`
+}
+
+// LoadMetrics aggregates a load test, live while it runs and final once done
+type LoadMetrics struct {
+	ElapsedMs  int64                `json:"elapsed_ms"`
+	ActiveVUs  int                  `json:"active_vus"`
+	Iterations LoadStats            `json:"iterations"` // executions of the whole flow
+	Requests   LoadStats            `json:"requests"`   // API node executions across the flow
+	Nodes      map[string]LoadStats `json:"nodes"`
+}
+
+// LoadStats measures one kind of execution. Rates are per second of the
+// test's elapsed time.
+type LoadStats struct {
+	Count     int64          `json:"count"`
+	Errors    int64          `json:"errors"`
+	Rate      float64        `json:"rate"`
+	ErrorRate float64        `json:"error_rate"` // errors / count
+	Latency   LatencySummary `json:"latency_ms"`
+}
+
+// LatencySummary reads latency percentiles, in milliseconds, from an HDR histogram
+type LatencySummary struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// ThresholdResult is how a load test fared against one threshold
+type ThresholdResult struct {
+	Threshold string   `json:"threshold"`
+	Scope     string   `json:"scope"` // requests, iterations or a node ID
+	Metric    string   `json:"metric"`
+	Actual    *float64 `json:"actual"` // nil when the scope recorded nothing
+	Passed    bool     `json:"passed"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginAttempt represents a recorded password login attempt
+type LoginAttempt struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Email     string     `json:"email" db:"email"`
+	UserID    *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	IPAddress string     `json:"ip_address" db:"ip_address"`
+	UserAgent string     `json:"user_agent" db:"user_agent"`
+	Success   bool       `json:"success" db:"success"`
+	Reason    string     `json:"reason,omitempty" db:"reason"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Login attempt failure reasons
+const (
+	LoginReasonInvalidCredentials = "invalid_credentials"
+	LoginReasonUnknownUser        = "unknown_user"
+	LoginReasonLocked             = "locked"
+)
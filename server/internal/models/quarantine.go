@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NodeQuarantine marks a node whose failures should not fail its flow's runs
+type NodeQuarantine struct {
+	FlowID        uuid.UUID  `json:"flow_id" db:"flow_id"`
+	NodeID        string     `json:"node_id" db:"node_id"`
+	Reason        string     `json:"reason,omitempty" db:"reason"`
+	QuarantinedBy *uuid.UUID `json:"quarantined_by,omitempty" db:"quarantined_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Schema is a JSON Schema in the workspace's shared library. Verification
+// nodes reference it by ID with assertionType "schema".
+type Schema struct {
+	ID          uuid.UUID   `json:"id" db:"id"`
+	Name        string      `json:"name" db:"name"`
+	Description string      `json:"description" db:"description"`
+	Schema      interface{} `json:"schema" db:"schema"`
+	CreatedBy   *uuid.UUID  `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NodeSnapshot is the golden value a snapshot assertion compares against.
+// It is recorded by the first run of the node or approved from a later run.
+type NodeSnapshot struct {
+	FlowID          uuid.UUID   `json:"flow_id" db:"flow_id"`
+	NodeID          string      `json:"node_id" db:"node_id"`
+	Value           interface{} `json:"value" db:"value"`
+	SourceTestRunID *uuid.UUID  `json:"source_test_run_id,omitempty" db:"source_test_run_id"`
+	ApprovedBy      *uuid.UUID  `json:"approved_by,omitempty" db:"approved_by"` // nil when recorded automatically
+	CreatedAt       time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// SnapshotRecorded is the snapshot status a verification node reports when
+// its run recorded the node's first snapshot
+const SnapshotRecorded = "recorded"
+
+// RecordedSnapshotNodes returns the IDs of the nodes whose first snapshot was
+// recorded by the run
+func RecordedSnapshotNodes(run *TestRun) []string {
+	var nodeIDs []string
+	for nodeID, result := range run.NodeResults {
+		output, ok := result.Output.(map[string]interface{})
+		if ok && output["snapshot"] == SnapshotRecorded {
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+	}
+	return nodeIDs
+}
@@ -8,16 +8,58 @@ import (
 
 // TestRun represents a test execution record
 type TestRun struct {
-	ID          uuid.UUID              `json:"id" db:"id"`
-	FlowID      uuid.UUID              `json:"flow_id" db:"flow_id"`
-	FlowName    string                 `json:"flow_name,omitempty"`
-	Status      ExecutionStatus        `json:"status" db:"status"`
-	StartedAt   time.Time              `json:"started_at" db:"started_at"`
-	CompletedAt *time.Time             `json:"completed_at,omitempty" db:"completed_at"`
-	DurationMs  *int                   `json:"duration_ms,omitempty" db:"duration_ms"`
-	NodeResults map[string]NodeResult  `json:"node_results" db:"node_results"`
-	Error       string                 `json:"error,omitempty" db:"error"`
-	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	ID          uuid.UUID             `json:"id" db:"id"`
+	FlowID      uuid.UUID             `json:"flow_id" db:"flow_id"`
+	FlowName    string                `json:"flow_name,omitempty"`
+	FlowVersion int                   `json:"flow_version" db:"flow_version"`
+	Status      ExecutionStatus       `json:"status" db:"status"`
+	StartedAt   time.Time             `json:"started_at" db:"started_at"`
+	CompletedAt *time.Time            `json:"completed_at,omitempty" db:"completed_at"`
+	DurationMs  *int                  `json:"duration_ms,omitempty" db:"duration_ms"`
+	NodeResults map[string]NodeResult `json:"node_results,omitempty" db:"node_results"`
+	Error       string                `json:"error,omitempty" db:"error"`
+
+	TriggerSource string     `json:"trigger_source" db:"trigger_source"`
+	Environment   string     `json:"environment,omitempty" db:"environment"`
+	Tags          []string   `json:"tags" db:"tags"`
+	CompactedAt   *time.Time `json:"compacted_at,omitempty" db:"compacted_at"` // node outputs were pruned by retention
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Trigger sources record what started a test run
+const (
+	TriggerSourceManual   = "manual"
+	TriggerSourceAPI      = "api"
+	TriggerSourceSchedule = "schedule"
+	TriggerSourceCI       = "ci"
+
+	// TriggerSourceLoad marks the iterations of a load test, which aren't
+	// stored as test runs
+	TriggerSourceLoad = "load"
+)
+
+// ValidTriggerSource reports whether s is a known trigger source
+func ValidTriggerSource(s string) bool {
+	switch s {
+	case TriggerSourceManual, TriggerSourceAPI, TriggerSourceSchedule, TriggerSourceCI:
+		return true
+	}
+	return false
+}
+
+// TestRunFilter selects test runs across flows
+type TestRunFilter struct {
+	UserID        uuid.UUID // owner of the flows
+	FlowID        *uuid.UUID
+	Status        ExecutionStatus
+	TriggerSource string
+	Environment   string
+	Tag           string
+	From          *time.Time
+	To            *time.Time
+	Limit         int
+	Cursor        string
 }
 
 // NodeResult represents the result of a single node execution
@@ -26,16 +68,34 @@ type NodeResult struct {
 	Output   interface{}    `json:"output,omitempty"`
 	Error    string         `json:"error,omitempty"`
 	Duration int            `json:"duration"` // in milliseconds
+
+	// Quarantined nodes still run and report, but their failures don't fail the run
+	Quarantined bool `json:"quarantined,omitempty"`
+
+	// Assertions lists the structured assertion failures the node reported,
+	// e.g. API contract violations
+	Assertions []AssertionResult `json:"assertions,omitempty"`
+}
+
+// AssertionResult is the outcome of one assertion
+type AssertionResult struct {
+	Path     string      `json:"path"`
+	Operator string      `json:"operator"`
+	Expected interface{} `json:"expected,omitempty"`
+	Actual   interface{} `json:"actual,omitempty"`
+	Passed   bool        `json:"passed"`
+	Message  string      `json:"message,omitempty"`
 }
 
 // ExecutionStatus represents the status of an execution
 type ExecutionStatus string
 
 const (
-	ExecutionStatusPending ExecutionStatus = "pending"
-	ExecutionStatusRunning ExecutionStatus = "running"
-	ExecutionStatusSuccess ExecutionStatus = "success"
-	ExecutionStatusFailed  ExecutionStatus = "failed"
-	ExecutionStatusSkipped ExecutionStatus = "skipped"
+	ExecutionStatusPending   ExecutionStatus = "pending"
+	ExecutionStatusRunning   ExecutionStatus = "running"
+	ExecutionStatusSuccess   ExecutionStatus = "success"
+	ExecutionStatusFailed    ExecutionStatus = "failed"
+	ExecutionStatusSkipped   ExecutionStatus = "skipped"
+	ExecutionStatusCancelled ExecutionStatus = "cancelled"
 )
 
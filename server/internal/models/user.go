@@ -8,14 +8,23 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID           uuid.UUID `json:"id" db:"id"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"-" db:"password_hash"`
-	Name         string    `json:"name" db:"name"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID  `json:"id" db:"id"`
+	Email           string     `json:"email" db:"email"`
+	PasswordHash    string     `json:"-" db:"password_hash"`
+	Name            string     `json:"name" db:"name"`
+	Role            string     `json:"role" db:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// Workspace roles
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
 // CreateUserRequest represents a user creation request
 type CreateUserRequest struct {
 	Email    string `json:"email" binding:"required,email"`
@@ -29,6 +38,47 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// UpdateProfileRequest represents a profile update request.
+// Changing the email requires the current password, or an emailed
+// confirmation token for accounts without a password.
+type UpdateProfileRequest struct {
+	Name              *string `json:"name"`
+	Email             *string `json:"email" binding:"omitempty,email"`
+	CurrentPassword   string  `json:"current_password"`
+	ConfirmationToken string  `json:"confirmation_token"`
+}
+
+// ChangePasswordRequest represents a password change request.
+// Accounts without a password confirm with an emailed token instead.
+type ChangePasswordRequest struct {
+	CurrentPassword   string `json:"current_password"`
+	ConfirmationToken string `json:"confirmation_token"`
+	NewPassword       string `json:"new_password" binding:"required,min=8"`
+}
+
+// ForgotPasswordRequest represents a password reset email request
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents a password reset using an emailed token
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+// VerifyEmailRequest represents an email verification using an emailed token
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// DeleteAccountRequest represents an account deletion request.
+// Accounts without a password confirm with an emailed token instead.
+type DeleteAccountRequest struct {
+	Password          string `json:"password"`
+	ConfirmationToken string `json:"confirmation_token"`
+}
+
 // AuthResponse represents an authentication response
 type AuthResponse struct {
 	Token string `json:"token"`
@@ -7,9 +7,17 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"time"
+
+	"github.com/visual-api-testing-platform/server/internal/openapi"
 )
 
+// SupportedMethods are the HTTP methods API nodes can send
+var SupportedMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true, "HEAD": true, "OPTIONS": true,
+}
+
 // APINode executes HTTP requests
 type APINode struct {
 	BaseNode
@@ -48,13 +56,14 @@ func (a *APINode) ValidateConfig() error {
 	}
 
 	// Validate HTTP method
-	validMethods := map[string]bool{
-		"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true,
-	}
-	if !validMethods[method] {
+	if !SupportedMethods[method] {
 		return fmt.Errorf("invalid HTTP method: %s", method)
 	}
 
+	if _, err := parseContract(a.Config); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -65,11 +74,27 @@ func (a *APINode) Execute(ctx context.Context, input map[string]interface{}) (ma
 	}
 
 	method := a.Config["method"].(string)
-	url := a.Config["url"].(string)
+
+	// {{name}} placeholders in the URL, headers and body take the run's variables
+	exec, _ := ExecutionFromContext(ctx)
+	url := ExpandVariables(a.Config["url"].(string), exec.Variables)
+
+	// A contract binds the node to an operation its exchange is validated against
+	contract, _ := parseContract(a.Config)
+	var operation *openapi.Operation
+	if contract != nil {
+		op, err := contract.operation(ctx, method)
+		if err != nil {
+			return nil, err
+		}
+		operation = op
+	}
 
 	// Create request
 	var body io.Reader
-	if bodyStr, ok := a.Config["body"].(string); ok && bodyStr != "" {
+	bodyStr, _ := a.Config["body"].(string)
+	bodyStr = ExpandVariables(bodyStr, exec.Variables)
+	if bodyStr != "" {
 		body = bytes.NewBufferString(bodyStr)
 	}
 
@@ -82,7 +107,7 @@ func (a *APINode) Execute(ctx context.Context, input map[string]interface{}) (ma
 	if headers, ok := a.Config["headers"].(map[string]interface{}); ok {
 		for k, v := range headers {
 			if str, ok := v.(string); ok {
-				req.Header.Set(k, str)
+				req.Header.Set(k, ExpandVariables(str, exec.Variables))
 			}
 		}
 	}
@@ -92,7 +117,18 @@ func (a *APINode) Execute(ctx context.Context, input map[string]interface{}) (ma
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	// Execute request
+	violations := []openapi.Violation{}
+	if operation != nil && contract.ValidateRequest {
+		violations = append(violations, operation.ValidateRequest(openapi.Request{
+			Method: method,
+			URL:    req.URL,
+			Header: req.Header,
+			Body:   []byte(bodyStr),
+		})...)
+	}
+
+	// Execute request; the duration covers sending it and reading the body
+	start := time.Now()
 	resp, err := a.Client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
@@ -104,6 +140,7 @@ func (a *APINode) Execute(ctx context.Context, input map[string]interface{}) (ma
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
+	duration := time.Since(start)
 
 	// Parse JSON response if possible
 	var jsonData interface{}
@@ -117,8 +154,55 @@ func (a *APINode) Execute(ctx context.Context, input map[string]interface{}) (ma
 		"statusText": resp.Status,
 		"headers":    resp.Header,
 		"data":       jsonData,
+		"durationMs": duration.Milliseconds(),
+		"size":       len(respBody),
+		"request":    requestSummary(req),
+	}
+
+	if operation != nil {
+		if contract.ValidateResponse {
+			violations = append(violations, operation.ValidateResponse(openapi.Response{
+				StatusCode: resp.StatusCode,
+				Header:     resp.Header,
+				Body:       respBody,
+			})...)
+		}
+		report, err := contractReport(ctx, contract, operation, violations)
+		result["contract"] = report
+		if err != nil {
+			return result, err
+		}
 	}
 
 	return result, nil
 }
 
+// requestSummary records what a request exercised, for coverage reports: its
+// method, host and path and the names of its query parameters, headers and
+// cookies. Values are left out as they may hold credentials.
+func requestSummary(req *http.Request) map[string]interface{} {
+	query := make([]string, 0)
+	for name := range req.URL.Query() {
+		query = append(query, name)
+	}
+	headers := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		headers = append(headers, name)
+	}
+	cookies := make([]string, 0)
+	for _, cookie := range req.Cookies() {
+		cookies = append(cookies, cookie.Name)
+	}
+	sort.Strings(query)
+	sort.Strings(headers)
+	sort.Strings(cookies)
+
+	return map[string]interface{}{
+		"method":  req.Method,
+		"host":    req.URL.Host,
+		"path":    req.URL.EscapedPath(),
+		"query":   query,
+		"headers": headers,
+		"cookies": cookies,
+	}
+}
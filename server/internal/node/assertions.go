@@ -0,0 +1,447 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/visual-api-testing-platform/server/internal/jsondiff"
+	"github.com/visual-api-testing-platform/server/internal/jsonpath"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// Assertion operators
+const (
+	OpEq         = "eq"
+	OpNe         = "ne"
+	OpGt         = "gt"
+	OpGte        = "gte"
+	OpLt         = "lt"
+	OpLte        = "lte"
+	OpIn         = "in"
+	OpContains   = "contains"
+	OpStartsWith = "startsWith"
+	OpMatches    = "matches"
+	OpExists     = "exists"
+	OpIsType     = "isType"
+	OpLength     = "length"
+	OpIsEmpty    = "isEmpty"
+)
+
+var validOperators = map[string]bool{
+	OpEq: true, OpNe: true, OpGt: true, OpGte: true, OpLt: true, OpLte: true,
+	OpIn: true, OpContains: true, OpStartsWith: true, OpMatches: true,
+	OpExists: true, OpIsType: true, OpLength: true, OpIsEmpty: true,
+}
+
+// fieldAssertion is one entry of the assertions config
+type fieldAssertion struct {
+	Path     string
+	Operator string
+	Value    interface{}
+	HasValue bool
+}
+
+// parseAssertions reads the assertions config: a list of objects with path,
+// operator and value
+func parseAssertions(config interface{}) ([]fieldAssertion, error) {
+	list, ok := config.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("assertions must be a non-empty list")
+	}
+
+	assertions := make([]fieldAssertion, 0, len(list))
+	for i, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("assertion %d must be an object", i)
+		}
+		path, _ := m["path"].(string)
+		operator, _ := m["operator"].(string)
+		value, hasValue := m["value"]
+		assertions = append(assertions, fieldAssertion{
+			Path:     path,
+			Operator: operator,
+			Value:    jsondiff.Normalize(value),
+			HasValue: hasValue,
+		})
+	}
+	return assertions, nil
+}
+
+// ResponseRoot builds the document assertion paths are evaluated against.
+// It is the node input with each dependency's output under its node ID and
+// the response of the first dependency lifted to the top: status,
+// statusText, headers (one string per header, canonical names), body (also
+// available as data), durationMs and size.
+func ResponseRoot(input map[string]interface{}, exec Execution) map[string]interface{} {
+	root, _ := jsondiff.Normalize(input).(map[string]interface{})
+	if root == nil {
+		root = make(map[string]interface{})
+	}
+	for _, u := range exec.Upstream {
+		if u.Output != nil {
+			root[u.NodeID] = jsondiff.Normalize(u.Output)
+		}
+	}
+
+	dependencies := exec.Dependencies()
+	if len(dependencies) == 0 {
+		return root
+	}
+	response, ok := root[dependencies[0]].(map[string]interface{})
+	if !ok {
+		return root
+	}
+
+	for _, key := range []string{"status", "statusText", "data", "durationMs", "size"} {
+		if v, ok := response[key]; ok {
+			root[key] = v
+		}
+	}
+	if data, ok := response["data"]; ok {
+		root["body"] = data
+	}
+	if headers, ok := response["headers"].(map[string]interface{}); ok {
+		root["headers"] = flattenHeaders(headers)
+	}
+
+	return root
+}
+
+// flattenHeaders joins multi-valued headers into one comma-separated string
+func flattenHeaders(headers map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{}, len(headers))
+	for name, value := range headers {
+		key := http.CanonicalHeaderKey(name)
+		switch v := value.(type) {
+		case []interface{}:
+			parts := make([]string, 0, len(v))
+			for _, part := range v {
+				parts = append(parts, fmt.Sprint(part))
+			}
+			flat[key] = strings.Join(parts, ", ")
+		default:
+			flat[key] = v
+		}
+	}
+	return flat
+}
+
+// fieldAssertions evaluates the assertions config against the response root.
+// Every assertion runs; the output lists each result and the error summarizes
+// the failures.
+func (v *VerificationNode) fieldAssertions(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
+	assertions, err := parseAssertions(v.Config["assertions"])
+	if err != nil {
+		return nil, err
+	}
+
+	exec, _ := ExecutionFromContext(ctx)
+	results := evaluateAssertions(ResponseRoot(input, exec), assertions)
+
+	var failures []string
+	for _, r := range results {
+		if !r.Passed {
+			failures = append(failures, fmt.Sprintf("%s %s: %s", r.Path, r.Operator, r.Message))
+		}
+	}
+
+	output := map[string]interface{}{
+		"passed":     len(failures) == 0,
+		"total":      len(results),
+		"failed":     len(failures),
+		"assertions": results,
+	}
+	if len(failures) > 0 {
+		return output, fmt.Errorf("%d of %d assertions failed: %s", len(failures), len(results), strings.Join(failures, "; "))
+	}
+	return output, nil
+}
+
+// evaluateAssertions runs every assertion against root and returns all results
+func evaluateAssertions(root interface{}, assertions []fieldAssertion) []models.AssertionResult {
+	results := make([]models.AssertionResult, 0, len(assertions))
+	for _, a := range assertions {
+		results = append(results, evaluateAssertion(root, a))
+	}
+	return results
+}
+
+func evaluateAssertion(root interface{}, a fieldAssertion) models.AssertionResult {
+	result := models.AssertionResult{Path: a.Path, Operator: a.Operator, Expected: a.Value}
+
+	if !validOperators[a.Operator] {
+		result.Message = fmt.Sprintf("unknown operator: %s", a.Operator)
+		return result
+	}
+
+	path, err := jsonpath.Parse(a.Path)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	// Indefinite paths assert on the list of matches; definite ones on the match itself
+	var actual interface{}
+	found := true
+	if path.Definite() {
+		actual, found = path.Lookup(root)
+	} else {
+		matches := path.Get(root)
+		found = len(matches) > 0
+		actual = matches
+	}
+	if found {
+		result.Actual = actual
+	}
+
+	if a.Operator == OpExists {
+		want := true
+		if a.HasValue {
+			b, ok := a.Value.(bool)
+			if !ok {
+				result.Message = "exists expects true or false"
+				return result
+			}
+			want = b
+		}
+		result.Passed = found == want
+		if !result.Passed {
+			if want {
+				result.Message = "no value at path"
+			} else {
+				result.Message = "value exists at path"
+			}
+		}
+		return result
+	}
+
+	if !found {
+		result.Message = "no value at path"
+		return result
+	}
+
+	passed, err := applyOperator(a.Operator, actual, a.Value, a.HasValue)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Passed = passed
+	if !passed {
+		result.Message = fmt.Sprintf("expected %s %s, got %s", a.Operator, formatValue(a.Value), formatValue(actual))
+	}
+	return result
+}
+
+// applyOperator reports whether actual satisfies the operator. An error means
+// the assertion itself is invalid, e.g. an unknown operator or operand type.
+func applyOperator(operator string, actual, expected interface{}, hasExpected bool) (bool, error) {
+	if !hasExpected && operator != OpIsEmpty {
+		return false, fmt.Errorf("%s needs a value", operator)
+	}
+
+	switch operator {
+	case OpEq:
+		return reflect.DeepEqual(actual, expected), nil
+	case OpNe:
+		return !reflect.DeepEqual(actual, expected), nil
+
+	case OpGt, OpGte, OpLt, OpLte:
+		cmp, err := compareOrdered(actual, expected)
+		if err != nil {
+			return false, err
+		}
+		switch operator {
+		case OpGt:
+			return cmp > 0, nil
+		case OpGte:
+			return cmp >= 0, nil
+		case OpLt:
+			return cmp < 0, nil
+		default:
+			return cmp <= 0, nil
+		}
+
+	case OpIn:
+		options, ok := expected.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("in expects a list")
+		}
+		for _, option := range options {
+			if reflect.DeepEqual(actual, option) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case OpContains:
+		switch v := actual.(type) {
+		case string:
+			s, ok := expected.(string)
+			if !ok {
+				return false, fmt.Errorf("contains on a string expects a string")
+			}
+			return strings.Contains(v, s), nil
+		case []interface{}:
+			for _, item := range v {
+				if reflect.DeepEqual(item, expected) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			key, ok := expected.(string)
+			if !ok {
+				return false, fmt.Errorf("contains on an object expects a key")
+			}
+			_, ok = v[key]
+			return ok, nil
+		}
+		return false, fmt.Errorf("contains needs a string, array or object, got %s", jsondiff.TypeOf(actual))
+
+	case OpStartsWith:
+		s, ok := actual.(string)
+		prefix, prefixOK := expected.(string)
+		if !ok || !prefixOK {
+			return false, fmt.Errorf("startsWith compares strings")
+		}
+		return strings.HasPrefix(s, prefix), nil
+
+	case OpMatches:
+		pattern, ok := expected.(string)
+		if !ok {
+			return false, fmt.Errorf("matches expects a regular expression")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		s, ok := actual.(string)
+		if !ok {
+			s = formatValue(actual)
+		}
+		return re.MatchString(s), nil
+
+	case OpIsType:
+		name, ok := expected.(string)
+		if !ok {
+			return false, fmt.Errorf("isType expects a type name")
+		}
+		return isType(actual, name)
+
+	case OpLength:
+		n, err := lengthOf(actual)
+		if err != nil {
+			return false, err
+		}
+		// A bare number must match exactly; an object applies comparisons, e.g. {"gte": 1}
+		if bounds, ok := expected.(map[string]interface{}); ok {
+			if len(bounds) == 0 {
+				return false, fmt.Errorf("length expects a number or comparisons")
+			}
+			for op, bound := range bounds {
+				passed, err := applyOperator(op, float64(n), bound, true)
+				if err != nil {
+					return false, err
+				}
+				if !passed {
+					return false, nil
+				}
+			}
+			return true, nil
+		}
+		want, ok := expected.(float64)
+		if !ok {
+			return false, fmt.Errorf("length expects a number or comparisons")
+		}
+		return float64(n) == want, nil
+
+	case OpIsEmpty:
+		want := true
+		if hasExpected {
+			b, ok := expected.(bool)
+			if !ok {
+				return false, fmt.Errorf("isEmpty expects true or false")
+			}
+			want = b
+		}
+		return isEmpty(actual) == want, nil
+	}
+
+	return false, fmt.Errorf("unknown operator: %s", operator)
+}
+
+// compareOrdered compares two numbers or two strings
+func compareOrdered(actual, expected interface{}) (int, error) {
+	switch a := actual.(type) {
+	case float64:
+		if e, ok := expected.(float64); ok {
+			switch {
+			case a < e:
+				return -1, nil
+			case a > e:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if e, ok := expected.(string); ok {
+			return strings.Compare(a, e), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", jsondiff.TypeOf(actual), jsondiff.TypeOf(expected))
+}
+
+// isType checks a JSON type name; integer matches whole numbers
+func isType(value interface{}, name string) (bool, error) {
+	switch name {
+	case "null", "boolean", "number", "string", "array", "object":
+		return jsondiff.TypeOf(value) == name, nil
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n), nil
+	}
+	return false, fmt.Errorf("unknown type: %s", name)
+}
+
+func lengthOf(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case string:
+		return utf8.RuneCountInString(v), nil
+	case []interface{}:
+		return len(v), nil
+	case map[string]interface{}:
+		return len(v), nil
+	}
+	return 0, fmt.Errorf("length needs a string, array or object, got %s", jsondiff.TypeOf(value))
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// formatValue renders a value as JSON for messages
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,158 @@
+package node
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyOperator(t *testing.T) {
+	list := []interface{}{1.0, "two", nil}
+	object := map[string]interface{}{"id": 1.0, "name": "x"}
+
+	tests := []struct {
+		name       string
+		operator   string
+		actual     interface{}
+		expected   interface{}
+		noExpected bool
+		want       bool
+		wantErr    string
+	}{
+		{name: "eq number", operator: OpEq, actual: 200.0, expected: 200.0, want: true},
+		{name: "eq number against string", operator: OpEq, actual: 200.0, expected: "200", want: false},
+		{name: "eq object", operator: OpEq, actual: object, expected: map[string]interface{}{"name": "x", "id": 1.0}, want: true},
+		{name: "eq null", operator: OpEq, actual: nil, expected: nil, want: true},
+		{name: "ne", operator: OpNe, actual: "a", expected: "b", want: true},
+		{name: "eq without value", operator: OpEq, actual: 1.0, noExpected: true, wantErr: "needs a value"},
+
+		{name: "gt", operator: OpGt, actual: 2.0, expected: 1.0, want: true},
+		{name: "gt equal", operator: OpGt, actual: 1.0, expected: 1.0, want: false},
+		{name: "gte equal", operator: OpGte, actual: 1.0, expected: 1.0, want: true},
+		{name: "lt", operator: OpLt, actual: 1.0, expected: 2.0, want: true},
+		{name: "lte greater", operator: OpLte, actual: 3.0, expected: 2.0, want: false},
+		{name: "lt strings", operator: OpLt, actual: "2024-01-01", expected: "2024-06-01", want: true},
+		{name: "gt number against string", operator: OpGt, actual: 2.0, expected: "1", wantErr: "cannot compare number with string"},
+		{name: "gt string against number", operator: OpGt, actual: "2", expected: 1.0, wantErr: "cannot compare string with number"},
+		{name: "lt null", operator: OpLt, actual: nil, expected: 1.0, wantErr: "cannot compare null"},
+		{name: "gte booleans", operator: OpGte, actual: true, expected: false, wantErr: "cannot compare boolean"},
+
+		{name: "in", operator: OpIn, actual: "two", expected: list, want: true},
+		{name: "in null", operator: OpIn, actual: nil, expected: list, want: true},
+		{name: "not in", operator: OpIn, actual: 2.0, expected: list, want: false},
+		{name: "in not a list", operator: OpIn, actual: 1.0, expected: 1.0, wantErr: "in expects a list"},
+
+		{name: "contains substring", operator: OpContains, actual: "hello world", expected: "o w", want: true},
+		{name: "contains array item", operator: OpContains, actual: list, expected: "two", want: true},
+		{name: "contains missing array item", operator: OpContains, actual: list, expected: 2.0, want: false},
+		{name: "contains object key", operator: OpContains, actual: object, expected: "name", want: true},
+		{name: "contains missing object key", operator: OpContains, actual: object, expected: "email", want: false},
+		{name: "contains number in string", operator: OpContains, actual: "a1", expected: 1.0, wantErr: "expects a string"},
+		{name: "contains number key", operator: OpContains, actual: object, expected: 1.0, wantErr: "expects a key"},
+		{name: "contains on number", operator: OpContains, actual: 12.0, expected: "1", wantErr: "got number"},
+
+		{name: "startsWith", operator: OpStartsWith, actual: "application/json; charset=utf-8", expected: "application/json", want: true},
+		{name: "startsWith other prefix", operator: OpStartsWith, actual: "text/html", expected: "application/", want: false},
+		{name: "startsWith number", operator: OpStartsWith, actual: 200.0, expected: "2", wantErr: "compares strings"},
+
+		{name: "matches", operator: OpMatches, actual: "abc-123", expected: `^[a-z]+-\d+$`, want: true},
+		{name: "matches number as JSON", operator: OpMatches, actual: 204.0, expected: `^2\d\d$`, want: true},
+		{name: "matches no match", operator: OpMatches, actual: "abc", expected: `^\d+$`, want: false},
+		{name: "matches invalid pattern", operator: OpMatches, actual: "abc", expected: "(", wantErr: "invalid regular expression"},
+		{name: "matches non-string pattern", operator: OpMatches, actual: "abc", expected: 1.0, wantErr: "expects a regular expression"},
+
+		{name: "isType integer", operator: OpIsType, actual: 3.0, expected: "integer", want: true},
+		{name: "isType fraction is not integer", operator: OpIsType, actual: 3.5, expected: "integer", want: false},
+		{name: "isType number", operator: OpIsType, actual: 3.5, expected: "number", want: true},
+		{name: "isType null", operator: OpIsType, actual: nil, expected: "null", want: true},
+		{name: "isType array is not object", operator: OpIsType, actual: list, expected: "object", want: false},
+		{name: "isType unknown", operator: OpIsType, actual: 1.0, expected: "float", wantErr: "unknown type"},
+		{name: "isType non-string", operator: OpIsType, actual: 1.0, expected: 1.0, wantErr: "expects a type name"},
+
+		{name: "length exact", operator: OpLength, actual: list, expected: 3.0, want: true},
+		{name: "length exact mismatch", operator: OpLength, actual: list, expected: 2.0, want: false},
+		{name: "length counts runes", operator: OpLength, actual: "héllo", expected: 5.0, want: true},
+		{name: "length of object", operator: OpLength, actual: object, expected: 2.0, want: true},
+		{name: "length within bounds", operator: OpLength, actual: list, expected: map[string]interface{}{"gte": 1.0, "lte": 3.0}, want: true},
+		{name: "length below lower bound", operator: OpLength, actual: list, expected: map[string]interface{}{"gt": 3.0}, want: false},
+		{name: "length above upper bound", operator: OpLength, actual: list, expected: map[string]interface{}{"gte": 1.0, "lt": 3.0}, want: false},
+		{name: "length empty bounds", operator: OpLength, actual: list, expected: map[string]interface{}{}, wantErr: "number or comparisons"},
+		{name: "length string bound", operator: OpLength, actual: list, expected: map[string]interface{}{"gte": "1"}, wantErr: "cannot compare"},
+		{name: "length unknown bound", operator: OpLength, actual: list, expected: map[string]interface{}{"between": 1.0}, wantErr: "unknown operator"},
+		{name: "length string expected", operator: OpLength, actual: list, expected: "3", wantErr: "number or comparisons"},
+		{name: "length of number", operator: OpLength, actual: 3.0, expected: 1.0, wantErr: "got number"},
+		{name: "length of null", operator: OpLength, actual: nil, expected: 0.0, wantErr: "got null"},
+
+		{name: "isEmpty string", operator: OpIsEmpty, actual: "", noExpected: true, want: true},
+		{name: "isEmpty null", operator: OpIsEmpty, actual: nil, noExpected: true, want: true},
+		{name: "isEmpty array", operator: OpIsEmpty, actual: []interface{}{}, noExpected: true, want: true},
+		{name: "isEmpty object", operator: OpIsEmpty, actual: map[string]interface{}{}, noExpected: true, want: true},
+		{name: "isEmpty zero is not empty", operator: OpIsEmpty, actual: 0.0, noExpected: true, want: false},
+		{name: "isEmpty false", operator: OpIsEmpty, actual: list, expected: false, want: true},
+		{name: "isEmpty non-boolean", operator: OpIsEmpty, actual: list, expected: "no", wantErr: "expects true or false"},
+
+		{name: "unknown operator", operator: "between", actual: 1.0, expected: 1.0, wantErr: "unknown operator"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyOperator(tt.operator, tt.actual, tt.expected, !tt.noExpected)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateAssertionMissingPaths(t *testing.T) {
+	root := map[string]interface{}{
+		"status": 200.0,
+		"body":   map[string]interface{}{"items": []interface{}{}, "note": nil},
+	}
+
+	tests := []struct {
+		name        string
+		assertion   fieldAssertion
+		wantPassed  bool
+		wantMessage string
+	}{
+		{name: "exists", assertion: fieldAssertion{Path: "body.items", Operator: OpExists}, wantPassed: true},
+		{name: "exists on null", assertion: fieldAssertion{Path: "body.note", Operator: OpExists}, wantPassed: true},
+		{name: "exists on missing path", assertion: fieldAssertion{Path: "body.missing", Operator: OpExists}, wantMessage: "no value at path"},
+		{name: "exists false on missing path", assertion: fieldAssertion{Path: "body.missing", Operator: OpExists, Value: false, HasValue: true}, wantPassed: true},
+		{name: "exists false on present path", assertion: fieldAssertion{Path: "status", Operator: OpExists, Value: false, HasValue: true}, wantMessage: "value exists at path"},
+		{name: "exists non-boolean", assertion: fieldAssertion{Path: "status", Operator: OpExists, Value: "yes", HasValue: true}, wantMessage: "expects true or false"},
+		{name: "exists on missing wildcard", assertion: fieldAssertion{Path: "body.items[*].id", Operator: OpExists}, wantMessage: "no value at path"},
+
+		{name: "isEmpty", assertion: fieldAssertion{Path: "body.items", Operator: OpIsEmpty}, wantPassed: true},
+		{name: "isEmpty on null", assertion: fieldAssertion{Path: "body.note", Operator: OpIsEmpty}, wantPassed: true},
+		// A missing value is reported rather than treated as empty
+		{name: "isEmpty on missing path", assertion: fieldAssertion{Path: "body.missing", Operator: OpIsEmpty}, wantMessage: "no value at path"},
+		{name: "isEmpty false on missing path", assertion: fieldAssertion{Path: "body.missing", Operator: OpIsEmpty, Value: false, HasValue: true}, wantMessage: "no value at path"},
+		{name: "eq on missing path", assertion: fieldAssertion{Path: "body.missing", Operator: OpEq, Value: nil, HasValue: true}, wantMessage: "no value at path"},
+
+		{name: "failure message", assertion: fieldAssertion{Path: "status", Operator: OpEq, Value: 201.0, HasValue: true}, wantMessage: "expected eq 201, got 200"},
+		{name: "invalid path", assertion: fieldAssertion{Path: "$.", Operator: OpExists}, wantMessage: "ends with a dot"},
+		{name: "unknown operator", assertion: fieldAssertion{Path: "status", Operator: "between", Value: 1.0, HasValue: true}, wantMessage: "unknown operator"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := evaluateAssertion(root, tt.assertion)
+			if result.Passed != tt.wantPassed {
+				t.Errorf("passed = %v, want %v (%s)", result.Passed, tt.wantPassed, result.Message)
+			}
+			if !strings.Contains(result.Message, tt.wantMessage) {
+				t.Errorf("message %q doesn't mention %q", result.Message, tt.wantMessage)
+			}
+		})
+	}
+}
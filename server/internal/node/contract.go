@@ -0,0 +1,168 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+	"github.com/visual-api-testing-platform/server/internal/openapi"
+)
+
+// contract binds an API node to an operation of a library spec, by
+// operationId or by method and path template
+type contract struct {
+	SpecID           uuid.UUID
+	OperationID      string
+	Method           string
+	Path             string
+	ValidateRequest  bool
+	ValidateResponse bool
+}
+
+// parseContract reads the contract config. It returns nil when the node has none.
+func parseContract(config map[string]interface{}) (*contract, error) {
+	raw, ok := config["contract"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	fields, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("contract must be an object")
+	}
+
+	idStr, _ := fields["specId"].(string)
+	specID, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fmt.Errorf("contract.specId must be a valid spec ID")
+	}
+
+	c := &contract{SpecID: specID, ValidateRequest: true, ValidateResponse: true}
+	c.OperationID, _ = fields["operationId"].(string)
+	c.Method, _ = fields["method"].(string)
+	c.Path, _ = fields["path"].(string)
+	if c.OperationID == "" && c.Path == "" {
+		return nil, fmt.Errorf("contract needs an operationId or a path")
+	}
+	for key, target := range map[string]*bool{"validateRequest": &c.ValidateRequest, "validateResponse": &c.ValidateResponse} {
+		if v, ok := fields[key]; ok {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("contract.%s must be a boolean", key)
+			}
+			*target = b
+		}
+	}
+	return c, nil
+}
+
+// operation loads the spec from the library and finds the bound operation.
+// method is the node's, used when the contract names a path without one.
+func (c *contract) operation(ctx context.Context, method string) (*openapi.Operation, error) {
+	exec, ok := ExecutionFromContext(ctx)
+	if !ok || exec.Specs == nil {
+		return nil, fmt.Errorf("contracts can only be validated as part of a flow run")
+	}
+
+	doc, err := specDocuments.document(ctx, exec.Specs, c.SpecID)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.OperationID != "" {
+		return doc.Operation(c.OperationID)
+	}
+	if c.Method != "" {
+		method = c.Method
+	}
+	return doc.OperationFor(method, c.Path)
+}
+
+// specDocuments holds the parsed library specs API nodes validate against
+var specDocuments = &specCache{entries: make(map[uuid.UUID]cachedSpec)}
+
+// specCache keeps each spec's parsed document, or the reason it's invalid,
+// until the spec's updated_at changes, so contract checks don't load and
+// parse the document on every execution
+type specCache struct {
+	mu      sync.Mutex
+	entries map[uuid.UUID]cachedSpec
+}
+
+type cachedSpec struct {
+	updatedAt time.Time
+	doc       *openapi.Document
+	err       error
+}
+
+func (c *specCache) document(ctx context.Context, store SpecStore, id uuid.UUID) (*openapi.Document, error) {
+	updatedAt, err := store.GetUpdatedAt(ctx, id)
+	if err != nil {
+		// The spec may have been deleted
+		c.mu.Lock()
+		delete(c.entries, id)
+		c.mu.Unlock()
+		return nil, fmt.Errorf("failed to load spec %s: %w", id, err)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[id]
+	c.mu.Unlock()
+	if ok && entry.updatedAt.Equal(updatedAt) {
+		return entry.doc, entry.err
+	}
+
+	spec, err := store.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spec %s: %w", id, err)
+	}
+	entry = cachedSpec{updatedAt: spec.UpdatedAt}
+	if entry.doc, err = openapi.Parse(spec.Document); err != nil {
+		entry.err = fmt.Errorf("spec %s is invalid: %w", spec.Name, err)
+	}
+
+	c.mu.Lock()
+	c.entries[id] = entry
+	c.mu.Unlock()
+	return entry.doc, entry.err
+}
+
+// contractReport summarizes a contract check for the node output and records
+// each violation as a failed assertion on the node result
+func contractReport(ctx context.Context, c *contract, op *openapi.Operation, violations []openapi.Violation) (map[string]interface{}, error) {
+	report := map[string]interface{}{
+		"specId":     c.SpecID.String(),
+		"operation":  op.Name(),
+		"method":     op.Method,
+		"path":       op.Path,
+		"passed":     len(violations) == 0,
+		"violations": violations,
+	}
+	if len(violations) == 0 {
+		return report, nil
+	}
+
+	results := make([]models.AssertionResult, 0, len(violations))
+	parts := make([]string, 0, maxReportedFailures)
+	for i, v := range violations {
+		results = append(results, models.AssertionResult{
+			Path:     v.Location,
+			Operator: v.Keyword,
+			Expected: v.Expected,
+			Actual:   v.Actual,
+			Message:  v.Message,
+		})
+		if i < maxReportedFailures {
+			parts = append(parts, fmt.Sprintf("%s: %s", v.Location, v.Message))
+		} else if i == maxReportedFailures {
+			parts = append(parts, fmt.Sprintf("and %d more", len(violations)-i))
+		}
+	}
+	exec, _ := ExecutionFromContext(ctx)
+	exec.Assertions.Record(results...)
+
+	return report, fmt.Errorf("contract %s violated %d time(s): %s", op.Name(), len(violations), strings.Join(parts, "; "))
+}
@@ -0,0 +1,133 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// fakeSpecStore keeps specs in memory and counts document loads
+type fakeSpecStore struct {
+	mu    sync.Mutex
+	specs map[uuid.UUID]models.APISpec
+	loads int
+}
+
+func (s *fakeSpecStore) GetByID(ctx context.Context, id uuid.UUID) (*models.APISpec, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	spec, ok := s.specs[id]
+	if !ok {
+		return nil, errors.New("no rows in result set")
+	}
+	s.loads++
+	return &spec, nil
+}
+
+func (s *fakeSpecStore) GetUpdatedAt(ctx context.Context, id uuid.UUID) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	spec, ok := s.specs[id]
+	if !ok {
+		return time.Time{}, errors.New("no rows in result set")
+	}
+	return spec.UpdatedAt, nil
+}
+
+func (s *fakeSpecStore) put(spec models.APISpec) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.specs[spec.ID] = spec
+}
+
+func (s *fakeSpecStore) loadCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loads
+}
+
+func petsSpec(id uuid.UUID, updatedAt time.Time, operationID string) models.APISpec {
+	return models.APISpec{
+		ID:        id,
+		Name:      "pets",
+		UpdatedAt: updatedAt,
+		Document: map[string]interface{}{
+			"openapi": "3.1.0",
+			"info":    map[string]interface{}{"title": "Pets", "version": "1"},
+			"paths": map[string]interface{}{
+				"/pets": map[string]interface{}{
+					"get": map[string]interface{}{
+						"operationId": operationID,
+						"responses":   map[string]interface{}{"200": map[string]interface{}{"description": "ok"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestContractOperationCachesSpecs(t *testing.T) {
+	id := uuid.New()
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &fakeSpecStore{specs: make(map[uuid.UUID]models.APISpec)}
+	store.put(petsSpec(id, created, "listPets"))
+	ctx := WithExecution(context.Background(), Execution{Specs: store})
+
+	c := &contract{SpecID: id, OperationID: "listPets"}
+	for i := 0; i < 3; i++ {
+		op, err := c.operation(ctx, "GET")
+		if err != nil {
+			t.Fatalf("operation: %v", err)
+		}
+		if op.Path != "/pets" {
+			t.Errorf("operation path = %s, want /pets", op.Path)
+		}
+	}
+	if loads := store.loadCount(); loads != 1 {
+		t.Errorf("spec loaded %d times, want once", loads)
+	}
+
+	// Editing the spec bumps updated_at and the new document is used
+	store.put(petsSpec(id, created.Add(time.Second), "getPets"))
+	if _, err := c.operation(ctx, "GET"); err == nil {
+		t.Error("operation found listPets in the updated spec")
+	}
+	c = &contract{SpecID: id, OperationID: "getPets"}
+	if _, err := c.operation(ctx, "GET"); err != nil {
+		t.Errorf("operation: %v", err)
+	}
+	if loads := store.loadCount(); loads != 2 {
+		t.Errorf("spec loaded %d times, want twice", loads)
+	}
+}
+
+func TestContractOperationCachesInvalidSpecs(t *testing.T) {
+	id := uuid.New()
+	store := &fakeSpecStore{specs: make(map[uuid.UUID]models.APISpec)}
+	store.put(models.APISpec{ID: id, Name: "broken", Document: map[string]interface{}{"openapi": "2.0"}})
+	ctx := WithExecution(context.Background(), Execution{Specs: store})
+
+	c := &contract{SpecID: id, OperationID: "listPets"}
+	for i := 0; i < 2; i++ {
+		if _, err := c.operation(ctx, "GET"); err == nil || !strings.Contains(err.Error(), "spec broken is invalid") {
+			t.Errorf("error = %v, want the spec to be invalid", err)
+		}
+	}
+	if loads := store.loadCount(); loads != 1 {
+		t.Errorf("spec loaded %d times, want once", loads)
+	}
+
+	// Deleted specs fail to load
+	store.mu.Lock()
+	delete(store.specs, id)
+	store.mu.Unlock()
+	if _, err := c.operation(ctx, "GET"); err == nil || !strings.Contains(err.Error(), "failed to load spec") {
+		t.Errorf("error = %v, want the spec to be missing", err)
+	}
+}
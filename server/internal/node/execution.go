@@ -0,0 +1,153 @@
+package node
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// SnapshotStore persists the golden values used by snapshot assertions
+type SnapshotStore interface {
+	// FindSnapshot returns the node's snapshot, or nil if none has been recorded
+	FindSnapshot(ctx context.Context, flowID uuid.UUID, nodeID string) (*models.NodeSnapshot, error)
+
+	// RecordSnapshot stores snapshot unless the node already has one and
+	// reports whether it was stored
+	RecordSnapshot(ctx context.Context, snapshot *models.NodeSnapshot) (bool, error)
+}
+
+// SchemaStore loads JSON Schemas from the shared library for schema assertions
+type SchemaStore interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Schema, error)
+}
+
+// SpecStore loads OpenAPI documents from the spec library for API node contracts
+type SpecStore interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.APISpec, error)
+
+	// GetUpdatedAt returns when the spec last changed, so parsed documents
+	// can be reused until it does
+	GetUpdatedAt(ctx context.Context, id uuid.UUID) (time.Time, error)
+}
+
+// Assertions collects the structured assertion results a node reports for its
+// NodeResult. It is safe for concurrent use; a nil Assertions discards results.
+type Assertions struct {
+	mu      sync.Mutex
+	results []models.AssertionResult
+}
+
+// Record adds results to the node's report
+func (a *Assertions) Record(results ...models.AssertionResult) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.results = append(a.results, results...)
+	a.mu.Unlock()
+}
+
+// Results returns everything recorded so far
+func (a *Assertions) Results() []models.AssertionResult {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]models.AssertionResult(nil), a.results...)
+}
+
+// RunInfo describes the test run a node is executing in
+type RunInfo struct {
+	TestRunID     uuid.UUID
+	FlowID        uuid.UUID
+	FlowName      string
+	FlowVersion   int
+	TriggerSource string
+	Environment   string
+	Tags          []string
+	StartedAt     time.Time
+}
+
+// Upstream is the result of the node at the other end of an incoming edge
+type Upstream struct {
+	NodeID       string
+	SourceHandle string
+	TargetHandle string
+	Status       models.ExecutionStatus
+	Output       map[string]interface{} // nil unless the node succeeded
+	Error        string
+}
+
+// Execution is the context a node executes in within a flow run. Each node
+// gets its own copy of upstream outputs and variables, so nodes running in
+// parallel never share mutable state.
+type Execution struct {
+	Run       RunInfo
+	NodeID    string
+	Snapshots SnapshotStore
+	Schemas   SchemaStore
+	Specs     SpecStore
+
+	// Assertions receives the node's structured assertion results
+	Assertions *Assertions
+
+	// Upstream holds one entry per incoming edge, in edge order
+	Upstream []Upstream
+
+	// Variables are the flow's variables overridden by those of the run
+	Variables map[string]interface{}
+}
+
+// Dependencies returns the IDs of the nodes feeding this node in edge order,
+// each once
+func (e Execution) Dependencies() []string {
+	ids := make([]string, 0, len(e.Upstream))
+	seen := make(map[string]bool, len(e.Upstream))
+	for _, u := range e.Upstream {
+		if !seen[u.NodeID] {
+			seen[u.NodeID] = true
+			ids = append(ids, u.NodeID)
+		}
+	}
+	return ids
+}
+
+// UpstreamOutput returns the output of the upstream node nodeID, if it succeeded
+func (e Execution) UpstreamOutput(nodeID string) (map[string]interface{}, bool) {
+	for _, u := range e.Upstream {
+		if u.NodeID == nodeID && u.Output != nil {
+			return u.Output, true
+		}
+	}
+	return nil, false
+}
+
+// UpstreamForHandle returns the upstream results connected to the node's
+// targetHandle input
+func (e Execution) UpstreamForHandle(targetHandle string) []Upstream {
+	var matches []Upstream
+	for _, u := range e.Upstream {
+		if u.TargetHandle == targetHandle {
+			matches = append(matches, u)
+		}
+	}
+	return matches
+}
+
+type executionKey struct{}
+
+// WithExecution returns a context carrying the node's execution details
+func WithExecution(ctx context.Context, exec Execution) context.Context {
+	return context.WithValue(ctx, executionKey{}, exec)
+}
+
+// ExecutionFromContext returns the execution details set by WithExecution.
+// Nodes executed on their own, outside a flow run, have none.
+func ExecutionFromContext(ctx context.Context) (Execution, bool) {
+	exec, ok := ctx.Value(executionKey{}).(Execution)
+	return exec, ok
+}
@@ -0,0 +1,60 @@
+package node
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Input merge strategies, set per node with the inputMerge config
+const (
+	// MergeStrategyMerge copies the data fields of every upstream output to
+	// the top level of the input; later edges win on conflicts. The default.
+	MergeStrategyMerge = "merge"
+	// MergeStrategyNone only keys upstream outputs by node ID
+	MergeStrategyNone = "none"
+	// MergeStrategyStrict merges like MergeStrategyMerge but fails the node
+	// when two upstream nodes provide different values for the same field
+	MergeStrategyStrict = "strict"
+)
+
+// BuildInput builds a node's input from its upstream results: each
+// successful output keyed by node ID, plus their data fields merged into the
+// top level according to strategy. Node ID keys always take precedence over
+// merged fields.
+func BuildInput(upstream []Upstream, strategy string) (map[string]interface{}, error) {
+	if strategy == "" {
+		strategy = MergeStrategyMerge
+	}
+	switch strategy {
+	case MergeStrategyMerge, MergeStrategyNone, MergeStrategyStrict:
+	default:
+		return nil, fmt.Errorf("invalid inputMerge strategy: %s", strategy)
+	}
+
+	input := make(map[string]interface{})
+
+	if strategy != MergeStrategyNone {
+		providers := make(map[string]string)
+		for _, u := range upstream {
+			data, ok := u.Output["data"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for k, v := range data {
+				if previous, exists := providers[k]; exists && previous != u.NodeID &&
+					strategy == MergeStrategyStrict && !reflect.DeepEqual(input[k], v) {
+					return nil, fmt.Errorf("input field %q is provided by both %s and %s", k, previous, u.NodeID)
+				}
+				input[k] = v
+				providers[k] = u.NodeID
+			}
+		}
+	}
+
+	for _, u := range upstream {
+		if u.Output != nil {
+			input[u.NodeID] = u.Output
+		}
+	}
+	return input, nil
+}
@@ -0,0 +1,125 @@
+package node
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+func succeeded(nodeID string, data interface{}) Upstream {
+	return Upstream{NodeID: nodeID, Status: models.ExecutionStatusSuccess, Output: map[string]interface{}{"data": data}}
+}
+
+func TestBuildInput(t *testing.T) {
+	a := succeeded("a", map[string]interface{}{"id": 1.0, "name": "a"})
+	b := succeeded("b", map[string]interface{}{"id": 2.0, "extra": true})
+	sameID := succeeded("c", map[string]interface{}{"id": 1.0})
+	failed := Upstream{NodeID: "failed", Status: models.ExecutionStatusFailed, Error: "boom"}
+	list := succeeded("list", []interface{}{1.0, 2.0})
+	// A data field named after an upstream node
+	shadow := succeeded("shadow", map[string]interface{}{"a": "field", "other": "x"})
+
+	tests := []struct {
+		name     string
+		upstream []Upstream
+		strategy string
+		want     map[string]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "merge overlays in edge order",
+			upstream: []Upstream{a, b},
+			strategy: MergeStrategyMerge,
+			want:     map[string]interface{}{"id": 2.0, "name": "a", "extra": true, "a": a.Output, "b": b.Output},
+		},
+		{
+			name:     "merge overlays in reverse edge order",
+			upstream: []Upstream{b, a},
+			strategy: MergeStrategyMerge,
+			want:     map[string]interface{}{"id": 1.0, "name": "a", "extra": true, "a": a.Output, "b": b.Output},
+		},
+		{
+			name:     "merge is the default",
+			upstream: []Upstream{a, b},
+			want:     map[string]interface{}{"id": 2.0, "name": "a", "extra": true, "a": a.Output, "b": b.Output},
+		},
+		{
+			name:     "none only keys by node ID",
+			upstream: []Upstream{a, b},
+			strategy: MergeStrategyNone,
+			want:     map[string]interface{}{"a": a.Output, "b": b.Output},
+		},
+		{
+			name:     "strict errors on conflicting fields",
+			upstream: []Upstream{a, b},
+			strategy: MergeStrategyStrict,
+			wantErr:  `input field "id" is provided by both a and b`,
+		},
+		{
+			name:     "strict allows equal fields",
+			upstream: []Upstream{a, sameID},
+			strategy: MergeStrategyStrict,
+			want:     map[string]interface{}{"id": 1.0, "name": "a", "a": a.Output, "c": sameID.Output},
+		},
+		{
+			name:     "strict allows two edges from one node",
+			upstream: []Upstream{a, {NodeID: "a", SourceHandle: "other", Status: models.ExecutionStatusSuccess, Output: map[string]interface{}{"data": map[string]interface{}{"id": 3.0}}}},
+			strategy: MergeStrategyStrict,
+			want:     map[string]interface{}{"id": 3.0, "name": "a", "a": map[string]interface{}{"data": map[string]interface{}{"id": 3.0}}},
+		},
+		{
+			name:     "failed upstreams are left out",
+			upstream: []Upstream{failed, a},
+			strategy: MergeStrategyMerge,
+			want:     map[string]interface{}{"id": 1.0, "name": "a", "a": a.Output},
+		},
+		{
+			name:     "failed upstreams don't conflict",
+			upstream: []Upstream{a, failed},
+			strategy: MergeStrategyStrict,
+			want:     map[string]interface{}{"id": 1.0, "name": "a", "a": a.Output},
+		},
+		{
+			name:     "data that isn't an object is only keyed",
+			upstream: []Upstream{list, a},
+			want:     map[string]interface{}{"id": 1.0, "name": "a", "a": a.Output, "list": list.Output},
+		},
+		{
+			name:     "node IDs take precedence over fields",
+			upstream: []Upstream{a, shadow},
+			strategy: MergeStrategyStrict,
+			want:     map[string]interface{}{"id": 1.0, "name": "a", "other": "x", "a": a.Output, "shadow": shadow.Output},
+		},
+		{
+			name:     "no upstream",
+			strategy: MergeStrategyStrict,
+			want:     map[string]interface{}{},
+		},
+		{
+			name:     "invalid strategy",
+			upstream: []Upstream{a},
+			strategy: "overwrite",
+			wantErr:  "invalid inputMerge strategy: overwrite",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildInput(tt.upstream, tt.strategy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("input =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
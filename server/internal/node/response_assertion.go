@@ -0,0 +1,384 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/jsondiff"
+	"github.com/visual-api-testing-platform/server/internal/jsonschema"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// responseAssertion checks the metadata of an upstream response: the source
+// config node, or the first dependency. Config:
+//
+//	status        a code (200), a class ("2xx") or a list of either
+//	headers       name -> true (present), false (absent), a string (equal) or
+//	              {"operator": ..., "value": ...} using the field assertion operators
+//	contentType   media type, compared without parameters such as charset
+//	maxDurationMs response time budget
+//	maxSizeBytes  body size budget
+//	schema        JSON Schema for the body, or schemaId from the schema library
+//
+// Every check runs; the output lists each result like field assertions.
+func (v *VerificationNode) responseAssertion(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
+	exec, _ := ExecutionFromContext(ctx)
+
+	source, _ := v.Config["source"].(string)
+	if dependencies := exec.Dependencies(); source == "" && len(dependencies) > 0 {
+		source = dependencies[0]
+	}
+	if source == "" {
+		return nil, fmt.Errorf("response assertions need an upstream node")
+	}
+
+	var raw interface{}
+	if output, ok := exec.UpstreamOutput(source); ok {
+		raw = output
+	} else {
+		raw = input[source]
+	}
+	response, ok := jsondiff.Normalize(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no output from upstream node %s", source)
+	}
+
+	var headers map[string]interface{}
+	if h, ok := response["headers"].(map[string]interface{}); ok {
+		headers = flattenHeaders(h)
+	}
+
+	var results []models.AssertionResult
+
+	if spec, ok := v.Config["status"]; ok && spec != nil {
+		result := models.AssertionResult{Path: "status", Operator: "status", Expected: spec, Actual: response["status"]}
+		status, isNumber := response["status"].(float64)
+		if !isNumber {
+			result.Message = "response has no status"
+		} else if result.Passed = statusMatches(spec, int(status)); !result.Passed {
+			result.Message = fmt.Sprintf("expected status %s, got %d", formatStatusSpec(spec), int(status))
+		}
+		results = append(results, result)
+	}
+
+	if expected, ok := v.Config["headers"].(map[string]interface{}); ok {
+		results = append(results, headerResults(expected, headers)...)
+	}
+
+	if want, ok := v.Config["contentType"].(string); ok && want != "" {
+		actual, present := headers["Content-Type"].(string)
+		result := models.AssertionResult{Path: "headers.Content-Type", Operator: "contentType", Expected: want}
+		if !present {
+			result.Message = "response has no Content-Type"
+		} else {
+			result.Actual = actual
+			if result.Passed = mediaType(actual) == mediaType(want); !result.Passed {
+				result.Message = fmt.Sprintf("expected content type %s, got %s", want, actual)
+			}
+		}
+		results = append(results, result)
+	}
+
+	if budget, ok := v.Config["maxDurationMs"].(float64); ok {
+		results = append(results, budgetResult("durationMs", "ms", budget, response["durationMs"]))
+	}
+	if budget, ok := v.Config["maxSizeBytes"].(float64); ok {
+		results = append(results, budgetResult("size", "bytes", budget, response["size"]))
+	}
+
+	if hasSchemaCheck(v.Config) {
+		schemaChecks, err := v.bodySchemaResults(ctx, response["data"])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, schemaChecks...)
+	}
+
+	var failures []string
+	for _, r := range results {
+		if !r.Passed {
+			failures = append(failures, fmt.Sprintf("%s: %s", r.Path, r.Message))
+		}
+	}
+
+	output := map[string]interface{}{
+		"passed":     len(failures) == 0,
+		"source":     source,
+		"total":      len(results),
+		"failed":     len(failures),
+		"assertions": results,
+	}
+	if len(failures) > 0 {
+		return output, fmt.Errorf("%d of %d response checks failed: %s", len(failures), len(results), strings.Join(failures, "; "))
+	}
+	return output, nil
+}
+
+// validateResponseConfig checks that the response assertion config has at
+// least one well-formed check
+func validateResponseConfig(config map[string]interface{}) error {
+	checks := 0
+	if spec, ok := config["status"]; ok && spec != nil {
+		if err := validateStatusSpec(spec); err != nil {
+			return err
+		}
+		checks++
+	}
+	if headers, ok := config["headers"]; ok && headers != nil {
+		m, ok := headers.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("headers must be an object")
+		}
+		for name, expected := range m {
+			if _, _, err := headerExpectation(expected); err != nil {
+				return fmt.Errorf("header %s: %w", name, err)
+			}
+		}
+		checks += len(m)
+	}
+	if ct, ok := config["contentType"].(string); ok && ct != "" {
+		checks++
+	}
+	for _, key := range []string{"maxDurationMs", "maxSizeBytes"} {
+		if value, ok := config[key]; ok && value != nil {
+			if n, ok := value.(float64); !ok || n < 0 {
+				return fmt.Errorf("%s must be a non-negative number", key)
+			}
+			checks++
+		}
+	}
+	if hasSchemaCheck(config) {
+		if document, ok := config["schema"]; ok && document != nil {
+			if _, err := jsonschema.Compile(jsondiff.Normalize(document)); err != nil {
+				return fmt.Errorf("invalid schema: %w", err)
+			}
+		} else if id, _ := config["schemaId"].(string); uuid.Validate(id) != nil {
+			return fmt.Errorf("schemaId must be a valid schema ID")
+		}
+		checks++
+	}
+	if checks == 0 {
+		return fmt.Errorf("response assertions need at least one of status, headers, contentType, maxDurationMs, maxSizeBytes or schema")
+	}
+	return nil
+}
+
+func hasSchemaCheck(config map[string]interface{}) bool {
+	return config["schema"] != nil || config["schemaId"] != nil
+}
+
+// bodySchemaResults validates the response body against the schema config,
+// with one result per violation or a single passing result
+func (v *VerificationNode) bodySchemaResults(ctx context.Context, body interface{}) ([]models.AssertionResult, error) {
+	document, err := v.loadSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := jsonschema.Compile(document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	violations := schema.Validate(body)
+	if len(violations) == 0 {
+		return []models.AssertionResult{{Path: "body", Operator: "schema", Passed: true}}, nil
+	}
+	results := make([]models.AssertionResult, 0, len(violations))
+	for _, violation := range violations {
+		results = append(results, models.AssertionResult{
+			Path:     "body" + violation.InstancePath,
+			Operator: "schema",
+			Message:  fmt.Sprintf("%s: %s", violation.Keyword, violation.Message),
+		})
+	}
+	return results, nil
+}
+
+// statusMatches reports whether status satisfies a code, a class such as
+// "2xx" or a list of either
+func statusMatches(spec interface{}, status int) bool {
+	switch s := spec.(type) {
+	case float64:
+		return int(s) == status
+	case string:
+		if isStatusClass(s) {
+			return status/100 == int(s[0]-'0')
+		}
+		n, err := strconv.Atoi(s)
+		return err == nil && n == status
+	case []interface{}:
+		for _, item := range s {
+			if statusMatches(item, status) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func validateStatusSpec(spec interface{}) error {
+	switch s := spec.(type) {
+	case float64:
+		if s < 100 || s > 599 {
+			return fmt.Errorf("invalid status %v", s)
+		}
+		return nil
+	case string:
+		if isStatusClass(s) {
+			return nil
+		}
+		if n, err := strconv.Atoi(s); err == nil {
+			return validateStatusSpec(float64(n))
+		}
+		return fmt.Errorf("invalid status %q: use a code or a class such as 2xx", s)
+	case []interface{}:
+		if len(s) == 0 {
+			return fmt.Errorf("status list is empty")
+		}
+		for _, item := range s {
+			if _, nested := item.([]interface{}); nested {
+				return fmt.Errorf("status lists cannot be nested")
+			}
+			if err := validateStatusSpec(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("status must be a code, a class or a list")
+}
+
+func isStatusClass(s string) bool {
+	s = strings.ToLower(s)
+	return len(s) == 3 && s[0] >= '1' && s[0] <= '5' && s[1:] == "xx"
+}
+
+func formatStatusSpec(spec interface{}) string {
+	if list, ok := spec.([]interface{}); ok {
+		parts := make([]string, len(list))
+		for i, item := range list {
+			parts[i] = formatStatusSpec(item)
+		}
+		return "one of " + strings.Join(parts, ", ")
+	}
+	if f, ok := spec.(float64); ok {
+		return strconv.Itoa(int(f))
+	}
+	return fmt.Sprint(spec)
+}
+
+// headerExpectation turns a headers config value into an operator and operand
+func headerExpectation(expected interface{}) (string, interface{}, error) {
+	switch e := expected.(type) {
+	case bool:
+		return OpExists, e, nil
+	case string:
+		return OpEq, e, nil
+	case map[string]interface{}:
+		operator, _ := e["operator"].(string)
+		if !validOperators[operator] {
+			return "", nil, fmt.Errorf("unknown operator: %s", operator)
+		}
+		value, ok := e["value"]
+		if !ok {
+			if operator == OpExists {
+				return OpExists, true, nil
+			}
+			if operator != OpIsEmpty {
+				return "", nil, fmt.Errorf("%s needs a value", operator)
+			}
+		}
+		return operator, jsondiff.Normalize(value), nil
+	}
+	return "", nil, fmt.Errorf("expected true, false, a string or {operator, value}")
+}
+
+// headerResults checks each expected header against the response headers,
+// matching names case-insensitively
+func headerResults(expected map[string]interface{}, headers map[string]interface{}) []models.AssertionResult {
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]models.AssertionResult, 0, len(names))
+	for _, name := range names {
+		key := http.CanonicalHeaderKey(name)
+		result := models.AssertionResult{Path: "headers." + key}
+
+		operator, value, err := headerExpectation(expected[name])
+		if err != nil {
+			result.Message = err.Error()
+			results = append(results, result)
+			continue
+		}
+		result.Operator = operator
+		result.Expected = value
+
+		actual, present := headers[key]
+		if present {
+			result.Actual = actual
+		}
+
+		if operator == OpExists {
+			want := value.(bool)
+			result.Passed = present == want
+			if !result.Passed {
+				if want {
+					result.Message = "header is missing"
+				} else {
+					result.Message = "header is present"
+				}
+			}
+			results = append(results, result)
+			continue
+		}
+		if !present {
+			result.Message = "header is missing"
+			results = append(results, result)
+			continue
+		}
+
+		passed, err := applyOperator(operator, actual, value, true)
+		switch {
+		case err != nil:
+			result.Message = err.Error()
+		case !passed:
+			result.Message = fmt.Sprintf("expected %s %s, got %s", operator, formatValue(value), formatValue(actual))
+		default:
+			result.Passed = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// budgetResult checks that a measured response value stays within budget
+func budgetResult(path, unit string, budget float64, actual interface{}) models.AssertionResult {
+	result := models.AssertionResult{Path: path, Operator: OpLte, Expected: budget}
+	value, ok := actual.(float64)
+	if !ok {
+		result.Message = fmt.Sprintf("response has no %s; only API nodes measure it", path)
+		return result
+	}
+	result.Actual = value
+	if result.Passed = value <= budget; !result.Passed {
+		result.Message = fmt.Sprintf("%v %s exceeds the budget of %v %s", value, unit, budget, unit)
+	}
+	return result
+}
+
+// mediaType returns the lower-case media type without parameters
+func mediaType(contentType string) string {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+}
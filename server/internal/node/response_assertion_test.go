@@ -0,0 +1,221 @@
+package node
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// apiOutput is shaped like an API node's output, before it is stored
+func apiOutput() map[string]interface{} {
+	return map[string]interface{}{
+		"status":     201,
+		"statusText": "201 Created",
+		"headers": http.Header{
+			"Content-Type": {"application/json; charset=utf-8"},
+			"Location":     {"/items/7"},
+			"Set-Cookie":   {"a=1", "b=2"},
+			"X-Request-Id": {"abc-123"},
+		},
+		"data":       map[string]interface{}{"id": 7.0},
+		"durationMs": int64(120),
+		"size":       512,
+	}
+}
+
+func runResponseAssertion(config map[string]interface{}) (map[string]interface{}, error) {
+	config["assertionType"] = "response"
+	ctx := WithExecution(context.Background(), Execution{
+		NodeID: "verify",
+		Upstream: []Upstream{
+			{NodeID: "api", Status: models.ExecutionStatusSuccess, Output: apiOutput()},
+			{NodeID: "transform", Status: models.ExecutionStatusSuccess, Output: map[string]interface{}{"data": map[string]interface{}{"status": 201.0}}},
+		},
+	})
+	return NewVerificationNode("verify", "Verify", config).Execute(ctx, map[string]interface{}{})
+}
+
+func TestResponseAssertion(t *testing.T) {
+	type check struct {
+		path    string
+		passed  bool
+		message string // a substring of the failure message
+	}
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   []check
+	}{
+		{name: "status code", config: map[string]interface{}{"status": 201.0}, want: []check{{path: "status", passed: true}}},
+		{name: "status code as a string", config: map[string]interface{}{"status": "201"}, want: []check{{path: "status", passed: true}}},
+		{name: "status class", config: map[string]interface{}{"status": "2xx"}, want: []check{{path: "status", passed: true}}},
+		{name: "status class in capitals", config: map[string]interface{}{"status": "2XX"}, want: []check{{path: "status", passed: true}}},
+		{name: "other status class", config: map[string]interface{}{"status": "4xx"}, want: []check{{path: "status", message: "expected status 4xx, got 201"}}},
+		{name: "other status code", config: map[string]interface{}{"status": 200.0}, want: []check{{path: "status", message: "expected status 200, got 201"}}},
+		{name: "status list", config: map[string]interface{}{"status": []interface{}{200.0, "2xx"}}, want: []check{{path: "status", passed: true}}},
+		{
+			name:   "status list without a match",
+			config: map[string]interface{}{"status": []interface{}{200.0, "3xx"}},
+			want:   []check{{path: "status", message: "expected status one of 200, 3xx, got 201"}},
+		},
+
+		{name: "header present", config: map[string]interface{}{"headers": map[string]interface{}{"location": true}}, want: []check{{path: "headers.Location", passed: true}}},
+		{name: "header missing", config: map[string]interface{}{"headers": map[string]interface{}{"etag": true}}, want: []check{{path: "headers.Etag", message: "header is missing"}}},
+		{name: "header absent", config: map[string]interface{}{"headers": map[string]interface{}{"x-debug": false}}, want: []check{{path: "headers.X-Debug", passed: true}}},
+		{name: "header not absent", config: map[string]interface{}{"headers": map[string]interface{}{"Location": false}}, want: []check{{path: "headers.Location", message: "header is present"}}},
+		{name: "header value", config: map[string]interface{}{"headers": map[string]interface{}{"LOCATION": "/items/7"}}, want: []check{{path: "headers.Location", passed: true}}},
+		{
+			name:   "header other value",
+			config: map[string]interface{}{"headers": map[string]interface{}{"location": "/items/8"}},
+			want:   []check{{path: "headers.Location", message: `expected eq "/items/8", got "/items/7"`}},
+		},
+		{name: "header value when missing", config: map[string]interface{}{"headers": map[string]interface{}{"etag": "W/1"}}, want: []check{{path: "headers.Etag", message: "header is missing"}}},
+		{name: "repeated header values are joined", config: map[string]interface{}{"headers": map[string]interface{}{"set-cookie": "a=1, b=2"}}, want: []check{{path: "headers.Set-Cookie", passed: true}}},
+		{
+			name:   "header operator",
+			config: map[string]interface{}{"headers": map[string]interface{}{"location": map[string]interface{}{"operator": "startsWith", "value": "/items/"}}},
+			want:   []check{{path: "headers.Location", passed: true}},
+		},
+		{
+			name:   "header operator without a match",
+			config: map[string]interface{}{"headers": map[string]interface{}{"x-request-id": map[string]interface{}{"operator": "matches", "value": `^\d+$`}}},
+			want:   []check{{path: "headers.X-Request-Id", message: `expected matches "^\\d+$", got "abc-123"`}},
+		},
+		{
+			name: "headers are checked in name order",
+			config: map[string]interface{}{"headers": map[string]interface{}{
+				"x-request-id": true,
+				"content-type": map[string]interface{}{"operator": "contains", "value": "json"},
+				"etag":         true,
+			}},
+			want: []check{{path: "headers.Content-Type", passed: true}, {path: "headers.Etag", message: "header is missing"}, {path: "headers.X-Request-Id", passed: true}},
+		},
+
+		{name: "content type without parameters", config: map[string]interface{}{"contentType": "Application/JSON"}, want: []check{{path: "headers.Content-Type", passed: true}}},
+		{name: "other content type", config: map[string]interface{}{"contentType": "text/html"}, want: []check{{path: "headers.Content-Type", message: "expected content type text/html"}}},
+
+		{name: "duration within budget", config: map[string]interface{}{"maxDurationMs": 500.0}, want: []check{{path: "durationMs", passed: true}}},
+		{name: "duration at budget", config: map[string]interface{}{"maxDurationMs": 120.0}, want: []check{{path: "durationMs", passed: true}}},
+		{name: "duration over budget", config: map[string]interface{}{"maxDurationMs": 100.0}, want: []check{{path: "durationMs", message: "120 ms exceeds the budget of 100 ms"}}},
+		{name: "size within budget", config: map[string]interface{}{"maxSizeBytes": 1024.0}, want: []check{{path: "size", passed: true}}},
+		{name: "size at budget", config: map[string]interface{}{"maxSizeBytes": 512.0}, want: []check{{path: "size", passed: true}}},
+		{name: "size over budget", config: map[string]interface{}{"maxSizeBytes": 511.0}, want: []check{{path: "size", message: "512 bytes exceeds the budget of 511 bytes"}}},
+		{
+			name:   "budgets on a node that doesn't measure them",
+			config: map[string]interface{}{"source": "transform", "maxDurationMs": 100.0, "maxSizeBytes": 100.0},
+			want: []check{
+				{path: "durationMs", message: "response has no durationMs; only API nodes measure it"},
+				{path: "size", message: "response has no size; only API nodes measure it"},
+			},
+		},
+
+		{
+			name: "every check runs",
+			config: map[string]interface{}{
+				"status":        "2xx",
+				"headers":       map[string]interface{}{"location": true},
+				"contentType":   "application/json",
+				"maxDurationMs": 100.0,
+				"maxSizeBytes":  1024.0,
+			},
+			want: []check{
+				{path: "status", passed: true},
+				{path: "headers.Location", passed: true},
+				{path: "headers.Content-Type", passed: true},
+				{path: "durationMs", message: "exceeds the budget"},
+				{path: "size", passed: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runResponseAssertion(tt.config)
+			if output == nil {
+				t.Fatalf("no output: %v", err)
+			}
+			results, _ := output["assertions"].([]models.AssertionResult)
+			if len(results) != len(tt.want) {
+				t.Fatalf("got %d results, want %d: %+v", len(results), len(tt.want), results)
+			}
+
+			failed := 0
+			for i, want := range tt.want {
+				got := results[i]
+				if got.Path != want.path || got.Passed != want.passed {
+					t.Errorf("result %d = %s passed %v, want %s passed %v (%s)", i, got.Path, got.Passed, want.path, want.passed, got.Message)
+				}
+				if !strings.Contains(got.Message, want.message) {
+					t.Errorf("result %d message %q doesn't mention %q", i, got.Message, want.message)
+				}
+				if !want.passed {
+					failed++
+				}
+			}
+
+			if output["passed"] != (failed == 0) || output["failed"] != failed || output["total"] != len(tt.want) {
+				t.Errorf("output passed %v, failed %v of %v, want %d of %d failed", output["passed"], output["failed"], output["total"], failed, len(tt.want))
+			}
+			if (err != nil) != (failed > 0) {
+				t.Errorf("error = %v, want one only when a check fails", err)
+			}
+		})
+	}
+}
+
+func TestResponseAssertionSource(t *testing.T) {
+	// Without a source the first dependency is checked
+	if _, err := runResponseAssertion(map[string]interface{}{"status": 201.0}); err != nil {
+		t.Errorf("first dependency: %v", err)
+	}
+	output, err := runResponseAssertion(map[string]interface{}{"source": "transform", "status": 201.0})
+	if err == nil || !strings.Contains(err.Error(), "response has no status") {
+		t.Errorf("error = %v, want the transform node to have no status", err)
+	}
+	if output["source"] != "transform" {
+		t.Errorf("source = %v, want transform", output["source"])
+	}
+
+	if _, err := runResponseAssertion(map[string]interface{}{"source": "missing", "status": 201.0}); err == nil || !strings.Contains(err.Error(), "no output from upstream node missing") {
+		t.Errorf("error = %v, want no output from missing", err)
+	}
+}
+
+func TestValidateResponseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{name: "status and budgets", config: map[string]interface{}{"status": []interface{}{200.0, "2xx"}, "maxDurationMs": 0.0, "maxSizeBytes": 10.0}},
+		{name: "no checks", config: map[string]interface{}{}, wantErr: "need at least one of"},
+		{name: "status out of range", config: map[string]interface{}{"status": 99.0}, wantErr: "invalid status 99"},
+		{name: "status class out of range", config: map[string]interface{}{"status": "6xx"}, wantErr: "use a code or a class"},
+		{name: "empty status list", config: map[string]interface{}{"status": []interface{}{}}, wantErr: "status list is empty"},
+		{name: "nested status list", config: map[string]interface{}{"status": []interface{}{[]interface{}{200.0}}}, wantErr: "cannot be nested"},
+		{name: "header with an unknown operator", config: map[string]interface{}{"headers": map[string]interface{}{"etag": map[string]interface{}{"operator": "between"}}}, wantErr: "header etag: unknown operator"},
+		{name: "header without a value", config: map[string]interface{}{"headers": map[string]interface{}{"etag": map[string]interface{}{"operator": "eq"}}}, wantErr: "eq needs a value"},
+		{name: "header of another type", config: map[string]interface{}{"headers": map[string]interface{}{"etag": 1.0}}, wantErr: "expected true, false"},
+		{name: "negative duration", config: map[string]interface{}{"maxDurationMs": -1.0}, wantErr: "maxDurationMs must be a non-negative number"},
+		{name: "size as a string", config: map[string]interface{}{"maxSizeBytes": "1kb"}, wantErr: "maxSizeBytes must be a non-negative number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateResponseConfig(tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
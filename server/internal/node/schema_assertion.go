@@ -0,0 +1,94 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/jsondiff"
+	"github.com/visual-api-testing-platform/server/internal/jsonpath"
+	"github.com/visual-api-testing-platform/server/internal/jsonschema"
+)
+
+// schemaAssertion validates the upstream response body, or the value at the
+// path config, against the schema config or the library schema schemaId.
+// Every violation is listed in the output.
+func (v *VerificationNode) schemaAssertion(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
+	document, err := v.loadSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := jsonschema.Compile(document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	exec, _ := ExecutionFromContext(ctx)
+	root := ResponseRoot(input, exec)
+
+	var target interface{}
+	if expr, ok := v.Config["path"].(string); ok && expr != "" {
+		path, err := jsonpath.Parse(expr)
+		if err != nil {
+			return nil, err
+		}
+		value, found := path.Lookup(root)
+		if !found {
+			return nil, fmt.Errorf("no value at path %s", expr)
+		}
+		target = value
+	} else if body, ok := root["body"]; ok {
+		target = body
+	} else {
+		target = jsondiff.Normalize(AssertionSubject(input))
+	}
+
+	violations := schema.Validate(target)
+	output := map[string]interface{}{
+		"passed":     len(violations) == 0,
+		"violations": violations,
+	}
+	if len(violations) == 0 {
+		return output, nil
+	}
+
+	parts := make([]string, 0, maxReportedFailures)
+	for i, violation := range violations {
+		if i == maxReportedFailures {
+			parts = append(parts, fmt.Sprintf("and %d more", len(violations)-i))
+			break
+		}
+		instancePath := violation.InstancePath
+		if instancePath == "" {
+			instancePath = "/"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s: %s", instancePath, violation.Keyword, violation.Message))
+	}
+	return output, fmt.Errorf("schema validation failed with %d violation(s): %s", len(violations), strings.Join(parts, "; "))
+}
+
+// loadSchema returns the inline schema config or loads schemaId from the library
+func (v *VerificationNode) loadSchema(ctx context.Context) (interface{}, error) {
+	if document, ok := v.Config["schema"]; ok && document != nil {
+		return jsondiff.Normalize(document), nil
+	}
+
+	idStr, _ := v.Config["schemaId"].(string)
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fmt.Errorf("schema or a valid schemaId is required")
+	}
+
+	exec, ok := ExecutionFromContext(ctx)
+	if !ok || exec.Schemas == nil {
+		return nil, fmt.Errorf("library schemas can only be used as part of a flow run")
+	}
+
+	stored, err := exec.Schemas.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema %s: %w", id, err)
+	}
+	return stored.Schema, nil
+}
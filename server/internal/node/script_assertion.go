@@ -0,0 +1,127 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/visual-api-testing-platform/server/internal/script"
+)
+
+// Script time limits; timeoutMs config may lower or raise the default up to the maximum
+const (
+	defaultScriptTimeout = time.Second
+	maxScriptTimeout     = 10 * time.Second
+)
+
+// scriptAssertion runs the customScript config in the script sandbox. The
+// script sees input, response (as in field assertions), outputs (dependency
+// outputs by node ID) and variables. It passes when it returns true, returns
+// {pass, message} with a truthy pass, or returns nothing, and no assert() or
+// fail() call failed.
+func (v *VerificationNode) scriptAssertion(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
+	runCtx, cancel := context.WithTimeout(ctx, scriptTimeout(v.Config))
+	defer cancel()
+
+	program, err := compileScript(runCtx, v.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	exec, _ := ExecutionFromContext(ctx)
+	outputs := make(map[string]interface{}, len(exec.Upstream))
+	for _, u := range exec.Upstream {
+		if u.Output != nil {
+			outputs[u.NodeID] = u.Output
+		}
+	}
+	variables := exec.Variables
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+
+	result, err := program.Run(runCtx, map[string]interface{}{
+		"input":     input,
+		"response":  ResponseRoot(input, exec),
+		"outputs":   outputs,
+		"variables": variables,
+	}, script.Limits{})
+	if err != nil {
+		return nil, fmt.Errorf("script error at %w", err)
+	}
+
+	passed := len(result.Failures) == 0
+	var message string
+	switch value := result.Value.(type) {
+	case bool:
+		passed = passed && value
+	case map[string]interface{}:
+		pass, ok := value["pass"].(bool)
+		if !ok {
+			return nil, fmt.Errorf("script result must have a boolean pass field")
+		}
+		passed = passed && pass
+		message, _ = value["message"].(string)
+	default:
+		if result.Defined {
+			return nil, fmt.Errorf("script must return a boolean or {pass, message}, got %T", value)
+		}
+	}
+
+	failures := result.Failures
+	if failures == nil {
+		failures = []script.Failure{}
+	}
+	output := map[string]interface{}{
+		"passed":   passed,
+		"failures": failures,
+	}
+	if message != "" {
+		output["message"] = message
+	}
+	if passed {
+		return output, nil
+	}
+
+	parts := make([]string, 0, maxReportedFailures+1)
+	if message != "" {
+		parts = append(parts, message)
+	}
+	for i, failure := range failures {
+		if i == maxReportedFailures {
+			parts = append(parts, fmt.Sprintf("and %d more", len(failures)-i))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("line %d: %s", failure.Line, failure.Message))
+	}
+	if len(parts) == 0 {
+		return output, fmt.Errorf("custom script failed")
+	}
+	return output, fmt.Errorf("custom script failed: %s", strings.Join(parts, "; "))
+}
+
+// scriptTimeout is the time limit for compiling and running the script
+func scriptTimeout(config map[string]interface{}) time.Duration {
+	timeout := defaultScriptTimeout
+	if ms, ok := config["timeoutMs"].(float64); ok && ms > 0 {
+		timeout = time.Duration(ms) * time.Millisecond
+		if timeout > maxScriptTimeout {
+			timeout = maxScriptTimeout
+		}
+	}
+	return timeout
+}
+
+// compileScript compiles the customScript config, giving up when ctx is done
+func compileScript(ctx context.Context, config map[string]interface{}) (*script.Program, error) {
+	src, _ := config["customScript"].(string)
+	if strings.TrimSpace(src) == "" {
+		return nil, fmt.Errorf("customScript is required for custom assertions")
+	}
+	program, err := script.CompileContext(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("script syntax error at %w", err)
+	}
+	return program, nil
+}
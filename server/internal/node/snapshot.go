@@ -0,0 +1,111 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/visual-api-testing-platform/server/internal/jsondiff"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// maxReportedFailures caps how many differences or violations a failed
+// assertion lists in its error
+const maxReportedFailures = 5
+
+// snapshotAssertion compares actual with the node's stored snapshot. The first
+// run records actual as the snapshot and passes. The snapshot is recorded
+// without a source run because the run isn't saved until it completes, and
+// single-node and load test runs never are; TestRunRepository.Create links it
+// to the run. Paths in the ignorePaths
+// config are not compared and at typeOnlyPaths only the JSON type must match.
+func (v *VerificationNode) snapshotAssertion(ctx context.Context, actual interface{}) (map[string]interface{}, error) {
+	exec, ok := ExecutionFromContext(ctx)
+	if !ok || exec.Snapshots == nil {
+		return nil, fmt.Errorf("snapshot assertions can only run as part of a flow run")
+	}
+
+	value := jsondiff.Normalize(actual)
+
+	snapshot, err := exec.Snapshots.FindSnapshot(ctx, exec.Run.FlowID, exec.NodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+
+	if snapshot == nil {
+		recorded, err := exec.Snapshots.RecordSnapshot(ctx, &models.NodeSnapshot{
+			FlowID: exec.Run.FlowID,
+			NodeID: exec.NodeID,
+			Value:  value,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record snapshot: %w", err)
+		}
+		if recorded {
+			return map[string]interface{}{
+				"passed":   true,
+				"snapshot": models.SnapshotRecorded,
+				"actual":   value,
+			}, nil
+		}
+
+		// A concurrent run recorded the snapshot first; compare against it
+		snapshot, err = exec.Snapshots.FindSnapshot(ctx, exec.Run.FlowID, exec.NodeID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load snapshot: %w", err)
+		}
+		if snapshot == nil {
+			return nil, fmt.Errorf("snapshot was removed while the run was in progress")
+		}
+	}
+
+	filter := jsondiff.NewFilter(stringList(v.Config["ignorePaths"]), stringList(v.Config["typeOnlyPaths"]))
+	if changes := jsondiff.Compare(snapshot.Value, value, filter); len(changes) > 0 {
+		return nil, snapshotMismatch(changes)
+	}
+
+	return map[string]interface{}{
+		"passed":   true,
+		"snapshot": "matched",
+		"actual":   value,
+	}, nil
+}
+
+// snapshotMismatch describes the first few differences from the snapshot
+func snapshotMismatch(changes []jsondiff.Change) error {
+	parts := make([]string, 0, maxReportedFailures)
+	for i, change := range changes {
+		if i == maxReportedFailures {
+			parts = append(parts, fmt.Sprintf("and %d more", len(changes)-i))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", change.Path, change.Kind))
+	}
+	return fmt.Errorf("snapshot mismatch: %d difference(s): %s", len(changes), strings.Join(parts, ", "))
+}
+
+// stringList reads a config value given as a list of strings or a
+// comma-separated string. Entries are trimmed and empty ones dropped.
+func stringList(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []string:
+		items = v
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+	}
+
+	var list []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
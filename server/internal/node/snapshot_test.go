@@ -0,0 +1,142 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// fakeSnapshotStore keeps snapshots in memory. Like node_snapshots it rejects
+// a source run that hasn't been saved.
+type fakeSnapshotStore struct {
+	mu        sync.Mutex
+	snapshots map[string]models.NodeSnapshot
+	savedRuns map[uuid.UUID]bool
+}
+
+func newFakeSnapshotStore() *fakeSnapshotStore {
+	return &fakeSnapshotStore{snapshots: make(map[string]models.NodeSnapshot), savedRuns: make(map[uuid.UUID]bool)}
+}
+
+func (s *fakeSnapshotStore) FindSnapshot(ctx context.Context, flowID uuid.UUID, nodeID string) (*models.NodeSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.snapshots[flowID.String()+" "+nodeID]
+	if !ok {
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+func (s *fakeSnapshotStore) RecordSnapshot(ctx context.Context, snapshot *models.NodeSnapshot) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snapshot.SourceTestRunID != nil && !s.savedRuns[*snapshot.SourceTestRunID] {
+		return false, errors.New("violates foreign key constraint on source_test_run_id")
+	}
+	key := snapshot.FlowID.String() + " " + snapshot.NodeID
+	if _, ok := s.snapshots[key]; ok {
+		return false, nil
+	}
+	s.snapshots[key] = *snapshot
+	return true, nil
+}
+
+func runSnapshotAssertion(t *testing.T, store SnapshotStore, run RunInfo, config map[string]interface{}, data map[string]interface{}) (map[string]interface{}, error) {
+	t.Helper()
+	config["assertionType"] = "snapshot"
+	ctx := WithExecution(context.Background(), Execution{Run: run, NodeID: "verify", Snapshots: store})
+	return NewVerificationNode("verify", "Verify", config).Execute(ctx, map[string]interface{}{"data": data})
+}
+
+func TestSnapshotAssertionRecordsFirstRun(t *testing.T) {
+	tests := []struct {
+		name string
+		run  RunInfo
+	}{
+		// The run's row is only saved once the run completes
+		{name: "flow run", run: RunInfo{TestRunID: uuid.New(), FlowID: uuid.New()}},
+		// Single-node executions and load test iterations are never saved
+		{name: "unsaved run", run: RunInfo{FlowID: uuid.New()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeSnapshotStore()
+
+			output, err := runSnapshotAssertion(t, store, tt.run, map[string]interface{}{}, map[string]interface{}{"id": 1})
+			if err != nil {
+				t.Fatalf("first run failed: %v", err)
+			}
+			if output["snapshot"] != models.SnapshotRecorded {
+				t.Errorf("snapshot = %v, want recorded", output["snapshot"])
+			}
+
+			snapshot, _ := store.FindSnapshot(context.Background(), tt.run.FlowID, "verify")
+			if snapshot == nil {
+				t.Fatal("no snapshot was stored")
+			}
+			if snapshot.SourceTestRunID != nil {
+				t.Errorf("snapshot recorded with source %v, want none until the run is saved", snapshot.SourceTestRunID)
+			}
+
+			run := &models.TestRun{NodeResults: map[string]models.NodeResult{
+				"verify": {Status: models.ExecutionStatusSuccess, Output: output},
+			}}
+			if nodes := models.RecordedSnapshotNodes(run); !reflect.DeepEqual(nodes, []string{"verify"}) {
+				t.Errorf("RecordedSnapshotNodes = %v, want [verify]", nodes)
+			}
+
+			// Later runs compare against the recording
+			output, err = runSnapshotAssertion(t, store, tt.run, map[string]interface{}{}, map[string]interface{}{"id": 1})
+			if err != nil || output["snapshot"] != "matched" {
+				t.Errorf("second run = %v, %v; want matched", output, err)
+			}
+			if _, err := runSnapshotAssertion(t, store, tt.run, map[string]interface{}{}, map[string]interface{}{"id": 2}); err == nil {
+				t.Error("changed value matched the snapshot")
+			}
+		})
+	}
+}
+
+func TestSnapshotAssertionIgnorePaths(t *testing.T) {
+	store := newFakeSnapshotStore()
+	run := RunInfo{FlowID: uuid.New()}
+	config := map[string]interface{}{"ignorePaths": " id, ,updatedAt "}
+
+	if _, err := runSnapshotAssertion(t, store, run, config, map[string]interface{}{"id": 1, "updatedAt": "a", "name": "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runSnapshotAssertion(t, store, run, config, map[string]interface{}{"id": 2, "updatedAt": "b", "name": "x"}); err != nil {
+		t.Errorf("ignored paths were compared: %v", err)
+	}
+}
+
+func TestStringList(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []string
+	}{
+		{name: "comma separated", value: "$.id,$.name", want: []string{"$.id", "$.name"}},
+		{name: "spaces trimmed", value: " $.id , $.name ", want: []string{"$.id", "$.name"}},
+		{name: "empty entries dropped", value: "$.id,, ,$.name,", want: []string{"$.id", "$.name"}},
+		{name: "empty string", value: "", want: nil},
+		{name: "string slice", value: []string{" $.id", ""}, want: []string{"$.id"}},
+		{name: "JSON array", value: []interface{}{"$.id", 3, " $.name "}, want: []string{"$.id", "$.name"}},
+		{name: "other type", value: 3, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stringList(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stringList(%#v) = %#v, want %#v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package node
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+var templateVariable = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// ExpandVariables replaces {{name}} placeholders with the run's variables.
+// Strings are inserted as they are and other values as JSON; placeholders for
+// undefined variables are left in place.
+func ExpandVariables(s string, variables map[string]interface{}) string {
+	if len(variables) == 0 {
+		return s
+	}
+	return templateVariable.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := templateVariable.FindStringSubmatch(placeholder)[1]
+		value, ok := variables[name]
+		if !ok {
+			return placeholder
+		}
+		if str, ok := value.(string); ok {
+			return str
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return placeholder
+		}
+		return string(encoded)
+	})
+}
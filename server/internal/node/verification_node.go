@@ -37,24 +37,61 @@ func (v *VerificationNode) ValidateConfig() error {
 	}
 
 	validTypes := map[string]bool{
-		"equals": true, "contains": true, "regex": true, "custom": true,
+		"equals": true, "contains": true, "regex": true, "custom": true, "snapshot": true, "assertions": true, "schema": true, "response": true,
 	}
 	if !validTypes[assertionType] {
 		return fmt.Errorf("invalid assertion type: %s", assertionType)
 	}
 
+	switch assertionType {
+	case "assertions":
+		if _, err := parseAssertions(v.Config["assertions"]); err != nil {
+			return err
+		}
+	case "custom":
+		ctx, cancel := context.WithTimeout(context.Background(), scriptTimeout(v.Config))
+		defer cancel()
+		if _, err := compileScript(ctx, v.Config); err != nil {
+			return err
+		}
+	case "response":
+		return validateResponseConfig(v.Config)
+	}
+
 	return nil
 }
 
 // Execute performs the verification
 func (v *VerificationNode) Execute(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
+	assertionType, _ := v.Config["assertionType"].(string)
+	if assertionType == "" {
+		assertionType = "equals"
+	}
+
+	// Scripts are compiled within their time limit, so they skip ValidateConfig
+	if assertionType == "custom" {
+		return v.scriptAssertion(ctx, input)
+	}
 	if err := v.ValidateConfig(); err != nil {
 		return nil, err
 	}
 
-	assertionType, _ := v.Config["assertionType"].(string)
-	if assertionType == "" {
-		assertionType = "equals"
+	// Field, schema and response assertions evaluate the response and report every failure
+	switch assertionType {
+	case "response":
+		return v.responseAssertion(ctx, input)
+	case "assertions":
+		return v.fieldAssertions(ctx, input)
+	case "schema":
+		return v.schemaAssertion(ctx, input)
+	}
+
+	// Get actual value from input (usually from previous node)
+	actual := AssertionSubject(input)
+
+	// Snapshots compare against a stored value instead of expected
+	if assertionType == "snapshot" {
+		return v.snapshotAssertion(ctx, actual)
 	}
 
 	expected, exists := v.Config["expected"]
@@ -62,12 +99,6 @@ func (v *VerificationNode) Execute(ctx context.Context, input map[string]interfa
 		return nil, fmt.Errorf("expected value is required")
 	}
 
-	// Get actual value from input (usually from previous node)
-	actual, ok := input["data"].(map[string]interface{})
-	if !ok {
-		actual = input // Use entire input as actual
-	}
-
 	var passed bool
 	var err error
 
@@ -78,10 +109,6 @@ func (v *VerificationNode) Execute(ctx context.Context, input map[string]interfa
 		passed, err = v.containsAssertion(expected, actual)
 	case "regex":
 		passed, err = v.regexAssertion(expected, actual)
-	case "custom":
-		// Custom script execution would go here
-		// For now, we'll return a placeholder
-		passed = true // Placeholder
 	default:
 		return nil, fmt.Errorf("unsupported assertion type: %s", assertionType)
 	}
@@ -95,12 +122,21 @@ func (v *VerificationNode) Execute(ctx context.Context, input map[string]interfa
 	}
 
 	return map[string]interface{}{
-		"passed":  true,
+		"passed":   true,
 		"expected": expected,
 		"actual":   actual,
 	}, nil
 }
 
+// AssertionSubject returns the value a verification node asserts on: the data
+// merged from its inputs, or the entire input when there is none
+func AssertionSubject(input map[string]interface{}) map[string]interface{} {
+	if data, ok := input["data"].(map[string]interface{}); ok {
+		return data
+	}
+	return input
+}
+
 func (v *VerificationNode) equalsAssertion(expected, actual interface{}) (bool, error) {
 	expectedJSON, err := json.Marshal(expected)
 	if err != nil {
@@ -136,4 +172,3 @@ func (v *VerificationNode) regexAssertion(expected, actual interface{}) (bool, e
 	matched, err := regexp.MatchString(pattern, string(actualJSON))
 	return matched, err
 }
-
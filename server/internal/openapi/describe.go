@@ -0,0 +1,366 @@
+package openapi
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxExampleDepth stops example generation for recursive schemas
+const maxExampleDepth = 8
+
+// Parameter describes an operation parameter for tools that build requests
+type Parameter struct {
+	Name     string
+	In       string
+	Required bool
+	Schema   interface{}
+	Example  interface{} // the documented example, if any
+}
+
+// Body describes a request or response body
+type Body struct {
+	MediaType string
+	Required  bool
+	Schema    interface{}
+	Example   interface{} // the documented example, if any
+}
+
+// SecurityScheme is one way an operation authenticates
+type SecurityScheme struct {
+	Name   string // the key in components.securitySchemes
+	Type   string // apiKey, http, oauth2 or openIdConnect
+	Scheme string // for http: bearer, basic, ...
+	In     string // for apiKey: header, query or cookie
+	Param  string // for apiKey: the header, query parameter or cookie name
+}
+
+// Servers returns the document's server URLs with variables set to their defaults
+func (d *Document) Servers() []string {
+	servers, _ := d.root["servers"].([]interface{})
+	var urls []string
+	for _, s := range servers {
+		server, _ := s.(map[string]interface{})
+		address, _ := server["url"].(string)
+		variables, _ := server["variables"].(map[string]interface{})
+		for name, v := range variables {
+			variable, _ := v.(map[string]interface{})
+			value, _ := variable["default"].(string)
+			address = strings.ReplaceAll(address, "{"+name+"}", value)
+		}
+		urls = append(urls, address)
+	}
+	return urls
+}
+
+// Parameters returns the operation's parameters, including those inherited
+// from its path item
+func (o *Operation) Parameters() []Parameter {
+	params := make([]Parameter, 0, len(o.parameters))
+	for _, p := range o.parameters {
+		params = append(params, Parameter{
+			Name:     p.name,
+			In:       p.in,
+			Required: p.required,
+			Schema:   p.raw["schema"],
+			Example:  documentedExample(p.raw),
+		})
+	}
+	return params
+}
+
+// RequestBody describes the operation's request body, preferring a JSON media
+// type. It is nil when the operation takes no body.
+func (o *Operation) RequestBody() *Body {
+	if o.requestBody == nil || len(o.requestBody.content) == 0 {
+		return nil
+	}
+	body := describeContent(o.requestBody.content)
+	body.Required = o.requestBody.required
+	return body
+}
+
+// SuccessResponse returns the lowest documented 2xx status code, or 2XX, and
+// describes its body. The body is nil when the response has no content.
+func (o *Operation) SuccessResponse() (string, *Body) {
+	for _, code := range o.StatusCodes() {
+		if len(code) == 3 && code[0] == '2' {
+			resp := o.responses[code]
+			if len(resp.content) == 0 {
+				return code, nil
+			}
+			return code, describeContent(resp.content)
+		}
+	}
+	return "", nil
+}
+
+// Security returns the schemes of the operation's first security requirement,
+// or of the document's when the operation doesn't override it. It is empty
+// for public operations.
+func (o *Operation) Security() []SecurityScheme {
+	requirements := o.security
+	if requirements == nil {
+		requirements = o.doc.root["security"]
+	}
+	list, _ := requirements.([]interface{})
+	if len(list) == 0 {
+		return nil
+	}
+	first, _ := list[0].(map[string]interface{})
+
+	components, _ := o.doc.root["components"].(map[string]interface{})
+	definitions, _ := components["securitySchemes"].(map[string]interface{})
+
+	var schemes []SecurityScheme
+	for _, name := range sortedKeys(first) {
+		fields, _, err := o.doc.resolveObject(definitions[name], "#/components/securitySchemes/"+escapePointer(name))
+		if err != nil {
+			continue
+		}
+		scheme := SecurityScheme{Name: name}
+		scheme.Type, _ = fields["type"].(string)
+		scheme.Scheme, _ = fields["scheme"].(string)
+		scheme.Scheme = strings.ToLower(scheme.Scheme)
+		scheme.In, _ = fields["in"].(string)
+		scheme.Param, _ = fields["name"].(string)
+		schemes = append(schemes, scheme)
+	}
+	return schemes
+}
+
+// describeContent picks a JSON media type from content, or else the first one
+func describeContent(content []mediaType) *Body {
+	chosen := content[0]
+	for _, m := range content {
+		if isJSON(m.name) {
+			chosen = m
+			break
+		}
+	}
+	return &Body{
+		MediaType: chosen.name,
+		Schema:    chosen.raw["schema"],
+		Example:   documentedExample(chosen.raw),
+	}
+}
+
+// documentedExample returns the example or the first of the examples of a
+// parameter or media type object
+func documentedExample(fields map[string]interface{}) interface{} {
+	if example, ok := fields["example"]; ok {
+		return example
+	}
+	examples, _ := fields["examples"].(map[string]interface{})
+	for _, name := range sortedKeys(examples) {
+		if example, ok := examples[name].(map[string]interface{}); ok {
+			if value, ok := example["value"]; ok {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+// Example makes up a value that satisfies schema: its example, default, const
+// or first enum value, or else one built from its type and format. Read-only
+// properties are left out, as examples are meant for requests.
+func (d *Document) Example(schema interface{}) interface{} {
+	return d.example(schema, 0)
+}
+
+func (d *Document) example(schema interface{}, depth int) interface{} {
+	fields, ok := schema.(map[string]interface{})
+	if !ok || depth > maxExampleDepth {
+		return nil
+	}
+	if ref, ok := fields["$ref"].(string); ok {
+		target, found := d.lookup(ref)
+		if !found {
+			return nil
+		}
+		return d.example(target, depth+1)
+	}
+
+	for _, key := range []string{"example", "default", "const"} {
+		if value, ok := fields[key]; ok {
+			return value
+		}
+	}
+	for _, key := range []string{"examples", "enum"} {
+		if list, ok := fields[key].([]interface{}); ok && len(list) > 0 {
+			return list[0]
+		}
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if list, ok := fields[key].([]interface{}); ok && len(list) > 0 {
+			return d.example(list[0], depth+1)
+		}
+	}
+
+	kind := ""
+	switch t := fields["type"].(type) {
+	case string:
+		kind = t
+	case []interface{}:
+		for _, name := range t {
+			if s, ok := name.(string); ok && s != "null" {
+				kind = s
+				break
+			}
+		}
+	}
+	if kind == "" {
+		switch {
+		case fields["properties"] != nil || fields["allOf"] != nil:
+			kind = "object"
+		case fields["items"] != nil:
+			kind = "array"
+		}
+	}
+
+	switch kind {
+	case "object":
+		object := make(map[string]interface{})
+		if list, ok := fields["allOf"].([]interface{}); ok {
+			for _, sub := range list {
+				if part, ok := d.example(sub, depth+1).(map[string]interface{}); ok {
+					for key, value := range part {
+						object[key] = value
+					}
+				}
+			}
+		}
+		properties, _ := fields["properties"].(map[string]interface{})
+		for _, name := range sortedKeys(properties) {
+			if property, ok := properties[name].(map[string]interface{}); ok && property["readOnly"] == true {
+				continue
+			}
+			object[name] = d.example(properties[name], depth+1)
+		}
+		return object
+	case "array":
+		if fields["items"] == nil {
+			return []interface{}{}
+		}
+		return []interface{}{d.example(fields["items"], depth+1)}
+	case "string":
+		return exampleString(fields)
+	case "integer", "number":
+		return exampleNumber(fields, kind == "integer")
+	case "boolean":
+		return true
+	}
+	return nil
+}
+
+var formatExamples = map[string]string{
+	"date-time": "2024-01-01T00:00:00Z",
+	"date":      "2024-01-01",
+	"time":      "12:00:00Z",
+	"email":     "user@example.com",
+	"uri":       "https://example.com",
+	"url":       "https://example.com",
+	"uuid":      "00000000-0000-4000-8000-000000000000",
+	"hostname":  "example.com",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+}
+
+func exampleString(fields map[string]interface{}) string {
+	format, _ := fields["format"].(string)
+	if example, ok := formatExamples[format]; ok {
+		return example
+	}
+	value := "string"
+	if n, ok := fields["minLength"].(float64); ok && int(n) > len(value) {
+		value += strings.Repeat("x", int(n)-len(value))
+	}
+	if n, ok := fields["maxLength"].(float64); ok && int(n) < len(value) {
+		value = value[:int(n)]
+	}
+	return value
+}
+
+func exampleNumber(fields map[string]interface{}, integer bool) float64 {
+	value := 0.0
+	if n, ok := fields["minimum"].(float64); ok {
+		value = n
+	} else if n, ok := fields["exclusiveMinimum"].(float64); ok {
+		value = n + 1
+	} else if n, ok := fields["maximum"].(float64); ok && n < value {
+		value = n
+	} else if n, ok := fields["exclusiveMaximum"].(float64); ok && n <= value {
+		value = n - 1
+	}
+	if integer {
+		value = math.Ceil(value)
+	}
+	return value
+}
+
+// Standalone returns a copy of schema that no longer depends on the document:
+// the schemas it references are copied under $defs and its $refs point there.
+func (d *Document) Standalone(schema interface{}) interface{} {
+	defs := make(map[string]interface{})
+	names := make(map[string]string) // ref -> name under $defs
+	taken := make(map[string]bool)
+
+	var rewrite func(value interface{}) interface{}
+	rewrite = func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			out := make(map[string]interface{}, len(v))
+			for key, child := range v {
+				ref, isRef := child.(string)
+				if key != "$ref" || !isRef {
+					out[key] = rewrite(child)
+					continue
+				}
+				name, seen := names[ref]
+				if !seen {
+					name = defName(ref, taken)
+					names[ref] = name
+					target, _ := d.lookup(ref)
+					defs[name] = rewrite(target)
+				}
+				out[key] = "#/$defs/" + escapePointer(name)
+			}
+			return out
+		case []interface{}:
+			out := make([]interface{}, len(v))
+			for i, child := range v {
+				out[i] = rewrite(child)
+			}
+			return out
+		}
+		return value
+	}
+
+	result := rewrite(schema)
+	if object, ok := result.(map[string]interface{}); ok && len(defs) > 0 {
+		object["$defs"] = defs
+	}
+	return result
+}
+
+// defName picks an unused $defs name for a reference, e.g. Pet for
+// #/components/schemas/Pet
+func defName(ref string, taken map[string]bool) string {
+	pointer, err := url.PathUnescape(strings.TrimPrefix(ref, "#"))
+	if err != nil {
+		pointer = ref
+	}
+	base := pointer[strings.LastIndex(pointer, "/")+1:]
+	base = strings.NewReplacer("~1", "/", "~0", "~").Replace(base)
+	if base == "" {
+		base = "schema"
+	}
+	name := base
+	for i := 2; taken[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	taken[name] = true
+	return name
+}
@@ -0,0 +1,611 @@
+// Package openapi parses OpenAPI 3 documents and checks HTTP exchanges against
+// their operations: parameters, request bodies, response status codes, headers
+// and bodies.
+//
+// Schemas are validated with the jsonschema package. In OpenAPI 3.0 documents,
+// nullable and the boolean forms of exclusiveMinimum and exclusiveMaximum are
+// translated to their JSON Schema equivalents first.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/visual-api-testing-platform/server/internal/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// methods are the operation keys of a path item, in the order operations are listed
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Document is a parsed OpenAPI 3 document
+type Document struct {
+	OpenAPI string
+	Title   string
+	Version string
+
+	root       map[string]interface{}
+	basePaths  []string
+	operations []*Operation
+}
+
+// Operation is one method on one path of a document
+type Operation struct {
+	ID         string
+	Method     string // upper case
+	Path       string // the path template, e.g. /pets/{id}
+	Summary    string
+	Tags       []string
+	Deprecated bool
+
+	doc         *Document
+	security    interface{} // the operation's security requirements; nil inherits the document's
+	pattern     *regexp.Regexp
+	pathParams  []string
+	parameters  []*parameter
+	requestBody *requestBody
+	responses   map[string]*response
+}
+
+type parameter struct {
+	name     string
+	in       string
+	required bool
+	schema   *jsonschema.Schema
+	types    []string // the schema's top-level types
+	items    []string // the types of array items
+	explode  bool
+	json     bool // the value is JSON, from a content map instead of a schema
+	raw      map[string]interface{}
+}
+
+type mediaType struct {
+	name   string
+	schema *jsonschema.Schema
+	raw    map[string]interface{}
+}
+
+type requestBody struct {
+	required bool
+	content  []mediaType
+}
+
+type response struct {
+	headers []*parameter
+	content []mediaType
+}
+
+// Decode reads a JSON or YAML document into plain JSON values
+func Decode(data []byte) (interface{}, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	// Round trip through JSON so numbers are float64 like every other decoded document
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("document must only use string keys: %w", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(normalized, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Parse checks a decoded OpenAPI 3 document and prepares its operations for
+// validation. It fails on unresolvable $refs, invalid schemas and duplicate
+// operation IDs.
+func Parse(document interface{}) (*Document, error) {
+	root, ok := document.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("document must be an object")
+	}
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("only OpenAPI 3 documents are supported")
+	}
+	if strings.HasPrefix(version, "3.0") {
+		root = translate(root).(map[string]interface{})
+	}
+
+	doc := &Document{OpenAPI: version, root: root}
+	if info, ok := root["info"].(map[string]interface{}); ok {
+		doc.Title, _ = info["title"].(string)
+		doc.Version, _ = info["version"].(string)
+	}
+	doc.basePaths = basePaths(doc.Servers())
+
+	paths, ok := root["paths"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("paths must be an object")
+	}
+
+	ids := make(map[string]string)
+	for _, path := range sortedKeys(paths) {
+		itemRef := "#/paths/" + escapePointer(path)
+		item, itemRef, err := doc.resolveObject(paths[path], itemRef)
+		if err != nil {
+			return nil, err
+		}
+		shared, err := doc.parseParameters(item["parameters"], itemRef+"/parameters")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, method := range methods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			op, err := doc.parseOperation(path, method, raw, itemRef+"/"+method, shared)
+			if err != nil {
+				return nil, err
+			}
+			if op.ID != "" {
+				if other, taken := ids[op.ID]; taken {
+					return nil, fmt.Errorf("operationId %q is used by both %s and %s %s", op.ID, other, op.Method, op.Path)
+				}
+				ids[op.ID] = op.Method + " " + op.Path
+			}
+			doc.operations = append(doc.operations, op)
+		}
+	}
+
+	return doc, nil
+}
+
+// Operations returns every operation, ordered by path and then method
+func (d *Document) Operations() []*Operation {
+	return d.operations
+}
+
+// Root returns the decoded document, with OpenAPI 3.0 schemas translated
+func (d *Document) Root() map[string]interface{} {
+	return d.root
+}
+
+// Operation finds the operation with the given operationId
+func (d *Document) Operation(operationID string) (*Operation, error) {
+	for _, op := range d.operations {
+		if op.ID == operationID {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("operation %q not found", operationID)
+}
+
+// OperationFor finds the operation for method on the path template, e.g.
+// GET /pets/{id}
+func (d *Document) OperationFor(method, path string) (*Operation, error) {
+	method = strings.ToUpper(method)
+	for _, op := range d.operations {
+		if op.Method == method && op.Path == path {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("operation %s %s not found", method, path)
+}
+
+// Match finds the operation for a request's method and escaped path, below
+// one of the document's server base paths. When several path templates match,
+// the one with the fewest parameters wins, as concrete paths take precedence
+// over templated ones.
+func (d *Document) Match(method, path string) (*Operation, bool) {
+	method = strings.ToUpper(method)
+	var best *Operation
+	for _, op := range d.operations {
+		if op.Method != method || (best != nil && len(op.pathParams) >= len(best.pathParams)) {
+			continue
+		}
+		if _, ok := op.matchPath(path); ok {
+			best = op
+		}
+	}
+	return best, best != nil
+}
+
+// Name identifies the operation, by operationId when it has one
+func (o *Operation) Name() string {
+	if o.ID != "" {
+		return o.ID
+	}
+	return o.Method + " " + o.Path
+}
+
+// StatusCodes returns the documented response codes, e.g. 200, 4XX and default
+func (o *Operation) StatusCodes() []string {
+	return sortedKeys(o.responses)
+}
+
+func (d *Document) parseOperation(path, method string, raw interface{}, ref string, shared []*parameter) (*Operation, error) {
+	fields, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: operation must be an object", ref)
+	}
+
+	op := &Operation{
+		Method:    strings.ToUpper(method),
+		Path:      path,
+		doc:       d,
+		responses: make(map[string]*response),
+	}
+	op.ID, _ = fields["operationId"].(string)
+	op.Summary, _ = fields["summary"].(string)
+	op.Deprecated, _ = fields["deprecated"].(bool)
+	op.security = fields["security"]
+	tags, _ := fields["tags"].([]interface{})
+	for _, tag := range tags {
+		if name, ok := tag.(string); ok {
+			op.Tags = append(op.Tags, name)
+		}
+	}
+
+	pattern, names, err := compileTemplate(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+	op.pattern, op.pathParams = pattern, names
+
+	own, err := d.parseParameters(fields["parameters"], ref+"/parameters")
+	if err != nil {
+		return nil, err
+	}
+	// Operation parameters override path item parameters with the same name and location
+	op.parameters = own
+	for _, p := range shared {
+		overridden := false
+		for _, o := range own {
+			if o.name == p.name && o.in == p.in {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			op.parameters = append(op.parameters, p)
+		}
+	}
+
+	if raw, ok := fields["requestBody"]; ok {
+		body, bodyRef, err := d.resolveObject(raw, ref+"/requestBody")
+		if err != nil {
+			return nil, err
+		}
+		content, err := d.parseContent(body["content"], bodyRef+"/content")
+		if err != nil {
+			return nil, err
+		}
+		required, _ := body["required"].(bool)
+		op.requestBody = &requestBody{required: required, content: content}
+	}
+
+	responses, _ := fields["responses"].(map[string]interface{})
+	for _, code := range sortedKeys(responses) {
+		respRef := ref + "/responses/" + escapePointer(code)
+		respFields, respRef, err := d.resolveObject(responses[code], respRef)
+		if err != nil {
+			return nil, err
+		}
+		resp := &response{}
+		if resp.content, err = d.parseContent(respFields["content"], respRef+"/content"); err != nil {
+			return nil, err
+		}
+		headers, _ := respFields["headers"].(map[string]interface{})
+		for _, name := range sortedKeys(headers) {
+			header, err := d.parseParameter(headers[name], respRef+"/headers/"+escapePointer(name))
+			if err != nil {
+				return nil, err
+			}
+			header.name, header.in = name, "header"
+			resp.headers = append(resp.headers, header)
+		}
+		op.responses[strings.ToUpper(code)] = resp
+	}
+
+	return op, nil
+}
+
+func (d *Document) parseParameters(raw interface{}, ref string) ([]*parameter, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an array", ref)
+	}
+	params := make([]*parameter, 0, len(list))
+	for i, item := range list {
+		p, err := d.parseParameter(item, fmt.Sprintf("%s/%d", ref, i))
+		if err != nil {
+			return nil, err
+		}
+		switch p.in {
+		case "path", "query", "header", "cookie":
+		default:
+			return nil, fmt.Errorf("%s/%d: parameter %q has invalid location %q", ref, i, p.name, p.in)
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// parseParameter reads a parameter or header object; headers have no name or in
+func (d *Document) parseParameter(raw interface{}, ref string) (*parameter, error) {
+	fields, ref, err := d.resolveObject(raw, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parameter{raw: fields}
+	p.name, _ = fields["name"].(string)
+	p.in, _ = fields["in"].(string)
+	p.required, _ = fields["required"].(bool)
+	if p.in == "path" {
+		p.required = true
+	}
+	// Form style, the query and cookie default, explodes arrays into repeated values
+	style, _ := fields["style"].(string)
+	p.explode = style == "form" || (style == "" && (p.in == "query" || p.in == "cookie"))
+	if explode, ok := fields["explode"].(bool); ok {
+		p.explode = explode
+	}
+
+	schemaRef := ref + "/schema"
+	if _, ok := fields["schema"]; !ok {
+		content, _ := fields["content"].(map[string]interface{})
+		names := sortedKeys(content)
+		if len(names) == 0 {
+			return p, nil
+		}
+		p.json = isJSON(names[0])
+		schemaRef = ref + "/content/" + escapePointer(names[0]) + "/schema"
+		if media, ok := content[names[0]].(map[string]interface{}); !ok || media["schema"] == nil {
+			return p, nil
+		}
+	}
+
+	if p.schema, err = jsonschema.CompileRef(d.root, schemaRef); err != nil {
+		return nil, fmt.Errorf("%s: invalid schema: %w", schemaRef, err)
+	}
+	p.types = d.schemaTypes(schemaRef)
+	if containsString(p.types, "array") {
+		p.items = d.schemaTypes(schemaRef + "/items")
+	}
+	return p, nil
+}
+
+func (d *Document) parseContent(raw interface{}, ref string) ([]mediaType, error) {
+	content, _ := raw.(map[string]interface{})
+	var media []mediaType
+	for _, name := range sortedKeys(content) {
+		fields, _ := content[name].(map[string]interface{})
+		m := mediaType{name: strings.ToLower(name), raw: fields}
+		if _, ok := fields["schema"]; ok {
+			schemaRef := ref + "/" + escapePointer(name) + "/schema"
+			schema, err := jsonschema.CompileRef(d.root, schemaRef)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid schema: %w", schemaRef, err)
+			}
+			m.schema = schema
+		}
+		media = append(media, m)
+	}
+	return media, nil
+}
+
+// schemaTypes returns the type keyword of the schema at ref, following $refs
+func (d *Document) schemaTypes(ref string) []string {
+	for depth := 0; depth < maxRefDepth; depth++ {
+		schema, ok := d.lookup(ref)
+		fields, isObject := schema.(map[string]interface{})
+		if !ok || !isObject {
+			return nil
+		}
+		if next, ok := fields["$ref"].(string); ok {
+			ref = next
+			continue
+		}
+		switch t := fields["type"].(type) {
+		case string:
+			return []string{t}
+		case []interface{}:
+			types := make([]string, 0, len(t))
+			for _, name := range t {
+				if s, ok := name.(string); ok {
+					types = append(types, s)
+				}
+			}
+			return types
+		}
+		return nil
+	}
+	return nil
+}
+
+// maxRefDepth bounds $ref chains between OpenAPI objects
+const maxRefDepth = 32
+
+// resolveObject follows $refs from value, found at ref, to an object and
+// returns it with its own location
+func (d *Document) resolveObject(value interface{}, ref string) (map[string]interface{}, string, error) {
+	for depth := 0; depth < maxRefDepth; depth++ {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("%s: must be an object", ref)
+		}
+		next, ok := fields["$ref"].(string)
+		if !ok {
+			return fields, ref, nil
+		}
+		target, found := d.lookup(next)
+		if !found {
+			return nil, "", fmt.Errorf("%s: reference %q does not resolve", ref, next)
+		}
+		value, ref = target, next
+	}
+	return nil, "", fmt.Errorf("%s: maximum reference depth exceeded", ref)
+}
+
+// lookup resolves a JSON pointer reference within the document
+func (d *Document) lookup(ref string) (interface{}, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, false
+	}
+	pointer, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, false
+	}
+	var current interface{} = d.root
+	if pointer == "" {
+		return current, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[token]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			current = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+var templateParam = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// compileTemplate turns a path template into a regexp capturing its parameters
+func compileTemplate(path string) (*regexp.Regexp, []string, error) {
+	var pattern strings.Builder
+	var names []string
+	pattern.WriteString("^")
+	last := 0
+	for _, match := range templateParam.FindAllStringSubmatchIndex(path, -1) {
+		pattern.WriteString(regexp.QuoteMeta(path[last:match[0]]))
+		pattern.WriteString("([^/]+)")
+		names = append(names, path[match[2]:match[3]])
+		last = match[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(path[last:]))
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid path template %q", path)
+	}
+	return re, names, nil
+}
+
+// basePaths returns the path prefixes of the server URLs, longest first
+func basePaths(servers []string) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, address := range servers {
+		u, err := url.Parse(address)
+		if err != nil {
+			continue
+		}
+		path := strings.TrimSuffix(u.Path, "/")
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	if !seen[""] {
+		paths = append(paths, "")
+	}
+	sort.SliceStable(paths, func(i, j int) bool { return len(paths[i]) > len(paths[j]) })
+	return paths
+}
+
+// translate copies an OpenAPI 3.0 document, rewriting schema keywords that
+// differ from JSON Schema
+func translate(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			out[key] = translate(child)
+		}
+
+		if nullable, ok := out["nullable"].(bool); ok {
+			delete(out, "nullable")
+			if nullable {
+				switch t := out["type"].(type) {
+				case string:
+					out["type"] = []interface{}{t, "null"}
+				case []interface{}:
+					out["type"] = append(t, "null")
+				}
+				if enum, ok := out["enum"].([]interface{}); ok {
+					out["enum"] = append(enum, nil)
+				}
+			}
+		}
+		for _, bound := range []string{"Minimum", "Maximum"} {
+			exclusive, ok := out["exclusive"+bound].(bool)
+			if !ok {
+				continue
+			}
+			delete(out, "exclusive"+bound)
+			limit := strings.ToLower(bound)
+			if n, ok := out[limit]; ok && exclusive {
+				out["exclusive"+bound] = n
+				delete(out, limit)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = translate(child)
+		}
+		return out
+	}
+	return value
+}
+
+// isJSON reports whether a media type carries JSON, e.g. application/problem+json
+func isJSON(mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,315 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/visual-api-testing-platform/server/internal/jsonschema"
+)
+
+// Violation describes one way an exchange breaks its operation's contract
+type Violation struct {
+	// Location is where the problem is, e.g. request.query.limit,
+	// response.status or response.body/items/0/id
+	Location string      `json:"location"`
+	Keyword  string      `json:"keyword"`
+	Message  string      `json:"message"`
+	Expected interface{} `json:"expected,omitempty"`
+	Actual   interface{} `json:"actual,omitempty"`
+}
+
+// Request is an outgoing HTTP request
+type Request struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// Response is the HTTP response to a request
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// ValidateRequest checks a request's method, path, parameters and body
+// against the operation. An empty result means the request conforms.
+func (o *Operation) ValidateRequest(req Request) []Violation {
+	violations := []Violation{}
+
+	if !strings.EqualFold(req.Method, o.Method) {
+		violations = append(violations, Violation{
+			Location: "request.method",
+			Keyword:  "method",
+			Message:  fmt.Sprintf("operation %s expects %s", o.Name(), o.Method),
+			Expected: o.Method,
+			Actual:   req.Method,
+		})
+	}
+
+	pathValues, matched := o.matchPath(req.URL.EscapedPath())
+	if !matched {
+		violations = append(violations, Violation{
+			Location: "request.path",
+			Keyword:  "path",
+			Message:  fmt.Sprintf("path does not match %s", o.Path),
+			Expected: o.Path,
+			Actual:   req.URL.Path,
+		})
+	}
+
+	query := req.URL.Query()
+	cookies := (&http.Request{Header: req.Header}).Cookies()
+	for _, p := range o.parameters {
+		var values []string
+		switch p.in {
+		case "path":
+			if !matched {
+				continue
+			}
+			if value, ok := pathValues[p.name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[p.name]
+		case "header":
+			// Content negotiation and authorization headers are described elsewhere
+			switch http.CanonicalHeaderKey(p.name) {
+			case "Accept", "Content-Type", "Authorization":
+				continue
+			}
+			values = req.Header.Values(p.name)
+		case "cookie":
+			for _, cookie := range cookies {
+				if cookie.Name == p.name {
+					values = append(values, cookie.Value)
+				}
+			}
+		}
+		violations = append(violations, p.validate("request."+p.in+"."+p.name, values)...)
+	}
+
+	if o.requestBody != nil {
+		if len(req.Body) == 0 {
+			if o.requestBody.required {
+				violations = append(violations, Violation{
+					Location: "request.body",
+					Keyword:  "required",
+					Message:  "request body is required",
+				})
+			}
+		} else if len(o.requestBody.content) > 0 {
+			violations = append(violations, validateBody("request.body", o.requestBody.content, req.Header.Get("Content-Type"), req.Body)...)
+		}
+	}
+
+	return violations
+}
+
+// ValidateResponse checks that a response's status code is documented and
+// that its headers and body match the documented response. An empty result
+// means the response conforms.
+func (o *Operation) ValidateResponse(resp Response) []Violation {
+	violations := []Violation{}
+
+	documented, ok := o.response(resp.StatusCode)
+	if !ok {
+		return append(violations, Violation{
+			Location: "response.status",
+			Keyword:  "status",
+			Message:  fmt.Sprintf("status %d is not documented for %s", resp.StatusCode, o.Name()),
+			Expected: o.StatusCodes(),
+			Actual:   resp.StatusCode,
+		})
+	}
+
+	for _, header := range documented.headers {
+		// Content-Type is described by the response content
+		if http.CanonicalHeaderKey(header.name) == "Content-Type" {
+			continue
+		}
+		violations = append(violations, header.validate("response.header."+header.name, resp.Header.Values(header.name))...)
+	}
+
+	if len(resp.Body) > 0 && len(documented.content) > 0 {
+		violations = append(violations, validateBody("response.body", documented.content, resp.Header.Get("Content-Type"), resp.Body)...)
+	}
+
+	return violations
+}
+
+// ResponseCode finds the documented response code for a status code: an
+// exact match, then its class (e.g. 4XX), then DEFAULT
+func (o *Operation) ResponseCode(status int) (string, bool) {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", "DEFAULT"} {
+		if _, ok := o.responses[key]; ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// response finds the documented response for a status code
+func (o *Operation) response(status int) (*response, bool) {
+	key, ok := o.ResponseCode(status)
+	if !ok {
+		return nil, false
+	}
+	return o.responses[key], true
+}
+
+// matchPath matches a request path against the operation's template below
+// one of the document's server base paths and returns the path parameters
+func (o *Operation) matchPath(path string) (map[string]string, bool) {
+	for _, base := range o.doc.basePaths {
+		if !strings.HasPrefix(path, base) {
+			continue
+		}
+		rest := path[len(base):]
+		if rest == "" {
+			rest = "/"
+		}
+		match := o.pattern.FindStringSubmatch(rest)
+		if match == nil {
+			continue
+		}
+		values := make(map[string]string, len(o.pathParams))
+		for i, name := range o.pathParams {
+			value, err := url.PathUnescape(match[i+1])
+			if err != nil {
+				value = match[i+1]
+			}
+			values[name] = value
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// validate checks the raw string values of a parameter or header
+func (p *parameter) validate(location string, values []string) []Violation {
+	if len(values) == 0 {
+		if p.required {
+			return []Violation{{Location: location, Keyword: "required", Message: fmt.Sprintf("%s %q is required", p.in, p.name)}}
+		}
+		return nil
+	}
+	if p.schema == nil {
+		return nil
+	}
+
+	var value interface{}
+	switch {
+	case p.json:
+		if err := json.Unmarshal([]byte(values[0]), &value); err != nil {
+			return []Violation{{Location: location, Keyword: "content", Message: "must be valid JSON", Actual: values[0]}}
+		}
+	case containsString(p.types, "object"):
+		// Object serialization styles are not checked
+		return nil
+	case containsString(p.types, "array"):
+		items := values
+		if len(values) == 1 && !(p.explode && p.in == "query") {
+			items = strings.Split(values[0], ",")
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			list[i] = coerce(item, p.items)
+		}
+		value = list
+	default:
+		value = coerce(values[0], p.types)
+	}
+
+	return schemaViolations(location, p.schema.Validate(value))
+}
+
+// coerce converts a string to the first of the schema types it parses as,
+// leaving it a string otherwise so a type mismatch is reported
+func coerce(s string, types []string) interface{} {
+	for _, t := range types {
+		switch t {
+		case "integer", "number":
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				return n
+			}
+		case "boolean":
+			if s == "true" || s == "false" {
+				return s == "true"
+			}
+		case "string":
+			return s
+		}
+	}
+	return s
+}
+
+// validateBody checks a body's media type against the documented content and
+// validates JSON bodies against its schema
+func validateBody(location string, content []mediaType, contentType string, body []byte) []Violation {
+	declared := make([]string, len(content))
+	for i, m := range content {
+		declared[i] = m.name
+	}
+
+	name, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		name = ""
+	}
+	media, ok := matchMediaType(content, name)
+	if !ok {
+		message := fmt.Sprintf("content type %q is not documented", contentType)
+		if contentType == "" {
+			message = "content type is missing"
+		}
+		return []Violation{{Location: location, Keyword: "contentType", Message: message, Expected: declared, Actual: contentType}}
+	}
+	if media.schema == nil || !isJSON(name) {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []Violation{{Location: location, Keyword: "contentType", Message: fmt.Sprintf("body is not valid JSON: %v", err)}}
+	}
+	return schemaViolations(location, media.schema.Validate(value))
+}
+
+// matchMediaType finds the content entry for a media type: an exact match,
+// then a type/* range, then */*
+func matchMediaType(content []mediaType, name string) (mediaType, bool) {
+	if name == "" {
+		return mediaType{}, false
+	}
+	candidates := []string{name, "*/*"}
+	if slash := strings.Index(name, "/"); slash >= 0 {
+		candidates = []string{name, name[:slash] + "/*", "*/*"}
+	}
+	for _, candidate := range candidates {
+		for _, m := range content {
+			if m.name == candidate {
+				return m, true
+			}
+		}
+	}
+	return mediaType{}, false
+}
+
+func schemaViolations(location string, violations []jsonschema.Violation) []Violation {
+	result := make([]Violation, 0, len(violations))
+	for _, v := range violations {
+		result = append(result, Violation{
+			Location: location + v.InstancePath,
+			Keyword:  v.Keyword,
+			Message:  v.Message,
+		})
+	}
+	return result
+}
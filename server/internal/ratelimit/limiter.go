@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter is an in-memory token bucket rate limiter keyed by an arbitrary string
+// (client IP, user ID, ...). Each key may burst up to limit requests and refills
+// at limit per window.
+type Limiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New creates a limiter allowing limit requests per window for each key
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		rate:      float64(limit) / window.Seconds(),
+		burst:     float64(limit),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow consumes a token for key. When the key is out of tokens it returns false
+// and how long the caller should wait before retrying.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets that have refilled completely; callers must hold l.mu
+func (l *Limiter) sweep(now time.Time) {
+	fullAfter := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.lastSweep) < fullAfter {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= fullAfter {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// clock is a manually advanced time source
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(limit int, window time.Duration) (*Limiter, *clock) {
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := New(limit, window)
+	l.now = func() time.Time { return c.now }
+	l.lastSweep = c.now
+	return l, c
+}
+
+func TestAllowBurst(t *testing.T) {
+	l, _ := newTestLimiter(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if ok, wait := l.Allow("a"); !ok || wait != 0 {
+			t.Fatalf("request %d = %v, %v, want allowed", i+1, ok, wait)
+		}
+	}
+
+	// Out of tokens: one refills every 20s
+	ok, wait := l.Allow("a")
+	if ok || wait != 20*time.Second {
+		t.Errorf("request 4 = %v, %v, want refused for 20s", ok, wait)
+	}
+}
+
+func TestAllowRefill(t *testing.T) {
+	l, c := newTestLimiter(3, time.Minute)
+	for i := 0; i < 3; i++ {
+		l.Allow("a")
+	}
+
+	tests := []struct {
+		advance time.Duration
+		want    bool
+		wait    time.Duration
+	}{
+		{advance: 5 * time.Second, want: false, wait: 15 * time.Second},
+		// Refused requests don't consume the partial token
+		{advance: 5 * time.Second, want: false, wait: 10 * time.Second},
+		{advance: 10 * time.Second, want: true},
+		{advance: 0, want: false, wait: 20 * time.Second},
+		// Refilling stops at the burst
+		{advance: time.Hour, want: true},
+		{advance: 0, want: true},
+		{advance: 0, want: true},
+		{advance: 0, want: false, wait: 20 * time.Second},
+	}
+
+	for i, tt := range tests {
+		c.advance(tt.advance)
+		ok, wait := l.Allow("a")
+		if ok != tt.want || wait != tt.wait {
+			t.Errorf("step %d: Allow = %v, %v, want %v, %v", i, ok, wait, tt.want, tt.wait)
+		}
+	}
+}
+
+func TestAllowPerKey(t *testing.T) {
+	l, _ := newTestLimiter(2, time.Minute)
+
+	l.Allow("a")
+	l.Allow("a")
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("a allowed past its burst")
+	}
+
+	// Other keys have buckets of their own
+	for _, key := range []string{"b", "b", "c"} {
+		if ok, _ := l.Allow(key); !ok {
+			t.Errorf("%s refused because a ran out", key)
+		}
+	}
+	if ok, _ := l.Allow("b"); ok {
+		t.Error("b allowed past its burst")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l, c := newTestLimiter(2, time.Minute)
+
+	l.Allow("idle")
+	c.advance(30 * time.Second)
+	l.Allow("busy")
+	l.Allow("busy")
+	if len(l.buckets) != 2 {
+		t.Fatalf("got %d buckets, want 2", len(l.buckets))
+	}
+
+	// Sweeps run once per window, dropping the buckets that have refilled
+	c.advance(40 * time.Second)
+	l.Allow("new")
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket was kept after refilling")
+	}
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("busy bucket was dropped before refilling")
+	}
+
+	c.advance(10 * time.Second)
+	l.Allow("new")
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("swept again within the window")
+	}
+
+	// A dropped bucket starts again with a full burst
+	c.advance(time.Minute)
+	l.Allow("new")
+	if _, ok := l.buckets["busy"]; ok {
+		t.Error("busy bucket was kept after refilling")
+	}
+	if ok, _ := l.Allow("busy"); !ok {
+		t.Error("busy refused after its bucket was dropped")
+	}
+}
@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+const (
+	// mostFailingLimit is how many nodes FlowStats reports as most failing
+	mostFailingLimit = 5
+
+	// A node is flaky when at least flakyMinFlips of its transitions flip
+	// and they make up at least flakyMinScore of them
+	flakyMinFlips = 2
+	flakyMinScore = 0.1
+)
+
+// AnalyticsRepository aggregates test run history
+type AnalyticsRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAnalyticsRepository creates a new analytics repository
+func NewAnalyticsRepository(db *pgxpool.Pool) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// FlowStats aggregates the runs of a flow created in [from, to).
+// Per-node figures come from test_run_nodes; labels and types are taken from the
+// flow's current nodes, so nodes that have since been removed have neither.
+func (r *AnalyticsRepository) FlowStats(ctx context.Context, flowID uuid.UUID, from, to time.Time) (*models.FlowStats, error) {
+	stats := &models.FlowStats{
+		FlowID:      flowID,
+		From:        from,
+		To:          to,
+		Nodes:       []models.NodeStats{},
+		MostFailing: []models.NodeStats{},
+		Daily:       []models.DailyStats{},
+	}
+
+	runQuery := `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE status = 'success'),
+		       COUNT(*) FILTER (WHERE status = 'failed'),
+		       COUNT(*) FILTER (WHERE status = 'cancelled'),
+		       COALESCE(AVG(duration_ms), 0),
+		       COALESCE(percentile_cont(0.50) WITHIN GROUP (ORDER BY duration_ms), 0),
+		       COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY duration_ms), 0),
+		       COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY duration_ms), 0)
+		FROM test_runs
+		WHERE flow_id = $1 AND created_at >= $2 AND created_at < $3
+	`
+
+	err := r.db.QueryRow(ctx, runQuery, flowID, from, to).Scan(
+		&stats.Runs,
+		&stats.Passed,
+		&stats.Failed,
+		&stats.Cancelled,
+		&stats.Duration.Mean,
+		&stats.Duration.P50,
+		&stats.Duration.P95,
+		&stats.Duration.P99,
+	)
+	if err != nil {
+		return nil, err
+	}
+	stats.PassRate = passRate(stats.Passed, stats.Failed)
+
+	if err := r.loadNodeStats(ctx, stats, flowID, from, to); err != nil {
+		return nil, err
+	}
+	if err := r.loadDailyStats(ctx, stats, flowID, from, to); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (r *AnalyticsRepository) loadNodeStats(ctx context.Context, stats *models.FlowStats, flowID uuid.UUID, from, to time.Time) error {
+	query := `
+		SELECT n.node_id,
+		       COALESCE(fn.data_label, ''),
+		       COALESCE(fn.type, ''),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE n.status = 'success'),
+		       COUNT(*) FILTER (WHERE n.status = 'failed'),
+		       COUNT(*) FILTER (WHERE n.status = 'skipped'),
+		       COALESCE(AVG(n.duration_ms), 0),
+		       COALESCE(percentile_cont(0.50) WITHIN GROUP (ORDER BY n.duration_ms), 0),
+		       COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY n.duration_ms), 0),
+		       COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY n.duration_ms), 0)
+		FROM test_run_nodes n
+		LEFT JOIN flow_nodes fn ON fn.flow_id = n.flow_id AND fn.node_id = n.node_id
+		WHERE n.flow_id = $1 AND n.created_at >= $2 AND n.created_at < $3
+		GROUP BY n.node_id, fn.data_label, fn.type, fn.sort_order
+		ORDER BY fn.sort_order NULLS LAST, n.node_id
+	`
+
+	rows, err := r.db.Query(ctx, query, flowID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var node models.NodeStats
+		err := rows.Scan(
+			&node.NodeID,
+			&node.Label,
+			&node.Type,
+			&node.Runs,
+			&node.Passed,
+			&node.Failed,
+			&node.Skipped,
+			&node.Duration.Mean,
+			&node.Duration.P50,
+			&node.Duration.P95,
+			&node.Duration.P99,
+		)
+		if err != nil {
+			return err
+		}
+		if node.Runs > 0 {
+			node.FailureRate = float64(node.Failed) / float64(node.Runs)
+		}
+		stats.Nodes = append(stats.Nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, node := range stats.Nodes {
+		if node.Failed > 0 {
+			stats.MostFailing = append(stats.MostFailing, node)
+		}
+	}
+	sort.SliceStable(stats.MostFailing, func(i, j int) bool {
+		a, b := stats.MostFailing[i], stats.MostFailing[j]
+		if a.Failed != b.Failed {
+			return a.Failed > b.Failed
+		}
+		return a.FailureRate > b.FailureRate
+	})
+	if len(stats.MostFailing) > mostFailingLimit {
+		stats.MostFailing = stats.MostFailing[:mostFailingLimit]
+	}
+
+	return nil
+}
+
+// loadDailyStats buckets runs by UTC day; days without runs are included with zero counts
+func (r *AnalyticsRepository) loadDailyStats(ctx context.Context, stats *models.FlowStats, flowID uuid.UUID, from, to time.Time) error {
+	query := `
+		SELECT day,
+		       COUNT(t.id),
+		       COUNT(t.id) FILTER (WHERE t.status = 'success'),
+		       COUNT(t.id) FILTER (WHERE t.status = 'failed'),
+		       COALESCE(AVG(t.duration_ms), 0),
+		       COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY t.duration_ms), 0)
+		FROM generate_series(
+		         date_trunc('day', $2::timestamptz AT TIME ZONE 'UTC'),
+		         $3::timestamptz AT TIME ZONE 'UTC',
+		         interval '1 day'
+		     ) AS day
+		LEFT JOIN test_runs t
+		       ON t.flow_id = $1
+		      AND t.created_at >= day AT TIME ZONE 'UTC'
+		      AND t.created_at < (day + interval '1 day') AT TIME ZONE 'UTC'
+		      AND t.created_at >= $2 AND t.created_at < $3
+		GROUP BY day
+		ORDER BY day
+	`
+
+	rows, err := r.db.Query(ctx, query, flowID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day models.DailyStats
+		if err := rows.Scan(&day.Date, &day.Runs, &day.Passed, &day.Failed, &day.MeanMs, &day.P95Ms); err != nil {
+			return err
+		}
+		day.PassRate = passRate(day.Passed, day.Failed)
+		stats.Daily = append(stats.Daily, day)
+	}
+
+	return rows.Err()
+}
+
+// FlakyNodes scores every node of a flow that passed or failed in a run created
+// since the given time, most flaky first. Only consecutive runs on the same flow
+// version are compared, so failures caused by editing the flow don't count as flips.
+func (r *AnalyticsRepository) FlakyNodes(ctx context.Context, flowID uuid.UUID, since time.Time) ([]models.FlakyNode, error) {
+	query := `
+		WITH results AS (
+			SELECT n.node_id, n.status, n.created_at,
+			       LAG(n.status) OVER (
+			           PARTITION BY n.node_id, t.flow_version
+			           ORDER BY n.created_at, n.test_run_id
+			       ) AS previous_status
+			FROM test_run_nodes n
+			JOIN test_runs t ON t.id = n.test_run_id
+			WHERE n.flow_id = $1 AND n.created_at >= $2 AND n.status IN ('success', 'failed')
+		)
+		SELECT r.node_id,
+		       COALESCE(fn.data_label, ''),
+		       COALESCE(fn.type, ''),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE r.status = 'failed'),
+		       COUNT(r.previous_status),
+		       COUNT(*) FILTER (WHERE r.previous_status IS NOT NULL AND r.previous_status <> r.status),
+		       MAX(r.created_at) FILTER (WHERE r.status = 'failed')
+		FROM results r
+		LEFT JOIN flow_nodes fn ON fn.flow_id = $1 AND fn.node_id = r.node_id
+		GROUP BY r.node_id, fn.data_label, fn.type
+	`
+
+	rows, err := r.db.Query(ctx, query, flowID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes := []models.FlakyNode{}
+	for rows.Next() {
+		var node models.FlakyNode
+		err := rows.Scan(
+			&node.NodeID,
+			&node.Label,
+			&node.Type,
+			&node.Runs,
+			&node.Failures,
+			&node.Transitions,
+			&node.Flips,
+			&node.LastFailureAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if node.Transitions > 0 {
+			node.Score = float64(node.Flips) / float64(node.Transitions)
+		}
+		node.Flaky = node.Flips >= flakyMinFlips && node.Score >= flakyMinScore
+		nodes = append(nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Score != nodes[j].Score {
+			return nodes[i].Score > nodes[j].Score
+		}
+		return nodes[i].NodeID < nodes[j].NodeID
+	})
+
+	return nodes, nil
+}
+
+func passRate(passed, failed int) *float64 {
+	if passed+failed == 0 {
+		return nil
+	}
+	rate := float64(passed) / float64(passed+failed)
+	return &rate
+}
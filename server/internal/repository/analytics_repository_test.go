@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+func TestFlakyNodes(t *testing.T) {
+	db := testDB(t)
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	at := func(minute int) time.Time { return start.Add(time.Duration(minute) * time.Minute) }
+
+	flow := testFlow(t, db,
+		testNode("api", "Get user", map[string]interface{}{}),
+		testNode("check", "Check user", map[string]interface{}{}),
+		testNode("wait", "Wait", map[string]interface{}{}),
+	)
+
+	const (
+		s = models.ExecutionStatusSuccess
+		f = models.ExecutionStatusFailed
+		k = models.ExecutionStatusSkipped
+	)
+	runs := []struct {
+		version  int
+		statuses map[string]models.ExecutionStatus
+	}{
+		{1, map[string]models.ExecutionStatus{"api": s, "check": s, "wait": s}},
+		{1, map[string]models.ExecutionStatus{"api": f, "check": s, "wait": k}},
+		{1, map[string]models.ExecutionStatus{"api": s, "check": s, "wait": s}},
+		{1, map[string]models.ExecutionStatus{"api": f, "check": s, "wait": s}},
+		{1, map[string]models.ExecutionStatus{"api": s, "check": s, "wait": s}},
+		{1, map[string]models.ExecutionStatus{"api": f, "check": f, "wait": s}},
+		// The flow was edited: the first runs of a version don't flip against the last of the previous one
+		{2, map[string]models.ExecutionStatus{"api": f, "check": s, "wait": s, "gone": f}},
+		{2, map[string]models.ExecutionStatus{"api": f, "check": s, "wait": s, "gone": s}},
+	}
+
+	// A run before the window isn't counted
+	testRun(t, db, flow, 1, at(-10), map[string]models.NodeResult{"api": {Status: f}, "check": {Status: f}})
+	for i, run := range runs {
+		results := make(map[string]models.NodeResult)
+		for nodeID, status := range run.statuses {
+			results[nodeID] = models.NodeResult{Status: status}
+		}
+		testRun(t, db, flow, run.version, at(i), results)
+	}
+
+	got, err := NewAnalyticsRepository(db).FlakyNodes(context.Background(), flow.ID, at(-1))
+	if err != nil {
+		t.Fatalf("FlakyNodes: %v", err)
+	}
+
+	want := []models.FlakyNode{
+		// Nodes no longer in the flow have neither a label nor a type
+		{NodeID: "gone", Runs: 2, Failures: 1, Transitions: 1, Flips: 1, Score: 1},
+		{NodeID: "api", Label: "Get user", Type: "custom", Runs: 8, Failures: 5, Transitions: 6, Flips: 5, Score: 5.0 / 6, Flaky: true},
+		{NodeID: "check", Label: "Check user", Type: "custom", Runs: 8, Failures: 1, Transitions: 6, Flips: 1, Score: 1.0 / 6},
+		{NodeID: "wait", Label: "Wait", Type: "custom", Runs: 7, Transitions: 5},
+	}
+	wantLastFailure := map[string]time.Time{"gone": at(6), "api": at(7), "check": at(5)}
+
+	if len(got) != len(want) {
+		t.Fatalf("nodes =\n%+v\nwant\n%+v", got, want)
+	}
+	for i := range want {
+		node := got[i]
+		lastFailure := node.LastFailureAt
+		node.LastFailureAt = nil
+		if !reflect.DeepEqual(node, want[i]) {
+			t.Errorf("node %d =\n%+v\nwant\n%+v", i, node, want[i])
+		}
+
+		wantAt, failed := wantLastFailure[node.NodeID]
+		if (lastFailure != nil) != failed || (failed && !lastFailure.Equal(wantAt)) {
+			t.Errorf("%s last failed at %v, want %v", node.NodeID, lastFailure, wantAt)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// ErrSpecNameTaken is returned when another spec already has the name
+var ErrSpecNameTaken = errors.New("a spec with this name already exists")
+
+// APISpecRepository handles OpenAPI spec library database operations
+type APISpecRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAPISpecRepository creates a new API spec repository
+func NewAPISpecRepository(db *pgxpool.Pool) *APISpecRepository {
+	return &APISpecRepository{db: db}
+}
+
+// Create adds a spec to the library
+func (r *APISpecRepository) Create(ctx context.Context, spec *models.APISpec) error {
+	documentJSON, err := json.Marshal(spec.Document)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO api_specs (id, name, description, title, api_version, document, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err = r.db.Exec(
+		ctx,
+		query,
+		spec.ID,
+		spec.Name,
+		spec.Description,
+		spec.Title,
+		spec.APIVersion,
+		documentJSON,
+		spec.CreatedBy,
+		spec.CreatedAt,
+		spec.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return ErrSpecNameTaken
+	}
+	return err
+}
+
+// GetByID retrieves a spec with its document by ID
+func (r *APISpecRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.APISpec, error) {
+	query := `
+		SELECT id, name, description, title, api_version, document, created_by, created_at, updated_at
+		FROM api_specs
+		WHERE id = $1
+	`
+
+	var spec models.APISpec
+	var documentJSON []byte
+
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&spec.ID,
+		&spec.Name,
+		&spec.Description,
+		&spec.Title,
+		&spec.APIVersion,
+		&documentJSON,
+		&spec.CreatedBy,
+		&spec.CreatedAt,
+		&spec.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(documentJSON, &spec.Document); err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+// GetUpdatedAt returns when a spec was last changed
+func (r *APISpecRepository) GetUpdatedAt(ctx context.Context, id uuid.UUID) (time.Time, error) {
+	var updatedAt time.Time
+	err := r.db.QueryRow(ctx, `SELECT updated_at FROM api_specs WHERE id = $1`, id).Scan(&updatedAt)
+	return updatedAt, err
+}
+
+// List retrieves every spec in the library without the documents
+func (r *APISpecRepository) List(ctx context.Context) ([]models.APISpec, error) {
+	query := `
+		SELECT id, name, description, title, api_version, created_by, created_at, updated_at
+		FROM api_specs
+		ORDER BY name
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	specs := []models.APISpec{}
+	for rows.Next() {
+		var spec models.APISpec
+		err := rows.Scan(
+			&spec.ID,
+			&spec.Name,
+			&spec.Description,
+			&spec.Title,
+			&spec.APIVersion,
+			&spec.CreatedBy,
+			&spec.CreatedAt,
+			&spec.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+
+	return specs, rows.Err()
+}
+
+// Update replaces a spec's name, description and document
+func (r *APISpecRepository) Update(ctx context.Context, spec *models.APISpec) error {
+	documentJSON, err := json.Marshal(spec.Document)
+	if err != nil {
+		return err
+	}
+
+	spec.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE api_specs
+		SET name = $2, description = $3, title = $4, api_version = $5, document = $6, updated_at = $7
+		WHERE id = $1
+	`
+
+	_, err = r.db.Exec(ctx, query, spec.ID, spec.Name, spec.Description, spec.Title, spec.APIVersion, documentJSON, spec.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrSpecNameTaken
+	}
+	return err
+}
+
+// Delete removes a spec from the library
+func (r *APISpecRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM api_specs WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// AuditRepository handles audit event database operations
+type AuditRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create appends an audit event. Events with an actor ID but no actor email
+// get the actor's current email, so they keep their actor once the user is
+// deleted and actor_id is cleared.
+func (r *AuditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	event.CreatedAt = time.Now()
+	metadataJSON, _ := json.Marshal(event.Metadata)
+
+	query := `
+		INSERT INTO audit_events (
+			id, actor_id, actor_email, action, resource_type, resource_id,
+			ip_address, user_agent, metadata, created_at
+		)
+		VALUES (
+			$1, $2, COALESCE(NULLIF($3, ''), (SELECT email FROM users WHERE id = $2), ''), $4, $5, $6,
+			$7, $8, $9, $10
+		)
+		RETURNING actor_email
+	`
+
+	return r.db.QueryRow(
+		ctx,
+		query,
+		event.ID,
+		event.ActorID,
+		event.ActorEmail,
+		event.Action,
+		event.ResourceType,
+		event.ResourceID,
+		event.IPAddress,
+		event.UserAgent,
+		metadataJSON,
+		event.CreatedAt,
+	).Scan(&event.ActorEmail)
+}
+
+// List retrieves audit events matching the filter, newest first
+func (r *AuditRepository) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if filter.ActorID != nil {
+		addCondition("actor_id = $%d", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if filter.ResourceType != "" {
+		addCondition("resource_type = $%d", filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		addCondition("resource_id = $%d", filter.ResourceID)
+	}
+	if filter.From != nil {
+		addCondition("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("created_at < $%d", *filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf(`
+		SELECT id, actor_id, actor_email, action, resource_type, resource_id,
+		       ip_address, user_agent, metadata, created_at
+		FROM audit_events
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d
+	`, where, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		var metadataJSON []byte
+
+		err := rows.Scan(
+			&event.ID,
+			&event.ActorID,
+			&event.ActorEmail,
+			&event.Action,
+			&event.ResourceType,
+			&event.ResourceID,
+			&event.IPAddress,
+			&event.UserAgent,
+			&metadataJSON,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		json.Unmarshal(metadataJSON, &event.Metadata)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// DeleteBefore removes events older than the cutoff and returns how many were deleted
+func (r *AuditRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM audit_events WHERE created_at < $1`
+	tag, err := r.db.Exec(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -3,37 +3,63 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/visual-api-testing-platform/server/internal/models"
 )
 
 // FlowRepository handles flow database operations
 type FlowRepository struct {
-	db         *pgxpool.Pool
-	nodeRepo   *NodeRepository
+	db          *pgxpool.Pool
+	nodeRepo    *NodeRepository
+	versionRepo *FlowVersionRepository
 }
 
 // NewFlowRepository creates a new flow repository
 func NewFlowRepository(db *pgxpool.Pool) *FlowRepository {
 	return &FlowRepository{
-		db:       db,
-		nodeRepo: NewNodeRepository(db),
+		db:          db,
+		nodeRepo:    NewNodeRepository(db),
+		versionRepo: NewFlowVersionRepository(db),
 	}
 }
 
-// Create creates a new flow
-func (r *FlowRepository) Create(ctx context.Context, flow *models.Flow) error {
+// Create creates a new flow with its nodes and records it as version 1.
+// All writes happen in a single transaction.
+func (r *FlowRepository) Create(ctx context.Context, flow *models.Flow, authorID uuid.UUID, message string) error {
+	return r.CreateAll(ctx, []*models.Flow{flow}, authorID, message)
+}
+
+// CreateAll creates several flows as Create does in a single transaction, so
+// either all of them are stored or none are
+func (r *FlowRepository) CreateAll(ctx context.Context, flows []*models.Flow, authorID uuid.UUID, message string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		for _, flow := range flows {
+			if err := r.insert(ctx, tx, flow, authorID, message); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *FlowRepository) insert(ctx context.Context, tx pgx.Tx, flow *models.Flow, authorID uuid.UUID, message string) error {
 	edgesJSON, _ := json.Marshal(flow.Edges)
+	variablesJSON := marshalVariables(flow.Variables)
+	flow.Version = 1
 
 	query := `
-		INSERT INTO flows (id, user_id, name, description, tags, edges, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO flows (id, user_id, name, description, tags, edges, variables, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 
-	_, err := r.db.Exec(
+	_, err := tx.Exec(
 		ctx,
 		query,
 		flow.ID,
@@ -42,6 +68,8 @@ func (r *FlowRepository) Create(ctx context.Context, flow *models.Flow) error {
 		flow.Description,
 		flow.Tags,
 		edgesJSON,
+		variablesJSON,
+		flow.Version,
 		flow.CreatedAt,
 		flow.UpdatedAt,
 	)
@@ -50,23 +78,20 @@ func (r *FlowRepository) Create(ctx context.Context, flow *models.Flow) error {
 		return err
 	}
 
-	// Create nodes separately
-	for i := range flow.Nodes {
-		if err := r.nodeRepo.Create(ctx, flow.ID, &flow.Nodes[i]); err != nil {
-			return err
-		}
+	if err := r.nodeRepo.withTx(tx).BulkUpsert(ctx, flow.ID, flow.Nodes); err != nil {
+		return err
 	}
 
-	return nil
+	return r.versionRepo.withTx(tx).Create(ctx, models.NewFlowVersion(flow, authorID, message))
 }
 
 // GetByID retrieves a flow by ID with nodes joined
 func (r *FlowRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Flow, error) {
 	var flow models.Flow
-	var edgesJSON []byte
+	var edgesJSON, variablesJSON []byte
 
 	query := `
-		SELECT id, user_id, name, description, tags, edges, created_at, updated_at
+		SELECT id, user_id, name, description, tags, edges, variables, version, created_at, updated_at
 		FROM flows
 		WHERE id = $1
 	`
@@ -78,6 +103,8 @@ func (r *FlowRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Flo
 		&flow.Description,
 		&flow.Tags,
 		&edgesJSON,
+		&variablesJSON,
+		&flow.Version,
 		&flow.CreatedAt,
 		&flow.UpdatedAt,
 	)
@@ -86,8 +113,9 @@ func (r *FlowRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Flo
 		return nil, err
 	}
 
-	// Parse edges
+	// Parse edges and variables
 	json.Unmarshal(edgesJSON, &flow.Edges)
+	json.Unmarshal(variablesJSON, &flow.Variables)
 
 	// Get nodes using JOIN
 	nodes, err := r.nodeRepo.GetByFlowID(ctx, id)
@@ -99,10 +127,11 @@ func (r *FlowRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Flo
 	return &flow, nil
 }
 
-// GetByUserID retrieves all flows for a user with nodes joined
+// GetByUserID retrieves all flows for a user with their nodes.
+// Use ListSummaries when node data is not needed.
 func (r *FlowRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.Flow, error) {
 	query := `
-		SELECT id, user_id, name, description, tags, edges, created_at, updated_at
+		SELECT id, user_id, name, description, tags, edges, variables, version, created_at, updated_at
 		FROM flows
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -117,7 +146,7 @@ func (r *FlowRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]m
 	var flows []models.Flow
 	for rows.Next() {
 		var flow models.Flow
-		var edgesJSON []byte
+		var edgesJSON, variablesJSON []byte
 
 		err := rows.Scan(
 			&flow.ID,
@@ -126,6 +155,8 @@ func (r *FlowRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]m
 			&flow.Description,
 			&flow.Tags,
 			&edgesJSON,
+			&variablesJSON,
+			&flow.Version,
 			&flow.CreatedAt,
 			&flow.UpdatedAt,
 		)
@@ -133,49 +164,300 @@ func (r *FlowRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]m
 			return nil, err
 		}
 
-		// Parse edges
+		// Parse edges and variables
 		json.Unmarshal(edgesJSON, &flow.Edges)
+		json.Unmarshal(variablesJSON, &flow.Variables)
+
+		flows = append(flows, flow)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Load the nodes of every flow in one query rather than one per flow
+	flowIDs := make([]uuid.UUID, len(flows))
+	for i := range flows {
+		flowIDs[i] = flows[i].ID
+	}
+	nodes, err := r.nodeRepo.GetByFlowIDs(ctx, flowIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range flows {
+		flows[i].Nodes = nodes[flows[i].ID]
+	}
+
+	return flows, nil
+}
+
+// ListSummaries retrieves a page of flow summaries for a user in a single query.
+// It returns the cursor for the next page, or "" when there are no more flows.
+func (r *FlowRepository) ListSummaries(ctx context.Context, userID uuid.UUID, opts models.FlowListOptions) ([]models.FlowSummary, string, error) {
+	sortColumn := "f.updated_at"
+	switch opts.Sort {
+	case models.FlowSortCreatedAt:
+		sortColumn = "f.created_at"
+	case models.FlowSortName:
+		sortColumn = "f.name"
+	}
 
-		// Get nodes using JOIN
-		nodes, err := r.nodeRepo.GetByFlowID(ctx, flow.ID)
+	direction, comparison := "ASC", ">"
+	if opts.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	limit := opts.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	conditions, args := flowFilterConditions(userID, opts)
+
+	if opts.Cursor != "" {
+		cursor, err := decodeCursor(opts.Cursor)
 		if err != nil {
+			return nil, "", err
+		}
+
+		var value interface{} = cursor.Value
+		if sortColumn != "f.name" {
+			t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+			if err != nil {
+				return nil, "", ErrInvalidCursor
+			}
+			value = t
+		}
+
+		args = append(args, value, cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, f.id) %s ($%d, $%d)", sortColumn, comparison, len(args)-1, len(args)))
+	}
+
+	// Fetch one extra row to know whether another page exists
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT f.id, f.user_id, f.name, f.description, f.tags, f.version,
+		       jsonb_array_length(f.edges), f.created_at, f.updated_at,
+		       COALESCE(nodes.counts, '{}'::jsonb), last_run.status, last_run.started_at
+		FROM flows f
+		LEFT JOIN LATERAL (
+			SELECT jsonb_object_agg(type, count) AS counts
+			FROM (
+				SELECT type, COUNT(*) AS count
+				FROM flow_nodes
+				WHERE flow_id = f.id
+				GROUP BY type
+			) by_type
+		) nodes ON true
+		LEFT JOIN LATERAL (
+			SELECT status, started_at
+			FROM test_runs
+			WHERE flow_id = f.id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) last_run ON true
+		WHERE %s
+		ORDER BY %s %s, f.id %s
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), sortColumn, direction, direction, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	summaries := []models.FlowSummary{}
+	for rows.Next() {
+		var summary models.FlowSummary
+		var description *string
+		var countsJSON []byte
+		var lastRunStatus *string
+
+		err := rows.Scan(
+			&summary.ID,
+			&summary.UserID,
+			&summary.Name,
+			&description,
+			&summary.Tags,
+			&summary.Version,
+			&summary.EdgeCount,
+			&summary.CreatedAt,
+			&summary.UpdatedAt,
+			&countsJSON,
+			&lastRunStatus,
+			&summary.LastRunAt,
+		)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if description != nil {
+			summary.Description = *description
+		}
+		json.Unmarshal(countsJSON, &summary.NodeCounts)
+		for _, n := range summary.NodeCounts {
+			summary.NodeCount += n
+		}
+		if lastRunStatus != nil {
+			status := models.ExecutionStatus(*lastRunStatus)
+			summary.LastRunStatus = &status
+		}
+
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(summaries) > limit {
+		summaries = summaries[:limit]
+		last := summaries[limit-1]
+
+		value := last.Name
+		switch sortColumn {
+		case "f.updated_at":
+			value = last.UpdatedAt.Format(time.RFC3339Nano)
+		case "f.created_at":
+			value = last.CreatedAt.Format(time.RFC3339Nano)
+		}
+		nextCursor = encodeCursor(value, last.ID)
+	}
+
+	return summaries, nextCursor, nil
+}
+
+// TagFacets counts the tags of every flow matching the filters in opts,
+// ignoring pagination. Facets are ordered by count, most common first.
+func (r *FlowRepository) TagFacets(ctx context.Context, userID uuid.UUID, opts models.FlowListOptions) ([]models.TagFacet, error) {
+	conditions, args := flowFilterConditions(userID, opts)
+
+	query := fmt.Sprintf(`
+		SELECT tag, COUNT(*)
+		FROM flows f, unnest(f.tags) AS tag
+		WHERE %s
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag ASC
+	`, strings.Join(conditions, " AND "))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := []models.TagFacet{}
+	for rows.Next() {
+		var facet models.TagFacet
+		if err := rows.Scan(&facet.Tag, &facet.Count); err != nil {
 			return nil, err
 		}
-		flow.Nodes = nodes
+		facets = append(facets, facet)
+	}
 
-		flows = append(flows, flow)
+	return facets, rows.Err()
+}
+
+// flowFilterConditions builds the WHERE conditions shared by flow listing and facets.
+// Conditions reference the flows table as f.
+func flowFilterConditions(userID uuid.UUID, opts models.FlowListOptions) ([]string, []interface{}) {
+	conditions := []string{"f.user_id = $1"}
+	args := []interface{}{userID}
+
+	addCondition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, strings.ReplaceAll(format, "$?", fmt.Sprintf("$%d", len(args))))
 	}
 
-	return flows, nil
+	if opts.Query != "" {
+		addCondition(`(
+			f.search_vector @@ websearch_to_tsquery('simple', $?)
+			OR EXISTS (
+				SELECT 1 FROM flow_nodes n
+				WHERE n.flow_id = f.id AND n.search_vector @@ websearch_to_tsquery('simple', $?)
+			)
+		)`, opts.Query)
+	}
+	if len(opts.Tags) > 0 {
+		addCondition("f.tags @> $?", opts.Tags)
+	}
+	if opts.HasNodeType != "" {
+		addCondition("EXISTS (SELECT 1 FROM flow_nodes n WHERE n.flow_id = f.id AND n.type = $?)", opts.HasNodeType)
+	}
+	if opts.URLContains != "" {
+		addCondition(`EXISTS (
+			SELECT 1 FROM flow_nodes n
+			WHERE n.flow_id = f.id AND n.data_config->>'url' ILIKE '%' || $? || '%' ESCAPE '\'
+		)`, escapeLike(opts.URLContains))
+	}
+
+	return conditions, args
 }
 
-// Update updates a flow and its nodes
-func (r *FlowRepository) Update(ctx context.Context, flow *models.Flow) error {
+// escapeLike escapes LIKE wildcards so s matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Update saves a flow and its nodes and records the result as a new version.
+// flow.Version must be the version the changes were based on; if the flow has
+// been saved since, nothing is written and a *VersionConflictError is returned.
+// On success flow.Version is set to the new version.
+func (r *FlowRepository) Update(ctx context.Context, flow *models.Flow, authorID uuid.UUID, message string) error {
 	edgesJSON, _ := json.Marshal(flow.Edges)
+	variablesJSON := marshalVariables(flow.Variables)
+	expected := flow.Version
 
 	query := `
 		UPDATE flows
-		SET name = $2, description = $3, tags = $4, edges = $5, updated_at = $6
-		WHERE id = $1
+		SET name = $2, description = $3, tags = $4, edges = $5, variables = $6, updated_at = $7, version = version + 1
+		WHERE id = $1 AND version = $8
+		RETURNING version
 	`
 
-	_, err := r.db.Exec(
-		ctx,
-		query,
-		flow.ID,
-		flow.Name,
-		flow.Description,
-		flow.Tags,
-		edgesJSON,
-		time.Now(),
-	)
+	var newVersion int
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(
+			ctx,
+			query,
+			flow.ID,
+			flow.Name,
+			flow.Description,
+			flow.Tags,
+			edgesJSON,
+			variablesJSON,
+			time.Now(),
+			expected,
+		).Scan(&newVersion)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			var current int
+			if err := tx.QueryRow(ctx, `SELECT version FROM flows WHERE id = $1`, flow.ID).Scan(&current); err != nil {
+				return err
+			}
+			return &VersionConflictError{Expected: expected, Current: current}
+		}
+		if err != nil {
+			return err
+		}
+
+		// Update nodes using bulk upsert
+		if err := r.nodeRepo.withTx(tx).BulkUpsert(ctx, flow.ID, flow.Nodes); err != nil {
+			return err
+		}
+
+		snapshot := *flow
+		snapshot.Version = newVersion
+		return r.versionRepo.withTx(tx).Create(ctx, models.NewFlowVersion(&snapshot, authorID, message))
+	})
 
 	if err != nil {
 		return err
 	}
 
-	// Update nodes using bulk upsert
-	return r.nodeRepo.BulkUpsert(ctx, flow.ID, flow.Nodes)
+	flow.Version = newVersion
+	return nil
 }
 
 // Delete deletes a flow (nodes will be deleted via CASCADE)
@@ -185,3 +467,11 @@ func (r *FlowRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+// marshalVariables encodes flow variables, storing an empty object rather than null
+func marshalVariables(variables map[string]interface{}) []byte {
+	if variables == nil {
+		return []byte("{}")
+	}
+	data, _ := json.Marshal(variables)
+	return data
+}
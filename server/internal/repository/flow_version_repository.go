@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// FlowVersionRepository handles flow version database operations.
+// Versions are immutable: there is no update or delete.
+type FlowVersionRepository struct {
+	db querier
+}
+
+// NewFlowVersionRepository creates a new flow version repository
+func NewFlowVersionRepository(db *pgxpool.Pool) *FlowVersionRepository {
+	return &FlowVersionRepository{db: db}
+}
+
+// withTx returns a copy of the repository that runs its statements in tx
+func (r *FlowVersionRepository) withTx(tx pgx.Tx) *FlowVersionRepository {
+	return &FlowVersionRepository{db: tx}
+}
+
+// Create stores a flow version
+func (r *FlowVersionRepository) Create(ctx context.Context, version *models.FlowVersion) error {
+	nodesJSON, _ := json.Marshal(version.Nodes)
+	edgesJSON, _ := json.Marshal(version.Edges)
+
+	query := `
+		INSERT INTO flow_versions (
+			id, flow_id, version, name, description, tags,
+			nodes, edges, variables, author_id, message, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	_, err := r.db.Exec(
+		ctx,
+		query,
+		version.ID,
+		version.FlowID,
+		version.Version,
+		version.Name,
+		version.Description,
+		version.Tags,
+		nodesJSON,
+		edgesJSON,
+		marshalVariables(version.Variables),
+		version.AuthorID,
+		version.Message,
+		version.CreatedAt,
+	)
+
+	return err
+}
+
+// ListByFlowID retrieves version metadata for a flow, newest first.
+// Nodes and edges are not loaded.
+func (r *FlowVersionRepository) ListByFlowID(ctx context.Context, flowID uuid.UUID) ([]models.FlowVersion, error) {
+	query := `
+		SELECT id, flow_id, version, name, description, tags,
+		       jsonb_array_length(nodes), author_id, message, created_at
+		FROM flow_versions
+		WHERE flow_id = $1
+		ORDER BY version DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, flowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []models.FlowVersion{}
+	for rows.Next() {
+		var version models.FlowVersion
+		var description *string
+
+		err := rows.Scan(
+			&version.ID,
+			&version.FlowID,
+			&version.Version,
+			&version.Name,
+			&description,
+			&version.Tags,
+			&version.NodeCount,
+			&version.AuthorID,
+			&version.Message,
+			&version.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if description != nil {
+			version.Description = *description
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+// GetByVersion retrieves a single version of a flow with its nodes and edges
+func (r *FlowVersionRepository) GetByVersion(ctx context.Context, flowID uuid.UUID, number int) (*models.FlowVersion, error) {
+	var version models.FlowVersion
+	var description *string
+	var nodesJSON, edgesJSON, variablesJSON []byte
+
+	query := `
+		SELECT id, flow_id, version, name, description, tags,
+		       nodes, edges, variables, author_id, message, created_at
+		FROM flow_versions
+		WHERE flow_id = $1 AND version = $2
+	`
+
+	err := r.db.QueryRow(ctx, query, flowID, number).Scan(
+		&version.ID,
+		&version.FlowID,
+		&version.Version,
+		&version.Name,
+		&description,
+		&version.Tags,
+		&nodesJSON,
+		&edgesJSON,
+		&variablesJSON,
+		&version.AuthorID,
+		&version.Message,
+		&version.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if description != nil {
+		version.Description = *description
+	}
+	json.Unmarshal(nodesJSON, &version.Nodes)
+	json.Unmarshal(edgesJSON, &version.Edges)
+	json.Unmarshal(variablesJSON, &version.Variables)
+	version.NodeCount = len(version.Nodes)
+
+	return &version, nil
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// LoadRunRepository handles load run database operations
+type LoadRunRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewLoadRunRepository creates a new load run repository
+func NewLoadRunRepository(db *pgxpool.Pool) *LoadRunRepository {
+	return &LoadRunRepository{db: db}
+}
+
+// loadRunColumns are selected by every load run query, in scanLoadRun order
+const loadRunColumns = `
+	id, flow_id, flow_version, status, config, metrics, thresholds, error,
+	environment, tags, started_at, completed_at, duration_ms, created_at
+`
+
+// Create creates a new load run, normally when it starts
+func (r *LoadRunRepository) Create(ctx context.Context, loadRun *models.LoadRun) error {
+	configJSON, _ := json.Marshal(loadRun.Config)
+	thresholdsJSON, _ := json.Marshal(loadRun.Thresholds)
+
+	var metricsJSON []byte
+	if loadRun.Metrics != nil {
+		metricsJSON, _ = json.Marshal(loadRun.Metrics)
+	}
+
+	tags := loadRun.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	query := `
+		INSERT INTO load_runs (
+			id, flow_id, flow_version, status, config, metrics, thresholds, error,
+			environment, tags, started_at, completed_at, duration_ms
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING created_at
+	`
+
+	return r.db.QueryRow(
+		ctx,
+		query,
+		loadRun.ID,
+		loadRun.FlowID,
+		loadRun.FlowVersion,
+		string(loadRun.Status),
+		configJSON,
+		metricsJSON,
+		thresholdsJSON,
+		loadRun.Error,
+		loadRun.Environment,
+		tags,
+		loadRun.StartedAt,
+		loadRun.CompletedAt,
+		loadRun.DurationMs,
+	).Scan(&loadRun.CreatedAt)
+}
+
+// Update records the outcome of a load run
+func (r *LoadRunRepository) Update(ctx context.Context, loadRun *models.LoadRun) error {
+	thresholdsJSON, _ := json.Marshal(loadRun.Thresholds)
+
+	var metricsJSON []byte
+	if loadRun.Metrics != nil {
+		metricsJSON, _ = json.Marshal(loadRun.Metrics)
+	}
+
+	query := `
+		UPDATE load_runs
+		SET status = $2, metrics = $3, thresholds = $4, error = $5, completed_at = $6, duration_ms = $7
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(
+		ctx,
+		query,
+		loadRun.ID,
+		string(loadRun.Status),
+		metricsJSON,
+		thresholdsJSON,
+		loadRun.Error,
+		loadRun.CompletedAt,
+		loadRun.DurationMs,
+	)
+	return err
+}
+
+// GetByID retrieves a load run by ID
+func (r *LoadRunRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.LoadRun, error) {
+	query := `SELECT ` + loadRunColumns + ` FROM load_runs WHERE id = $1`
+
+	return scanLoadRun(r.db.QueryRow(ctx, query, id))
+}
+
+// GetByFlowID retrieves the most recent load runs for a flow
+func (r *LoadRunRepository) GetByFlowID(ctx context.Context, flowID uuid.UUID, limit int) ([]models.LoadRun, error) {
+	query := `
+		SELECT ` + loadRunColumns + `
+		FROM load_runs
+		WHERE flow_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, flowID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loadRuns := []models.LoadRun{}
+	for rows.Next() {
+		loadRun, err := scanLoadRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		loadRuns = append(loadRuns, *loadRun)
+	}
+
+	return loadRuns, rows.Err()
+}
+
+// scanLoadRun scans a row selected with loadRunColumns
+func scanLoadRun(row pgx.Row) (*models.LoadRun, error) {
+	var loadRun models.LoadRun
+	var statusStr string
+	var flowVersion *int
+	var configJSON, metricsJSON, thresholdsJSON []byte
+	var errorStr *string
+
+	err := row.Scan(
+		&loadRun.ID,
+		&loadRun.FlowID,
+		&flowVersion,
+		&statusStr,
+		&configJSON,
+		&metricsJSON,
+		&thresholdsJSON,
+		&errorStr,
+		&loadRun.Environment,
+		&loadRun.Tags,
+		&loadRun.StartedAt,
+		&loadRun.CompletedAt,
+		&loadRun.DurationMs,
+		&loadRun.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	loadRun.Status = models.ExecutionStatus(statusStr)
+	if flowVersion != nil {
+		loadRun.FlowVersion = *flowVersion
+	}
+	if errorStr != nil {
+		loadRun.Error = *errorStr
+	}
+	json.Unmarshal(configJSON, &loadRun.Config)
+	if len(metricsJSON) > 0 {
+		json.Unmarshal(metricsJSON, &loadRun.Metrics)
+	}
+	json.Unmarshal(thresholdsJSON, &loadRun.Thresholds)
+	if loadRun.Thresholds == nil {
+		loadRun.Thresholds = []models.ThresholdResult{}
+	}
+
+	return &loadRun, nil
+}
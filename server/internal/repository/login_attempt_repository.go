@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// LoginAttemptRepository handles login attempt database operations
+type LoginAttemptRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewLoginAttemptRepository creates a new login attempt repository
+func NewLoginAttemptRepository(db *pgxpool.Pool) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// Create records a login attempt
+func (r *LoginAttemptRepository) Create(ctx context.Context, attempt *models.LoginAttempt) error {
+	if attempt.ID == uuid.Nil {
+		attempt.ID = uuid.New()
+	}
+	attempt.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO login_attempts (id, email, user_id, ip_address, user_agent, success, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(
+		ctx,
+		query,
+		attempt.ID,
+		attempt.Email,
+		attempt.UserID,
+		attempt.IPAddress,
+		attempt.UserAgent,
+		attempt.Success,
+		attempt.Reason,
+		attempt.CreatedAt,
+	)
+
+	return err
+}
+
+// CountRecentFailures counts failed attempts for an email since the given time and
+// since its last successful login. Attempts rejected while locked are not counted,
+// so a lockout is not extended by retries made during it.
+func (r *LoginAttemptRepository) CountRecentFailures(ctx context.Context, email string, since time.Time) (int, *time.Time, error) {
+	var count int
+	var lastFailure *time.Time
+
+	query := `
+		SELECT COUNT(*), MAX(created_at)
+		FROM login_attempts
+		WHERE email = $1
+		  AND success = false
+		  AND reason <> $3
+		  AND created_at > $2
+		  AND created_at > COALESCE(
+		      (SELECT MAX(created_at) FROM login_attempts WHERE email = $1 AND success = true),
+		      '-infinity'::timestamp
+		  )
+	`
+
+	err := r.db.QueryRow(ctx, query, email, since, models.LoginReasonLocked).Scan(&count, &lastFailure)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return count, lastFailure, nil
+}
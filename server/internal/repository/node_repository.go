@@ -6,13 +6,14 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/visual-api-testing-platform/server/internal/models"
 )
 
 // NodeRepository handles node database operations
 type NodeRepository struct {
-	db *pgxpool.Pool
+	db querier
 }
 
 // NewNodeRepository creates a new node repository
@@ -20,7 +21,12 @@ func NewNodeRepository(db *pgxpool.Pool) *NodeRepository {
 	return &NodeRepository{db: db}
 }
 
-// Create creates a new node
+// withTx returns a copy of the repository that runs its statements in tx
+func (r *NodeRepository) withTx(tx pgx.Tx) *NodeRepository {
+	return &NodeRepository{db: tx}
+}
+
+// Create creates a new node after the flow's existing nodes
 func (r *NodeRepository) Create(ctx context.Context, flowID uuid.UUID, node *models.FlowNode) error {
 	configJSON, _ := json.Marshal(node.Data.Config)
 	outputJSON, _ := json.Marshal(node.Data.Output)
@@ -30,9 +36,12 @@ func (r *NodeRepository) Create(ctx context.Context, flowID uuid.UUID, node *mod
 			flow_id, node_id, type, position_x, position_y,
 			data_id, data_type, data_label, data_status,
 			data_config, data_output, data_error,
-			created_at, updated_at
+			created_at, updated_at, sort_order
+		)
+		VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14,
+			(SELECT COALESCE(MAX(sort_order) + 1, 0) FROM flow_nodes WHERE flow_id = $1)
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
 	_, err := r.db.Exec(
@@ -60,23 +69,49 @@ func (r *NodeRepository) Create(ctx context.Context, flowID uuid.UUID, node *mod
 // GetByFlowID retrieves all nodes for a flow
 func (r *NodeRepository) GetByFlowID(ctx context.Context, flowID uuid.UUID) ([]models.FlowNode, error) {
 	query := `
-		SELECT 
-			node_id, type, position_x, position_y,
+		SELECT
+			flow_id, node_id, type, position_x, position_y,
 			data_id, data_type, data_label, data_status,
 			data_config, data_output, data_error
 		FROM flow_nodes
 		WHERE flow_id = $1
-		ORDER BY created_at ASC
+		ORDER BY sort_order ASC, created_at ASC
+	`
+
+	byFlow, err := r.queryNodes(ctx, query, flowID)
+	if err != nil {
+		return nil, err
+	}
+
+	return byFlow[flowID], nil
+}
+
+// GetByFlowIDs retrieves the nodes of several flows in one query, keyed by flow ID
+func (r *NodeRepository) GetByFlowIDs(ctx context.Context, flowIDs []uuid.UUID) (map[uuid.UUID][]models.FlowNode, error) {
+	query := `
+		SELECT
+			flow_id, node_id, type, position_x, position_y,
+			data_id, data_type, data_label, data_status,
+			data_config, data_output, data_error
+		FROM flow_nodes
+		WHERE flow_id = ANY($1)
+		ORDER BY flow_id, sort_order ASC, created_at ASC
 	`
 
-	rows, err := r.db.Query(ctx, query, flowID)
+	return r.queryNodes(ctx, query, flowIDs)
+}
+
+// queryNodes runs a node query and groups the rows by flow ID, keeping their order
+func (r *NodeRepository) queryNodes(ctx context.Context, query string, args ...interface{}) (map[uuid.UUID][]models.FlowNode, error) {
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var nodes []models.FlowNode
+	byFlow := make(map[uuid.UUID][]models.FlowNode)
 	for rows.Next() {
+		var flowID uuid.UUID
 		var node models.FlowNode
 		var configJSON, outputJSON []byte
 		var status *string
@@ -84,6 +119,7 @@ func (r *NodeRepository) GetByFlowID(ctx context.Context, flowID uuid.UUID) ([]m
 		var errorStr *string
 
 		err := rows.Scan(
+			&flowID,
 			&node.ID,
 			&node.Type,
 			&node.Position.X,
@@ -120,10 +156,10 @@ func (r *NodeRepository) GetByFlowID(ctx context.Context, flowID uuid.UUID) ([]m
 			node.Data.Error = *errorStr
 		}
 
-		nodes = append(nodes, node)
+		byFlow[flowID] = append(byFlow[flowID], node)
 	}
 
-	return nodes, nil
+	return byFlow, rows.Err()
 }
 
 // Update updates a node
@@ -174,20 +210,77 @@ func (r *NodeRepository) DeleteByFlowID(ctx context.Context, flowID uuid.UUID) e
 	return err
 }
 
-// BulkUpsert creates or updates multiple nodes for a flow
+// BulkUpsert makes the flow's stored nodes match nodes in a single round trip.
+// New nodes are inserted, changed nodes updated and missing nodes deleted;
+// unchanged rows are left alone so their created_at and updated_at are preserved.
+// Node order is stored as sort_order.
 func (r *NodeRepository) BulkUpsert(ctx context.Context, flowID uuid.UUID, nodes []models.FlowNode) error {
-	// Delete existing nodes
-	if err := r.DeleteByFlowID(ctx, flowID); err != nil {
-		return err
-	}
+	upsert := `
+		INSERT INTO flow_nodes (
+			flow_id, node_id, type, position_x, position_y,
+			data_id, data_type, data_label, data_status,
+			data_config, data_output, data_error,
+			sort_order, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $14)
+		ON CONFLICT (flow_id, node_id) DO UPDATE
+		SET type = EXCLUDED.type,
+		    position_x = EXCLUDED.position_x,
+		    position_y = EXCLUDED.position_y,
+		    data_id = EXCLUDED.data_id,
+		    data_type = EXCLUDED.data_type,
+		    data_label = EXCLUDED.data_label,
+		    data_status = EXCLUDED.data_status,
+		    data_config = EXCLUDED.data_config,
+		    data_output = EXCLUDED.data_output,
+		    data_error = EXCLUDED.data_error,
+		    sort_order = EXCLUDED.sort_order,
+		    updated_at = EXCLUDED.updated_at
+		WHERE (
+			flow_nodes.type, flow_nodes.position_x, flow_nodes.position_y,
+			flow_nodes.data_id, flow_nodes.data_type, flow_nodes.data_label, flow_nodes.data_status,
+			flow_nodes.data_config, flow_nodes.data_output, flow_nodes.data_error,
+			flow_nodes.sort_order
+		) IS DISTINCT FROM (
+			EXCLUDED.type, EXCLUDED.position_x, EXCLUDED.position_y,
+			EXCLUDED.data_id, EXCLUDED.data_type, EXCLUDED.data_label, EXCLUDED.data_status,
+			EXCLUDED.data_config, EXCLUDED.data_output, EXCLUDED.data_error,
+			EXCLUDED.sort_order
+		)
+	`
+
+	now := time.Now()
+	nodeIDs := make([]string, 0, len(nodes))
+	batch := &pgx.Batch{}
 
-	// Insert new nodes
+	// Remove nodes that are no longer part of the flow
 	for _, node := range nodes {
-		if err := r.Create(ctx, flowID, &node); err != nil {
-			return err
-		}
+		nodeIDs = append(nodeIDs, node.ID)
 	}
+	batch.Queue(`DELETE FROM flow_nodes WHERE flow_id = $1 AND NOT (node_id = ANY($2))`, flowID, nodeIDs)
 
-	return nil
-}
+	for i, node := range nodes {
+		configJSON, _ := json.Marshal(node.Data.Config)
+		outputJSON, _ := json.Marshal(node.Data.Output)
 
+		batch.Queue(
+			upsert,
+			flowID,
+			node.ID,
+			node.Type,
+			node.Position.X,
+			node.Position.Y,
+			node.Data.ID,
+			node.Data.Type,
+			node.Data.Label,
+			node.Data.Status,
+			configJSON,
+			outputJSON,
+			node.Data.Error,
+			i,
+			now,
+		)
+	}
+
+	return r.db.SendBatch(ctx, batch).Close()
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+type nodeRow struct {
+	nodeID    string
+	sortOrder int
+	updatedAt time.Time
+}
+
+// nodeRows reads a flow's nodes in sort order, with the timestamps
+// BulkUpsert only touches when a node changes
+func nodeRows(t *testing.T, db *pgxpool.Pool, flowID uuid.UUID) []nodeRow {
+	t.Helper()
+
+	rows, err := db.Query(context.Background(), `
+		SELECT node_id, sort_order, updated_at FROM flow_nodes WHERE flow_id = $1 ORDER BY sort_order
+	`, flowID)
+	if err != nil {
+		t.Fatalf("query nodes: %v", err)
+	}
+	defer rows.Close()
+
+	var got []nodeRow
+	for rows.Next() {
+		var row nodeRow
+		if err := rows.Scan(&row.nodeID, &row.sortOrder, &row.updatedAt); err != nil {
+			t.Fatalf("scan node: %v", err)
+		}
+		got = append(got, row)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("query nodes: %v", err)
+	}
+	return got
+}
+
+// backdateNodes moves a flow's node timestamps an hour back, so rows that are
+// written again show a newer updated_at
+func backdateNodes(t *testing.T, db *pgxpool.Pool, flowID uuid.UUID) {
+	t.Helper()
+
+	_, err := db.Exec(context.Background(), `
+		UPDATE flow_nodes
+		SET created_at = created_at - interval '1 hour', updated_at = updated_at - interval '1 hour'
+		WHERE flow_id = $1
+	`, flowID)
+	if err != nil {
+		t.Fatalf("backdate nodes: %v", err)
+	}
+}
+
+func TestNodeBulkUpsert(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	repo := NewNodeRepository(db)
+
+	login := testNode("login", "Log in", map[string]interface{}{"method": "POST", "url": "{{baseUrl}}/login"})
+	cart := testNode("cart", "Add to cart", map[string]interface{}{"method": "POST", "retries": 2.0})
+	check := testNode("check", "Check cart", map[string]interface{}{"assertionType": "response", "status": 201.0})
+	flow := testFlow(t, db, login, cart, check)
+
+	// Another flow's nodes with the same IDs are left alone
+	other := testFlow(t, db, login, cart)
+
+	if got := nodeRows(t, db, flow.ID); len(got) != 3 || got[0].nodeID != "login" || got[1].nodeID != "cart" || got[2].nodeID != "check" {
+		t.Fatalf("created nodes = %+v, want login, cart and check in order", got)
+	}
+	backdateNodes(t, db, flow.ID)
+	before := nodeRows(t, db, flow.ID)
+
+	// login is unchanged, cart is removed, check moves and is relabelled and
+	// report is added
+	check.Data.Label = "Verify cart"
+	report := testNode("report", "Report", map[string]interface{}{})
+	if err := repo.BulkUpsert(ctx, flow.ID, []models.FlowNode{login, check, report}); err != nil {
+		t.Fatalf("BulkUpsert: %v", err)
+	}
+
+	got := nodeRows(t, db, flow.ID)
+	if len(got) != 3 {
+		t.Fatalf("nodes = %+v, want login, check and report", got)
+	}
+	for i, id := range []string{"login", "check", "report"} {
+		if got[i].nodeID != id || got[i].sortOrder != i {
+			t.Errorf("node %d = %s at %d, want %s at %d", i, got[i].nodeID, got[i].sortOrder, id, i)
+		}
+	}
+	if !got[0].updatedAt.Equal(before[0].updatedAt) {
+		t.Errorf("unchanged login updated at %v, want %v", got[0].updatedAt, before[0].updatedAt)
+	}
+	if !got[1].updatedAt.After(before[2].updatedAt) {
+		t.Errorf("changed check updated at %v, want after %v", got[1].updatedAt, before[2].updatedAt)
+	}
+
+	nodes, err := repo.GetByFlowID(ctx, flow.ID)
+	if err != nil {
+		t.Fatalf("GetByFlowID: %v", err)
+	}
+	if want := []models.FlowNode{login, check, report}; !reflect.DeepEqual(nodes, want) {
+		t.Errorf("nodes =\n%+v\nwant\n%+v", nodes, want)
+	}
+
+	// Moving a node is a change even when nothing else is
+	backdateNodes(t, db, flow.ID)
+	before = nodeRows(t, db, flow.ID)
+	if err := repo.BulkUpsert(ctx, flow.ID, []models.FlowNode{check, login, report}); err != nil {
+		t.Fatalf("BulkUpsert: %v", err)
+	}
+	got = nodeRows(t, db, flow.ID)
+	if got[0].nodeID != "check" || !got[0].updatedAt.After(before[1].updatedAt) {
+		t.Errorf("moved check = %+v, want first and updated", got[0])
+	}
+	if got[1].nodeID != "login" || !got[1].updatedAt.After(before[0].updatedAt) {
+		t.Errorf("moved login = %+v, want second and updated", got[1])
+	}
+	if got[2].nodeID != "report" || !got[2].updatedAt.Equal(before[2].updatedAt) {
+		t.Errorf("unmoved report = %+v, want third and not updated", got[2])
+	}
+
+	// No nodes removes them all
+	if err := repo.BulkUpsert(ctx, flow.ID, nil); err != nil {
+		t.Fatalf("BulkUpsert: %v", err)
+	}
+	if got := nodeRows(t, db, flow.ID); len(got) != 0 {
+		t.Errorf("nodes = %+v, want none", got)
+	}
+
+	if got := nodeRows(t, db, other.ID); len(got) != 2 {
+		t.Errorf("other flow's nodes = %+v, want login and cart", got)
+	}
+}
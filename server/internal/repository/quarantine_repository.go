@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// QuarantineRepository handles node quarantine database operations
+type QuarantineRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewQuarantineRepository creates a new quarantine repository
+func NewQuarantineRepository(db *pgxpool.Pool) *QuarantineRepository {
+	return &QuarantineRepository{db: db}
+}
+
+// Set quarantines a node, replacing the reason if it is already quarantined
+func (r *QuarantineRepository) Set(ctx context.Context, quarantine *models.NodeQuarantine) error {
+	query := `
+		INSERT INTO node_quarantines (flow_id, node_id, reason, quarantined_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (flow_id, node_id) DO UPDATE
+		SET reason = EXCLUDED.reason, quarantined_by = EXCLUDED.quarantined_by
+		RETURNING created_at
+	`
+
+	return r.db.QueryRow(
+		ctx,
+		query,
+		quarantine.FlowID,
+		quarantine.NodeID,
+		quarantine.Reason,
+		quarantine.QuarantinedBy,
+	).Scan(&quarantine.CreatedAt)
+}
+
+// Delete releases a node from quarantine. It reports whether the node was quarantined.
+func (r *QuarantineRepository) Delete(ctx context.Context, flowID uuid.UUID, nodeID string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM node_quarantines WHERE flow_id = $1 AND node_id = $2`, flowID, nodeID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListByFlowID retrieves the quarantined nodes of a flow
+func (r *QuarantineRepository) ListByFlowID(ctx context.Context, flowID uuid.UUID) ([]models.NodeQuarantine, error) {
+	query := `
+		SELECT flow_id, node_id, reason, quarantined_by, created_at
+		FROM node_quarantines
+		WHERE flow_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, flowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quarantines := []models.NodeQuarantine{}
+	for rows.Next() {
+		var quarantine models.NodeQuarantine
+		err := rows.Scan(
+			&quarantine.FlowID,
+			&quarantine.NodeID,
+			&quarantine.Reason,
+			&quarantine.QuarantinedBy,
+			&quarantine.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		quarantines = append(quarantines, quarantine)
+	}
+
+	return quarantines, rows.Err()
+}
+
+// NodeIDs returns the set of quarantined node IDs of a flow
+func (r *QuarantineRepository) NodeIDs(ctx context.Context, flowID uuid.UUID) (map[string]bool, error) {
+	quarantines, err := r.ListByFlowID(ctx, flowID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool, len(quarantines))
+	for _, q := range quarantines {
+		ids[q.NodeID] = true
+	}
+	return ids, nil
+}
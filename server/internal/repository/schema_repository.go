@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// ErrSchemaNameTaken is returned when another schema already has the name
+var ErrSchemaNameTaken = errors.New("a schema with this name already exists")
+
+// SchemaRepository handles schema library database operations
+type SchemaRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSchemaRepository creates a new schema repository
+func NewSchemaRepository(db *pgxpool.Pool) *SchemaRepository {
+	return &SchemaRepository{db: db}
+}
+
+// Create adds a schema to the library
+func (r *SchemaRepository) Create(ctx context.Context, schema *models.Schema) error {
+	schemaJSON, err := json.Marshal(schema.Schema)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO schemas (id, name, description, schema, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = r.db.Exec(
+		ctx,
+		query,
+		schema.ID,
+		schema.Name,
+		schema.Description,
+		schemaJSON,
+		schema.CreatedBy,
+		schema.CreatedAt,
+		schema.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return ErrSchemaNameTaken
+	}
+	return err
+}
+
+// GetByID retrieves a schema by ID
+func (r *SchemaRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Schema, error) {
+	query := `
+		SELECT id, name, description, schema, created_by, created_at, updated_at
+		FROM schemas
+		WHERE id = $1
+	`
+
+	var schema models.Schema
+	var schemaJSON []byte
+
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&schema.ID,
+		&schema.Name,
+		&schema.Description,
+		&schemaJSON,
+		&schema.CreatedBy,
+		&schema.CreatedAt,
+		&schema.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(schemaJSON, &schema.Schema); err != nil {
+		return nil, err
+	}
+
+	return &schema, nil
+}
+
+// List retrieves every schema in the library without the schema documents
+func (r *SchemaRepository) List(ctx context.Context) ([]models.Schema, error) {
+	query := `
+		SELECT id, name, description, created_by, created_at, updated_at
+		FROM schemas
+		ORDER BY name
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schemas := []models.Schema{}
+	for rows.Next() {
+		var schema models.Schema
+		err := rows.Scan(
+			&schema.ID,
+			&schema.Name,
+			&schema.Description,
+			&schema.CreatedBy,
+			&schema.CreatedAt,
+			&schema.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+
+	return schemas, rows.Err()
+}
+
+// Update replaces a schema's name, description and document
+func (r *SchemaRepository) Update(ctx context.Context, schema *models.Schema) error {
+	schemaJSON, err := json.Marshal(schema.Schema)
+	if err != nil {
+		return err
+	}
+
+	schema.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE schemas
+		SET name = $2, description = $3, schema = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	_, err = r.db.Exec(ctx, query, schema.ID, schema.Name, schema.Description, schemaJSON, schema.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrSchemaNameTaken
+	}
+	return err
+}
+
+// Delete removes a schema from the library
+func (r *SchemaRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM schemas WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// SnapshotRepository handles node snapshot database operations
+type SnapshotRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSnapshotRepository creates a new snapshot repository
+func NewSnapshotRepository(db *pgxpool.Pool) *SnapshotRepository {
+	return &SnapshotRepository{db: db}
+}
+
+// Get retrieves a node's snapshot
+func (r *SnapshotRepository) Get(ctx context.Context, flowID uuid.UUID, nodeID string) (*models.NodeSnapshot, error) {
+	query := `
+		SELECT flow_id, node_id, value, source_test_run_id, approved_by, created_at, updated_at
+		FROM node_snapshots
+		WHERE flow_id = $1 AND node_id = $2
+	`
+
+	var snapshot models.NodeSnapshot
+	var valueJSON []byte
+
+	err := r.db.QueryRow(ctx, query, flowID, nodeID).Scan(
+		&snapshot.FlowID,
+		&snapshot.NodeID,
+		&valueJSON,
+		&snapshot.SourceTestRunID,
+		&snapshot.ApprovedBy,
+		&snapshot.CreatedAt,
+		&snapshot.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(valueJSON, &snapshot.Value); err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// FindSnapshot retrieves a node's snapshot, returning nil if it has none
+func (r *SnapshotRepository) FindSnapshot(ctx context.Context, flowID uuid.UUID, nodeID string) (*models.NodeSnapshot, error) {
+	snapshot, err := r.Get(ctx, flowID, nodeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return snapshot, err
+}
+
+// RecordSnapshot stores a snapshot unless the node already has one, so
+// concurrent first runs cannot overwrite each other. It reports whether the
+// snapshot was stored.
+func (r *SnapshotRepository) RecordSnapshot(ctx context.Context, snapshot *models.NodeSnapshot) (bool, error) {
+	valueJSON, err := json.Marshal(snapshot.Value)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO node_snapshots (flow_id, node_id, value, source_test_run_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (flow_id, node_id) DO NOTHING
+		RETURNING created_at, updated_at
+	`
+
+	err = r.db.QueryRow(ctx, query, snapshot.FlowID, snapshot.NodeID, valueJSON, snapshot.SourceTestRunID).
+		Scan(&snapshot.CreatedAt, &snapshot.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Save creates or replaces a node's snapshot
+func (r *SnapshotRepository) Save(ctx context.Context, snapshot *models.NodeSnapshot) error {
+	valueJSON, err := json.Marshal(snapshot.Value)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO node_snapshots (flow_id, node_id, value, source_test_run_id, approved_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (flow_id, node_id) DO UPDATE
+		SET value = EXCLUDED.value,
+		    source_test_run_id = EXCLUDED.source_test_run_id,
+		    approved_by = EXCLUDED.approved_by,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING created_at, updated_at
+	`
+
+	return r.db.QueryRow(
+		ctx,
+		query,
+		snapshot.FlowID,
+		snapshot.NodeID,
+		valueJSON,
+		snapshot.SourceTestRunID,
+		snapshot.ApprovedBy,
+	).Scan(&snapshot.CreatedAt, &snapshot.UpdatedAt)
+}
+
+// Delete removes a node's snapshot so the next run records a new one.
+// It reports whether the node had a snapshot.
+func (r *SnapshotRepository) Delete(ctx context.Context, flowID uuid.UUID, nodeID string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM node_snapshots WHERE flow_id = $1 AND node_id = $2`, flowID, nodeID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
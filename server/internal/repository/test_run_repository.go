@@ -3,8 +3,12 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/visual-api-testing-platform/server/internal/models"
 )
@@ -19,112 +23,275 @@ func NewTestRunRepository(db *pgxpool.Pool) *TestRunRepository {
 	return &TestRunRepository{db: db}
 }
 
-// Create creates a new test run
+// testRunColumns are selected by every test run query, in scanTestRun order
+const testRunColumns = `
+	t.id, t.flow_id, t.flow_version, t.status, t.started_at, t.completed_at, t.duration_ms,
+	t.node_results, t.error, t.trigger_source, t.environment, t.tags, t.compacted_at, t.created_at
+`
+
+// Create creates a new test run together with its per-node rows in test_run_nodes
 func (r *TestRunRepository) Create(ctx context.Context, testRun *models.TestRun) error {
 	nodeResultsJSON, _ := json.Marshal(testRun.NodeResults)
 
+	tags := testRun.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
 	query := `
-		INSERT INTO test_runs (id, flow_id, status, started_at, completed_at, duration_ms, node_results, error)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO test_runs (
+			id, flow_id, flow_version, status, started_at, completed_at, duration_ms,
+			node_results, error, trigger_source, environment, tags
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 
-	_, err := r.db.Exec(
-		ctx,
-		query,
-		testRun.ID,
-		testRun.FlowID,
-		string(testRun.Status),
-		testRun.StartedAt,
-		testRun.CompletedAt,
-		testRun.DurationMs,
-		nodeResultsJSON,
-		testRun.Error,
-	)
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(
+			ctx,
+			query,
+			testRun.ID,
+			testRun.FlowID,
+			testRun.FlowVersion,
+			string(testRun.Status),
+			testRun.StartedAt,
+			testRun.CompletedAt,
+			testRun.DurationMs,
+			nodeResultsJSON,
+			testRun.Error,
+			testRun.TriggerSource,
+			testRun.Environment,
+			tags,
+		)
+		if err != nil {
+			return err
+		}
 
+		if err := insertRunNodes(ctx, tx, testRun); err != nil {
+			return err
+		}
+		return linkRecordedSnapshots(ctx, tx, testRun)
+	})
+}
+
+// linkRecordedSnapshots sets the run as the source of the snapshots it
+// recorded. Snapshots are recorded while the run executes, before its row
+// exists, so they are stored without a source.
+func linkRecordedSnapshots(ctx context.Context, tx pgx.Tx, testRun *models.TestRun) error {
+	nodeIDs := models.RecordedSnapshotNodes(testRun)
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE node_snapshots
+		SET source_test_run_id = $1
+		WHERE flow_id = $2 AND node_id = ANY($3)
+		  AND source_test_run_id IS NULL AND approved_by IS NULL
+	`, testRun.ID, testRun.FlowID, nodeIDs)
 	return err
 }
 
 // GetByID retrieves a test run by ID
 func (r *TestRunRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.TestRun, error) {
-	var testRun models.TestRun
-	var statusStr string
-	var nodeResultsJSON []byte
+	query := `SELECT ` + testRunColumns + ` FROM test_runs t WHERE t.id = $1`
 
+	return scanTestRun(r.db.QueryRow(ctx, query, id))
+}
+
+// GetByFlowID retrieves the most recent test runs for a flow
+func (r *TestRunRepository) GetByFlowID(ctx context.Context, flowID uuid.UUID, limit int) ([]models.TestRun, error) {
 	query := `
-		SELECT id, flow_id, status, started_at, completed_at, duration_ms, node_results, error, created_at
-		FROM test_runs
-		WHERE id = $1
+		SELECT ` + testRunColumns + `
+		FROM test_runs t
+		WHERE t.flow_id = $1
+		ORDER BY t.created_at DESC
+		LIMIT $2
 	`
 
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&testRun.ID,
-		&testRun.FlowID,
-		&statusStr,
-		&testRun.StartedAt,
-		&testRun.CompletedAt,
-		&testRun.DurationMs,
-		&nodeResultsJSON,
-		&testRun.Error,
-		&testRun.CreatedAt,
-	)
-
+	rows, err := r.db.Query(ctx, query, flowID, limit)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	testRun.Status = models.ExecutionStatus(statusStr)
-	json.Unmarshal(nodeResultsJSON, &testRun.NodeResults)
+	var testRuns []models.TestRun
+	for rows.Next() {
+		testRun, err := scanTestRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		testRuns = append(testRuns, *testRun)
+	}
 
-	return &testRun, nil
+	return testRuns, rows.Err()
 }
 
-// GetByFlowID retrieves all test runs for a flow
-func (r *TestRunRepository) GetByFlowID(ctx context.Context, flowID uuid.UUID, limit int) ([]models.TestRun, error) {
+// GetRecentByUserID retrieves the runs of the user's flows created since the
+// given time, newest first, with their node results. Compacted runs, which no
+// longer have node outputs, are left out.
+func (r *TestRunRepository) GetRecentByUserID(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]models.TestRun, error) {
 	query := `
-		SELECT id, flow_id, status, started_at, completed_at, duration_ms, node_results, error, created_at
-		FROM test_runs
-		WHERE flow_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2
+		SELECT ` + testRunColumns + `
+		FROM test_runs t
+		JOIN flows f ON f.id = t.flow_id
+		WHERE f.user_id = $1 AND t.created_at >= $2 AND t.compacted_at IS NULL
+		ORDER BY t.created_at DESC
+		LIMIT $3
 	`
 
-	rows, err := r.db.Query(ctx, query, flowID, limit)
+	rows, err := r.db.Query(ctx, query, userID, since, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var testRuns []models.TestRun
+	for rows.Next() {
+		testRun, err := scanTestRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		testRuns = append(testRuns, *testRun)
+	}
+
+	return testRuns, rows.Err()
+}
+
+// GetLastSuccessful retrieves the newest successful run of a flow that started
+// before the given time
+func (r *TestRunRepository) GetLastSuccessful(ctx context.Context, flowID uuid.UUID, before time.Time) (*models.TestRun, error) {
+	query := `
+		SELECT ` + testRunColumns + `
+		FROM test_runs t
+		WHERE t.flow_id = $1 AND t.status = $2 AND t.started_at < $3
+		ORDER BY t.started_at DESC
+		LIMIT 1
+	`
+
+	return scanTestRun(r.db.QueryRow(ctx, query, flowID, string(models.ExecutionStatusSuccess), before))
+}
+
+// List retrieves a page of test runs across the user's flows, newest first.
+// Node results are not loaded. It returns the cursor for the next page, or ""
+// when there are no more runs.
+func (r *TestRunRepository) List(ctx context.Context, filter models.TestRunFilter) ([]models.TestRun, string, error) {
+	conditions := []string{"f.user_id = $1"}
+	args := []interface{}{filter.UserID}
+
+	addCondition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if filter.FlowID != nil {
+		addCondition("t.flow_id = $%d", *filter.FlowID)
+	}
+	if filter.Status != "" {
+		addCondition("t.status = $%d", string(filter.Status))
+	}
+	if filter.TriggerSource != "" {
+		addCondition("t.trigger_source = $%d", filter.TriggerSource)
+	}
+	if filter.Environment != "" {
+		addCondition("t.environment = $%d", filter.Environment)
+	}
+	if filter.Tag != "" {
+		addCondition("$%d = ANY(t.tags)", filter.Tag)
+	}
+	if filter.From != nil {
+		addCondition("t.created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("t.created_at < $%d", *filter.To)
+	}
+	if filter.Cursor != "" {
+		cursor, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		args = append(args, createdAt, cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(t.created_at, t.id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	// Fetch one extra row to know whether another page exists
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT t.id, t.flow_id, f.name, t.flow_version, t.status, t.started_at, t.completed_at,
+		       t.duration_ms, t.error, t.trigger_source, t.environment, t.tags, t.compacted_at, t.created_at
+		FROM test_runs t
+		JOIN flows f ON f.id = t.flow_id
+		WHERE %s
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	testRuns := []models.TestRun{}
 	for rows.Next() {
 		var testRun models.TestRun
+		var flowVersion *int
 		var statusStr string
-		var nodeResultsJSON []byte
+		var errorStr *string
 
 		err := rows.Scan(
 			&testRun.ID,
 			&testRun.FlowID,
+			&testRun.FlowName,
+			&flowVersion,
 			&statusStr,
 			&testRun.StartedAt,
 			&testRun.CompletedAt,
 			&testRun.DurationMs,
-			&nodeResultsJSON,
-			&testRun.Error,
+			&errorStr,
+			&testRun.TriggerSource,
+			&testRun.Environment,
+			&testRun.Tags,
+			&testRun.CompactedAt,
 			&testRun.CreatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
 		testRun.Status = models.ExecutionStatus(statusStr)
-		json.Unmarshal(nodeResultsJSON, &testRun.NodeResults)
-
+		if flowVersion != nil {
+			testRun.FlowVersion = *flowVersion
+		}
+		if errorStr != nil {
+			testRun.Error = *errorStr
+		}
 		testRuns = append(testRuns, testRun)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
 
-	return testRuns, nil
+	nextCursor := ""
+	if len(testRuns) > limit {
+		testRuns = testRuns[:limit]
+		last := testRuns[limit-1]
+		nextCursor = encodeCursor(last.CreatedAt.Format(time.RFC3339Nano), last.ID)
+	}
+
+	return testRuns, nextCursor, nil
 }
 
-// Update updates a test run
+// Update updates a test run and replaces its per-node rows
 func (r *TestRunRepository) Update(ctx context.Context, testRun *models.TestRun) error {
 	nodeResultsJSON, _ := json.Marshal(testRun.NodeResults)
 
@@ -134,17 +301,139 @@ func (r *TestRunRepository) Update(ctx context.Context, testRun *models.TestRun)
 		WHERE id = $1
 	`
 
-	_, err := r.db.Exec(
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(
+			ctx,
+			query,
+			testRun.ID,
+			string(testRun.Status),
+			testRun.CompletedAt,
+			testRun.DurationMs,
+			nodeResultsJSON,
+			testRun.Error,
+		)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM test_run_nodes WHERE test_run_id = $1`, testRun.ID); err != nil {
+			return err
+		}
+
+		return insertRunNodes(ctx, tx, testRun)
+	})
+}
+
+// insertRunNodes copies a run's node results into test_run_nodes for analytics
+func insertRunNodes(ctx context.Context, tx pgx.Tx, testRun *models.TestRun) error {
+	if len(testRun.NodeResults) == 0 {
+		return nil
+	}
+
+	rows := make([][]interface{}, 0, len(testRun.NodeResults))
+	for nodeID, result := range testRun.NodeResults {
+		var errorStr *string
+		if result.Error != "" {
+			errorStr = &result.Error
+		}
+		rows = append(rows, []interface{}{
+			testRun.ID, testRun.FlowID, nodeID, string(result.Status), result.Duration, errorStr, result.Quarantined, testRun.StartedAt,
+		})
+	}
+
+	_, err := tx.CopyFrom(
 		ctx,
-		query,
-		testRun.ID,
-		string(testRun.Status),
-		testRun.CompletedAt,
-		testRun.DurationMs,
-		nodeResultsJSON,
-		testRun.Error,
+		pgx.Identifier{"test_run_nodes"},
+		[]string{"test_run_id", "flow_id", "node_id", "status", "duration_ms", "error", "quarantined", "created_at"},
+		pgx.CopyFromRows(rows),
 	)
-
 	return err
 }
 
+// CompactExpired strips node outputs from runs that fall outside the retention
+// policy, keeping their status, errors and durations. A run is retained in full
+// while it is one of the keepRuns newest runs of its flow or newer than keepSince;
+// a zero keepRuns or keepSince disables that rule. At most batchSize runs are
+// compacted per call; it returns how many were.
+func (r *TestRunRepository) CompactExpired(ctx context.Context, keepRuns int, keepSince time.Time, batchSize int) (int64, error) {
+	var retained []string
+	var args []interface{}
+	if keepRuns > 0 {
+		args = append(args, keepRuns)
+		retained = append(retained, fmt.Sprintf("ranked.position <= $%d", len(args)))
+	}
+	if !keepSince.IsZero() {
+		args = append(args, keepSince)
+		retained = append(retained, fmt.Sprintf("ranked.created_at >= $%d", len(args)))
+	}
+	if len(retained) == 0 {
+		return 0, nil
+	}
+	args = append(args, batchSize)
+
+	query := fmt.Sprintf(`
+		UPDATE test_runs t
+		SET node_results = COALESCE((
+				SELECT jsonb_object_agg(key, value - 'output')
+				FROM jsonb_each(t.node_results)
+			), '{}'::jsonb),
+			compacted_at = CURRENT_TIMESTAMP
+		WHERE t.id IN (
+			SELECT ranked.id
+			FROM (
+				SELECT id, created_at, compacted_at,
+				       ROW_NUMBER() OVER (PARTITION BY flow_id ORDER BY created_at DESC) AS position
+				FROM test_runs
+			) ranked
+			WHERE ranked.compacted_at IS NULL AND NOT (%s)
+			LIMIT $%d
+		)
+	`, strings.Join(retained, " OR "), len(args))
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// scanTestRun scans a row selected with testRunColumns
+func scanTestRun(row pgx.Row) (*models.TestRun, error) {
+	var testRun models.TestRun
+	var statusStr string
+	var flowVersion *int
+	var nodeResultsJSON []byte
+	var errorStr *string
+
+	err := row.Scan(
+		&testRun.ID,
+		&testRun.FlowID,
+		&flowVersion,
+		&statusStr,
+		&testRun.StartedAt,
+		&testRun.CompletedAt,
+		&testRun.DurationMs,
+		&nodeResultsJSON,
+		&errorStr,
+		&testRun.TriggerSource,
+		&testRun.Environment,
+		&testRun.Tags,
+		&testRun.CompactedAt,
+		&testRun.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	testRun.Status = models.ExecutionStatus(statusStr)
+	if flowVersion != nil {
+		testRun.FlowVersion = *flowVersion
+	}
+	if errorStr != nil {
+		testRun.Error = *errorStr
+	}
+	json.Unmarshal(nodeResultsJSON, &testRun.NodeResults)
+
+	return &testRun, nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// testRun stores a run of the flow started, and created, at the given time
+func testRun(t *testing.T, db *pgxpool.Pool, flow *models.Flow, version int, startedAt time.Time, results map[string]models.NodeResult) *models.TestRun {
+	t.Helper()
+	ctx := context.Background()
+
+	status := models.ExecutionStatusSuccess
+	for _, result := range results {
+		if result.Status == models.ExecutionStatusFailed {
+			status = models.ExecutionStatusFailed
+		}
+	}
+
+	run := &models.TestRun{
+		ID:            uuid.New(),
+		FlowID:        flow.ID,
+		FlowVersion:   version,
+		Status:        status,
+		StartedAt:     startedAt,
+		NodeResults:   results,
+		TriggerSource: models.TriggerSourceManual,
+	}
+	if err := NewTestRunRepository(db).Create(ctx, run); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	if _, err := db.Exec(ctx, `UPDATE test_runs SET created_at = $2 WHERE id = $1`, run.ID, startedAt); err != nil {
+		t.Fatalf("backdate run: %v", err)
+	}
+	return run
+}
+
+// compactAll compacts until no run is left to compact
+func compactAll(t *testing.T, repo *TestRunRepository, keepRuns int, keepSince time.Time) {
+	t.Helper()
+
+	for {
+		n, err := repo.CompactExpired(context.Background(), keepRuns, keepSince, 100)
+		if err != nil {
+			t.Fatalf("CompactExpired: %v", err)
+		}
+		if n == 0 {
+			return
+		}
+	}
+}
+
+func apiResults() map[string]models.NodeResult {
+	return map[string]models.NodeResult{
+		"api":   {Status: models.ExecutionStatusSuccess, Duration: 120, Output: map[string]interface{}{"status": 200.0}},
+		"check": {Status: models.ExecutionStatusFailed, Duration: 3, Error: "1 of 1 assertions failed", Output: map[string]interface{}{"passed": false}},
+	}
+}
+
+func TestCompactExpired(t *testing.T) {
+	db := testDB(t)
+	repo := NewTestRunRepository(db)
+	now := time.Now()
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		keepRuns  int
+		keepSince time.Time
+		retained  []bool // for runs 6 days old down to 1 day old
+	}{
+		{name: "newest runs", keepRuns: 2, retained: []bool{false, false, false, false, true, true}},
+		{name: "recent runs", keepSince: now.Add(-3*day - day/2), retained: []bool{false, false, false, true, true, true}},
+		{name: "retained by either rule", keepRuns: 4, keepSince: now.Add(-day - day/2), retained: []bool{false, false, true, true, true, true}},
+		{name: "no rules", retained: []bool{true, true, true, true, true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flow := testFlow(t, db)
+			var runs []*models.TestRun
+			for i := range tt.retained {
+				runs = append(runs, testRun(t, db, flow, 1, now.Add(-time.Duration(6-i)*day), apiResults()))
+			}
+
+			compactAll(t, repo, tt.keepRuns, tt.keepSince)
+
+			for i, run := range runs {
+				got, err := repo.GetByID(context.Background(), run.ID)
+				if err != nil {
+					t.Fatalf("GetByID: %v", err)
+				}
+				api, check := got.NodeResults["api"], got.NodeResults["check"]
+
+				// Compaction only drops the outputs
+				if api.Status != models.ExecutionStatusSuccess || api.Duration != 120 ||
+					check.Status != models.ExecutionStatusFailed || check.Duration != 3 || check.Error != "1 of 1 assertions failed" {
+					t.Errorf("run %d results = %+v, want statuses, durations and errors kept", i, got.NodeResults)
+				}
+
+				if tt.retained[i] {
+					if got.CompactedAt != nil || api.Output == nil || check.Output == nil {
+						t.Errorf("run %d compacted at %v with results %+v, want it retained", i, got.CompactedAt, got.NodeResults)
+					}
+				} else if got.CompactedAt == nil || api.Output != nil || check.Output != nil {
+					t.Errorf("run %d compacted at %v with results %+v, want it compacted", i, got.CompactedAt, got.NodeResults)
+				}
+			}
+		})
+	}
+}
+
+func TestCompactExpiredBatches(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	repo := NewTestRunRepository(db)
+	start := time.Now().Add(-time.Hour)
+
+	flow := testFlow(t, db)
+	var runs []*models.TestRun
+	for i := 0; i < 5; i++ {
+		runs = append(runs, testRun(t, db, flow, 1, start.Add(time.Duration(i)*time.Minute), apiResults()))
+	}
+	// The newest run is counted per flow
+	other := testFlow(t, db)
+	otherRun := testRun(t, db, other, 1, start.Add(-time.Hour), apiResults())
+
+	if n, err := repo.CompactExpired(ctx, 1, time.Time{}, 2); err != nil || n != 2 {
+		t.Fatalf("CompactExpired = %d, %v, want 2 runs", n, err)
+	}
+	compactAll(t, repo, 1, time.Time{})
+
+	compactedAt := make([]*time.Time, len(runs))
+	for i, run := range runs {
+		got, err := repo.GetByID(ctx, run.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if (got.CompactedAt == nil) != (i == len(runs)-1) {
+			t.Errorf("run %d compacted at %v, want all but the newest compacted", i, got.CompactedAt)
+		}
+		compactedAt[i] = got.CompactedAt
+	}
+	if got, err := repo.GetByID(ctx, otherRun.ID); err != nil || got.CompactedAt != nil {
+		t.Errorf("other flow's run = %+v, %v, want it retained", got, err)
+	}
+
+	// Compacted runs aren't compacted again
+	if n, err := repo.CompactExpired(ctx, 1, time.Time{}, 2); err != nil || n != 0 {
+		t.Errorf("CompactExpired = %d, %v, want no runs", n, err)
+	}
+	for i, run := range runs[:len(runs)-1] {
+		got, err := repo.GetByID(ctx, run.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.CompactedAt == nil || !got.CompactedAt.Equal(*compactedAt[i]) {
+			t.Errorf("run %d compacted at %v, want %v", i, got.CompactedAt, compactedAt[i])
+		}
+	}
+}
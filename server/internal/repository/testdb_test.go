@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// testDB connects to the database named by TEST_DATABASE_URL, which must have
+// the schema from database/init.sql. Tests that need it are skipped without one.
+func testDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+// testFlow creates a flow with the given nodes, owned by a new user. Deleting
+// the user when the test ends removes the flow and its runs.
+func testFlow(t *testing.T, db *pgxpool.Pool, nodes ...models.FlowNode) *models.Flow {
+	t.Helper()
+	ctx := context.Background()
+
+	userRepo := NewUserRepository(db)
+	user := &models.User{ID: uuid.New(), Name: "Test"}
+	user.Email = user.ID.String() + "@example.com"
+	if err := userRepo.Create(ctx, user, "password"); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := userRepo.Delete(context.Background(), user.ID); err != nil {
+			t.Errorf("delete user: %v", err)
+		}
+	})
+
+	flow := &models.Flow{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Name:      t.Name(),
+		Nodes:     nodes,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := NewFlowRepository(db).Create(ctx, flow, user.ID, ""); err != nil {
+		t.Fatalf("create flow: %v", err)
+	}
+	return flow
+}
+
+func testNode(id, label string, config map[string]interface{}) models.FlowNode {
+	return models.FlowNode{
+		ID:       id,
+		Type:     "custom",
+		Position: models.Position{X: 100, Y: 100},
+		Data:     models.NodeData{ID: id, Type: "api", Label: label, Config: config},
+	}
+}
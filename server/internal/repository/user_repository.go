@@ -6,8 +6,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"golang.org/x/crypto/bcrypt"
 	"github.com/visual-api-testing-platform/server/internal/models"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // UserRepository handles user database operations
@@ -28,21 +28,44 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User, password
 	}
 
 	user.PasswordHash = string(hashedPassword)
+	return r.insert(ctx, user, nil, nil)
+}
+
+// CreateExternal creates a user that signs in through an OIDC identity provider.
+// The account has no local password and its email is verified by the provider.
+func (r *UserRepository) CreateExternal(ctx context.Context, user *models.User, issuer, subject string) error {
+	verifiedAt := time.Now()
+	user.PasswordHash = ""
+	user.EmailVerifiedAt = &verifiedAt
+	return r.insert(ctx, user, &issuer, &subject)
+}
+
+func (r *UserRepository) insert(ctx context.Context, user *models.User, issuer, subject *string) error {
+	if user.Role == "" {
+		user.Role = models.RoleEditor
+	}
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 
 	query := `
-		INSERT INTO users (id, email, password_hash, name, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO users (
+			id, email, password_hash, name, role, email_verified_at,
+			oidc_issuer, oidc_subject, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 
-	_, err = r.db.Exec(
+	_, err := r.db.Exec(
 		ctx,
 		query,
 		user.ID,
 		user.Email,
 		user.PasswordHash,
 		user.Name,
+		user.Role,
+		user.EmailVerifiedAt,
+		issuer,
+		subject,
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
@@ -55,7 +78,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 	var user models.User
 
 	query := `
-		SELECT id, email, password_hash, name, created_at, updated_at
+		SELECT id, email, password_hash, name, role, email_verified_at, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -65,6 +88,8 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 		&user.Email,
 		&user.PasswordHash,
 		&user.Name,
+		&user.Role,
+		&user.EmailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -81,7 +106,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 	var user models.User
 
 	query := `
-		SELECT id, email, password_hash, name, created_at, updated_at
+		SELECT id, email, password_hash, name, role, email_verified_at, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -91,6 +116,36 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 		&user.Email,
 		&user.PasswordHash,
 		&user.Name,
+		&user.Role,
+		&user.EmailVerifiedAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// GetByOIDCIdentity retrieves the user linked to an identity provider subject
+func (r *UserRepository) GetByOIDCIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	var user models.User
+
+	query := `
+		SELECT id, email, password_hash, name, role, email_verified_at, created_at, updated_at
+		FROM users
+		WHERE oidc_issuer = $1 AND oidc_subject = $2
+	`
+
+	err := r.db.QueryRow(ctx, query, issuer, subject).Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.Name,
+		&user.Role,
+		&user.EmailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -102,9 +157,74 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 	return &user, nil
 }
 
+// LinkOIDCIdentity links an existing user to an identity provider subject
+func (r *UserRepository) LinkOIDCIdentity(ctx context.Context, userID uuid.UUID, issuer, subject string) error {
+	query := `UPDATE users SET oidc_issuer = $2, oidc_subject = $3 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, userID, issuer, subject)
+	return err
+}
+
+// UpdateRole updates a user's workspace role
+func (r *UserRepository) UpdateRole(ctx context.Context, userID uuid.UUID, role string) error {
+	query := `UPDATE users SET role = $2 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, userID, role)
+	return err
+}
+
+// UpdateName updates a user's display name
+func (r *UserRepository) UpdateName(ctx context.Context, userID uuid.UUID, name string) error {
+	query := `UPDATE users SET name = $2 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, userID, name)
+	return err
+}
+
+// UpdateEmail changes a user's email and marks it unverified
+func (r *UserRepository) UpdateEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	query := `UPDATE users SET email = $2, email_verified_at = NULL WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, userID, email)
+	return err
+}
+
+// UpdatePassword hashes and stores a new password
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users SET password_hash = $2 WHERE id = $1`
+	_, err = r.db.Exec(ctx, query, userID, string(hashedPassword))
+	return err
+}
+
+// MarkEmailVerified marks the user's email verified if it still matches the verified address
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, email string) (bool, error) {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND email = $2
+	`
+	tag, err := r.db.Exec(ctx, query, userID, email)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// Delete deletes a user. Flows, nodes, test runs and tokens are removed via CASCADE;
+// audit records keep their rows with the user reference cleared.
+func (r *UserRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM users WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
+
 // VerifyPassword verifies a password against the stored hash
 func (r *UserRepository) VerifyPassword(user *models.User, password string) bool {
+	// SSO-only accounts have no password to verify against
+	if user.PasswordHash == "" {
+		return false
+	}
 	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	return err == nil
 }
-
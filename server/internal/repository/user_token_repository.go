@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// UserTokenRepository handles single-use user token database operations.
+// Only SHA-256 digests of tokens are stored.
+type UserTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewUserTokenRepository creates a new user token repository
+func NewUserTokenRepository(db *pgxpool.Pool) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+// Create stores a token digest for a user
+func (r *UserTokenRepository) Create(ctx context.Context, token *models.UserToken, tokenHash string) error {
+	if token.ID == uuid.Nil {
+		token.ID = uuid.New()
+	}
+	token.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO user_tokens (id, user_id, purpose, token_hash, email, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.Exec(
+		ctx,
+		query,
+		token.ID,
+		token.UserID,
+		token.Purpose,
+		tokenHash,
+		token.Email,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+
+	return err
+}
+
+// Consume atomically marks an unused, unexpired token as used and returns it
+func (r *UserTokenRepository) Consume(ctx context.Context, tokenHash, purpose string) (*models.UserToken, error) {
+	var token models.UserToken
+
+	query := `
+		UPDATE user_tokens
+		SET used_at = $3
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+		RETURNING id, user_id, purpose, email, expires_at, used_at, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, tokenHash, purpose, time.Now()).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.Email,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// DeleteByUser removes a user's outstanding tokens for a purpose
+func (r *UserTokenRepository) DeleteByUser(ctx context.Context, userID uuid.UUID, purpose string) error {
+	query := `DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2`
+	_, err := r.db.Exec(ctx, query, userID, purpose)
+	return err
+}
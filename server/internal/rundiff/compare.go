@@ -0,0 +1,156 @@
+package rundiff
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/jsondiff"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+// Node change kinds
+const (
+	NodeAdded     = "added"     // only the second run executed the node
+	NodeRemoved   = "removed"   // only the first run executed the node
+	NodeChanged   = "changed"   // status, error or output differ
+	NodeUnchanged = "unchanged" // only the duration may differ
+)
+
+// Comparison describes how a test run differs from an earlier one
+type Comparison struct {
+	From            RunRef           `json:"from"`
+	To              RunRef           `json:"to"`
+	StatusChanged   bool             `json:"status_changed"`
+	DurationDeltaMs *int             `json:"duration_delta_ms,omitempty"`
+	Nodes           []NodeComparison `json:"nodes"`
+	Summary         Summary          `json:"summary"`
+}
+
+// RunRef identifies one side of a comparison
+type RunRef struct {
+	ID          uuid.UUID              `json:"id"`
+	FlowID      uuid.UUID              `json:"flow_id"`
+	FlowVersion int                    `json:"flow_version"`
+	Status      models.ExecutionStatus `json:"status"`
+	DurationMs  *int                   `json:"duration_ms,omitempty"`
+	StartedAt   time.Time              `json:"started_at"`
+	Compacted   bool                   `json:"compacted"` // node outputs were pruned by retention
+}
+
+// NodeComparison compares the results of one node in both runs
+type NodeComparison struct {
+	NodeID          string                 `json:"node_id"`
+	Change          string                 `json:"change"`
+	FromStatus      models.ExecutionStatus `json:"from_status,omitempty"`
+	ToStatus        models.ExecutionStatus `json:"to_status,omitempty"`
+	StatusChanged   bool                   `json:"status_changed"`
+	FromError       string                 `json:"from_error,omitempty"`
+	ToError         string                 `json:"to_error,omitempty"`
+	FromDurationMs  int                    `json:"from_duration_ms"`
+	ToDurationMs    int                    `json:"to_duration_ms"`
+	DurationDeltaMs int                    `json:"duration_delta_ms"`
+	OutputCompared  bool                   `json:"output_compared"` // false when either run was compacted
+	OutputChanges   []jsondiff.Change      `json:"output_changes"`
+}
+
+// Summary counts nodes by change kind
+type Summary struct {
+	Added         int `json:"added"`
+	Removed       int `json:"removed"`
+	Changed       int `json:"changed"`
+	Unchanged     int `json:"unchanged"`
+	StatusChanged int `json:"status_changed"`
+	OutputChanged int `json:"output_changed"`
+}
+
+// Compare compares the node results of two runs, from the earlier run to the
+// later one. Output paths matched by filter are left out of the output diff;
+// filter may be nil. Nodes are sorted by ID so the result is stable.
+func Compare(from, to *models.TestRun, filter *jsondiff.Filter) *Comparison {
+	c := &Comparison{
+		From:          refOf(from),
+		To:            refOf(to),
+		StatusChanged: from.Status != to.Status,
+		Nodes:         []NodeComparison{},
+	}
+	if from.DurationMs != nil && to.DurationMs != nil {
+		delta := *to.DurationMs - *from.DurationMs
+		c.DurationDeltaMs = &delta
+	}
+
+	compareOutputs := from.CompactedAt == nil && to.CompactedAt == nil
+
+	nodeIDs := make([]string, 0, len(from.NodeResults)+len(to.NodeResults))
+	for id := range from.NodeResults {
+		nodeIDs = append(nodeIDs, id)
+	}
+	for id := range to.NodeResults {
+		if _, ok := from.NodeResults[id]; !ok {
+			nodeIDs = append(nodeIDs, id)
+		}
+	}
+	sort.Strings(nodeIDs)
+
+	for _, id := range nodeIDs {
+		before, hadBefore := from.NodeResults[id]
+		after, hasAfter := to.NodeResults[id]
+
+		n := NodeComparison{
+			NodeID:         id,
+			FromStatus:     before.Status,
+			ToStatus:       after.Status,
+			FromError:      before.Error,
+			ToError:        after.Error,
+			FromDurationMs: before.Duration,
+			ToDurationMs:   after.Duration,
+			OutputChanges:  []jsondiff.Change{},
+		}
+
+		switch {
+		case !hadBefore:
+			n.Change = NodeAdded
+			c.Summary.Added++
+		case !hasAfter:
+			n.Change = NodeRemoved
+			c.Summary.Removed++
+		default:
+			n.StatusChanged = before.Status != after.Status
+			n.DurationDeltaMs = after.Duration - before.Duration
+			n.OutputCompared = compareOutputs
+			if compareOutputs {
+				n.OutputChanges = jsondiff.Compare(before.Output, after.Output, filter)
+			}
+
+			if n.StatusChanged || before.Error != after.Error || len(n.OutputChanges) > 0 {
+				n.Change = NodeChanged
+				c.Summary.Changed++
+			} else {
+				n.Change = NodeUnchanged
+				c.Summary.Unchanged++
+			}
+			if n.StatusChanged {
+				c.Summary.StatusChanged++
+			}
+			if len(n.OutputChanges) > 0 {
+				c.Summary.OutputChanged++
+			}
+		}
+
+		c.Nodes = append(c.Nodes, n)
+	}
+
+	return c
+}
+
+func refOf(run *models.TestRun) RunRef {
+	return RunRef{
+		ID:          run.ID,
+		FlowID:      run.FlowID,
+		FlowVersion: run.FlowVersion,
+		Status:      run.Status,
+		DurationMs:  run.DurationMs,
+		StartedAt:   run.StartedAt,
+		Compacted:   run.CompactedAt != nil,
+	}
+}
@@ -0,0 +1,126 @@
+package rundiff
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/visual-api-testing-platform/server/internal/jsondiff"
+	"github.com/visual-api-testing-platform/server/internal/models"
+)
+
+func apiResult(status models.ExecutionStatus, date, requestID string, items []interface{}, duration int) models.NodeResult {
+	return models.NodeResult{
+		Status:   status,
+		Duration: duration,
+		Output: map[string]interface{}{
+			"status":  200.0,
+			"headers": map[string]interface{}{"Date": date, "X-Request-Id": requestID},
+			"data":    map[string]interface{}{"items": items},
+		},
+	}
+}
+
+func item(id float64, updatedAt string) map[string]interface{} {
+	return map[string]interface{}{"id": id, "updatedAt": updatedAt}
+}
+
+func TestCompareFilter(t *testing.T) {
+	fromDuration, toDuration := 900, 750
+	from := &models.TestRun{
+		ID:         uuid.New(),
+		Status:     models.ExecutionStatusSuccess,
+		DurationMs: &fromDuration,
+		StartedAt:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NodeResults: map[string]models.NodeResult{
+			// Only ignored values change
+			"list": apiResult(models.ExecutionStatusSuccess, "Mon", "a", []interface{}{item(1, "t1"), item(2, "t1")}, 100),
+			// An ignored value and one that isn't
+			"get": apiResult(models.ExecutionStatusSuccess, "Mon", "b", []interface{}{item(1, "t1")}, 100),
+			// A new item is reported whole
+			"search": apiResult(models.ExecutionStatusSuccess, "Mon", "c", []interface{}{item(1, "t1")}, 50),
+			"check":  {Status: models.ExecutionStatusSuccess, Output: map[string]interface{}{"passed": true}},
+			"old":    {Status: models.ExecutionStatusSuccess},
+		},
+	}
+	to := &models.TestRun{
+		ID:         uuid.New(),
+		Status:     models.ExecutionStatusFailed,
+		DurationMs: &toDuration,
+		StartedAt:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		NodeResults: map[string]models.NodeResult{
+			"list":   apiResult(models.ExecutionStatusSuccess, "Tue", "d", []interface{}{item(1, "t2"), item(2, "t2")}, 80),
+			"get":    apiResult(models.ExecutionStatusSuccess, "Tue", "e", []interface{}{item(7, "t2")}, 100),
+			"search": apiResult(models.ExecutionStatusSuccess, "Tue", "f", []interface{}{item(1, "t2"), item(2, "t2")}, 50),
+			"check":  {Status: models.ExecutionStatusFailed, Error: "1 of 1 assertions failed", Output: map[string]interface{}{"passed": false}},
+			"new":    {Status: models.ExecutionStatusSuccess},
+		},
+	}
+
+	filter := jsondiff.NewFilter([]string{"headers.Date", "headers.X-*", "data.items[*].updatedAt"}, nil)
+	c := Compare(from, to, filter)
+
+	type summary struct {
+		id      string
+		change  string
+		changes []jsondiff.Change
+	}
+	var got []summary
+	for _, n := range c.Nodes {
+		got = append(got, summary{id: n.NodeID, change: n.Change, changes: n.OutputChanges})
+	}
+	want := []summary{
+		{id: "check", change: NodeChanged, changes: []jsondiff.Change{{Path: "passed", Kind: jsondiff.KindChanged, Old: true, New: false}}},
+		{id: "get", change: NodeChanged, changes: []jsondiff.Change{{Path: "data.items[0].id", Kind: jsondiff.KindChanged, Old: 1.0, New: 7.0}}},
+		{id: "list", change: NodeUnchanged, changes: []jsondiff.Change{}},
+		{id: "new", change: NodeAdded, changes: []jsondiff.Change{}},
+		{id: "old", change: NodeRemoved, changes: []jsondiff.Change{}},
+		{id: "search", change: NodeChanged, changes: []jsondiff.Change{
+			{Path: "data.items[1]", Kind: jsondiff.KindAdded, New: map[string]interface{}{"id": 2.0, "updatedAt": "t2"}},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("nodes =\n%+v\nwant\n%+v", got, want)
+	}
+
+	wantSummary := Summary{Added: 1, Removed: 1, Changed: 3, Unchanged: 1, StatusChanged: 1, OutputChanged: 3}
+	if c.Summary != wantSummary {
+		t.Errorf("summary = %+v, want %+v", c.Summary, wantSummary)
+	}
+	if !c.StatusChanged || c.DurationDeltaMs == nil || *c.DurationDeltaMs != -150 {
+		t.Errorf("status changed %v, duration delta %v, want true and -150", c.StatusChanged, c.DurationDeltaMs)
+	}
+	if list := c.Nodes[2]; !list.OutputCompared || list.DurationDeltaMs != -20 {
+		t.Errorf("list = %+v, want outputs compared and a -20ms delta", list)
+	}
+
+	// Without the filter every value that changed is reported
+	unfiltered := Compare(from, to, nil)
+	if changes := unfiltered.Nodes[2].OutputChanges; len(changes) != 4 {
+		t.Errorf("list changes = %+v, want the date, the request ID and both updatedAt values", changes)
+	}
+}
+
+func TestCompareCompacted(t *testing.T) {
+	compactedAt := time.Now()
+	from := &models.TestRun{NodeResults: map[string]models.NodeResult{
+		"api": {Status: models.ExecutionStatusSuccess, Output: map[string]interface{}{"status": 200.0}},
+	}}
+	to := &models.TestRun{CompactedAt: &compactedAt, NodeResults: map[string]models.NodeResult{
+		"api": {Status: models.ExecutionStatusSuccess},
+	}}
+
+	// Compacted outputs aren't compared, so pruning doesn't read as a change
+	c := Compare(from, to, nil)
+	n := c.Nodes[0]
+	if n.OutputCompared || len(n.OutputChanges) != 0 || n.Change != NodeUnchanged {
+		t.Errorf("api = %+v, want unchanged without comparing outputs", n)
+	}
+	if !c.To.Compacted || c.From.Compacted {
+		t.Errorf("compacted from %v to %v, want false and true", c.From.Compacted, c.To.Compacted)
+	}
+	if c.DurationDeltaMs != nil {
+		t.Errorf("duration delta = %v, want none without durations", *c.DurationDeltaMs)
+	}
+}
@@ -0,0 +1,177 @@
+package script
+
+// Expressions
+
+type expr interface {
+	position() Pos
+}
+
+type (
+	numberLit struct {
+		pos   Pos
+		value float64
+	}
+	stringLit struct {
+		pos   Pos
+		value string
+	}
+	boolLit struct {
+		pos   Pos
+		value bool
+	}
+	nullLit struct {
+		pos Pos
+	}
+	identifier struct {
+		pos  Pos
+		name string
+	}
+	arrayLit struct {
+		pos      Pos
+		elements []expr
+	}
+	objectLit struct {
+		pos   Pos
+		props []property
+	}
+	memberExpr struct {
+		pos      Pos
+		object   expr
+		property expr // a stringLit for obj.name
+		optional bool
+	}
+	callExpr struct {
+		pos      Pos
+		callee   expr
+		args     []expr
+		optional bool
+	}
+	unaryExpr struct {
+		pos     Pos
+		op      string
+		operand expr
+	}
+	binaryExpr struct {
+		pos         Pos
+		op          string
+		left, right expr
+	}
+	conditionalExpr struct {
+		pos                   Pos
+		test, consequent, alt expr
+	}
+	assignExpr struct {
+		pos    Pos
+		op     string
+		target expr
+		value  expr
+	}
+	updateExpr struct {
+		pos    Pos
+		op     string
+		prefix bool
+		target expr
+	}
+	funcLit struct {
+		pos    Pos
+		name   string
+		params []string
+		body   *blockStmt // nil for arrow functions with an expression body
+		expr   expr
+	}
+)
+
+type property struct {
+	key   string
+	value expr
+}
+
+func (e *numberLit) position() Pos       { return e.pos }
+func (e *stringLit) position() Pos       { return e.pos }
+func (e *boolLit) position() Pos         { return e.pos }
+func (e *nullLit) position() Pos         { return e.pos }
+func (e *identifier) position() Pos      { return e.pos }
+func (e *arrayLit) position() Pos        { return e.pos }
+func (e *objectLit) position() Pos       { return e.pos }
+func (e *memberExpr) position() Pos      { return e.pos }
+func (e *callExpr) position() Pos        { return e.pos }
+func (e *unaryExpr) position() Pos       { return e.pos }
+func (e *binaryExpr) position() Pos      { return e.pos }
+func (e *conditionalExpr) position() Pos { return e.pos }
+func (e *assignExpr) position() Pos      { return e.pos }
+func (e *updateExpr) position() Pos      { return e.pos }
+func (e *funcLit) position() Pos         { return e.pos }
+
+// Statements
+
+type stmt interface {
+	position() Pos
+}
+
+type (
+	varDecl struct {
+		pos      Pos
+		constant bool
+		name     string
+		init     expr // nil declares undefined
+	}
+	exprStmt struct {
+		pos  Pos
+		expr expr
+	}
+	blockStmt struct {
+		pos   Pos
+		stmts []stmt
+	}
+	ifStmt struct {
+		pos        Pos
+		test       expr
+		consequent stmt
+		alt        stmt
+	}
+	forOfStmt struct {
+		pos      Pos
+		constant bool
+		name     string
+		iterable expr
+		body     stmt
+	}
+	forStmt struct {
+		pos  Pos
+		init stmt // may be nil
+		test expr // may be nil
+		post expr // may be nil
+		body stmt
+	}
+	whileStmt struct {
+		pos  Pos
+		test expr
+		body stmt
+	}
+	returnStmt struct {
+		pos   Pos
+		value expr // may be nil
+	}
+	breakStmt struct {
+		pos Pos
+	}
+	continueStmt struct {
+		pos Pos
+	}
+	funcDecl struct {
+		pos Pos
+		fn  *funcLit
+	}
+)
+
+func (s *varDecl) position() Pos      { return s.pos }
+func (s *exprStmt) position() Pos     { return s.pos }
+func (s *blockStmt) position() Pos    { return s.pos }
+func (s *ifStmt) position() Pos       { return s.pos }
+func (s *forOfStmt) position() Pos    { return s.pos }
+func (s *forStmt) position() Pos      { return s.pos }
+func (s *whileStmt) position() Pos    { return s.pos }
+func (s *returnStmt) position() Pos   { return s.pos }
+func (s *breakStmt) position() Pos    { return s.pos }
+func (s *continueStmt) position() Pos { return s.pos }
+func (s *funcDecl) position() Pos     { return s.pos }
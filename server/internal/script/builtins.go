@@ -0,0 +1,666 @@
+package script
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type builtinFn = func(in *interp, pos Pos, this interface{}, args []interface{}) (interface{}, error)
+
+func newBuiltin(name string, call builtinFn) *builtin {
+	return &builtin{name: name, call: call}
+}
+
+func arg(args []interface{}, i int) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	return undefined
+}
+
+// stringArg converts argument i to a string, charging arrays for the string
+// they are joined into
+func (in *interp) stringArg(pos Pos, args []interface{}, i int) (string, error) {
+	return in.toString(pos, arg(args, i))
+}
+
+// Failure is a failed assert() or fail() call
+type Failure struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// globals returns the builtin global scope
+func globals() map[string]interface{} {
+	return map[string]interface{}{
+		"undefined": undefined,
+		"NaN":       math.NaN(),
+		"Infinity":  math.Inf(1),
+		"Math":      mathObject(),
+		"Object":    objectObject(),
+		"Array":     arrayObject(),
+		"JSON":      jsonObject(),
+
+		"assert": newBuiltin("assert", func(in *interp, pos Pos, _ interface{}, args []interface{}) (interface{}, error) {
+			ok := truthy(arg(args, 0))
+			if !ok {
+				message := "assertion failed"
+				if len(args) > 1 {
+					var err error
+					if message, err = in.stringArg(pos, args, 1); err != nil {
+						return nil, err
+					}
+				}
+				in.failures = append(in.failures, Failure{Line: pos.Line, Column: pos.Column, Message: message})
+			}
+			return ok, nil
+		}),
+		"fail": newBuiltin("fail", func(in *interp, pos Pos, _ interface{}, args []interface{}) (interface{}, error) {
+			message := "failed"
+			if len(args) > 0 {
+				var err error
+				if message, err = in.stringArg(pos, args, 0); err != nil {
+					return nil, err
+				}
+			}
+			in.failures = append(in.failures, Failure{Line: pos.Line, Column: pos.Column, Message: message})
+			return false, nil
+		}),
+		"matches": newBuiltin("matches", func(in *interp, pos Pos, _ interface{}, args []interface{}) (interface{}, error) {
+			pattern, err := in.stringArg(pos, args, 1)
+			if err != nil {
+				return nil, err
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, in.errorf(pos, "invalid pattern: %v", err)
+			}
+			s, err := in.stringArg(pos, args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return re.MatchString(s), nil
+		}),
+		"Number": newBuiltin("Number", func(_ *interp, _ Pos, _ interface{}, args []interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return 0.0, nil
+			}
+			return toNumber(args[0]), nil
+		}),
+		"String": newBuiltin("String", func(in *interp, pos Pos, _ interface{}, args []interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return "", nil
+			}
+			return in.stringArg(pos, args, 0)
+		}),
+		"Boolean": newBuiltin("Boolean", func(_ *interp, _ Pos, _ interface{}, args []interface{}) (interface{}, error) {
+			return truthy(arg(args, 0)), nil
+		}),
+		"isNaN": newBuiltin("isNaN", func(_ *interp, _ Pos, _ interface{}, args []interface{}) (interface{}, error) {
+			return math.IsNaN(toNumber(arg(args, 0))), nil
+		}),
+		"parseInt": newBuiltin("parseInt", func(in *interp, pos Pos, _ interface{}, args []interface{}) (interface{}, error) {
+			s, err := in.stringArg(pos, args, 0)
+			return parseLeadingNumber(s, true), err
+		}),
+		"parseFloat": newBuiltin("parseFloat", func(in *interp, pos Pos, _ interface{}, args []interface{}) (interface{}, error) {
+			s, err := in.stringArg(pos, args, 0)
+			return parseLeadingNumber(s, false), err
+		}),
+	}
+}
+
+// parseLeadingNumber parses the longest numeric prefix of s, like parseInt and parseFloat
+func parseLeadingNumber(s string, integer bool) float64 {
+	s = strings.TrimSpace(s)
+	end := 0
+	if end < len(s) && (s[end] == '-' || s[end] == '+') {
+		end++
+	}
+	digits := 0
+	for end < len(s) && isDigit(s[end]) {
+		end++
+		digits++
+	}
+	if !integer && end < len(s) && s[end] == '.' {
+		end++
+		for end < len(s) && isDigit(s[end]) {
+			end++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return math.NaN()
+	}
+	n, err := strconv.ParseFloat(strings.TrimSuffix(s[:end], "."), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return n
+}
+
+func mathObject() *object {
+	o := newObject()
+	unary := map[string]func(float64) float64{
+		"abs": math.Abs, "floor": math.Floor, "ceil": math.Ceil, "sqrt": math.Sqrt,
+		"trunc": math.Trunc, "log": math.Log,
+		"round": func(x float64) float64 { return math.Floor(x + 0.5) },
+	}
+	for name, fn := range unary {
+		fn := fn
+		o.set(name, newBuiltin("Math."+name, func(_ *interp, _ Pos, _ interface{}, args []interface{}) (interface{}, error) {
+			return fn(toNumber(arg(args, 0))), nil
+		}))
+	}
+	o.set("min", newBuiltin("Math.min", func(_ *interp, _ Pos, _ interface{}, args []interface{}) (interface{}, error) {
+		result := math.Inf(1)
+		for _, a := range args {
+			result = math.Min(result, toNumber(a))
+		}
+		return result, nil
+	}))
+	o.set("max", newBuiltin("Math.max", func(_ *interp, _ Pos, _ interface{}, args []interface{}) (interface{}, error) {
+		result := math.Inf(-1)
+		for _, a := range args {
+			result = math.Max(result, toNumber(a))
+		}
+		return result, nil
+	}))
+	o.set("pow", newBuiltin("Math.pow", func(_ *interp, _ Pos, _ interface{}, args []interface{}) (interface{}, error) {
+		return math.Pow(toNumber(arg(args, 0)), toNumber(arg(args, 1))), nil
+	}))
+	o.set("PI", math.Pi)
+	return o
+}
+
+func objectObject() *object {
+	o := newObject()
+	entries := func(name string, pick func(in *interp, key string, value interface{}) interface{}) {
+		o.set(name, newBuiltin("Object."+name, func(in *interp, pos Pos, _ interface{}, args []interface{}) (interface{}, error) {
+			obj, ok := arg(args, 0).(*object)
+			if !ok {
+				return &array{}, nil
+			}
+			result := &array{items: make([]interface{}, 0, len(obj.keys))}
+			for _, key := range obj.keys {
+				item := pick(in, key, obj.props[key])
+				if err := in.allocAt(pos, itemSize(item)); err != nil {
+					return nil, err
+				}
+				result.items = append(result.items, item)
+			}
+			return result, in.allocAt(pos, 24)
+		}))
+	}
+	entries("keys", func(_ *interp, key string, _ interface{}) interface{} { return key })
+	entries("values", func(_ *interp, _ string, value interface{}) interface{} { return value })
+	entries("entries", func(_ *interp, key string, value interface{}) interface{} {
+		return &array{items: []interface{}{key, value}}
+	})
+	return o
+}
+
+func arrayObject() *object {
+	o := newObject()
+	o.set("isArray", newBuiltin("Array.isArray", func(_ *interp, _ Pos, _ interface{}, args []interface{}) (interface{}, error) {
+		_, ok := arg(args, 0).(*array)
+		return ok, nil
+	}))
+	return o
+}
+
+func jsonObject() *object {
+	o := newObject()
+	o.set("stringify", newBuiltin("JSON.stringify", func(in *interp, pos Pos, _ interface{}, args []interface{}) (interface{}, error) {
+		value := arg(args, 0)
+		if value == undefined || isCallable(value) {
+			return undefined, nil
+		}
+		indent := 0
+		if n := toNumber(arg(args, 2)); n > 0 {
+			indent = int(math.Min(n, 10))
+		}
+		m := in.measure()
+		m.json(value, indent, 0)
+		if m.circular {
+			return nil, in.errorf(pos, "JSON.stringify: value contains itself")
+		}
+		// The Go values built by toGo take about as much memory as the text
+		if err := in.allocAt(pos, 2*m.n); err != nil {
+			return nil, err
+		}
+		var data []byte
+		var err error
+		if indent > 0 {
+			data, err = json.MarshalIndent(toGo(value), "", strings.Repeat(" ", indent))
+		} else {
+			data, err = json.Marshal(toGo(value))
+		}
+		if err != nil {
+			return nil, in.errorf(pos, "JSON.stringify: %v", err)
+		}
+		return string(data), nil
+	}))
+	o.set("parse", newBuiltin("JSON.parse", func(in *interp, pos Pos, _ interface{}, args []interface{}) (interface{}, error) {
+		s, err := in.stringArg(pos, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := in.allocAt(pos, 2*len(s)); err != nil {
+			return nil, err
+		}
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+			return nil, in.errorf(pos, "JSON.parse: %v", err)
+		}
+		return convertDecoded(decoded), nil
+	}))
+	return o
+}
+
+// Methods of strings
+
+var stringMethods map[string]*builtin
+
+// Methods of arrays
+
+var arrayMethods map[string]*builtin
+
+func init() {
+	str := func(name string, fn func(in *interp, pos Pos, s string, args []interface{}) (interface{}, error)) {
+		stringMethods[name] = newBuiltin(name, func(in *interp, pos Pos, this interface{}, args []interface{}) (interface{}, error) {
+			return fn(in, pos, this.(string), args)
+		})
+	}
+	stringMethods = make(map[string]*builtin)
+
+	str("includes", func(in *interp, pos Pos, s string, args []interface{}) (interface{}, error) {
+		sub, err := in.stringArg(pos, args, 0)
+		return strings.Contains(s, sub), err
+	})
+	str("startsWith", func(in *interp, pos Pos, s string, args []interface{}) (interface{}, error) {
+		prefix, err := in.stringArg(pos, args, 0)
+		return strings.HasPrefix(s, prefix), err
+	})
+	str("endsWith", func(in *interp, pos Pos, s string, args []interface{}) (interface{}, error) {
+		suffix, err := in.stringArg(pos, args, 0)
+		return strings.HasSuffix(s, suffix), err
+	})
+	str("indexOf", func(in *interp, pos Pos, s string, args []interface{}) (interface{}, error) {
+		sub, err := in.stringArg(pos, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		i := strings.Index(s, sub)
+		if i < 0 {
+			return -1.0, nil
+		}
+		return float64(len([]rune(s[:i]))), nil
+	})
+	str("toLowerCase", func(in *interp, pos Pos, s string, _ []interface{}) (interface{}, error) {
+		if err := in.allocAt(pos, len(s)); err != nil {
+			return nil, err
+		}
+		return strings.ToLower(s), nil
+	})
+	str("toUpperCase", func(in *interp, pos Pos, s string, _ []interface{}) (interface{}, error) {
+		if err := in.allocAt(pos, len(s)); err != nil {
+			return nil, err
+		}
+		return strings.ToUpper(s), nil
+	})
+	str("trim", func(_ *interp, _ Pos, s string, _ []interface{}) (interface{}, error) {
+		return strings.TrimFunc(s, unicode.IsSpace), nil
+	})
+	str("split", func(in *interp, pos Pos, s string, args []interface{}) (interface{}, error) {
+		if arg(args, 0) == undefined {
+			return &array{items: []interface{}{s}}, in.allocAt(pos, 24+itemSize(s))
+		}
+		sep, err := in.stringArg(pos, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		// The parts share s, but are charged like any other strings in an array
+		count := utf8.RuneCountInString(s)
+		if sep != "" {
+			count = strings.Count(s, sep) + 1
+		}
+		if err := in.allocAt(pos, 24+16*count+len(s)-len(sep)*(count-1)); err != nil {
+			return nil, err
+		}
+		parts := strings.Split(s, sep)
+		result := &array{items: make([]interface{}, len(parts))}
+		for i, part := range parts {
+			result.items[i] = part
+		}
+		return result, nil
+	})
+	str("slice", func(_ *interp, _ Pos, s string, args []interface{}) (interface{}, error) {
+		runes := []rune(s)
+		start, end := sliceBounds(len(runes), args, true)
+		return string(runes[start:end]), nil
+	})
+	str("substring", func(_ *interp, _ Pos, s string, args []interface{}) (interface{}, error) {
+		runes := []rune(s)
+		start, end := sliceBounds(len(runes), args, false)
+		return string(runes[start:end]), nil
+	})
+	replace := func(in *interp, pos Pos, s string, args []interface{}, n int) (interface{}, error) {
+		old, err := in.stringArg(pos, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		replacement, err := in.stringArg(pos, args, 1)
+		if err != nil {
+			return nil, err
+		}
+		// An empty old matches before every rune and at the end
+		count := strings.Count(s, old)
+		if n >= 0 && count > n {
+			count = n
+		}
+		if err := in.allocAt(pos, len(s)+count*(len(replacement)-len(old))); err != nil {
+			return nil, err
+		}
+		return strings.Replace(s, old, replacement, n), nil
+	}
+	str("replace", func(in *interp, pos Pos, s string, args []interface{}) (interface{}, error) {
+		return replace(in, pos, s, args, 1)
+	})
+	str("replaceAll", func(in *interp, pos Pos, s string, args []interface{}) (interface{}, error) {
+		return replace(in, pos, s, args, -1)
+	})
+
+	arr := func(name string, fn func(in *interp, pos Pos, a *array, args []interface{}) (interface{}, error)) {
+		arrayMethods[name] = newBuiltin(name, func(in *interp, pos Pos, this interface{}, args []interface{}) (interface{}, error) {
+			return fn(in, pos, this.(*array), args)
+		})
+	}
+	arrayMethods = make(map[string]*builtin)
+
+	arr("includes", func(_ *interp, _ Pos, a *array, args []interface{}) (interface{}, error) {
+		return indexOf(a, arg(args, 0)) >= 0, nil
+	})
+	arr("indexOf", func(_ *interp, _ Pos, a *array, args []interface{}) (interface{}, error) {
+		return float64(indexOf(a, arg(args, 0))), nil
+	})
+	arr("join", func(in *interp, pos Pos, a *array, args []interface{}) (interface{}, error) {
+		sep := ","
+		if arg(args, 0) != undefined {
+			var err error
+			if sep, err = in.stringArg(pos, args, 0); err != nil {
+				return nil, err
+			}
+		}
+		m := in.measure()
+		m.join(a, len(sep))
+		if m.circular {
+			return nil, in.errorf(pos, "cannot join an array that contains itself")
+		}
+		if err := in.allocAt(pos, m.n); err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(a.items))
+		for i, item := range a.items {
+			if !isNullish(item) {
+				parts[i] = toString(item)
+			}
+		}
+		return strings.Join(parts, sep), nil
+	})
+	arr("slice", func(in *interp, pos Pos, a *array, args []interface{}) (interface{}, error) {
+		start, end := sliceBounds(len(a.items), args, true)
+		if err := in.allocAt(pos, 24+itemsSize(a.items[start:end])); err != nil {
+			return nil, err
+		}
+		return &array{items: append([]interface{}(nil), a.items[start:end]...)}, nil
+	})
+	arr("concat", func(in *interp, pos Pos, a *array, args []interface{}) (interface{}, error) {
+		size := 24 + itemsSize(a.items)
+		for _, other := range args {
+			if o, ok := other.(*array); ok {
+				size += itemsSize(o.items)
+			} else {
+				size += itemSize(other)
+			}
+		}
+		if err := in.allocAt(pos, size); err != nil {
+			return nil, err
+		}
+		result := &array{items: append([]interface{}(nil), a.items...)}
+		for _, other := range args {
+			if o, ok := other.(*array); ok {
+				result.items = append(result.items, o.items...)
+			} else {
+				result.items = append(result.items, other)
+			}
+		}
+		return result, nil
+	})
+	arr("push", func(in *interp, pos Pos, a *array, args []interface{}) (interface{}, error) {
+		if err := in.allocAt(pos, itemsSize(args)); err != nil {
+			return nil, err
+		}
+		a.items = append(a.items, args...)
+		return float64(len(a.items)), nil
+	})
+	arr("reverse", func(_ *interp, _ Pos, a *array, _ []interface{}) (interface{}, error) {
+		for i, j := 0, len(a.items)-1; i < j; i, j = i+1, j-1 {
+			a.items[i], a.items[j] = a.items[j], a.items[i]
+		}
+		return a, nil
+	})
+	arr("sort", func(in *interp, pos Pos, a *array, args []interface{}) (interface{}, error) {
+		compare := arg(args, 0)
+		var sortErr error
+		sort.SliceStable(a.items, func(i, j int) bool {
+			if sortErr != nil {
+				return false
+			}
+			if compare == undefined {
+				x, err := in.toString(pos, a.items[i])
+				if err == nil {
+					var y string
+					y, err = in.toString(pos, a.items[j])
+					if err == nil {
+						return x < y
+					}
+				}
+				sortErr = err
+				return false
+			}
+			result, err := in.call(pos, compare, []interface{}{a.items[i], a.items[j]})
+			if err != nil {
+				sortErr = err
+				return false
+			}
+			return toNumber(result) < 0
+		})
+		return a, sortErr
+	})
+
+	// Iteration methods call fn(item, index) for each element
+	iterate := func(name string, fn func(in *interp, pos Pos, a *array, callback interface{}) (interface{}, error)) {
+		arr(name, func(in *interp, pos Pos, a *array, args []interface{}) (interface{}, error) {
+			callback := arg(args, 0)
+			if !isCallable(callback) {
+				return nil, in.errorf(pos, "%s expects a function", name)
+			}
+			return fn(in, pos, a, callback)
+		})
+	}
+	each := func(in *interp, pos Pos, a *array, callback interface{}, visit func(i int, item, result interface{}) bool) error {
+		for i := 0; i < len(a.items); i++ {
+			item := a.items[i]
+			result, err := in.call(pos, callback, []interface{}{item, float64(i)})
+			if err != nil {
+				return err
+			}
+			if !visit(i, item, result) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	iterate("map", func(in *interp, pos Pos, a *array, callback interface{}) (interface{}, error) {
+		if err := in.allocAt(pos, 24); err != nil {
+			return nil, err
+		}
+		result := &array{}
+		var allocErr error
+		err := each(in, pos, a, callback, func(_ int, _, r interface{}) bool {
+			if allocErr = in.allocAt(pos, itemSize(r)); allocErr != nil {
+				return false
+			}
+			result.items = append(result.items, r)
+			return true
+		})
+		if err == nil {
+			err = allocErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	})
+	iterate("filter", func(in *interp, pos Pos, a *array, callback interface{}) (interface{}, error) {
+		if err := in.allocAt(pos, 24); err != nil {
+			return nil, err
+		}
+		result := &array{}
+		var allocErr error
+		err := each(in, pos, a, callback, func(_ int, item, r interface{}) bool {
+			if !truthy(r) {
+				return true
+			}
+			if allocErr = in.allocAt(pos, itemSize(item)); allocErr != nil {
+				return false
+			}
+			result.items = append(result.items, item)
+			return true
+		})
+		if err == nil {
+			err = allocErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	})
+	iterate("forEach", func(in *interp, pos Pos, a *array, callback interface{}) (interface{}, error) {
+		return undefined, each(in, pos, a, callback, func(int, interface{}, interface{}) bool { return true })
+	})
+	iterate("some", func(in *interp, pos Pos, a *array, callback interface{}) (interface{}, error) {
+		found := false
+		err := each(in, pos, a, callback, func(_ int, _, r interface{}) bool {
+			found = truthy(r)
+			return !found
+		})
+		return found, err
+	})
+	iterate("every", func(in *interp, pos Pos, a *array, callback interface{}) (interface{}, error) {
+		all := true
+		err := each(in, pos, a, callback, func(_ int, _, r interface{}) bool {
+			all = truthy(r)
+			return all
+		})
+		return all, err
+	})
+	iterate("find", func(in *interp, pos Pos, a *array, callback interface{}) (interface{}, error) {
+		var found interface{} = undefined
+		err := each(in, pos, a, callback, func(_ int, item, r interface{}) bool {
+			if truthy(r) {
+				found = item
+				return false
+			}
+			return true
+		})
+		return found, err
+	})
+	iterate("findIndex", func(in *interp, pos Pos, a *array, callback interface{}) (interface{}, error) {
+		found := -1
+		err := each(in, pos, a, callback, func(i int, _, r interface{}) bool {
+			if truthy(r) {
+				found = i
+				return false
+			}
+			return true
+		})
+		return float64(found), err
+	})
+
+	arr("reduce", func(in *interp, pos Pos, a *array, args []interface{}) (interface{}, error) {
+		callback := arg(args, 0)
+		if !isCallable(callback) {
+			return nil, in.errorf(pos, "reduce expects a function")
+		}
+		items := a.items
+		var acc interface{}
+		if len(args) > 1 {
+			acc = args[1]
+		} else {
+			if len(items) == 0 {
+				return nil, in.errorf(pos, "reduce of empty array with no initial value")
+			}
+			acc, items = items[0], items[1:]
+		}
+		for i, item := range items {
+			var err error
+			if acc, err = in.call(pos, callback, []interface{}{acc, item, float64(i)}); err != nil {
+				return nil, err
+			}
+		}
+		return acc, nil
+	})
+}
+
+func indexOf(a *array, value interface{}) int {
+	for i, item := range a.items {
+		if strictEqual(item, value) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sliceBounds resolves start and end arguments against length n. Negative
+// indexes count from the end for slice but clamp to zero for substring.
+func sliceBounds(n int, args []interface{}, negative bool) (int, int) {
+	resolve := func(v interface{}, fallback int) int {
+		if v == undefined {
+			return fallback
+		}
+		f := toNumber(v)
+		if math.IsNaN(f) {
+			f = 0
+		}
+		i := int(math.Trunc(math.Max(math.Min(f, float64(n)), -float64(n)-1)))
+		if i < 0 {
+			if negative {
+				i += n
+			}
+			if i < 0 {
+				i = 0
+			}
+		}
+		return i
+	}
+	start := resolve(arg(args, 0), 0)
+	end := resolve(arg(args, 1), n)
+	if end < start {
+		if negative {
+			end = start
+		} else {
+			start, end = end, start
+		}
+	}
+	return start, end
+}
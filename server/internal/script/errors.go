@@ -0,0 +1,21 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	errUnterminatedString = errors.New("unterminated string")
+	errInvalidEscape      = errors.New("invalid escape sequence")
+)
+
+// Error is a syntax or runtime error at a position in the script
+type Error struct {
+	Pos
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
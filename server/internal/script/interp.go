@@ -0,0 +1,821 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Default limits applied when a Limits field is zero
+const (
+	DefaultMaxSteps  = 1_000_000
+	DefaultMaxMemory = 8 << 20
+	DefaultMaxDepth  = 64
+)
+
+// How many steps pass between checks of the context
+const contextCheckInterval = 1024
+
+// Limits bounds the work a script may do. Wall-clock time is bounded by the
+// context passed to Run.
+type Limits struct {
+	MaxSteps  int // evaluated expressions and statements
+	MaxMemory int // approximate bytes allocated for strings, arrays and objects
+	MaxDepth  int // nested function calls
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxSteps <= 0 {
+		l.MaxSteps = DefaultMaxSteps
+	}
+	if l.MaxMemory <= 0 {
+		l.MaxMemory = DefaultMaxMemory
+	}
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = DefaultMaxDepth
+	}
+	return l
+}
+
+type binding struct {
+	value    interface{}
+	constant bool
+}
+
+// env is a lexical scope
+type env struct {
+	vars   map[string]*binding
+	parent *env
+}
+
+func newEnv(parent *env) *env {
+	return &env{vars: make(map[string]*binding), parent: parent}
+}
+
+func (e *env) lookup(name string) *binding {
+	for scope := e; scope != nil; scope = scope.parent {
+		if b, ok := scope.vars[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// control reports how a statement completed
+type control int
+
+const (
+	ctlNormal control = iota
+	ctlReturn
+	ctlBreak
+	ctlContinue
+)
+
+type interp struct {
+	ctx      context.Context
+	limits   Limits
+	steps    int
+	memory   int
+	depth    int
+	failures []Failure
+}
+
+func (in *interp) errorf(pos Pos, format string, args ...interface{}) error {
+	return &Error{Pos: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// step counts one unit of work and enforces the step limit and the context
+func (in *interp) step(pos Pos) error {
+	in.steps++
+	if in.steps > in.limits.MaxSteps {
+		return in.errorf(pos, "step limit of %d exceeded", in.limits.MaxSteps)
+	}
+	if in.steps%contextCheckInterval == 0 {
+		if err := in.ctx.Err(); err != nil {
+			if err == context.DeadlineExceeded {
+				return in.errorf(pos, "script timed out")
+			}
+			return in.errorf(pos, "script cancelled")
+		}
+	}
+	return nil
+}
+
+// alloc accounts for n bytes of memory and enforces the memory limit
+func (in *interp) alloc(n int) error {
+	in.memory += n
+	if in.memory > in.limits.MaxMemory {
+		return fmt.Errorf("memory limit of %d bytes exceeded", in.limits.MaxMemory)
+	}
+	return nil
+}
+
+func (in *interp) allocAt(pos Pos, n int) error {
+	if err := in.alloc(n); err != nil {
+		return &Error{Pos: pos, Message: err.Error()}
+	}
+	return nil
+}
+
+// measure starts measuring a conversion against the memory that is left
+func (in *interp) measure() *measure {
+	return newMeasure(in.limits.MaxMemory - in.memory)
+}
+
+// toString is toString that charges arrays for the string they are joined
+// into before building it
+func (in *interp) toString(pos Pos, v interface{}) (string, error) {
+	a, ok := v.(*array)
+	if !ok {
+		return toString(v), nil
+	}
+	m := in.measure()
+	m.str(a)
+	if m.circular {
+		return "", in.errorf(pos, "cannot convert an array that contains itself to a string")
+	}
+	if err := in.allocAt(pos, m.n); err != nil {
+		return "", err
+	}
+	return toString(a), nil
+}
+
+// Statements
+
+func (in *interp) execBlock(stmts []stmt, scope *env) (control, interface{}, error) {
+	// Function declarations are hoisted to the top of their block
+	for _, s := range stmts {
+		if decl, ok := s.(*funcDecl); ok {
+			scope.vars[decl.fn.name] = &binding{value: &closure{fn: decl.fn, env: scope}}
+		}
+	}
+	for _, s := range stmts {
+		ctl, value, err := in.exec(s, scope)
+		if err != nil || ctl != ctlNormal {
+			return ctl, value, err
+		}
+	}
+	return ctlNormal, nil, nil
+}
+
+func (in *interp) exec(s stmt, scope *env) (control, interface{}, error) {
+	if err := in.step(s.position()); err != nil {
+		return ctlNormal, nil, err
+	}
+
+	switch s := s.(type) {
+	case *exprStmt:
+		_, err := in.eval(s.expr, scope)
+		return ctlNormal, nil, err
+
+	case *varDecl:
+		var value interface{} = undefined
+		if s.init != nil {
+			v, err := in.eval(s.init, scope)
+			if err != nil {
+				return ctlNormal, nil, err
+			}
+			value = v
+		}
+		if _, exists := scope.vars[s.name]; exists {
+			return ctlNormal, nil, in.errorf(s.pos, "%q is already declared", s.name)
+		}
+		scope.vars[s.name] = &binding{value: value, constant: s.constant}
+		return ctlNormal, nil, nil
+
+	case *funcDecl:
+		// Bound when the enclosing block was entered
+		return ctlNormal, nil, nil
+
+	case *blockStmt:
+		return in.execBlock(s.stmts, newEnv(scope))
+
+	case *ifStmt:
+		test, err := in.eval(s.test, scope)
+		if err != nil {
+			return ctlNormal, nil, err
+		}
+		if truthy(test) {
+			return in.exec(s.consequent, scope)
+		}
+		if s.alt != nil {
+			return in.exec(s.alt, scope)
+		}
+		return ctlNormal, nil, nil
+
+	case *whileStmt:
+		for {
+			test, err := in.eval(s.test, scope)
+			if err != nil {
+				return ctlNormal, nil, err
+			}
+			if !truthy(test) {
+				return ctlNormal, nil, nil
+			}
+			ctl, value, err := in.exec(s.body, scope)
+			if err != nil || ctl == ctlReturn {
+				return ctl, value, err
+			}
+			if ctl == ctlBreak {
+				return ctlNormal, nil, nil
+			}
+		}
+
+	case *forStmt:
+		loopScope := newEnv(scope)
+		if s.init != nil {
+			if _, _, err := in.exec(s.init, loopScope); err != nil {
+				return ctlNormal, nil, err
+			}
+		}
+		for {
+			if s.test != nil {
+				test, err := in.eval(s.test, loopScope)
+				if err != nil {
+					return ctlNormal, nil, err
+				}
+				if !truthy(test) {
+					return ctlNormal, nil, nil
+				}
+			}
+			ctl, value, err := in.exec(s.body, loopScope)
+			if err != nil || ctl == ctlReturn {
+				return ctl, value, err
+			}
+			if ctl == ctlBreak {
+				return ctlNormal, nil, nil
+			}
+			if s.post != nil {
+				if _, err := in.eval(s.post, loopScope); err != nil {
+					return ctlNormal, nil, err
+				}
+			}
+		}
+
+	case *forOfStmt:
+		iterable, err := in.eval(s.iterable, scope)
+		if err != nil {
+			return ctlNormal, nil, err
+		}
+		var items []interface{}
+		switch x := iterable.(type) {
+		case *array:
+			// Iterate over a snapshot so that pushing in the body terminates
+			items = append([]interface{}(nil), x.items...)
+		case string:
+			for _, r := range x {
+				items = append(items, string(r))
+			}
+		default:
+			return ctlNormal, nil, in.errorf(s.iterable.position(), "%s is not iterable", typeOf(iterable))
+		}
+		for _, item := range items {
+			iterScope := newEnv(scope)
+			iterScope.vars[s.name] = &binding{value: item, constant: s.constant}
+			ctl, value, err := in.exec(s.body, iterScope)
+			if err != nil || ctl == ctlReturn {
+				return ctl, value, err
+			}
+			if ctl == ctlBreak {
+				break
+			}
+		}
+		return ctlNormal, nil, nil
+
+	case *returnStmt:
+		var value interface{} = undefined
+		if s.value != nil {
+			v, err := in.eval(s.value, scope)
+			if err != nil {
+				return ctlNormal, nil, err
+			}
+			value = v
+		}
+		return ctlReturn, value, nil
+
+	case *breakStmt:
+		return ctlBreak, nil, nil
+
+	case *continueStmt:
+		return ctlContinue, nil, nil
+	}
+
+	return ctlNormal, nil, in.errorf(s.position(), "unsupported statement")
+}
+
+// Expressions
+
+func (in *interp) eval(e expr, scope *env) (interface{}, error) {
+	value, _, err := in.evalChain(e, scope)
+	return value, err
+}
+
+// evalChain evaluates e and reports whether an optional chain short-circuited,
+// so that a?.b.c is undefined rather than an error when a is null
+func (in *interp) evalChain(e expr, scope *env) (interface{}, bool, error) {
+	if err := in.step(e.position()); err != nil {
+		return nil, false, err
+	}
+
+	switch e := e.(type) {
+	case *numberLit:
+		return e.value, false, nil
+	case *stringLit:
+		return e.value, false, nil
+	case *boolLit:
+		return e.value, false, nil
+	case *nullLit:
+		return nil, false, nil
+
+	case *identifier:
+		b := scope.lookup(e.name)
+		if b == nil {
+			return nil, false, in.errorf(e.pos, "%s is not defined", e.name)
+		}
+		return b.value, false, nil
+
+	case *arrayLit:
+		if err := in.allocAt(e.pos, 24); err != nil {
+			return nil, false, err
+		}
+		arr := &array{items: make([]interface{}, 0, len(e.elements))}
+		for _, el := range e.elements {
+			v, err := in.eval(el, scope)
+			if err != nil {
+				return nil, false, err
+			}
+			if err := in.allocAt(e.pos, itemSize(v)); err != nil {
+				return nil, false, err
+			}
+			arr.items = append(arr.items, v)
+		}
+		return arr, false, nil
+
+	case *objectLit:
+		obj := newObject()
+		for _, prop := range e.props {
+			v, err := in.eval(prop.value, scope)
+			if err != nil {
+				return nil, false, err
+			}
+			if err := in.allocAt(e.pos, 32+len(prop.key)+itemSize(v)); err != nil {
+				return nil, false, err
+			}
+			obj.set(prop.key, v)
+		}
+		return obj, false, nil
+
+	case *funcLit:
+		return &closure{fn: e, env: scope}, false, nil
+
+	case *memberExpr:
+		receiver, short, err := in.evalChain(e.object, scope)
+		if err != nil || short {
+			return undefined, short, err
+		}
+		if e.optional && isNullish(receiver) {
+			return undefined, true, nil
+		}
+		key, err := in.eval(e.property, scope)
+		if err != nil {
+			return nil, false, err
+		}
+		v, err := in.getMember(e.pos, receiver, key)
+		return v, false, err
+
+	case *callExpr:
+		return in.evalCall(e, scope)
+
+	case *unaryExpr:
+		operand, err := in.eval(e.operand, scope)
+		if err != nil {
+			// typeof of an undeclared name is "undefined", as in JavaScript
+			if id, ok := e.operand.(*identifier); ok && e.op == "typeof" && scope.lookup(id.name) == nil {
+				return "undefined", false, nil
+			}
+			return nil, false, err
+		}
+		switch e.op {
+		case "!":
+			return !truthy(operand), false, nil
+		case "-":
+			return -toNumber(operand), false, nil
+		case "+":
+			return toNumber(operand), false, nil
+		case "typeof":
+			return typeOf(operand), false, nil
+		}
+		return nil, false, in.errorf(e.pos, "unknown operator %s", e.op)
+
+	case *binaryExpr:
+		v, err := in.evalBinary(e, scope)
+		return v, false, err
+
+	case *conditionalExpr:
+		test, err := in.eval(e.test, scope)
+		if err != nil {
+			return nil, false, err
+		}
+		if truthy(test) {
+			v, err := in.eval(e.consequent, scope)
+			return v, false, err
+		}
+		v, err := in.eval(e.alt, scope)
+		return v, false, err
+
+	case *assignExpr:
+		v, err := in.evalAssign(e, scope)
+		return v, false, err
+
+	case *updateExpr:
+		old, err := in.eval(e.target, scope)
+		if err != nil {
+			return nil, false, err
+		}
+		n := toNumber(old)
+		updated := n + 1
+		if e.op == "--" {
+			updated = n - 1
+		}
+		if err := in.assign(e.target, updated, scope); err != nil {
+			return nil, false, err
+		}
+		if e.prefix {
+			return updated, false, nil
+		}
+		return n, false, nil
+	}
+
+	return nil, false, in.errorf(e.position(), "unsupported expression")
+}
+
+func (in *interp) evalBinary(e *binaryExpr, scope *env) (interface{}, error) {
+	left, err := in.eval(e.left, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuiting operators
+	switch e.op {
+	case "&&":
+		if !truthy(left) {
+			return left, nil
+		}
+		return in.eval(e.right, scope)
+	case "||":
+		if truthy(left) {
+			return left, nil
+		}
+		return in.eval(e.right, scope)
+	case "??":
+		if !isNullish(left) {
+			return left, nil
+		}
+		return in.eval(e.right, scope)
+	}
+
+	right, err := in.eval(e.right, scope)
+	if err != nil {
+		return nil, err
+	}
+	return in.binaryOp(e.pos, e.op, left, right)
+}
+
+func (in *interp) binaryOp(pos Pos, op string, left, right interface{}) (interface{}, error) {
+	switch op {
+	case "===":
+		return strictEqual(left, right), nil
+	case "!==":
+		return !strictEqual(left, right), nil
+	case "==":
+		return looseEqual(left, right), nil
+	case "!=":
+		return !looseEqual(left, right), nil
+	case "+":
+		ls, lok := left.(string)
+		rs, rok := right.(string)
+		if lok || rok || isObjectLike(left) || isObjectLike(right) {
+			var err error
+			if !lok {
+				if ls, err = in.toString(pos, left); err != nil {
+					return nil, err
+				}
+			}
+			if !rok {
+				if rs, err = in.toString(pos, right); err != nil {
+					return nil, err
+				}
+			}
+			if err := in.allocAt(pos, len(ls)+len(rs)); err != nil {
+				return nil, err
+			}
+			return ls + rs, nil
+		}
+		return toNumber(left) + toNumber(right), nil
+	case "-":
+		return toNumber(left) - toNumber(right), nil
+	case "*":
+		return toNumber(left) * toNumber(right), nil
+	case "/":
+		return toNumber(left) / toNumber(right), nil
+	case "%":
+		return math.Mod(toNumber(left), toNumber(right)), nil
+	case "<", "<=", ">", ">=":
+		if ls, ok := left.(string); ok {
+			if rs, ok := right.(string); ok {
+				return compareResult(op, stringCompare(ls, rs)), nil
+			}
+		}
+		l, r := toNumber(left), toNumber(right)
+		if math.IsNaN(l) || math.IsNaN(r) {
+			return false, nil
+		}
+		switch {
+		case l < r:
+			return compareResult(op, -1), nil
+		case l > r:
+			return compareResult(op, 1), nil
+		}
+		return compareResult(op, 0), nil
+	}
+	return nil, in.errorf(pos, "unknown operator %s", op)
+}
+
+func isObjectLike(v interface{}) bool {
+	switch v.(type) {
+	case *array, *object:
+		return true
+	}
+	return false
+}
+
+func stringCompare(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareResult(op string, cmp int) bool {
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+func (in *interp) evalAssign(e *assignExpr, scope *env) (interface{}, error) {
+	value, err := in.eval(e.value, scope)
+	if err != nil {
+		return nil, err
+	}
+	if e.op != "=" {
+		old, err := in.eval(e.target, scope)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = in.binaryOp(e.pos, e.op[:1], old, value); err != nil {
+			return nil, err
+		}
+	}
+	return value, in.assign(e.target, value, scope)
+}
+
+// assign stores value in a variable, object property or array element
+func (in *interp) assign(target expr, value interface{}, scope *env) error {
+	switch t := target.(type) {
+	case *identifier:
+		b := scope.lookup(t.name)
+		if b == nil {
+			return in.errorf(t.pos, "%s is not defined", t.name)
+		}
+		if b.constant {
+			return in.errorf(t.pos, "assignment to constant %s", t.name)
+		}
+		b.value = value
+		return nil
+
+	case *memberExpr:
+		receiver, err := in.eval(t.object, scope)
+		if err != nil {
+			return err
+		}
+		key, err := in.eval(t.property, scope)
+		if err != nil {
+			return err
+		}
+		switch obj := receiver.(type) {
+		case *object:
+			name, err := in.toString(t.pos, key)
+			if err != nil {
+				return err
+			}
+			if _, exists := obj.props[name]; !exists {
+				if err := in.allocAt(t.pos, 32+len(name)+itemSize(value)); err != nil {
+					return err
+				}
+			}
+			obj.set(name, value)
+			return nil
+		case *array:
+			index, ok := arrayIndex(key)
+			if !ok || index > len(obj.items) {
+				return in.errorf(t.pos, "invalid array index %s", describeKey(key))
+			}
+			if index == len(obj.items) {
+				if err := in.allocAt(t.pos, itemSize(value)); err != nil {
+					return err
+				}
+				obj.items = append(obj.items, value)
+				return nil
+			}
+			obj.items[index] = value
+			return nil
+		}
+		return in.errorf(t.pos, "cannot set property %s of %s", describeKey(key), describe(receiver))
+	}
+	return in.errorf(target.position(), "invalid assignment target")
+}
+
+func arrayIndex(key interface{}) (int, bool) {
+	var f float64
+	switch k := key.(type) {
+	case float64:
+		f = k
+	case string:
+		n, err := strconv.Atoi(k)
+		if err != nil {
+			return 0, false
+		}
+		f = float64(n)
+	default:
+		return 0, false
+	}
+	if f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// describe names a value in error messages
+func describe(v interface{}) string {
+	switch v.(type) {
+	case undefinedType:
+		return "undefined"
+	case nil:
+		return "null"
+	}
+	return typeOf(v)
+}
+
+// describeKey names a property key in error messages without joining arrays
+func describeKey(key interface{}) string {
+	if _, ok := key.(*array); ok {
+		return "array"
+	}
+	return toString(key)
+}
+
+// getMember reads a property of an object, array or string, including methods
+func (in *interp) getMember(pos Pos, receiver, key interface{}) (interface{}, error) {
+	switch obj := receiver.(type) {
+	case *object:
+		name, err := in.toString(pos, key)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := obj.get(name); ok {
+			return v, nil
+		}
+		return undefined, nil
+
+	case *array:
+		if index, ok := arrayIndex(key); ok {
+			if index < len(obj.items) {
+				return obj.items[index], nil
+			}
+			return undefined, nil
+		}
+		name, err := in.toString(pos, key)
+		if err != nil {
+			return nil, err
+		}
+		if name == "length" {
+			return float64(len(obj.items)), nil
+		}
+		if fn, ok := arrayMethods[name]; ok {
+			return &method{this: obj, fn: fn}, nil
+		}
+		return undefined, nil
+
+	case string:
+		if index, ok := arrayIndex(key); ok {
+			runes := []rune(obj)
+			if index < len(runes) {
+				return string(runes[index]), nil
+			}
+			return undefined, nil
+		}
+		name, err := in.toString(pos, key)
+		if err != nil {
+			return nil, err
+		}
+		if name == "length" {
+			return float64(len([]rune(obj))), nil
+		}
+		if fn, ok := stringMethods[name]; ok {
+			return &method{this: obj, fn: fn}, nil
+		}
+		return undefined, nil
+
+	case nil, undefinedType:
+		return nil, in.errorf(pos, "cannot read property %s of %s", describeKey(key), describe(receiver))
+	}
+	return undefined, nil
+}
+
+func (in *interp) evalCall(e *callExpr, scope *env) (interface{}, bool, error) {
+	callee, short, err := in.evalChain(e.callee, scope)
+	if err != nil || short {
+		return undefined, short, err
+	}
+	if e.optional && isNullish(callee) {
+		return undefined, true, nil
+	}
+
+	args := make([]interface{}, 0, len(e.args))
+	for _, arg := range e.args {
+		v, err := in.eval(arg, scope)
+		if err != nil {
+			return nil, false, err
+		}
+		args = append(args, v)
+	}
+
+	if !isCallable(callee) {
+		return nil, false, in.errorf(e.pos, "%s is not a function", calleeName(e.callee))
+	}
+	v, err := in.call(e.pos, callee, args)
+	return v, false, err
+}
+
+func calleeName(e expr) string {
+	switch c := e.(type) {
+	case *identifier:
+		return c.name
+	case *memberExpr:
+		if name, ok := c.property.(*stringLit); ok {
+			return calleeName(c.object) + "." + name.value
+		}
+		return calleeName(c.object) + "[...]"
+	}
+	return "expression"
+}
+
+// call invokes a closure, builtin or bound method
+func (in *interp) call(pos Pos, fn interface{}, args []interface{}) (interface{}, error) {
+	switch f := fn.(type) {
+	case *builtin:
+		return f.call(in, pos, nil, args)
+	case *method:
+		return f.fn.call(in, pos, f.this, args)
+	case *closure:
+		in.depth++
+		defer func() { in.depth-- }()
+		if in.depth > in.limits.MaxDepth {
+			return nil, in.errorf(pos, "maximum call depth of %d exceeded", in.limits.MaxDepth)
+		}
+
+		scope := newEnv(f.env)
+		for i, name := range f.fn.params {
+			var value interface{} = undefined
+			if i < len(args) {
+				value = args[i]
+			}
+			scope.vars[name] = &binding{value: value}
+		}
+		if f.fn.body == nil {
+			return in.eval(f.fn.expr, scope)
+		}
+		ctl, value, err := in.execBlock(f.fn.body.stmts, scope)
+		if err != nil {
+			return nil, err
+		}
+		switch ctl {
+		case ctlReturn:
+			return value, nil
+		case ctlBreak, ctlContinue:
+			return nil, in.errorf(pos, "break or continue outside a loop")
+		}
+		return undefined, nil
+	}
+	return nil, in.errorf(pos, "%s is not a function", typeOf(fn))
+}
@@ -0,0 +1,171 @@
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// megabyte is a script prelude that builds a 1 MiB string
+const megabyte = `let s = "x"; for (let i = 0; i < 20; i++) { s = s + s; } `
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name:    "push a large string",
+			src:     megabyte + `const parts = []; for (let i = 0; i < 300; i++) { parts.push(s); } parts.join("")`,
+			wantErr: "memory limit",
+		},
+		{
+			name:    "array literal of a large string",
+			src:     megabyte + `const parts = [s, s, s, s, s, s, s, s]; parts.length`,
+			wantErr: "memory limit",
+		},
+		{
+			name:    "join nested arrays",
+			src:     `let a = ["abcdefgh"]; for (let i = 0; i < 40; i++) { a = [a, a]; } a.join("")`,
+			wantErr: "memory limit",
+		},
+		{
+			name:    "join with a long separator",
+			src:     megabyte + `const a = []; for (let i = 0; i < 1000; i++) { a.push(1); } a.join(s)`,
+			wantErr: "memory limit",
+		},
+		{
+			name:    "replaceAll",
+			src:     megabyte + `s.replaceAll("x", "0123456789abcdef")`,
+			wantErr: "memory limit",
+		},
+		{
+			name:    "replaceAll empty",
+			src:     megabyte + `s.replaceAll("", s)`,
+			wantErr: "memory limit",
+		},
+		{
+			name:    "concatenate nested arrays",
+			src:     `let a = ["abcdefgh"]; for (let i = 0; i < 40; i++) { a = [a, a]; } "" + a`,
+			wantErr: "memory limit",
+		},
+		{
+			name:    "String of nested arrays",
+			src:     `let a = ["abcdefgh"]; for (let i = 0; i < 40; i++) { a = [a, a]; } String(a)`,
+			wantErr: "memory limit",
+		},
+		{
+			name:    "stringify nested arrays",
+			src:     `let a = ["abcdefgh"]; for (let i = 0; i < 40; i++) { a = [a, a]; } JSON.stringify(a)`,
+			wantErr: "memory limit",
+		},
+		{
+			name:    "result of nested arrays",
+			src:     `let a = ["abcdefgh"]; for (let i = 0; i < 40; i++) { a = [a, a]; } a`,
+			wantErr: "memory limit",
+		},
+		{
+			name:    "join an array that contains itself",
+			src:     `const a = [1]; a.push(a); a.join()`,
+			wantErr: "contains itself",
+		},
+		{
+			name:    "stringify an object that contains itself",
+			src:     `const o = {}; o.self = o; JSON.stringify(o)`,
+			wantErr: "contains itself",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			_, err = program.Run(context.Background(), nil, Limits{})
+			runtime.ReadMemStats(&after)
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+			// Allow for interpreter overhead, but nothing like the size of the result
+			if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 8*DefaultMaxMemory {
+				t.Errorf("allocated %d bytes, limit is %d", allocated, DefaultMaxMemory)
+			}
+		})
+	}
+}
+
+func TestMemoryLimitAllowsSmallResults(t *testing.T) {
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{src: `["a", ["b", "c"], null].join("-")`, want: "a-b,c-"},
+		{src: `"a,b,,c".split(",")`, want: []interface{}{"a", "b", "", "c"}},
+		{src: `"abc".split("")`, want: []interface{}{"a", "b", "c"}},
+		{src: `"aXbXc".replaceAll("X", "--")`, want: "a--b--c"},
+		{src: `"aXbXc".replace("X", "")`, want: "abXc"},
+		{src: `"ab".replaceAll("", "-")`, want: "-a-b-"},
+		{src: `[1, 2].concat([3], 4)`, want: []interface{}{1.0, 2.0, 3.0, 4.0}},
+		{src: `"" + [1, [2, 3]]`, want: "1,2,3"},
+		{src: `JSON.stringify({a: [1, "<x>"], b: null})`, want: `{"a":[1,"\u003cx\u003e"],"b":null}`},
+		{src: `const a = [1]; [a, a].join()`, want: "1,1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			if got := run(t, tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeasureJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      interface{}
+		containers int
+	}{
+		{name: "scalars", value: []interface{}{1.0, 2.5, -3.0, true, nil, "x\ny"}, containers: 1},
+		{name: "escapes", value: []interface{}{"é", "\u2028", "<&>", "\t\"\\", "\x01", "\xff"}, containers: 1},
+		{name: "empty", value: map[string]interface{}{"a": []interface{}{}, "b": map[string]interface{}{}}, containers: 3},
+		{
+			name:       "nested",
+			value:      []interface{}{[]interface{}{1.0, []interface{}{2.0}}, map[string]interface{}{"k": []interface{}{3.0, map[string]interface{}{"l": "4"}}}},
+			containers: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, indent := range []int{0, 2} {
+			v := convertDecoded(tt.value)
+			var data []byte
+			var err error
+			if indent > 0 {
+				data, err = json.MarshalIndent(toGo(v), "", strings.Repeat(" ", indent))
+			} else {
+				data, err = json.Marshal(toGo(v))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := newMeasure(DefaultMaxMemory)
+			m.json(v, indent, 0)
+			// Each container visited counts one byte on top of its text
+			if got := m.n - tt.containers; got != len(data) {
+				t.Errorf("%s, indent %d: measured %d bytes, encoded %d: %s", tt.name, indent, got, len(data), data)
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package script
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokKeyword
+	tokPunct
+)
+
+// Pos is a position in the script source, 1-based
+type Pos struct {
+	Line   int
+	Column int
+}
+
+type token struct {
+	kind          tokenKind
+	text          string
+	num           float64
+	pos           Pos
+	newlineBefore bool // a line break precedes the token, ending the previous statement
+}
+
+var keywords = map[string]bool{
+	"let": true, "const": true, "var": true, "if": true, "else": true,
+	"for": true, "of": true, "while": true, "return": true, "break": true,
+	"continue": true, "function": true, "true": true, "false": true,
+	"null": true, "typeof": true,
+}
+
+// Longest punctuators first so that e.g. === is not read as == and =
+var punctuators = []string{
+	"===", "!==",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "+=", "-=", "*=", "/=", "++", "--",
+	"{", "}", "(", ")", "[", "]", ";", ",", ".", "?", ":", "=", "<", ">",
+	"+", "-", "*", "/", "%", "!",
+}
+
+// lex splits src into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+	line, col := 1, 1
+	newline := false
+
+	advance := func(n int) {
+		for _, r := range src[:n] {
+			if r == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+		}
+		src = src[n:]
+	}
+
+	for len(src) > 0 {
+		c := src[0]
+		switch {
+		case c == '\n':
+			newline = true
+			advance(1)
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			advance(1)
+			continue
+		case strings.HasPrefix(src, "//"):
+			end := strings.IndexByte(src, '\n')
+			if end < 0 {
+				end = len(src)
+			}
+			advance(end)
+			continue
+		case strings.HasPrefix(src, "/*"):
+			end := strings.Index(src[2:], "*/")
+			if end < 0 {
+				return nil, &Error{Pos: Pos{line, col}, Message: "unterminated comment"}
+			}
+			if strings.Contains(src[:end+4], "\n") {
+				newline = true
+			}
+			advance(end + 4)
+			continue
+		}
+
+		tok := token{pos: Pos{line, col}, newlineBefore: newline}
+		newline = false
+
+		switch {
+		case isDigit(c) || (c == '.' && len(src) > 1 && isDigit(src[1])):
+			n := 0
+			for n < len(src) && (isDigit(src[n]) || src[n] == '.' || src[n] == 'e' || src[n] == 'E' ||
+				((src[n] == '+' || src[n] == '-') && n > 0 && (src[n-1] == 'e' || src[n-1] == 'E'))) {
+				n++
+			}
+			num, err := strconv.ParseFloat(src[:n], 64)
+			if err != nil {
+				return nil, &Error{Pos: tok.pos, Message: "invalid number " + src[:n]}
+			}
+			tok.kind, tok.text, tok.num = tokNumber, src[:n], num
+			advance(n)
+
+		case c == '"' || c == '\'':
+			s, n, err := readString(src)
+			if err != nil {
+				return nil, &Error{Pos: tok.pos, Message: err.Error()}
+			}
+			tok.kind, tok.text = tokString, s
+			advance(n)
+
+		case isIdentStart(c):
+			n := 1
+			for n < len(src) && (isIdentStart(src[n]) || isDigit(src[n])) {
+				n++
+			}
+			tok.text = src[:n]
+			tok.kind = tokIdent
+			if keywords[tok.text] {
+				tok.kind = tokKeyword
+			}
+			advance(n)
+
+		default:
+			matched := false
+			for _, p := range punctuators {
+				if strings.HasPrefix(src, p) {
+					// a?.5 is a conditional, not optional chaining
+					if p == "?." && len(src) > 2 && isDigit(src[2]) {
+						continue
+					}
+					tok.kind, tok.text = tokPunct, p
+					advance(len(p))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &Error{Pos: tok.pos, Message: "unexpected character " + strconv.QuoteRune(rune(c))}
+			}
+		}
+
+		tokens = append(tokens, tok)
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: Pos{line, col}, newlineBefore: true})
+	return tokens, nil
+}
+
+// readString reads a quoted string literal and returns its value and length in src
+func readString(src string) (string, int, error) {
+	quote := src[0]
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\n':
+			return "", 0, errUnterminatedString
+		case c == '\\' && i+1 < len(src):
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'u':
+				if i+4 >= len(src) {
+					return "", 0, errInvalidEscape
+				}
+				code, err := strconv.ParseUint(src[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, errInvalidEscape
+				}
+				b.WriteRune(rune(code))
+				i += 4
+			default:
+				b.WriteByte(src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, errUnterminatedString
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,745 @@
+package script
+
+import (
+	"context"
+	"fmt"
+)
+
+// Parser limits. Nesting is bounded so deeply nested scripts fail to compile
+// instead of exhausting the stack.
+const (
+	maxSourceLength = 64 * 1024
+	maxNesting      = 256
+)
+
+type parser struct {
+	ctx    context.Context
+	tokens []token
+	pos    int
+	depth  int
+	calls  int
+}
+
+// parse compiles src into a list of statements, giving up when ctx is done
+func parse(ctx context.Context, src string) ([]stmt, error) {
+	if len(src) > maxSourceLength {
+		return nil, &Error{Pos: Pos{Line: 1, Column: 1}, Message: fmt.Sprintf("script is longer than %d bytes", maxSourceLength)}
+	}
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{ctx: ctx, tokens: tokens}
+	var stmts []stmt
+	for !p.at(tokEOF, "") {
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, s)
+	}
+	return stmts, nil
+}
+
+// enter is called by the recursive rules before descending. It fails once
+// the nesting limit is reached or the context is done.
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxNesting {
+		return &Error{Pos: p.peek().pos, Message: fmt.Sprintf("nesting is deeper than %d levels", maxNesting)}
+	}
+	p.calls++
+	if p.calls%1024 == 0 {
+		if err := p.ctx.Err(); err != nil {
+			return &Error{Pos: p.peek().pos, Message: "compilation stopped: " + err.Error()}
+		}
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// at reports whether the current token has the kind and, if text is set, the text
+func (p *parser) at(kind tokenKind, text string) bool {
+	tok := p.peek()
+	return tok.kind == kind && (text == "" || tok.text == text)
+}
+
+func (p *parser) atPunct(text string) bool {
+	return p.at(tokPunct, text)
+}
+
+func (p *parser) atKeyword(text string) bool {
+	return p.at(tokKeyword, text)
+}
+
+func (p *parser) expectPunct(text string) (token, error) {
+	if !p.atPunct(text) {
+		return token{}, p.unexpected(fmt.Sprintf("expected %q", text))
+	}
+	return p.next(), nil
+}
+
+func (p *parser) expectIdent() (token, error) {
+	if !p.at(tokIdent, "") {
+		return token{}, p.unexpected("expected a name")
+	}
+	return p.next(), nil
+}
+
+func (p *parser) unexpected(context string) error {
+	tok := p.peek()
+	desc := fmt.Sprintf("%q", tok.text)
+	if tok.kind == tokEOF {
+		desc = "end of script"
+	}
+	return &Error{Pos: tok.pos, Message: fmt.Sprintf("unexpected %s, %s", desc, context)}
+}
+
+// endStatement consumes an optional semicolon. Without one the statement must
+// end at a line break, a closing brace or the end of the script.
+func (p *parser) endStatement() error {
+	if p.atPunct(";") {
+		p.next()
+		return nil
+	}
+	tok := p.peek()
+	if tok.newlineBefore || tok.kind == tokEOF || (tok.kind == tokPunct && tok.text == "}") {
+		return nil
+	}
+	return p.unexpected("expected end of statement")
+}
+
+func (p *parser) statement() (stmt, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	tok := p.peek()
+
+	switch {
+	case p.atPunct(";"):
+		p.next()
+		return &blockStmt{pos: tok.pos}, nil
+	case p.atPunct("{"):
+		return p.block()
+	case p.atKeyword("let"), p.atKeyword("const"), p.atKeyword("var"):
+		decl, err := p.varDecl()
+		if err != nil {
+			return nil, err
+		}
+		return decl, p.endStatement()
+	case p.atKeyword("if"):
+		return p.ifStatement()
+	case p.atKeyword("for"):
+		return p.forStatement()
+	case p.atKeyword("while"):
+		p.next()
+		test, err := p.parenExpr()
+		if err != nil {
+			return nil, err
+		}
+		body, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		return &whileStmt{pos: tok.pos, test: test, body: body}, nil
+	case p.atKeyword("return"):
+		p.next()
+		ret := &returnStmt{pos: tok.pos}
+		next := p.peek()
+		if !next.newlineBefore && next.kind != tokEOF && !(next.kind == tokPunct && (next.text == ";" || next.text == "}")) {
+			value, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			ret.value = value
+		}
+		return ret, p.endStatement()
+	case p.atKeyword("break"):
+		p.next()
+		return &breakStmt{pos: tok.pos}, p.endStatement()
+	case p.atKeyword("continue"):
+		p.next()
+		return &continueStmt{pos: tok.pos}, p.endStatement()
+	case p.atKeyword("function") && p.peekAt(1).kind == tokIdent:
+		fn, err := p.function()
+		if err != nil {
+			return nil, err
+		}
+		return &funcDecl{pos: tok.pos, fn: fn}, nil
+	}
+
+	e, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return &exprStmt{pos: tok.pos, expr: e}, p.endStatement()
+}
+
+func (p *parser) block() (*blockStmt, error) {
+	open, err := p.expectPunct("{")
+	if err != nil {
+		return nil, err
+	}
+	b := &blockStmt{pos: open.pos}
+	for !p.atPunct("}") {
+		if p.at(tokEOF, "") {
+			return nil, p.unexpected(`expected "}"`)
+		}
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		b.stmts = append(b.stmts, s)
+	}
+	p.next()
+	return b, nil
+}
+
+func (p *parser) varDecl() (*varDecl, error) {
+	kw := p.next()
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	decl := &varDecl{pos: kw.pos, constant: kw.text == "const", name: name.text}
+	if p.atPunct("=") {
+		p.next()
+		if decl.init, err = p.assignment(); err != nil {
+			return nil, err
+		}
+	} else if decl.constant {
+		return nil, p.unexpected("const declarations need a value")
+	}
+	return decl, nil
+}
+
+func (p *parser) parenExpr() (expr, error) {
+	if _, err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	e, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (p *parser) ifStatement() (stmt, error) {
+	kw := p.next()
+	test, err := p.parenExpr()
+	if err != nil {
+		return nil, err
+	}
+	consequent, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	s := &ifStmt{pos: kw.pos, test: test, consequent: consequent}
+	if p.atKeyword("else") {
+		p.next()
+		if s.alt, err = p.statement(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) forStatement() (stmt, error) {
+	kw := p.next()
+	if _, err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+
+	// for (const x of items)
+	if (p.atKeyword("let") || p.atKeyword("const") || p.atKeyword("var")) &&
+		p.peekAt(1).kind == tokIdent && p.peekAt(2).kind == tokKeyword && p.peekAt(2).text == "of" {
+		decl := p.next()
+		name := p.next()
+		p.next()
+		iterable, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		body, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		return &forOfStmt{pos: kw.pos, constant: decl.text == "const", name: name.text, iterable: iterable, body: body}, nil
+	}
+
+	// for (init; test; post)
+	s := &forStmt{pos: kw.pos}
+	var err error
+	if !p.atPunct(";") {
+		if p.atKeyword("let") || p.atKeyword("const") || p.atKeyword("var") {
+			s.init, err = p.varDecl()
+		} else {
+			var e expr
+			e, err = p.expression()
+			s.init = &exprStmt{pos: kw.pos, expr: e}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expectPunct(";"); err != nil {
+		return nil, err
+	}
+	if !p.atPunct(";") {
+		if s.test, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expectPunct(";"); err != nil {
+		return nil, err
+	}
+	if !p.atPunct(")") {
+		if s.post, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	if s.body, err = p.statement(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// function parses function name(params) { body }; the name is optional
+func (p *parser) function() (*funcLit, error) {
+	kw := p.next()
+	fn := &funcLit{pos: kw.pos}
+	if p.at(tokIdent, "") {
+		fn.name = p.next().text
+	}
+	params, err := p.params()
+	if err != nil {
+		return nil, err
+	}
+	fn.params = params
+	if fn.body, err = p.block(); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+func (p *parser) params() ([]string, error) {
+	if _, err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var params []string
+	for !p.atPunct(")") {
+		name, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, name.text)
+		if !p.atPunct(")") {
+			if _, err := p.expectPunct(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return params, nil
+}
+
+func (p *parser) expression() (expr, error) {
+	return p.assignment()
+}
+
+func (p *parser) assignment() (expr, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	left, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	if tok.kind == tokPunct && tok.text == "=>" {
+		return p.arrowFunction(left)
+	}
+	if tok.kind == tokPunct && (tok.text == "=" || tok.text == "+=" || tok.text == "-=" || tok.text == "*=" || tok.text == "/=") {
+		switch left.(type) {
+		case *identifier, *memberExpr:
+		default:
+			return nil, &Error{Pos: tok.pos, Message: "invalid assignment target"}
+		}
+		p.next()
+		value, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		return &assignExpr{pos: tok.pos, op: tok.text, target: left, value: value}, nil
+	}
+	return left, nil
+}
+
+// arrowParams is a parenthesised list followed by =>. primary only returns
+// one when => is next, and assignment turns it into the arrow function's
+// parameters, so it never ends up in a compiled program.
+type arrowParams struct {
+	pos   Pos
+	items []expr
+}
+
+func (e *arrowParams) position() Pos { return e.pos }
+
+// arrowFunction parses the => and body of an arrow function whose parameters,
+// x or (a, b), have already been parsed as left
+func (p *parser) arrowFunction(left expr) (expr, error) {
+	var items []expr
+	switch l := left.(type) {
+	case *identifier:
+		items = []expr{l}
+	case *arrowParams:
+		items = l.items
+	default:
+		return nil, p.unexpected("expected an expression")
+	}
+
+	fn := &funcLit{pos: left.position()}
+	for _, item := range items {
+		id, ok := item.(*identifier)
+		if !ok {
+			return nil, &Error{Pos: item.position(), Message: "arrow function parameters must be names"}
+		}
+		fn.params = append(fn.params, id.name)
+	}
+	p.next()
+
+	var err error
+	if p.atPunct("{") {
+		fn.body, err = p.block()
+	} else {
+		fn.expr, err = p.assignment()
+	}
+	return fn, err
+}
+
+func (p *parser) conditional() (expr, error) {
+	test, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.atPunct("?") {
+		return test, nil
+	}
+	q := p.next()
+	consequent, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expectPunct(":"); err != nil {
+		return nil, err
+	}
+	alt, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	return &conditionalExpr{pos: q.pos, test: test, consequent: consequent, alt: alt}, nil
+}
+
+// Binary operator precedence, lowest first
+var precedence = map[string]int{
+	"??": 1,
+	"||": 2,
+	"&&": 3,
+	"==": 4, "!=": 4, "===": 4, "!==": 4,
+	"<": 5, "<=": 5, ">": 5, ">=": 5,
+	"+": 6, "-": 6,
+	"*": 7, "/": 7, "%": 7,
+}
+
+// binary parses operators binding tighter than minPrec by precedence climbing
+func (p *parser) binary(minPrec int) (expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		prec, ok := precedence[tok.text]
+		if tok.kind != tokPunct || !ok || prec <= minPrec {
+			return left, nil
+		}
+		p.next()
+		right, err := p.binary(prec)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{pos: tok.pos, op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	tok := p.peek()
+	if (tok.kind == tokPunct && (tok.text == "!" || tok.text == "-" || tok.text == "+")) || (tok.kind == tokKeyword && tok.text == "typeof") {
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{pos: tok.pos, op: tok.text, operand: operand}, nil
+	}
+	if tok.kind == tokPunct && (tok.text == "++" || tok.text == "--") {
+		p.next()
+		target, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &updateExpr{pos: tok.pos, op: tok.text, prefix: true, target: target}, nil
+	}
+
+	e, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	next := p.peek()
+	if next.kind == tokPunct && (next.text == "++" || next.text == "--") && !next.newlineBefore {
+		p.next()
+		return &updateExpr{pos: next.pos, op: next.text, target: e}, nil
+	}
+	return e, nil
+}
+
+// postfix parses member access, indexing and calls, including optional chaining
+func (p *parser) postfix() (expr, error) {
+	e, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok.kind != tokPunct {
+			return e, nil
+		}
+
+		optional := false
+		if tok.text == "?." {
+			optional = true
+			p.next()
+			tok = p.peek()
+			if tok.kind == tokIdent || tok.kind == tokKeyword {
+				p.next()
+				e = &memberExpr{pos: tok.pos, object: e, property: &stringLit{pos: tok.pos, value: tok.text}, optional: true}
+				continue
+			}
+		}
+
+		switch tok.text {
+		case ".":
+			p.next()
+			name := p.next()
+			if name.kind != tokIdent && name.kind != tokKeyword {
+				return nil, &Error{Pos: name.pos, Message: "expected a property name"}
+			}
+			e = &memberExpr{pos: name.pos, object: e, property: &stringLit{pos: name.pos, value: name.text}}
+		case "[":
+			p.next()
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expectPunct("]"); err != nil {
+				return nil, err
+			}
+			e = &memberExpr{pos: tok.pos, object: e, property: index, optional: optional}
+		case "(":
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			e = &callExpr{pos: tok.pos, callee: e, args: args, optional: optional}
+		default:
+			if optional {
+				return nil, p.unexpected("expected a property, index or call")
+			}
+			return e, nil
+		}
+	}
+}
+
+func (p *parser) arguments() ([]expr, error) {
+	p.next()
+	var args []expr
+	for !p.atPunct(")") {
+		arg, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.atPunct(")") {
+			if _, err := p.expectPunct(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return args, nil
+}
+
+func (p *parser) primary() (expr, error) {
+	tok := p.peek()
+
+	switch tok.kind {
+	case tokNumber:
+		p.next()
+		return &numberLit{pos: tok.pos, value: tok.num}, nil
+	case tokString:
+		p.next()
+		return &stringLit{pos: tok.pos, value: tok.text}, nil
+	case tokIdent:
+		p.next()
+		return &identifier{pos: tok.pos, name: tok.text}, nil
+	case tokKeyword:
+		switch tok.text {
+		case "true", "false":
+			p.next()
+			return &boolLit{pos: tok.pos, value: tok.text == "true"}, nil
+		case "null":
+			p.next()
+			return &nullLit{pos: tok.pos}, nil
+		case "function":
+			return p.function()
+		}
+	case tokPunct:
+		switch tok.text {
+		case "(":
+			return p.parenthesized()
+		case "[":
+			return p.arrayLiteral()
+		case "{":
+			return p.objectLiteral()
+		}
+	}
+
+	return nil, p.unexpected("expected an expression")
+}
+
+// parenthesized parses (e), or the parameter list of an arrow function when
+// => follows the closing parenthesis
+func (p *parser) parenthesized() (expr, error) {
+	open := p.next()
+	var items []expr
+	for !p.atPunct(")") {
+		e, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, e)
+		if !p.atPunct(")") {
+			if !p.atPunct(",") {
+				return nil, p.unexpected(`expected ")"`)
+			}
+			p.next()
+		}
+	}
+	p.next()
+
+	if p.atPunct("=>") {
+		return &arrowParams{pos: open.pos, items: items}, nil
+	}
+	if len(items) != 1 {
+		return nil, &Error{Pos: open.pos, Message: "expected an expression in parentheses"}
+	}
+	return items[0], nil
+}
+
+func (p *parser) arrayLiteral() (expr, error) {
+	open := p.next()
+	arr := &arrayLit{pos: open.pos}
+	for !p.atPunct("]") {
+		e, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		arr.elements = append(arr.elements, e)
+		if !p.atPunct("]") {
+			if _, err := p.expectPunct(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return arr, nil
+}
+
+func (p *parser) objectLiteral() (expr, error) {
+	open := p.next()
+	obj := &objectLit{pos: open.pos}
+	for !p.atPunct("}") {
+		key := p.next()
+		switch key.kind {
+		case tokIdent, tokKeyword, tokString:
+		case tokNumber:
+			key.text = formatNumber(key.num)
+		default:
+			return nil, &Error{Pos: key.pos, Message: "expected a property name"}
+		}
+
+		var value expr
+		if p.atPunct(":") {
+			p.next()
+			var err error
+			if value, err = p.assignment(); err != nil {
+				return nil, err
+			}
+		} else if key.kind == tokIdent {
+			// Shorthand { name }
+			value = &identifier{pos: key.pos, name: key.text}
+		} else {
+			return nil, p.unexpected(`expected ":"`)
+		}
+		obj.props = append(obj.props, property{key: key.text, value: value})
+
+		if !p.atPunct("}") {
+			if _, err := p.expectPunct(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return obj, nil
+}
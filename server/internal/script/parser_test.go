@@ -0,0 +1,109 @@
+package script
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func run(t *testing.T, src string) interface{} {
+	t.Helper()
+	program, err := Compile(src)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	result, err := program.Run(context.Background(), nil, Limits{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return result.Value
+}
+
+func TestArrowFunctions(t *testing.T) {
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{src: "const f = x => x * 2; f(4)", want: 8.0},
+		{src: "const f = (x) => x * 2; f(4)", want: 8.0},
+		{src: "const f = (a, b) => a + b; f(1, 2)", want: 3.0},
+		{src: "const f = () => 7; f()", want: 7.0},
+		{src: "const f = (a, b) => { return a * b }; f(3, 4)", want: 12.0},
+		{src: "[1, 2, 3].map(x => x + 1)", want: []interface{}{2.0, 3.0, 4.0}},
+		{src: "const add = a => b => a + b; add(1)(2)", want: 3.0},
+		{src: "true ? (x) => x : 0; (1 + 2) * 3", want: 9.0},
+		{src: "(((1 + 2)))", want: 3.0},
+		{src: "let f; f = (x) => -x; f(2)", want: -2.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			if got := run(t, tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{name: "empty parentheses", src: "()", wantErr: "expected an expression in parentheses"},
+		{name: "comma list", src: "(1, 2)", wantErr: "expected an expression in parentheses"},
+		{name: "non-name parameter", src: "(a, 1) => a", wantErr: "parameters must be names"},
+		{name: "arrow after an operator", src: "1 + (a) => a", wantErr: "expected an expression"},
+		{name: "member as parameter", src: "a.b => 1", wantErr: "expected an expression"},
+		{name: "unclosed parenthesis", src: "(1 + 2", wantErr: `expected ")"`},
+		{name: "too long", src: "1;" + strings.Repeat(" ", maxSourceLength), wantErr: "longer than"},
+		{name: "deep parentheses", src: strings.Repeat("(", 10000) + "1" + strings.Repeat(")", 10000), wantErr: "nesting is deeper"},
+		{name: "deep arrays", src: strings.Repeat("[", 10000), wantErr: "nesting is deeper"},
+		{name: "deep blocks", src: strings.Repeat("{", 10000), wantErr: "nesting is deeper"},
+		{name: "deep unary", src: strings.Repeat("!", 10000) + "x", wantErr: "nesting is deeper"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNestingLimit(t *testing.T) {
+	// Nesting within the limit still compiles
+	src := strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100)
+	if got := run(t, src); got != 1.0 {
+		t.Errorf("got %v, want 1", got)
+	}
+}
+
+func TestCompileLargeScript(t *testing.T) {
+	// Parentheses are parsed once, not rescanned looking for =>
+	src := strings.Repeat("(", 120) + "1" + strings.Repeat(")", 120) + ";"
+	src = strings.Repeat(src, maxSourceLength/len(src))
+
+	start := time.Now()
+	if _, err := Compile(src); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("compiling took %v", elapsed)
+	}
+}
+
+func TestCompileContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	src := strings.Repeat("x = 1;", 1000)
+	if _, err := CompileContext(ctx, src); err == nil || !strings.Contains(err.Error(), "compilation stopped") {
+		t.Errorf("error = %v, want compilation to stop", err)
+	}
+}
@@ -0,0 +1,111 @@
+// Package script implements a small, sandboxed JavaScript-like language for
+// custom assertions. Scripts can read the globals they are given, declare
+// variables and functions, and call a fixed set of builtins; they have no
+// access to the network, the filesystem or the host process. Execution is
+// bounded by step, memory and call-depth limits and by the context deadline.
+//
+// Differences from JavaScript worth knowing: == only differs from === in
+// treating null and undefined as equal, and both compare arrays and objects
+// by content rather than identity.
+package script
+
+import (
+	"context"
+)
+
+// Program is a compiled script
+type Program struct {
+	stmts []stmt
+}
+
+// Compile parses src, returning an *Error with the line and column of the
+// first syntax error
+func Compile(src string) (*Program, error) {
+	return CompileContext(context.Background(), src)
+}
+
+// CompileContext is Compile that gives up with an *Error once ctx is done
+func CompileContext(ctx context.Context, src string) (*Program, error) {
+	stmts, err := parse(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{stmts: stmts}, nil
+}
+
+// Result is the outcome of running a program
+type Result struct {
+	// Value is the returned value, or the value of the last top-level
+	// expression statement if the script does not return, as plain Go values
+	Value interface{}
+	// Defined is false when the script produced undefined
+	Defined bool
+	// Failures are the assert() and fail() calls that failed, in order
+	Failures []Failure
+}
+
+// Run executes the program with globals bound as read-only variables.
+// Global values are copied, so scripts cannot modify the caller's data.
+// Runtime errors, including exceeded limits, are returned as *Error.
+func (p *Program) Run(ctx context.Context, vars map[string]interface{}, limits Limits) (*Result, error) {
+	in := &interp{ctx: ctx, limits: limits.withDefaults()}
+
+	root := newEnv(nil)
+	for name, value := range globals() {
+		root.vars[name] = &binding{value: value, constant: true}
+	}
+	for name, value := range vars {
+		converted, err := in.fromGo(value)
+		if err != nil {
+			return nil, &Error{Message: "global " + name + ": " + err.Error()}
+		}
+		root.vars[name] = &binding{value: converted, constant: true}
+	}
+
+	scope := newEnv(root)
+	for _, s := range p.stmts {
+		if decl, ok := s.(*funcDecl); ok {
+			scope.vars[decl.fn.name] = &binding{value: &closure{fn: decl.fn, env: scope}}
+		}
+	}
+
+	var last interface{} = undefined
+	for _, s := range p.stmts {
+		if e, ok := s.(*exprStmt); ok {
+			if err := in.step(e.pos); err != nil {
+				return nil, err
+			}
+			value, err := in.eval(e.expr, scope)
+			if err != nil {
+				return nil, err
+			}
+			last = value
+			continue
+		}
+
+		ctl, value, err := in.exec(s, scope)
+		if err != nil {
+			return nil, err
+		}
+		switch ctl {
+		case ctlReturn:
+			last = value
+		case ctlBreak, ctlContinue:
+			return nil, &Error{Pos: s.position(), Message: "break or continue outside a loop"}
+		default:
+			continue
+		}
+		break
+	}
+
+	// Converting the result copies it, so it is measured like JSON.stringify
+	m := in.measure()
+	m.json(last, 0, 0)
+	if m.circular {
+		return nil, &Error{Message: "the result contains itself"}
+	}
+	if err := in.alloc(m.n); err != nil {
+		return nil, &Error{Message: err.Error()}
+	}
+	return &Result{Value: toGo(last), Defined: last != undefined, Failures: in.failures}, nil
+}
@@ -0,0 +1,452 @@
+package script
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// undefinedType is the type of the undefined value; JSON null is Go nil
+type undefinedType struct{}
+
+var undefined = undefinedType{}
+
+// array and object are reference types so scripts can build and mutate them
+type array struct {
+	items []interface{}
+}
+
+type object struct {
+	keys  []string // insertion order
+	props map[string]interface{}
+}
+
+func newObject() *object {
+	return &object{props: make(map[string]interface{})}
+}
+
+func (o *object) get(key string) (interface{}, bool) {
+	v, ok := o.props[key]
+	return v, ok
+}
+
+func (o *object) set(key string, value interface{}) {
+	if _, ok := o.props[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.props[key] = value
+}
+
+// closure is a function defined by the script
+type closure struct {
+	fn  *funcLit
+	env *env
+}
+
+// builtin is a function provided by the interpreter. this is the receiver for methods.
+type builtin struct {
+	name string
+	call func(in *interp, pos Pos, this interface{}, args []interface{}) (interface{}, error)
+}
+
+// method is a builtin bound to its receiver, e.g. "abc".toUpperCase
+type method struct {
+	this interface{}
+	fn   *builtin
+}
+
+// fromGo converts a Go value to a script value. Values are round-tripped
+// through JSON first, so scripts get a private copy of their inputs.
+func (in *interp) fromGo(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	if err := in.alloc(len(data)); err != nil {
+		return nil, err
+	}
+	return convertDecoded(decoded), nil
+}
+
+func convertDecoded(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		o := newObject()
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			o.set(k, convertDecoded(x[k]))
+		}
+		return o
+	case []interface{}:
+		a := &array{items: make([]interface{}, len(x))}
+		for i, item := range x {
+			a.items[i] = convertDecoded(item)
+		}
+		return a
+	}
+	return v
+}
+
+// toGo converts a script value to plain Go values: maps, slices, float64,
+// string, bool and nil. Functions and undefined become nil.
+func toGo(v interface{}) interface{} {
+	switch x := v.(type) {
+	case *object:
+		m := make(map[string]interface{}, len(x.props))
+		for k, val := range x.props {
+			if isCallable(val) {
+				continue
+			}
+			m[k] = toGo(val)
+		}
+		return m
+	case *array:
+		out := make([]interface{}, len(x.items))
+		for i, item := range x.items {
+			out[i] = toGo(item)
+		}
+		return out
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil
+		}
+		return x
+	case bool, string, nil:
+		return x
+	}
+	return nil
+}
+
+func isCallable(v interface{}) bool {
+	switch v.(type) {
+	case *closure, *builtin, *method:
+		return true
+	}
+	return false
+}
+
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil, undefinedType:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0 && !math.IsNaN(x)
+	case string:
+		return x != ""
+	}
+	return true
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case undefinedType:
+		return "undefined"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *closure, *builtin, *method:
+		return "function"
+	}
+	return "object"
+}
+
+func toNumber(v interface{}) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case bool:
+		if x {
+			return 1
+		}
+		return 0
+	case nil:
+		return 0
+	case string:
+		s := strings.TrimSpace(x)
+		if s == "" {
+			return 0
+		}
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return n
+	}
+	return math.NaN()
+}
+
+func formatNumber(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case undefinedType:
+		return "undefined"
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(x)
+	case float64:
+		return formatNumber(x)
+	case string:
+		return x
+	case *array:
+		parts := make([]string, len(x.items))
+		for i, item := range x.items {
+			if item != nil && item != undefined {
+				parts[i] = toString(item)
+			}
+		}
+		return strings.Join(parts, ",")
+	case *object:
+		return "[object Object]"
+	}
+	return "function"
+}
+
+// measure computes how many bytes toString and JSON.stringify would produce
+// without building the result, so conversions can be charged against the
+// memory limit before they allocate. It stops once the count passes limit,
+// and each array or object visited counts as one byte so that deeply shared
+// structures cannot make measuring itself expensive.
+type measure struct {
+	limit    int
+	n        int
+	visiting map[interface{}]bool
+	circular bool // a value contains itself
+}
+
+func newMeasure(limit int) *measure {
+	return &measure{limit: limit, visiting: make(map[interface{}]bool)}
+}
+
+func (m *measure) done() bool {
+	return m.circular || m.n > m.limit
+}
+
+func (m *measure) enter(v interface{}) bool {
+	if m.visiting[v] {
+		m.circular = true
+		return false
+	}
+	m.visiting[v] = true
+	m.n++
+	return true
+}
+
+// str counts the length of toString(v)
+func (m *measure) str(v interface{}) {
+	a, ok := v.(*array)
+	if !ok {
+		m.n += len(toString(v))
+		return
+	}
+	m.join(a, 1)
+}
+
+// join counts the length of the items of a joined by a separator of sepLen bytes
+func (m *measure) join(a *array, sepLen int) {
+	if !m.enter(a) {
+		return
+	}
+	defer delete(m.visiting, a)
+	if len(a.items) > 1 {
+		m.n += sepLen * (len(a.items) - 1)
+	}
+	for _, item := range a.items {
+		if m.done() {
+			return
+		}
+		if !isNullish(item) {
+			m.str(item)
+		}
+	}
+}
+
+// json counts the length of the JSON encoding of toGo(v), indented by indent
+// spaces per level. Numbers are counted as formatNumber writes them, which is
+// never shorter than encoding/json.
+func (m *measure) json(v interface{}, indent, depth int) {
+	switch x := v.(type) {
+	case string:
+		m.n += jsonStringSize(x)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			m.n += len("null")
+		} else {
+			m.n += len(formatNumber(x))
+		}
+	case bool:
+		m.n += len(strconv.FormatBool(x))
+	case *array:
+		if !m.enter(x) {
+			return
+		}
+		defer delete(m.visiting, x)
+		m.n += len("[]") + m.separators(len(x.items), indent, depth)
+		for _, item := range x.items {
+			if m.done() {
+				return
+			}
+			m.json(item, indent, depth+1)
+		}
+	case *object:
+		if !m.enter(x) {
+			return
+		}
+		defer delete(m.visiting, x)
+		count := 0
+		for _, key := range x.keys {
+			if m.done() {
+				return
+			}
+			value := x.props[key]
+			if isCallable(value) {
+				continue
+			}
+			count++
+			m.n += jsonStringSize(key) + len(":")
+			if indent > 0 {
+				m.n += len(" ")
+			}
+			m.json(value, indent, depth+1)
+		}
+		m.n += len("{}") + m.separators(count, indent, depth)
+	default:
+		m.n += len("null")
+	}
+}
+
+// separators counts the commas, newlines and indentation between the count
+// elements of a container at depth
+func (m *measure) separators(count, indent, depth int) int {
+	if count == 0 {
+		return 0
+	}
+	n := count - 1
+	if indent > 0 {
+		n += count*(1+indent*(depth+1)) + 1 + indent*depth
+	}
+	return n
+}
+
+// jsonStringSize is the length of s encoded as a JSON string by encoding/json
+func jsonStringSize(s string) int {
+	n := len(`""`)
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\' || c == '\n' || c == '\r' || c == '\t':
+				n += 2
+			case c < 0x20 || c == '<' || c == '>' || c == '&':
+				n += len(`\u0000`)
+			default:
+				n++
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			n += utf8.RuneLen(utf8.RuneError)
+		case r == '\u2028' || r == '\u2029':
+			n += len(`\u0000`)
+		default:
+			n += size
+		}
+		i += size
+	}
+	return n
+}
+
+// itemSize is the memory charged for storing v in an array or object. Strings
+// are charged for their length as well, so that containers holding many
+// copies of a large string count against the limit.
+func itemSize(v interface{}) int {
+	if s, ok := v.(string); ok {
+		return 16 + len(s)
+	}
+	return 16
+}
+
+func itemsSize(items []interface{}) int {
+	n := 0
+	for _, item := range items {
+		n += itemSize(item)
+	}
+	return n
+}
+
+// strictEqual compares primitives by value and arrays and objects by content
+func strictEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		return ok && x == y
+	case *array:
+		y, ok := b.(*array)
+		if !ok || len(x.items) != len(y.items) {
+			return false
+		}
+		for i := range x.items {
+			if !strictEqual(x.items[i], y.items[i]) {
+				return false
+			}
+		}
+		return true
+	case *object:
+		y, ok := b.(*object)
+		if !ok || len(x.props) != len(y.props) {
+			return false
+		}
+		for k, v := range x.props {
+			other, ok := y.props[k]
+			if !ok || !strictEqual(v, other) {
+				return false
+			}
+		}
+		return true
+	case *closure, *builtin, *method:
+		return a == b
+	}
+	return a == b
+}
+
+// looseEqual is strictEqual except that null and undefined are equal
+func looseEqual(a, b interface{}) bool {
+	if isNullish(a) && isNullish(b) {
+		return true
+	}
+	return strictEqual(a, b)
+}
+
+func isNullish(v interface{}) bool {
+	return v == nil || v == undefined
+}
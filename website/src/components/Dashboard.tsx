@@ -154,7 +154,8 @@ export const Dashboard: React.FC = () => {
                     key={flow.id}
                     className="cursor-pointer hover:bg-accent"
                     onClick={() => {
-                      setCurrentFlow(flow)
+                      // The list only holds summaries; the editor loads the full flow
+                      setCurrentFlow(null)
                       loadTestRuns(flow.id)
                       navigate(`/flow/${flow.id}/editor`)
                     }}
@@ -164,7 +165,8 @@ export const Dashboard: React.FC = () => {
                         <div>
                           <h3 className="font-semibold">{flow.name}</h3>
                           <p className="text-sm text-muted-foreground">
-                            {flow.nodes?.length || 0} nodes • {flow.edges?.length || 0} connections
+                            {'node_count' in flow ? flow.node_count : flow.nodes.length} nodes •{' '}
+                            {'edge_count' in flow ? flow.edge_count : flow.edges.length} connections
                           </p>
                         </div>
                         <Button
@@ -172,7 +174,7 @@ export const Dashboard: React.FC = () => {
                           size="sm"
                           onClick={(e) => {
                             e.stopPropagation()
-                            setCurrentFlow(flow)
+                            setCurrentFlow(null)
                             navigate(`/flow/${flow.id}/editor`)
                           }}
                         >
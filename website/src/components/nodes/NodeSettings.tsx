@@ -5,7 +5,7 @@ import { Input } from '@/components/ui/input'
 import { Label } from '@/components/ui/label'
 import { Textarea } from '@/components/ui/textarea'
 import { Tabs, TabsContent, TabsList, TabsTrigger } from '@/components/ui/tabs'
-import { NodeData, NodeType, NodeConfig } from '@/types'
+import { NodeData, NodeType, NodeConfig, StatusSpec } from '@/types'
 import {
   Select,
   SelectContent,
@@ -25,6 +25,17 @@ interface NodeSettingsProps {
   flowId?: string
 }
 
+// splitPaths turns a comma-separated input into a path list
+const splitPaths = (value: string) => value.split(',').map((path) => path.trim())
+
+// parseStatusSpec reads "2xx" or "200, 201" into a status code, class or list
+const parseStatusSpec = (value: string): StatusSpec | undefined => {
+  const parts = value.split(',').map((part) => part.trim()).filter(Boolean)
+    .map((part) => (/^\d+$/.test(part) ? Number(part) : part))
+  if (parts.length === 0) return undefined
+  return parts.length === 1 ? parts[0] : parts
+}
+
 export const NodeSettings: React.FC<NodeSettingsProps> = ({ node, open, onClose, onSave, onDelete, flowId }) => {
   const [config, setConfig] = useState<NodeConfig>(node?.config || {})
   const [showDeleteConfirm, setShowDeleteConfirm] = useState(false)
@@ -119,6 +130,8 @@ export const NodeSettings: React.FC<NodeSettingsProps> = ({ node, open, onClose,
             <SelectItem value="PUT">PUT</SelectItem>
             <SelectItem value="DELETE">DELETE</SelectItem>
             <SelectItem value="PATCH">PATCH</SelectItem>
+            <SelectItem value="HEAD">HEAD</SelectItem>
+            <SelectItem value="OPTIONS">OPTIONS</SelectItem>
           </SelectContent>
         </Select>
       </div>
@@ -158,6 +171,39 @@ export const NodeSettings: React.FC<NodeSettingsProps> = ({ node, open, onClose,
           className="font-mono text-xs"
         />
       </div>
+      <div>
+        <Label htmlFor="contractSpecId">Contract Spec ID</Label>
+        <Input
+          id="contractSpecId"
+          value={config.contract?.specId || ''}
+          onChange={(e) => setConfig({
+            ...config,
+            contract: e.target.value ? { ...config.contract, specId: e.target.value } : undefined,
+          })}
+          placeholder="Validate against an OpenAPI spec from the library"
+        />
+      </div>
+      {config.contract && (
+        <div>
+          <Label htmlFor="contractOperation">Operation ID or Path</Label>
+          <Input
+            id="contractOperation"
+            value={config.contract.operationId || config.contract.path || ''}
+            onChange={(e) => {
+              const value = e.target.value.trim()
+              setConfig({
+                ...config,
+                contract: {
+                  ...config.contract!,
+                  operationId: value.startsWith('/') ? undefined : value || undefined,
+                  path: value.startsWith('/') ? value : undefined,
+                },
+              })
+            }}
+            placeholder="getPet or /pets/{id}"
+          />
+        </div>
+      )}
     </div>
     )
   }
@@ -178,9 +224,158 @@ export const NodeSettings: React.FC<NodeSettingsProps> = ({ node, open, onClose,
             <SelectItem value="contains">Contains</SelectItem>
             <SelectItem value="regex">Regex Match</SelectItem>
             <SelectItem value="custom">Custom Script</SelectItem>
+            <SelectItem value="snapshot">Snapshot</SelectItem>
+            <SelectItem value="assertions">Field Assertions</SelectItem>
+            <SelectItem value="schema">JSON Schema</SelectItem>
+            <SelectItem value="response">Response Metadata</SelectItem>
           </SelectContent>
         </Select>
       </div>
+      {config.assertionType === 'assertions' ? (
+        <div>
+          <Label htmlFor="assertions">Assertions (JSON)</Label>
+          <Textarea
+            id="assertions"
+            defaultValue={config.assertions ? JSON.stringify(config.assertions, null, 2) : ''}
+            onChange={(e) => {
+              try {
+                const assertions = JSON.parse(e.target.value)
+                setConfig({ ...config, assertions })
+              } catch {
+                // Invalid JSON
+              }
+            }}
+            placeholder='[{"path": "status", "operator": "eq", "value": 200}]'
+            className="font-mono text-xs"
+            rows={8}
+          />
+        </div>
+      ) : config.assertionType === 'schema' ? (
+        <>
+          <div>
+            <Label htmlFor="schemaId">Library Schema ID</Label>
+            <Input
+              id="schemaId"
+              value={config.schemaId || ''}
+              onChange={(e) => setConfig({ ...config, schemaId: e.target.value || undefined })}
+              placeholder="Leave empty to use an inline schema"
+              className="font-mono text-xs"
+            />
+          </div>
+          <div>
+            <Label htmlFor="schema">Inline Schema (JSON)</Label>
+            <Textarea
+              id="schema"
+              defaultValue={config.schema ? JSON.stringify(config.schema, null, 2) : ''}
+              onChange={(e) => {
+                try {
+                  const schema = e.target.value ? JSON.parse(e.target.value) : undefined
+                  setConfig({ ...config, schema })
+                } catch {
+                  // Invalid JSON
+                }
+              }}
+              placeholder='{"type": "object", "required": ["id"]}'
+              className="font-mono text-xs"
+              rows={8}
+            />
+          </div>
+        </>
+      ) : config.assertionType === 'snapshot' ? (
+        <>
+          <div>
+            <Label htmlFor="ignorePaths">Ignored Paths</Label>
+            <Input
+              id="ignorePaths"
+              value={(config.ignorePaths || []).join(', ')}
+              onChange={(e) => setConfig({ ...config, ignorePaths: splitPaths(e.target.value) })}
+              placeholder="*.headers.Date, **.id"
+              className="font-mono text-xs"
+            />
+          </div>
+          <div>
+            <Label htmlFor="typeOnlyPaths">Type-only Paths</Label>
+            <Input
+              id="typeOnlyPaths"
+              value={(config.typeOnlyPaths || []).join(', ')}
+              onChange={(e) => setConfig({ ...config, typeOnlyPaths: splitPaths(e.target.value) })}
+              placeholder="**.createdAt"
+              className="font-mono text-xs"
+            />
+          </div>
+        </>
+      ) : config.assertionType === 'response' ? (
+        <>
+          <div>
+            <Label htmlFor="source">Source Node ID</Label>
+            <Input
+              id="source"
+              value={config.source || ''}
+              onChange={(e) => setConfig({ ...config, source: e.target.value || undefined })}
+              placeholder="Defaults to the first upstream node"
+              className="font-mono text-xs"
+            />
+          </div>
+          <div>
+            <Label htmlFor="status">Status</Label>
+            <Input
+              id="status"
+              value={config.status === undefined ? '' : [config.status].flat().join(', ')}
+              onChange={(e) => setConfig({ ...config, status: parseStatusSpec(e.target.value) })}
+              placeholder="2xx or 200, 201"
+              className="font-mono text-xs"
+            />
+          </div>
+          <div>
+            <Label htmlFor="contentType">Content Type</Label>
+            <Input
+              id="contentType"
+              value={config.contentType || ''}
+              onChange={(e) => setConfig({ ...config, contentType: e.target.value || undefined })}
+              placeholder="application/json"
+              className="font-mono text-xs"
+            />
+          </div>
+          <div className="grid grid-cols-2 gap-2">
+            <div>
+              <Label htmlFor="maxDurationMs">Max Duration (ms)</Label>
+              <Input
+                id="maxDurationMs"
+                type="number"
+                value={config.maxDurationMs ?? ''}
+                onChange={(e) => setConfig({ ...config, maxDurationMs: e.target.value ? Number(e.target.value) : undefined })}
+              />
+            </div>
+            <div>
+              <Label htmlFor="maxSizeBytes">Max Size (bytes)</Label>
+              <Input
+                id="maxSizeBytes"
+                type="number"
+                value={config.maxSizeBytes ?? ''}
+                onChange={(e) => setConfig({ ...config, maxSizeBytes: e.target.value ? Number(e.target.value) : undefined })}
+              />
+            </div>
+          </div>
+          <div>
+            <Label htmlFor="responseHeaders">Headers (JSON)</Label>
+            <Textarea
+              id="responseHeaders"
+              defaultValue={config.headers ? JSON.stringify(config.headers, null, 2) : ''}
+              onChange={(e) => {
+                try {
+                  const headers = e.target.value ? JSON.parse(e.target.value) : undefined
+                  setConfig({ ...config, headers })
+                } catch {
+                  // Invalid JSON
+                }
+              }}
+              placeholder='{"X-Request-Id": true, "Cache-Control": {"operator": "contains", "value": "no-store"}}'
+              className="font-mono text-xs"
+              rows={5}
+            />
+          </div>
+        </>
+      ) : (
       <div>
         <Label htmlFor="expected">Expected Value</Label>
         <Textarea
@@ -198,6 +393,7 @@ export const NodeSettings: React.FC<NodeSettingsProps> = ({ node, open, onClose,
           className="font-mono text-xs"
         />
       </div>
+      )}
       {config.assertionType === 'custom' && (
         <div>
           <Label htmlFor="customScript">Custom Script</Label>
@@ -205,11 +401,21 @@ export const NodeSettings: React.FC<NodeSettingsProps> = ({ node, open, onClose,
             id="customScript"
             value={config.customScript || ''}
             onChange={(e) => setConfig({ ...config, customScript: e.target.value })}
-            placeholder="// JavaScript verification code"
+            placeholder={'// input, response, outputs and variables are available\nassert(response.status === 200, "bad status")\nreturn response.body.items.length > 0'}
             className="font-mono text-xs"
             rows={10}
           />
         </div>
+        <div>
+          <Label htmlFor="timeoutMs">Timeout (ms)</Label>
+          <Input
+            id="timeoutMs"
+            type="number"
+            value={config.timeoutMs ?? ''}
+            onChange={(e) => setConfig({ ...config, timeoutMs: e.target.value ? parseInt(e.target.value) : undefined })}
+            placeholder="1000"
+          />
+        </div>
       )}
     </div>
   )
@@ -303,6 +509,22 @@ export const NodeSettings: React.FC<NodeSettingsProps> = ({ node, open, onClose,
             {node.type === NodeType.MOCK && renderMockSettings()}
             {node.type === NodeType.REPORT && renderReportSettings()}
             {node.type === NodeType.EVENT_TRIGGER && renderEventTriggerSettings()}
+            <div className="mt-4">
+              <Label htmlFor="inputMerge">Upstream Input</Label>
+              <Select
+                value={config.inputMerge || 'merge'}
+                onValueChange={(value) => setConfig({ ...config, inputMerge: value as NodeConfig['inputMerge'] })}
+              >
+                <SelectTrigger id="inputMerge">
+                  <SelectValue />
+                </SelectTrigger>
+                <SelectContent>
+                  <SelectItem value="merge">Merge data fields (later edges win)</SelectItem>
+                  <SelectItem value="strict">Merge data fields, fail on conflicts</SelectItem>
+                  <SelectItem value="none">Only by node ID</SelectItem>
+                </SelectContent>
+              </Select>
+            </div>
           </TabsContent>
           
           <TabsContent value="output" className="mt-4">
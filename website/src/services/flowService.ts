@@ -1,10 +1,12 @@
 import api from './api'
-import { Flow, TestRun } from '@/types'
+import { Flow, FlowSummary, TestRun } from '@/types'
 
 export const flowService = {
-  async getFlows(): Promise<Flow[]> {
-    const response = await api.get<Flow[]>('/flows')
-    return response.data
+  async getFlows(): Promise<FlowSummary[]> {
+    const response = await api.get<{ flows: FlowSummary[]; next_cursor: string }>('/flows', {
+      params: { limit: 100 },
+    })
+    return response.data.flows
   },
 
   async getFlow(id: string): Promise<Flow> {
@@ -18,7 +20,9 @@ export const flowService = {
   },
 
   async updateFlow(id: string, flow: Partial<Flow>): Promise<Flow> {
-    const response = await api.put<Flow>(`/flows/${id}`, flow)
+    // The server rejects saves based on a stale version with 409
+    const headers = flow.version !== undefined ? { 'If-Match': `"${flow.version}"` } : undefined
+    const response = await api.put<Flow>(`/flows/${id}`, flow, { headers })
     return response.data
   },
 
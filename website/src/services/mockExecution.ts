@@ -159,7 +159,8 @@ export class MockExecutionEngine {
         passed = true
         break
       case 'custom':
-        // Mock custom script execution
+      case 'snapshot':
+        // Scripts and snapshots are only evaluated by the server
         passed = true
         break
     }
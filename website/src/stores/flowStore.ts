@@ -1,9 +1,9 @@
 import { create } from 'zustand'
-import { Flow, FlowNode, FlowEdge, NodeData, NodeConfig, ExecutionStatus, NodeType } from '@/types'
+import { Flow, FlowSummary, FlowNode, FlowEdge, NodeData, NodeConfig, ExecutionStatus, NodeType } from '@/types'
 import { flowService } from '@/services/flowService'
 
 interface FlowState {
-  flows: Flow[]
+  flows: (Flow | FlowSummary)[]
   currentFlow: Flow | null
   selectedNode: NodeData | null
   isRunning: boolean
@@ -25,17 +25,69 @@ export interface NodeData {
   error?: string
 }
 
+export type AssertionOperator =
+  | 'eq' | 'ne' | 'gt' | 'gte' | 'lt' | 'lte' | 'in' | 'contains'
+  | 'startsWith' | 'matches' | 'exists' | 'isType' | 'length' | 'isEmpty'
+
+export interface FieldAssertion {
+  path: string
+  operator: AssertionOperator
+  value?: any
+}
+
+// Expected header for response assertions: presence, an exact value or an operator
+export type HeaderExpectation = boolean | string | { operator: AssertionOperator; value?: any }
+
+// Binds an API node to an operation of a library OpenAPI spec, by operationId or path template
+export interface ApiContract {
+  specId: string
+  operationId?: string
+  method?: string
+  path?: string
+  validateRequest?: boolean
+  validateResponse?: boolean
+}
+
+// Structured result of one assertion, e.g. a contract violation in a node result
+export interface AssertionResult {
+  path: string
+  operator: string
+  expected?: any
+  actual?: any
+  passed: boolean
+  message?: string
+}
+
+// Status for response assertions: a code, a class such as '2xx' or a list of either
+export type StatusSpec = number | string | (number | string)[]
+
 export interface NodeConfig {
+  // How upstream data fields are merged into the node input
+  inputMerge?: 'merge' | 'none' | 'strict'
+
   // API Node
   method?: string
   url?: string
-  headers?: Record<string, string>
+  headers?: Record<string, HeaderExpectation> // plain strings for API nodes
   body?: string
+  contract?: ApiContract
   
   // Verification Node
   expected?: any
-  assertionType?: 'equals' | 'contains' | 'regex' | 'custom'
+  assertionType?: 'equals' | 'contains' | 'regex' | 'custom' | 'snapshot' | 'assertions' | 'schema' | 'response'
+  assertions?: FieldAssertion[]
+  schema?: Record<string, any>
+  schemaId?: string
+  path?: string
+  ignorePaths?: string[]
+  typeOnlyPaths?: string[]
   customScript?: string
+  timeoutMs?: number
+  source?: string
+  status?: StatusSpec
+  contentType?: string
+  maxDurationMs?: number
+  maxSizeBytes?: number
   
   // Mock Node
   mockResponse?: any
@@ -71,10 +123,23 @@ export interface Flow {
   tags?: string[]
   nodes: FlowNode[]
   edges: FlowEdge[]
+  variables?: Record<string, any>
+  version?: number
   createdAt: string
   updatedAt: string
 }
 
+// Returned by the flow list instead of full flows; load nodes with getFlow
+export interface FlowSummary extends Omit<Flow, 'nodes' | 'edges'> {
+  nodes?: FlowNode[]
+  edges?: FlowEdge[]
+  node_count: number
+  node_counts: Record<string, number>
+  edge_count: number
+  last_run_status?: ExecutionStatus
+  last_run_at?: string
+}
+
 export interface TestRun {
   id: string
   flow_id: string
@@ -88,6 +153,8 @@ export interface TestRun {
     output?: any
     error?: string
     duration: number
+    quarantined?: boolean
+    assertions?: AssertionResult[]
   }>
   error?: string
   created_at: string